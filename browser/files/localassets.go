@@ -2,14 +2,18 @@ package files
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io/fs"
 	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/simulot/immich-go/browser"
 	"github.com/simulot/immich-go/helpers/fshelper"
+	"github.com/simulot/immich-go/helpers/gen"
 	"github.com/simulot/immich-go/immich/metadata"
 	"github.com/simulot/immich-go/logger"
 )
@@ -18,6 +22,13 @@ type LocalAssetBrowser struct {
 	fsyss  []fs.FS
 	albums map[string]string
 	log    *logger.Journal
+
+	NewerThan     time.Time // When set, files modified before are skipped without being read
+	LivePhotos    bool      // Pair the photo and the video of Live Photos
+	StartFrom     string    // When set, files before this path in the walk order are skipped
+	SidecarFormat string    // Format of the JSON sidecar files, see metadata.SidecarAuto
+	Keywords      bool      // Read the keywords of the images and of their XMP sidecars
+	Strict        bool      // Stop the browsing at the first error instead of skipping the file in error
 }
 
 func NewLocalFiles(ctx context.Context, log *logger.Journal, fsyss ...fs.FS) (*LocalAssetBrowser, error) {
@@ -25,6 +36,8 @@ func NewLocalFiles(ctx context.Context, log *logger.Journal, fsyss ...fs.FS) (*L
 		fsyss:  fsyss,
 		albums: map[string]string{},
 		log:    log,
+
+		SidecarFormat: metadata.SidecarAuto,
 	}, nil
 }
 
@@ -36,10 +49,19 @@ func (la *LocalAssetBrowser) Browse(ctx context.Context) chan *browser.LocalAsse
 	go func(ctx context.Context) {
 		defer close(fileChan)
 		for _, fsys := range la.fsyss {
+			var start []string
+			if la.StartFrom != "" {
+				start = startKey(fsys, la.StartFrom)
+			}
 			err := fs.WalkDir(fsys, ".",
 				func(name string, d fs.DirEntry, err error) error {
 					if err != nil {
-						return err
+						if la.Strict {
+							return err
+						}
+						// skip the file or the folder in error
+						la.log.AddEntry(name, logger.ERROR, err.Error())
+						return nil
 					}
 
 					// Check if the context has been cancelled
@@ -49,7 +71,10 @@ func (la *LocalAssetBrowser) Browse(ctx context.Context) chan *browser.LocalAsse
 						return ctx.Err()
 					default:
 						if d.IsDir() {
-							return la.handleFolder(ctx, fsys, fileChan, name)
+							if start != nil && skipFolder(start, name) {
+								return fs.SkipDir
+							}
+							return la.handleFolder(ctx, fsys, fileChan, name, start)
 						}
 					}
 					return nil
@@ -73,10 +98,14 @@ func (la *LocalAssetBrowser) Browse(ctx context.Context) chan *browser.LocalAsse
 	return fileChan
 }
 
-func (la *LocalAssetBrowser) handleFolder(ctx context.Context, fsys fs.FS, fileChan chan *browser.LocalAssetFile, folder string) error {
+func (la *LocalAssetBrowser) handleFolder(ctx context.Context, fsys fs.FS, fileChan chan *browser.LocalAssetFile, folder string, start []string) error {
 	entries, err := fs.ReadDir(fsys, folder)
 	if err != nil {
-		return err
+		if la.Strict {
+			return err
+		}
+		la.log.AddEntry(folder, logger.ERROR, err.Error())
+		return fs.SkipDir
 	}
 
 	fileMap := map[string][]fs.DirEntry{}
@@ -92,13 +121,35 @@ func (la *LocalAssetBrowser) handleFolder(ctx context.Context, fsys fs.FS, fileC
 		}
 	}
 
-	for _, es := range fileMap {
+	bases := gen.MapKeys(fileMap)
+	sort.Strings(bases)
+	for _, base := range bases {
+		es := fileMap[base]
+		if start != nil && skipFile(start, folder, base) {
+			for _, e := range es {
+				la.log.AddEntry(path.Join(folder, e.Name()), logger.NOT_SELECTED, "file before the -start-from path")
+			}
+			continue
+		}
+		var livePhoto, liveVideo string
+		if la.LivePhotos {
+			livePhoto, liveVideo = la.livePhotoPair(fsys, folder, es)
+		}
 
 		for _, e := range es {
 			fileName := path.Join(folder, e.Name())
 			la.log.AddEntry(fileName, logger.DISCOVERED_FILE, "")
 			name := e.Name()
 			ext := strings.ToLower(path.Ext(name))
+			if ext == ".xmp" {
+				// sidecars are attached to their asset by checkSidecar
+				continue
+			}
+			if name == liveVideo {
+				// uploaded with its photo
+				la.log.AddEntry(fileName, logger.LIVE_PHOTO, livePhoto)
+				continue
+			}
 			if fshelper.IsIgnoredExt(ext) {
 				la.log.AddEntry(fileName, logger.UNSUPPORTED, "")
 				continue
@@ -123,21 +174,37 @@ func (la *LocalAssetBrowser) handleFolder(ctx context.Context, fsys fs.FS, fileC
 				Err:       err,
 				DateTaken: metadata.TakeTimeFromName(filepath.Base(name)),
 			}
+			if name == livePhoto {
+				f.LivePhotoData = path.Join(folder, liveVideo)
+			}
 
 			s, err := e.Info()
 			if err != nil {
 				f.Err = err
 			} else {
+				if !la.NewerThan.IsZero() && s.ModTime().Before(la.NewerThan) {
+					la.log.AddEntry(fileName, logger.NOT_SELECTED, "file not modified since the last run")
+					continue
+				}
 				f.FileSize = int(s.Size())
 				if f.DateTaken.IsZero() {
 					err = la.ReadMetadataFromFile(&f)
 					_ = err
 					if f.DateTaken.Before(toOldDate) {
-						f.DateTaken = time.Now()
+						// The date is unknown
+						f.DateTaken = time.Time{}
 					}
 				}
-				if !la.checkSidecar(fsys, &f, name+".xmp") {
-					la.checkSidecar(fsys, &f, strings.TrimSuffix(name, ext)+".xmp")
+				if la.Keywords && ss[0] == "image" {
+					la.readKeywords(fsys, &f)
+				}
+				if !la.checkSidecar(fsys, &f, path.Join(folder, name+".xmp")) {
+					la.checkSidecar(fsys, &f, path.Join(folder, strings.TrimSuffix(name, path.Ext(name))+".xmp"))
+				}
+				if la.SidecarFormat != metadata.SidecarNone {
+					if !la.readJSONSidecar(fsys, &f, path.Join(folder, name+".json")) {
+						la.readJSONSidecar(fsys, &f, path.Join(folder, strings.TrimSuffix(name, path.Ext(name))+".json"))
+					}
 				}
 			}
 			// Check if the context has been cancelled
@@ -150,22 +217,150 @@ func (la *LocalAssetBrowser) handleFolder(ctx context.Context, fsys fs.FS, fileC
 			}
 		}
 	}
+	la.reportOrphans(folder, entries)
 	return nil
 }
 
+// reportOrphans reports the XMP and JSON sidecars of the folder whose media file is missing,
+// usually a media file that failed to export. The JSON files are sidecars when named like photo.jpg.json.
+func (la *LocalAssetBrowser) reportOrphans(folder string, entries []fs.DirEntry) {
+	media := map[string]bool{}
+	for _, e := range entries {
+		if _, err := fshelper.MimeFromExt(strings.ToLower(path.Ext(e.Name()))); err == nil && !e.IsDir() {
+			media[e.Name()] = true
+			media[strings.TrimSuffix(e.Name(), path.Ext(e.Name()))] = true
+		}
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		name := e.Name()
+		base := strings.TrimSuffix(name, path.Ext(name))
+		switch strings.ToLower(path.Ext(name)) {
+		case ".xmp":
+		case ".json":
+			if _, err := fshelper.MimeFromExt(strings.ToLower(path.Ext(base))); err != nil {
+				continue
+			}
+		default:
+			continue
+		}
+		if !media[base] {
+			la.log.AddEntry(path.Join(folder, name), logger.ORPHAN_SIDECAR, "no media file "+base)
+		}
+	}
+}
+
+// livePhotoPair returns the names of the photo and the video of a Live Photo when the group
+// of files sharing the same base name is made of one photo and one video having the same content identifier
+func (la *LocalAssetBrowser) livePhotoPair(fsys fs.FS, folder string, es []fs.DirEntry) (string, string) {
+	var photo, video string
+	for _, e := range es {
+		switch strings.ToLower(path.Ext(e.Name())) {
+		case ".xmp":
+		case ".heic", ".heif", ".jpg", ".jpeg":
+			if photo != "" {
+				return "", ""
+			}
+			photo = e.Name()
+		case ".mov", ".mp4":
+			if video != "" {
+				return "", ""
+			}
+			video = e.Name()
+		default:
+			return "", ""
+		}
+	}
+	if photo == "" || video == "" {
+		return "", ""
+	}
+	photoID, err := contentIdentifier(fsys, path.Join(folder, photo))
+	if err != nil {
+		return "", ""
+	}
+	videoID, err := contentIdentifier(fsys, path.Join(folder, video))
+	if err != nil || videoID != photoID {
+		return "", ""
+	}
+	return photo, video
+}
+
+func contentIdentifier(fsys fs.FS, name string) (string, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return metadata.GetContentIdentifier(f, path.Ext(name))
+}
+
+// checkSidecar attaches the XMP file name to the asset when it exists, and reads its rating.
+// The keywords of the sidecar take precedence over the ones of the file.
 func (la *LocalAssetBrowser) checkSidecar(fsys fs.FS, f *browser.LocalAssetFile, name string) bool {
-	_, err := fs.Stat(fsys, name+".xmp")
+	b, err := fs.ReadFile(fsys, name)
 	if err == nil {
 		la.log.AddEntry(name, logger.METADATA, "")
 		f.SideCar = &metadata.SideCar{
-			FileName: name + ".xmp",
+			FileName: name,
 			OnFSsys:  true,
 		}
+		f.Rating = metadata.XMPRating(b)
+		if la.Keywords {
+			if k := metadata.XMPKeywords(b); len(k) > 0 {
+				f.Tags = k
+			}
+		}
 		return true
 	}
 	return false
 }
 
+// readJSONSidecar sets the metadata found in the JSON sidecar file when it exists.
+// The values of the sidecar take precedence over the ones of the file.
+func (la *LocalAssetBrowser) readJSONSidecar(fsys fs.FS, f *browser.LocalAssetFile, name string) bool {
+	r, err := fsys.Open(name)
+	if err != nil {
+		return false
+	}
+	defer r.Close()
+	sc, err := metadata.ReadJSONSidecar(r, la.SidecarFormat)
+	if err != nil {
+		if errors.Is(err, metadata.ErrUnknownSidecar) && la.SidecarFormat == metadata.SidecarAuto {
+			la.log.AddEntry(name, logger.UNSUPPORTED, err.Error())
+		} else {
+			la.log.AddEntry(name, logger.ERROR, err.Error())
+			if la.Strict {
+				f.Err = fmt.Errorf("%s: %w", name, err)
+			}
+		}
+		return false
+	}
+	la.log.AddEntry(name, logger.METADATA, "")
+	f.JSONSideCar = name
+	if !sc.DateTaken.IsZero() {
+		f.DateTaken = sc.DateTaken
+	}
+	if sc.Latitude != 0 || sc.Longitude != 0 {
+		f.Latitude, f.Longitude, f.Altitude = sc.Latitude, sc.Longitude, sc.Altitude
+	}
+	if sc.Description != "" {
+		f.Description = sc.Description
+	}
+	return true
+}
+
+// readKeywords reads the keywords found in the file's metadata
+func (la *LocalAssetBrowser) readKeywords(fsys fs.FS, f *browser.LocalAssetFile) {
+	r, err := fsys.Open(f.FileName)
+	if err != nil {
+		return
+	}
+	defer r.Close()
+	f.Tags, _ = metadata.ReadKeywords(r)
+}
+
 func (la *LocalAssetBrowser) addAlbum(dir string) {
 	base := path.Base(dir)
 	la.albums[dir] = base
@@ -181,8 +376,12 @@ func (la *LocalAssetBrowser) ReadMetadataFromFile(a *browser.LocalAssetFile) err
 		return err
 	}
 	m, err := metadata.GetFromReader(r, ext)
+	a.Width, a.Height = m.Width, m.Height
 	if err == nil {
 		a.DateTaken = m.DateTaken
 	}
+	if a.Latitude == 0 && a.Longitude == 0 {
+		a.Latitude, a.Longitude, a.Altitude = m.Latitude, m.Longitude, m.Altitude
+	}
 	return err
 }
@@ -2,11 +2,15 @@ package files_test
 
 import (
 	"context"
+	"encoding/binary"
 	"errors"
+	"os"
 	"path"
+	"path/filepath"
 	"reflect"
 	"sort"
 	"testing"
+	"time"
 
 	"github.com/simulot/immich-go/browser/files"
 	"github.com/simulot/immich-go/logger"
@@ -40,7 +44,9 @@ func generateFS() *inMemFS {
 	return newInMemFS().
 		addFile("root_01.jpg").
 		addFile("photos/photo_01.jpg").
+		addFile("photos/photo_01.jpg.xmp").
 		addFile("photos/photo_02.cr3").
+		addFile("photos/photo_02.xmp").
 		addFile("photos/photo_03.jpg").
 		addFile("photos/summer 2023/20230801-001.jpg").
 		addFile("photos/summer 2023/20230801-002.jpg").
@@ -51,6 +57,7 @@ func TestLocalAssets(t *testing.T) {
 	tc := []struct {
 		name     string
 		expected []string
+		sidecars map[string]string
 	}{
 		{
 			name: "all",
@@ -63,6 +70,10 @@ func TestLocalAssets(t *testing.T) {
 				"photos/summer 2023/20230801-002.jpg",
 				"photos/summer 2023/20230801-003.cr3",
 			},
+			sidecars: map[string]string{
+				"photos/photo_01.jpg": "photos/photo_01.jpg.xmp",
+				"photos/photo_02.cr3": "photos/photo_02.xmp",
+			},
 		},
 	}
 
@@ -81,8 +92,12 @@ func TestLocalAssets(t *testing.T) {
 			}
 
 			results := []string{}
+			sidecars := map[string]string{}
 			for a := range b.Browse(ctx) {
 				results = append(results, a.FileName)
+				if a.SideCar != nil {
+					sidecars[a.FileName] = a.SideCar.FileName
+				}
 			}
 			sort.Strings(c.expected)
 			sort.Strings(results)
@@ -91,8 +106,327 @@ func TestLocalAssets(t *testing.T) {
 				t.Errorf("difference\n")
 				pretty.Ldiff(t, c.expected, results)
 			}
+			if !reflect.DeepEqual(sidecars, c.sidecars) {
+				t.Errorf("sidecars difference\n")
+				pretty.Ldiff(t, c.sidecars, sidecars)
+			}
 
 		})
 
 	}
 }
+
+func TestLocalAssetsNewerThan(t *testing.T) {
+	dir := t.TempDir()
+	lastRun := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	modTimes := map[string]time.Time{
+		"old.jpg":     lastRun.Add(-24 * time.Hour),
+		"new.jpg":     lastRun.Add(time.Hour),
+		"sub/new.mp4": lastRun.Add(time.Minute),
+		"sub/old.mp4": lastRun.Add(-time.Minute),
+	}
+	for name, mt := range modTimes {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(p, mt, mt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx := context.Background()
+	b, err := files.NewLocalFiles(ctx, logger.NewJournal(logger.NoLogger{}), os.DirFS(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.NewerThan = lastRun
+
+	results := []string{}
+	for a := range b.Browse(ctx) {
+		results = append(results, a.FileName)
+	}
+	sort.Strings(results)
+	expected := []string{"new.jpg", "sub/new.mp4"}
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("difference\n")
+		pretty.Ldiff(t, expected, results)
+	}
+}
+
+// livePhotoContent returns a minimal photo or video content carrying the Apple content identifier
+func livePhotoContent(video bool, id string) []byte {
+	if video {
+		return []byte("....ftypqt  \x00\x00\x00\x2emdtacom.apple.quicktime.content.identifier\x00\x00\x00\x34\x00\x00\x00\x01\x00\x00\x00\x2cdata\x00\x00\x00\x01\x00\x00\x00\x00" + id)
+	}
+	b := []byte("....ftypheicApple iOS\x00\x00\x01MM\x00\x01\x00\x11\x00\x02")
+	b = binary.BigEndian.AppendUint32(b, uint32(len(id)+1))
+	b = binary.BigEndian.AppendUint32(b, 16+12+4)
+	b = binary.BigEndian.AppendUint32(b, 0)
+	return append(b, id+"\x00"...)
+}
+
+func TestLocalAssetsLivePhotos(t *testing.T) {
+	const id1 = "2A7F0D3E-9C41-4B1E-8F2D-6E5A4B3C2D1E"
+	const id2 = "7B0E1C2D-3F4A-4B5C-9D6E-0F1A2B3C4D5E"
+	fsys := memfs.New()
+	content := map[string][]byte{
+		"IMG_0001.HEIC": livePhotoContent(false, id1),
+		"IMG_0001.MOV":  livePhotoContent(true, id1),
+		"IMG_0002.HEIC": livePhotoContent(false, id1),
+		"IMG_0002.MOV":  livePhotoContent(true, id2),
+		"IMG_0003.JPG":  livePhotoContent(false, id2),
+		"IMG_0003.MP4":  livePhotoContent(true, id2),
+		"IMG_0003.HEIC": livePhotoContent(false, id2),
+	}
+	for name, b := range content {
+		if err := fsys.WriteFile(name, b, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tc := []struct {
+		name     string
+		live     bool
+		expected map[string]string
+	}{
+		{
+			name: "live photos",
+			live: true,
+			expected: map[string]string{
+				"IMG_0001.HEIC": "IMG_0001.MOV",
+				"IMG_0002.HEIC": "",
+				"IMG_0002.MOV":  "",
+				"IMG_0003.JPG":  "",
+				"IMG_0003.MP4":  "",
+				"IMG_0003.HEIC": "",
+			},
+		},
+		{
+			name: "no live photos",
+			live: false,
+			expected: map[string]string{
+				"IMG_0001.HEIC": "",
+				"IMG_0001.MOV":  "",
+				"IMG_0002.HEIC": "",
+				"IMG_0002.MOV":  "",
+				"IMG_0003.JPG":  "",
+				"IMG_0003.MP4":  "",
+				"IMG_0003.HEIC": "",
+			},
+		},
+	}
+	for _, c := range tc {
+		t.Run(c.name, func(t *testing.T) {
+			ctx := context.Background()
+			b, err := files.NewLocalFiles(ctx, logger.NewJournal(logger.NoLogger{}), fsys)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b.LivePhotos = c.live
+
+			results := map[string]string{}
+			for a := range b.Browse(ctx) {
+				results[a.FileName] = a.LivePhotoData
+			}
+			if !reflect.DeepEqual(results, c.expected) {
+				t.Errorf("difference\n")
+				pretty.Ldiff(t, c.expected, results)
+			}
+		})
+	}
+}
+
+func TestLocalAssetsStartFrom(t *testing.T) {
+	fsys := generateFS()
+	fsys.addFile("photos/photo_02-1.jpg").
+		addFile("photos/summer 2023/august/20230815-001.jpg").
+		addFile("zoo/zoo_01.jpg")
+	if fsys.err != nil {
+		t.Fatal(fsys.err)
+	}
+
+	tc := []struct {
+		start    string
+		expected []string
+	}{
+		{
+			start: "",
+			expected: []string{
+				"root_01.jpg",
+				"photos/photo_01.jpg",
+				"photos/photo_02.cr3",
+				"photos/photo_02-1.jpg",
+				"photos/photo_03.jpg",
+				"photos/summer 2023/20230801-001.jpg",
+				"photos/summer 2023/20230801-002.jpg",
+				"photos/summer 2023/20230801-003.cr3",
+				"photos/summer 2023/august/20230815-001.jpg",
+				"zoo/zoo_01.jpg",
+			},
+		},
+		{
+			start: "photos/photo_02.cr3",
+			expected: []string{
+				"photos/photo_02.cr3",
+				"photos/photo_02-1.jpg",
+				"photos/photo_03.jpg",
+				"photos/summer 2023/20230801-001.jpg",
+				"photos/summer 2023/20230801-002.jpg",
+				"photos/summer 2023/20230801-003.cr3",
+				"photos/summer 2023/august/20230815-001.jpg",
+				"zoo/zoo_01.jpg",
+			},
+		},
+		{
+			start: "photos/summer 2023/",
+			expected: []string{
+				"photos/summer 2023/20230801-001.jpg",
+				"photos/summer 2023/20230801-002.jpg",
+				"photos/summer 2023/20230801-003.cr3",
+				"photos/summer 2023/august/20230815-001.jpg",
+				"zoo/zoo_01.jpg",
+			},
+		},
+		{
+			start: "photos/summer 2023/august",
+			expected: []string{
+				"photos/summer 2023/august/20230815-001.jpg",
+				"zoo/zoo_01.jpg",
+			},
+		},
+		{
+			start: "photos/summer 2023/20230801-002.jpg",
+			expected: []string{
+				"photos/summer 2023/20230801-002.jpg",
+				"photos/summer 2023/20230801-003.cr3",
+				"photos/summer 2023/august/20230815-001.jpg",
+				"zoo/zoo_01.jpg",
+			},
+		},
+		{
+			start:    "zoo/zzz",
+			expected: []string{},
+		},
+	}
+	for _, c := range tc {
+		t.Run(c.start, func(t *testing.T) {
+			ctx := context.Background()
+			b, err := files.NewLocalFiles(ctx, logger.NewJournal(logger.NoLogger{}), fsys)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b.StartFrom = c.start
+
+			results := []string{}
+			for a := range b.Browse(ctx) {
+				results = append(results, a.FileName)
+			}
+			if !reflect.DeepEqual(results, c.expected) {
+				t.Errorf("difference\n")
+				pretty.Ldiff(t, c.expected, results)
+			}
+		})
+	}
+}
+
+func TestLocalAssetsJSONSidecar(t *testing.T) {
+	fsys := memfs.New()
+	content := map[string]string{
+		"photo_01.jpg":      "photo_01.jpg",
+		"photo_01.jpg.json": `[{"SourceFile":"photo_01.jpg","DateTimeOriginal":"2023:08:01 10:20:30Z","GPSLatitude":48.5,"GPSLongitude":2.5,"Description":"summer"}]`,
+		"photo_02.jpg":      "photo_02.jpg",
+		"photo_02.json":     `[{"SourceFile":"photo_02.jpg","GPSLatitude":10,"GPSLongitude":20}]`,
+		"photo_03.jpg":      "photo_03.jpg",
+		"photo_03.jpg.json": `{"title":"photo_03.jpg"}`,
+	}
+	for name, b := range content {
+		if err := fsys.WriteFile(name, []byte(b), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	type result struct {
+		date        string
+		lat, lon    float64
+		description string
+		sidecar     string
+	}
+	tc := []struct {
+		format   string
+		expected map[string]result
+	}{
+		{
+			format: "auto",
+			expected: map[string]result{
+				"photo_01.jpg": {date: "2023-08-01T10:20:30Z", lat: 48.5, lon: 2.5, description: "summer", sidecar: "photo_01.jpg.json"},
+				"photo_02.jpg": {lat: 10, lon: 20, sidecar: "photo_02.json"},
+				"photo_03.jpg": {},
+			},
+		},
+		{
+			format: "none",
+			expected: map[string]result{
+				"photo_01.jpg": {},
+				"photo_02.jpg": {},
+				"photo_03.jpg": {},
+			},
+		},
+	}
+	for _, c := range tc {
+		t.Run(c.format, func(t *testing.T) {
+			ctx := context.Background()
+			b, err := files.NewLocalFiles(ctx, logger.NewJournal(logger.NoLogger{}), fsys)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b.SidecarFormat = c.format
+
+			results := map[string]result{}
+			for a := range b.Browse(ctx) {
+				r := result{lat: a.Latitude, lon: a.Longitude, description: a.Description, sidecar: a.JSONSideCar}
+				if !a.DateTaken.IsZero() {
+					r.date = a.DateTaken.UTC().Format(time.RFC3339)
+				}
+				results[a.FileName] = r
+			}
+			if !reflect.DeepEqual(results, c.expected) {
+				t.Errorf("difference\n")
+				pretty.Ldiff(t, c.expected, results)
+			}
+		})
+	}
+}
+
+func TestLocalAssetsOrphanSidecars(t *testing.T) {
+	fsys := memfs.New()
+	for _, name := range []string{
+		"photo_01.jpg", "photo_01.xmp",
+		"photo_02.jpg", "photo_02.jpg.xmp", "photo_02.jpg.json",
+		"photo_03.xmp",
+		"photo_04.jpg.json",
+		"photo_05.heic.xmp",
+		"notes.json",
+	} {
+		if err := fsys.WriteFile(name, []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx := context.Background()
+	jnl := logger.NewJournal(logger.NoLogger{})
+	jnl.KeepFiles(logger.ORPHAN_SIDECAR)
+	b, err := files.NewLocalFiles(ctx, jnl, fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range b.Browse(ctx) {
+	}
+	got := jnl.Files(logger.ORPHAN_SIDECAR)
+	sort.Strings(got)
+	want := []string{"photo_03.xmp", "photo_04.jpg.json", "photo_05.heic.xmp"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("orphans = %v, want %v", got, want)
+	}
+}
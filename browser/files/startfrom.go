@@ -0,0 +1,50 @@
+package files
+
+import (
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+)
+
+// The folders are walked in the lexical order of their names. The files of a folder
+// are handled before its sub-folders, in the lexical order of their names without extension.
+// This order is used by StartFrom to skip the files handled by a previous run.
+
+// walkKey gives the position of a folder or of a group of files in the walk order.
+// The empty component makes the files of a folder come before its sub-folders.
+func walkKey(folder string, base string) []string {
+	k := []string{}
+	if folder != "." && folder != "" {
+		k = strings.Split(folder, "/")
+	}
+	if base != "" {
+		k = append(k, "", base)
+	}
+	return k
+}
+
+// startKey gives the position of the -start-from path in the file system
+func startKey(fsys fs.FS, start string) []string {
+	start = strings.Trim(path.Clean(strings.ReplaceAll(start, "\\", "/")), "/")
+	if s, err := fs.Stat(fsys, start); err == nil && s.IsDir() {
+		return walkKey(start, "")
+	}
+	name := path.Base(start)
+	return walkKey(path.Dir(start), strings.TrimSuffix(name, path.Ext(name)))
+}
+
+// skipFolder tells if all the files of the folder and its sub-folders are before the start key
+func skipFolder(start []string, folder string) bool {
+	k := walkKey(folder, "")
+	if len(k) <= len(start) && slices.Equal(k, start[:len(k)]) {
+		// the start is inside the folder
+		return false
+	}
+	return slices.Compare(k, start) < 0
+}
+
+// skipFile tells if the group of files is before the start key
+func skipFile(start []string, folder string, base string) bool {
+	return slices.Compare(walkKey(folder, base), start) < 0
+}
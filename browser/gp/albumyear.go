@@ -0,0 +1,49 @@
+package gp
+
+import (
+	"fmt"
+
+	"github.com/simulot/immich-go/logger"
+)
+
+// suffixAlbumYears appends the year to the titles of the albums sharing the same title,
+// like "Birthday (2021)" and "Birthday (2022)", so they stay separate on the server.
+// The year of an album is the year of capture of its oldest asset.
+func (to *Takeout) suffixAlbumYears() {
+	byTitle := map[string][]string{} // album folders by title
+	for dir, al := range to.albums {
+		if al.Name != "" {
+			byTitle[al.Name] = append(byTitle[al.Name], dir)
+		}
+	}
+
+	years := map[string]int{} // year of the oldest asset by folder
+	for _, md := range to.jsonByYear {
+		t := md.PhotoTakenTime.Time()
+		if t.IsZero() {
+			continue
+		}
+		for _, p := range md.foundInPaths {
+			if y, ok := years[p]; !ok || t.Year() < y {
+				years[p] = t.Year()
+			}
+		}
+	}
+
+	for title, dirs := range byTitle {
+		if len(dirs) < 2 {
+			continue
+		}
+		for _, dir := range dirs {
+			y, ok := years[dir]
+			if !ok {
+				to.jnl.Warning("Can't find the year of the album %q in %s, its title is kept", title, dir)
+				continue
+			}
+			al := to.albums[dir]
+			al.Name = fmt.Sprintf("%s (%d)", title, y)
+			to.albums[dir] = al
+			to.jnl.AddEntry(dir, logger.METADATA, "Album title: "+al.Name)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package gp
+
+import (
+	"context"
+	"path"
+	"reflect"
+	"testing"
+
+	"github.com/simulot/immich-go/logger"
+)
+
+func sameAlbumTitles() *inMemFS {
+	return newInMemFS().
+		addJSONAlbum("Takeout/Google Photos/Birthday/metadata.json", "Birthday").
+		addJSONImage("Takeout/Google Photos/Birthday/IMG_2021.jpg.json", "IMG_2021.jpg", takenTime("20210612")).
+		addImage("Takeout/Google Photos/Birthday/IMG_2021.jpg", 10).
+		addJSONAlbum("Takeout/Google Photos/Birthday(1)/metadata.json", "Birthday").
+		addJSONImage("Takeout/Google Photos/Birthday(1)/IMG_2022.jpg.json", "IMG_2022.jpg", takenTime("20220612")).
+		addImage("Takeout/Google Photos/Birthday(1)/IMG_2022.jpg", 20).
+		addJSONImage("Takeout/Google Photos/Birthday(1)/IMG_2023.jpg.json", "IMG_2023.jpg", takenTime("20230101")).
+		addImage("Takeout/Google Photos/Birthday(1)/IMG_2023.jpg", 30).
+		addJSONAlbum("Takeout/Google Photos/Holidays/metadata.json", "Holidays").
+		addJSONImage("Takeout/Google Photos/Holidays/IMG_0001.jpg.json", "IMG_0001.jpg", takenTime("20220801")).
+		addImage("Takeout/Google Photos/Holidays/IMG_0001.jpg", 40)
+}
+
+func TestAlbumYearSuffix(t *testing.T) {
+	tests := []struct {
+		suffix bool
+		albums map[string]string // file -> album
+	}{
+		{
+			suffix: false,
+			albums: map[string]string{"IMG_2021.jpg": "Birthday", "IMG_2022.jpg": "Birthday", "IMG_2023.jpg": "Birthday", "IMG_0001.jpg": "Holidays"},
+		},
+		{
+			suffix: true,
+			albums: map[string]string{"IMG_2021.jpg": "Birthday (2021)", "IMG_2022.jpg": "Birthday (2022)", "IMG_2023.jpg": "Birthday (2022)", "IMG_0001.jpg": "Holidays"},
+		},
+	}
+	for _, tt := range tests {
+		fsys := sameAlbumTitles()
+		if fsys.err != nil {
+			t.Fatal(fsys.err)
+		}
+		ctx := context.Background()
+		b, err := NewTakeout(ctx, logger.NewJournal(logger.NoLogger{}), fsys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b.AlbumYearSuffix = tt.suffix
+
+		albums := map[string]string{}
+		for a := range b.Browse(ctx) {
+			for _, al := range a.Albums {
+				albums[path.Base(a.FileName)] = al.Name
+			}
+		}
+		if !reflect.DeepEqual(albums, tt.albums) {
+			t.Errorf("suffix %v: albums %v, want %v", tt.suffix, albums, tt.albums)
+		}
+	}
+}
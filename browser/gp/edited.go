@@ -0,0 +1,103 @@
+package gp
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// Values of the -keep-edited policy
+const (
+	KeepEditedOriginal = "original" // upload the original file only
+	KeepEditedEdited   = "edited"   // upload the edited file only, as shown by Google Photos
+	KeepEditedBoth     = "both"     // upload both files
+)
+
+func ParseKeepEdited(s string) (string, error) {
+	switch k := strings.ToLower(s); k {
+	case KeepEditedOriginal, KeepEditedEdited, KeepEditedBoth:
+		return k, nil
+	}
+	return "", fmt.Errorf("unknown -keep-edited policy %q, expecting %s, %s or %s", s, KeepEditedOriginal, KeepEditedEdited, KeepEditedBoth)
+}
+
+// editedSuffixes are the suffixes given to the edited files, in the languages of the takeout
+var editedSuffixes = []string{
+	"-edited",
+	"-modifié",
+	"-bearbeitet",
+	"-editado",
+	"-modificato",
+	"-bewerkt",
+	"-redigeret",
+	"-redigert",
+	"-redigerad",
+	"-muokattu",
+}
+
+// originalName gives the name of the original file of an edited file,
+// an empty string when the name isn't the one of an edited file
+//
+//	IMG_1234-edited.jpg
+//	IMG_1234.jpg
+func originalName(name string) string {
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for _, s := range editedSuffixes {
+		if strings.HasSuffix(base, s) {
+			return strings.TrimSuffix(base, s) + ext
+		}
+	}
+	return ""
+}
+
+// pairEdited finds the edited files having their original in the same folder, and discards
+// one of them according to the KeepEdited policy.
+// The kept file takes the metadata of the discarded one when it has none, and is
+// added into its albums.
+func (to *Takeout) pairEdited() {
+	if to.KeepEdited == KeepEditedBoth {
+		return
+	}
+
+	// the files of a folder can be in several parts of a split takeout
+	byDir := map[string]map[string]fs.FS{}
+	for _, w := range to.fsyss {
+		for dir, l := range to.catalogs[w] {
+			if byDir[dir] == nil {
+				byDir[dir] = map[string]fs.FS{}
+			}
+			for f := range l.files {
+				byDir[dir][f] = w
+			}
+		}
+	}
+
+	for dir, files := range byDir {
+		for edited, we := range files {
+			original := originalName(edited)
+			if original == "" {
+				continue
+			}
+			wo, ok := files[original]
+			if !ok {
+				continue
+			}
+			keep, keepW, drop, dropW := edited, we, original, wo
+			if to.KeepEdited == KeepEditedOriginal {
+				keep, keepW, drop, dropW = original, wo, edited, we
+			}
+			ki := to.catalogs[keepW][dir].files[keep]
+			di := to.catalogs[dropW][dir].files[drop]
+			if ki.md == nil {
+				ki.md = di.md
+			} else if di.md != ki.md {
+				ki.sibling = di.md
+			}
+			di.discardedFor = path.Join(dir, keep)
+			to.catalogs[keepW][dir].files[keep] = ki
+			to.catalogs[dropW][dir].files[drop] = di
+		}
+	}
+}
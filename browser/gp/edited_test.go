@@ -0,0 +1,93 @@
+package gp
+
+import (
+	"context"
+	"path"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/simulot/immich-go/logger"
+
+	"github.com/kr/pretty"
+)
+
+func Test_originalName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "IMG_1234-edited.jpg", want: "IMG_1234.jpg"},
+		{name: "PXL_20220405_090123740.PORTRAIT-modifié.jpg", want: "PXL_20220405_090123740.PORTRAIT.jpg"},
+		{name: "IMG_1234-bearbeitet.HEIC", want: "IMG_1234.HEIC"},
+		{name: "IMG_1234.jpg", want: ""},
+		{name: "IMG_1234-edited(1).jpg", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := originalName(tt.name); got != tt.want {
+				t.Errorf("originalName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeepEdited(t *testing.T) {
+	tests := []struct {
+		policy string
+		files  []string          // uploaded files
+		albums map[string]string // file -> album
+	}{
+		{
+			policy: KeepEditedEdited,
+			files:  []string{"IMG_1234-edited.jpg", "IMG_5678.jpg"},
+			albums: map[string]string{"IMG_1234-edited.jpg": "Holidays"},
+		},
+		{
+			policy: KeepEditedOriginal,
+			files:  []string{"IMG_1234.jpg", "IMG_5678.jpg"},
+			albums: map[string]string{"IMG_1234.jpg": "Holidays"},
+		},
+		{
+			policy: KeepEditedBoth,
+			files:  []string{"IMG_1234-edited.jpg", "IMG_1234.jpg", "IMG_5678.jpg"},
+			albums: map[string]string{"IMG_1234-edited.jpg": "Holidays", "IMG_1234.jpg": "Holidays"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			fsys := editedInAlbum()
+			if fsys.err != nil {
+				t.Fatal(fsys.err)
+			}
+			ctx := context.Background()
+			b, err := NewTakeout(ctx, logger.NewJournal(logger.NoLogger{}), fsys)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b.KeepEdited = tt.policy
+
+			files := []string{}
+			albums := map[string]string{}
+			for a := range b.Browse(ctx) {
+				name := path.Base(a.FileName)
+				files = append(files, name)
+				if a.Title != "IMG_1234.jpg" && a.Title != "IMG_5678.jpg" {
+					t.Errorf("%s: unexpected title %q", name, a.Title)
+				}
+				for _, al := range a.Albums {
+					albums[name] = al.Name
+				}
+			}
+			sort.Strings(files)
+			if !reflect.DeepEqual(files, tt.files) {
+				t.Errorf("files difference\n")
+				pretty.Ldiff(t, tt.files, files)
+			}
+			if !reflect.DeepEqual(albums, tt.albums) {
+				t.Errorf("albums difference\n")
+				pretty.Ldiff(t, tt.albums, albums)
+			}
+		})
+	}
+}
@@ -2,6 +2,8 @@ package gp
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io/fs"
 	"path"
@@ -19,11 +21,17 @@ import (
 
 type Takeout struct {
 	fsyss      []fs.FS
-	catalogs   map[fs.FS]walkerCatalog     // file catalogs by walker
-	jsonByYear map[jsonKey]*GoogleMetaData // assets by year of capture and base name
-	uploaded   map[fileKey]any             // track files already uploaded
-	albums     map[string]string           // tack album names by folder
+	catalogs   map[fs.FS]walkerCatalog       // file catalogs by walker
+	jsonByYear map[jsonKey]*GoogleMetaData   // assets by year of capture and base name
+	uploaded   map[fileKey]string            // track files already uploaded, with the name of their first copy
+	albums     map[string]browser.LocalAlbum // tack albums by folder
+	orphans    int                           // number of JSON files without media file
+	err        error                         // first error met while reading the takeout
 	jnl        *logger.Journal
+
+	KeepEdited      string // Policy for the pairs of edited and original files: KeepEditedOriginal, KeepEditedEdited or KeepEditedBoth
+	AlbumYearSuffix bool   // Append the year to the titles of the albums sharing the same title
+	Strict          bool   // Stop the browsing at the first error instead of skipping the file in error
 }
 
 // walkerCatalog collects all directory catalogs
@@ -38,8 +46,10 @@ type directoryCatalog struct {
 
 // fileInfo keep information collected during pass one
 type fileInfo struct {
-	length int             // file length in bytes
-	md     *GoogleMetaData // will point to the associated metadata
+	length       int             // file length in bytes
+	md           *GoogleMetaData // will point to the associated metadata
+	sibling      *GoogleMetaData // metadata of the discarded edited or original file, giving its albums
+	discardedFor string          // the file is discarded in favor of its edited or original file
 }
 
 // fileKey is the key of the uploaded files map
@@ -64,8 +74,9 @@ func NewTakeout(ctx context.Context, jnl *logger.Journal, fsyss ...fs.FS) (*Take
 	to := Takeout{
 		fsyss:      fsyss,
 		jsonByYear: map[jsonKey]*GoogleMetaData{},
-		albums:     map[string]string{},
+		albums:     map[string]browser.LocalAlbum{},
 		jnl:        jnl,
+		KeepEdited: KeepEditedEdited,
 	}
 	err := to.passOne(ctx)
 	if err != nil {
@@ -73,11 +84,40 @@ func NewTakeout(ctx context.Context, jnl *logger.Journal, fsyss ...fs.FS) (*Take
 	}
 
 	to.solvePuzzle(ctx)
+	to.flagSharedAlbums()
 	return &to, err
 }
 
+// flagSharedAlbums flags the albums shared with the user by other users:
+// all their assets come from a shared album, none is one of the user's assets.
+func (to *Takeout) flagSharedAlbums() {
+	own := map[string]bool{}
+	found := map[string]bool{}
+	for _, md := range to.jsonByYear {
+		for _, p := range md.foundInPaths {
+			if _, exists := to.albums[p]; !exists {
+				continue
+			}
+			found[p] = true
+			if !md.isShared() {
+				own[p] = true
+			}
+		}
+	}
+	for p, album := range to.albums {
+		if found[p] && !own[p] {
+			album.Shared = true
+			to.albums[p] = album
+			to.jnl.AddEntry(p, logger.METADATA, "Album shared with the user: "+album.Name)
+		}
+	}
+}
+
 // passOne scans all files in all walker to build the file catalog of the archive
 // metadata files content is read and kept
+//
+// All the parts of a split takeout (takeout-001.zip, takeout-002.zip...) are scanned before
+// associating the files with their metadata, so a file and its JSON can be in different parts.
 
 func (to *Takeout) passOne(ctx context.Context) error {
 	to.catalogs = map[fs.FS]walkerCatalog{}
@@ -96,7 +136,7 @@ func (to *Takeout) passOneFsWalk(ctx context.Context, w fs.FS) error {
 	err := fs.WalkDir(w, ".", func(name string, d fs.DirEntry, err error) error {
 
 		if err != nil {
-			return err
+			return to.fileError(name, err)
 		}
 
 		select {
@@ -124,7 +164,7 @@ func (to *Takeout) passOneFsWalk(ctx context.Context, w fs.FS) error {
 			}
 			finfo, err := d.Info()
 			if err != nil {
-				return err
+				return to.fileError(name, err)
 			}
 			switch ext {
 			case ".json":
@@ -135,13 +175,18 @@ func (to *Takeout) passOneFsWalk(ctx context.Context, w fs.FS) error {
 						to.addJson(w, dir, base, md)
 						to.jnl.AddEntry(name, logger.METADATA, "Asset Title: "+md.Title)
 					case md.isAlbum():
-						to.albums[dir] = md.Title
+						to.albums[dir] = browser.LocalAlbum{Path: dir, Name: md.Title, Description: md.Description, Cover: md.CoverPhoto}
 						to.jnl.AddEntry(name, logger.METADATA, "Album title: "+md.Title)
 					default:
 						to.jnl.AddEntry(name, logger.DISCARDED, "Unknown json file")
 						return nil
 					}
 				} else {
+					var typeErr *json.UnmarshalTypeError
+					if !errors.As(err, &typeErr) {
+						// the file is damaged, like a truncated JSON
+						return to.fileError(name, fmt.Errorf("can't read the JSON file: %w", err))
+					}
 					to.jnl.AddEntry(name, logger.DISCARDED, "Unknown json file")
 					return nil
 				}
@@ -179,6 +224,16 @@ func (to *Takeout) passOneFsWalk(ctx context.Context, w fs.FS) error {
 	return err
 }
 
+// fileError journals the error of the file and lets the walk continue.
+// The first error is kept to stop the browsing in Strict mode.
+func (to *Takeout) fileError(name string, err error) error {
+	to.jnl.AddEntry(name, logger.ERROR, err.Error())
+	if to.err == nil {
+		to.err = fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
 // addJson stores metadata and all paths where the combo base+year has been found
 func (to *Takeout) addJson(w fs.FS, dir, base string, md *GoogleMetaData) {
 	k := jsonKey{
@@ -189,7 +244,10 @@ func (to *Takeout) addJson(w fs.FS, dir, base string, md *GoogleMetaData) {
 	if mdPresent, ok := to.jsonByYear[k]; ok {
 		md = mdPresent
 	}
-	md.foundInPaths = append(md.foundInPaths, dir)
+	// The same metadata file can be repeated in several parts of a split takeout
+	if !slices.Contains(md.foundInPaths, dir) {
+		md.foundInPaths = append(md.foundInPaths, dir)
+	}
 	to.jsonByYear[k] = md
 }
 
@@ -243,6 +301,7 @@ func (to *Takeout) solvePuzzle(ctx context.Context) error {
 		return jsonKeys[i].name < jsonKeys[j].name
 	})
 
+	matched := map[jsonKey]bool{}
 	// For the most common matcher to the least,
 	for _, matcher := range matchers {
 		// Check files that match each json files
@@ -262,6 +321,7 @@ func (to *Takeout) solvePuzzle(ctx context.Context) error {
 						if l.files[f].md == nil {
 							if matcher(k.name, f) {
 								to.jnl.AddEntry(path.Join(d, f), logger.ASSOCIATED_META, fmt.Sprintf("%s (%d)", k.name, k.year))
+								matched[k] = true
 								// if not already matched
 								i := l.files[f]
 								i.md = md
@@ -274,6 +334,17 @@ func (to *Takeout) solvePuzzle(ctx context.Context) error {
 			}
 		}
 	}
+
+	// The JSON files without asset usually come from a media file that failed to export
+	for _, k := range jsonKeys {
+		if matched[k] {
+			continue
+		}
+		to.orphans++
+		for _, d := range to.jsonByYear[k].foundInPaths {
+			to.jnl.AddEntry(path.Join(d, k.name), logger.ORPHAN_SIDECAR, "no media file for "+to.jsonByYear[k].Title)
+		}
+	}
 	return nil
 }
 
@@ -404,15 +475,26 @@ func matchForgottenDuplicates(jsonName string, fileName string) bool {
 // each file net yet sent to immich is sent with associated metadata
 
 func (to *Takeout) Browse(ctx context.Context) chan *browser.LocalAssetFile {
-	to.uploaded = map[fileKey]any{}
+	to.uploaded = map[fileKey]string{}
+	to.pairEdited()
+	if to.AlbumYearSuffix {
+		to.suffixAlbumYears()
+	}
 	assetChan := make(chan *browser.LocalAssetFile)
 
 	go func() {
 		defer close(assetChan)
+		if to.Strict && to.err != nil {
+			assetChan <- &browser.LocalAssetFile{Err: to.err}
+			return
+		}
 		for _, w := range to.fsyss {
 			err := to.passTwoWalk(ctx, w, assetChan)
 			if err != nil {
 				assetChan <- &browser.LocalAssetFile{Err: err}
+				if to.Strict {
+					return
+				}
 			}
 		}
 	}()
@@ -423,6 +505,9 @@ func (to *Takeout) passTwoWalk(ctx context.Context, w fs.FS, assetChan chan *bro
 	to.jnl.OK("Ready to upload files")
 	return fs.WalkDir(w, ".", func(name string, d fs.DirEntry, err error) error {
 		if err != nil {
+			if to.Strict {
+				return err
+			}
 			return nil
 		}
 
@@ -446,12 +531,20 @@ func (to *Takeout) passTwoWalk(ctx context.Context, w fs.FS, assetChan chan *bro
 			return nil
 		}
 
+		if f.discardedFor != "" {
+			to.jnl.AddEntry(name, logger.DISCARDED, "-keep-edited "+to.KeepEdited+": "+f.discardedFor+" is kept")
+			return nil
+		}
+
 		if f.md == nil {
 			to.jnl.AddEntry(name, logger.ERROR, "JSON File not found for this file")
 			return nil
 		}
 		finfo, err := d.Info()
 		if err != nil {
+			if to.Strict {
+				return err
+			}
 			to.jnl.Error("can't browse: %s", err)
 			return nil
 		}
@@ -461,16 +554,24 @@ func (to *Takeout) passTwoWalk(ctx context.Context, w fs.FS, assetChan chan *bro
 			length: int(finfo.Size()),
 			year:   f.md.PhotoTakenTime.Time().Year(),
 		}
-		if _, exists := to.uploaded[key]; exists {
-			to.jnl.AddEntry(name, logger.LOCAL_DUPLICATE, "")
+		if first, exists := to.uploaded[key]; exists {
+			to.jnl.AddEntry(name, logger.LOCAL_DUPLICATE, "same as "+first)
 			return nil
 		}
 		a := to.googleMDToAsset(f.md, key, w, name)
+		if f.sibling != nil {
+			// keep the albums of the discarded file
+			for _, p := range f.sibling.foundInPaths {
+				if album, exists := to.albums[p]; exists {
+					a.AddAlbum(album)
+				}
+			}
+		}
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
 		case assetChan <- a: // the consumer must call a.File.Release()
-			to.uploaded[key] = nil // remember we have seen this file already
+			to.uploaded[key] = name // remember we have seen this file already
 		}
 		return nil
 	})
@@ -491,14 +592,15 @@ func (to *Takeout) googleMDToAsset(md *GoogleMetaData, key fileKey, fsys fs.FS,
 		}
 	}
 
+	location := md.location()
 	a := browser.LocalAssetFile{
 		FileName:    name,
 		FileSize:    key.length,
 		Title:       title,
 		Description: md.Description,
-		Altitude:    md.GeoDataExif.Altitude,
-		Latitude:    md.GeoDataExif.Latitude,
-		Longitude:   md.GeoDataExif.Longitude,
+		Altitude:    location.Altitude,
+		Latitude:    location.Latitude,
+		Longitude:   location.Longitude,
 		Archived:    md.Archived,
 		FromPartner: md.isPartner(),
 		Trashed:     md.Trashed,
@@ -507,9 +609,15 @@ func (to *Takeout) googleMDToAsset(md *GoogleMetaData, key fileKey, fsys fs.FS,
 		FSys:        fsys,
 	}
 
+	for _, p := range md.People {
+		if p.Name != "" {
+			a.People = append(a.People, p.Name)
+		}
+	}
+
 	for _, p := range md.foundInPaths {
 		if album, exists := to.albums[p]; exists {
-			a.Albums = append(a.Albums, browser.LocalAlbum{Path: p, Name: album})
+			a.Albums = append(a.Albums, album)
 		}
 	}
 	return &a
@@ -1,6 +1,16 @@
 package gp
 
-import "testing"
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/logger"
+)
 
 func Test_matchEditedName(t *testing.T) {
 	tests := []struct {
@@ -121,3 +131,212 @@ func Test_matchForgottenDuplicates(t *testing.T) {
 		})
 	}
 }
+
+func TestFavorited(t *testing.T) {
+	fsys := newInMemFS().
+		addJSONImage("Takeout/Google Photos/Photos from 2023/IMG_0001.jpg.json", "IMG_0001.jpg", favorited()).
+		addImage("Takeout/Google Photos/Photos from 2023/IMG_0001.jpg", 10).
+		addJSONImage("Takeout/Google Photos/Photos from 2023/IMG_0002.jpg.json", "IMG_0002.jpg").
+		addImage("Takeout/Google Photos/Photos from 2023/IMG_0002.jpg", 20)
+	if fsys.err != nil {
+		t.Fatal(fsys.err)
+	}
+	ctx := context.Background()
+	b, err := NewTakeout(ctx, logger.NewJournal(logger.NoLogger{}), fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	favorites := map[string]bool{}
+	for a := range b.Browse(ctx) {
+		favorites[a.Title] = a.Favorite
+	}
+	want := map[string]bool{"IMG_0001.jpg": true, "IMG_0002.jpg": false}
+	if !reflect.DeepEqual(favorites, want) {
+		t.Errorf("favorites %v, want %v", favorites, want)
+	}
+}
+
+func TestOrphanJSON(t *testing.T) {
+	fsys := newInMemFS().
+		addJSONImage("Takeout/Google Photos/Photos from 2023/IMG_0001.jpg.json", "IMG_0001.jpg").
+		addImage("Takeout/Google Photos/Photos from 2023/IMG_0001.jpg", 10).
+		addJSONImage("Takeout/Google Photos/Photos from 2023/IMG_0002.jpg.json", "IMG_0002.jpg")
+	if fsys.err != nil {
+		t.Fatal(fsys.err)
+	}
+	ctx := context.Background()
+	jnl := logger.NewJournal(logger.NoLogger{})
+	jnl.KeepFiles(logger.ORPHAN_SIDECAR)
+	b, err := NewTakeout(ctx, jnl, fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range b.Browse(ctx) {
+	}
+	want := []string{"Takeout/Google Photos/Photos from 2023/IMG_0002.jpg.json"}
+	if got := jnl.Files(logger.ORPHAN_SIDECAR); !reflect.DeepEqual(got, want) {
+		t.Errorf("orphans = %v, want %v", got, want)
+	}
+}
+
+func TestInventory(t *testing.T) {
+	archived := func(md *GoogleMetaData) { md.Archived = true }
+	trashed := func(md *GoogleMetaData) { md.Trashed = true }
+	partner := func(md *GoogleMetaData) { md.GooglePhotosOrigin.FromPartnerSharing = true }
+	fsys := newInMemFS().
+		addJSONImage("Takeout/Google Photos/Photos from 2023/IMG_0001.jpg.json", "IMG_0001.jpg").
+		addImage("Takeout/Google Photos/Photos from 2023/IMG_0001.jpg", 10).
+		addJSONImage("Takeout/Google Photos/Photos from 2023/IMG_0002.jpg.json", "IMG_0002.jpg", archived).
+		addImage("Takeout/Google Photos/Photos from 2023/IMG_0002.jpg", 20).
+		addJSONImage("Takeout/Google Photos/Photos from 2023/IMG_0003.jpg.json", "IMG_0003.jpg", trashed).
+		addImage("Takeout/Google Photos/Photos from 2023/IMG_0003.jpg", 30).
+		addJSONImage("Takeout/Google Photos/Photos from 2023/IMG_0004.jpg.json", "IMG_0004.jpg", partner).
+		addImage("Takeout/Google Photos/Photos from 2023/IMG_0004.jpg", 40).
+		addJSONImage("Takeout/Google Photos/Photos from 2023/IMG_0005.jpg.json", "IMG_0005.jpg").
+		addImage("Takeout/Google Photos/Photos from 2023/IMG_0006.jpg", 60).
+		addJSONAlbum("Takeout/Google Photos/Holidays/metadata.json", "Holidays").
+		addJSONImage("Takeout/Google Photos/Holidays/IMG_0001.jpg.json", "IMG_0001.jpg").
+		addImage("Takeout/Google Photos/Holidays/IMG_0001.jpg", 10)
+	if fsys.err != nil {
+		t.Fatal(fsys.err)
+	}
+	to, err := NewTakeout(context.Background(), logger.NewJournal(logger.NoLogger{}), fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Inventory{Matched: 4, WithoutJSON: 1, Orphans: 1, Albums: 1, Partner: 1, Archived: 1, Trashed: 1}
+	if got := to.Inventory(); got != want {
+		t.Errorf("Inventory() = %+v, want %+v", got, want)
+	}
+}
+
+func TestDamagedJSON(t *testing.T) {
+	for _, strict := range []bool{false, true} {
+		fsys := newInMemFS().
+			addJSONImage("Takeout/Google Photos/Photos from 2023/IMG_0001.jpg.json", "IMG_0001.jpg").
+			addImage("Takeout/Google Photos/Photos from 2023/IMG_0001.jpg", 10).
+			addFile("Takeout/Google Photos/Photos from 2023/IMG_0002.jpg.json", []byte(`{"title": "IMG_0002.jpg",`)).
+			addImage("Takeout/Google Photos/Photos from 2023/IMG_0002.jpg", 20)
+		if fsys.err != nil {
+			t.Fatal(fsys.err)
+		}
+		ctx := context.Background()
+		to, err := NewTakeout(ctx, logger.NewJournal(logger.NoLogger{}), fsys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		to.Strict = strict
+		var titles []string
+		var errs int
+		for a := range to.Browse(ctx) {
+			if a.Err != nil {
+				errs++
+				continue
+			}
+			titles = append(titles, a.Title)
+		}
+		switch {
+		case strict && (errs != 1 || len(titles) != 0):
+			t.Errorf("strict: got %d error(s) and the assets %v, want only the error", errs, titles)
+		case !strict && (errs != 0 || !reflect.DeepEqual(titles, []string{"IMG_0001.jpg"})):
+			t.Errorf("got %d error(s) and the assets %v, want [IMG_0001.jpg]", errs, titles)
+		}
+	}
+}
+
+// messageLogger keeps the messages of the journal
+type messageLogger struct {
+	logger.NoLogger
+	messages []string
+}
+
+func (l *messageLogger) Message(level logger.Level, f string, v ...any) {
+	l.messages = append(l.messages, fmt.Sprintf(f, v...))
+}
+
+func TestCopiesInAlbums(t *testing.T) {
+	fsys := newInMemFS().
+		addJSONImage("Takeout/Google Photos/Photos from 2023/IMG_0001.jpg.json", "IMG_0001.jpg").
+		addImage("Takeout/Google Photos/Photos from 2023/IMG_0001.jpg", 10)
+	for _, album := range []string{"Holidays", "Family", "Best of"} {
+		fsys.addJSONAlbum("Takeout/Google Photos/"+album+"/metadata.json", album).
+			addJSONImage("Takeout/Google Photos/"+album+"/IMG_0001.jpg.json", "IMG_0001.jpg").
+			addImage("Takeout/Google Photos/"+album+"/IMG_0001.jpg", 10)
+	}
+	if fsys.err != nil {
+		t.Fatal(fsys.err)
+	}
+	ctx := context.Background()
+	log := &messageLogger{}
+	jnl := logger.NewJournal(log)
+	to, err := NewTakeout(ctx, jnl, fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var assets []*browser.LocalAssetFile
+	for a := range to.Browse(ctx) {
+		assets = append(assets, a)
+	}
+	if len(assets) != 1 {
+		t.Fatalf("got %d assets, want 1", len(assets))
+	}
+	var albums []string
+	for _, al := range assets[0].Albums {
+		albums = append(albums, al.Name)
+	}
+	slices.Sort(albums)
+	if want := []string{"Best of", "Family", "Holidays"}; !reflect.DeepEqual(albums, want) {
+		t.Errorf("albums = %v, want %v", albums, want)
+	}
+	if got := jnl.Count(logger.LOCAL_DUPLICATE); got != 3 {
+		t.Errorf("local duplicates = %d, want 3", got)
+	}
+	// the duplicates name the uploaded copy
+	first := assets[0].FileName
+	named := 0
+	for _, m := range log.messages {
+		if strings.Contains(m, string(logger.LOCAL_DUPLICATE)) && strings.HasSuffix(m, "same as "+first) {
+			named++
+		}
+	}
+	if named != 3 {
+		t.Errorf("duplicates naming %q = %d, want 3: %v", first, named, log.messages)
+	}
+}
+
+func TestSharedAlbums(t *testing.T) {
+	fromSharedAlbum := func(md *GoogleMetaData) {
+		md.GooglePhotosOrigin.FromSharedAlbum = true
+	}
+	fsys := newInMemFS().
+		addJSONAlbum("Takeout/Google Photos/Mine/metadata.json", "Mine").
+		addJSONImage("Takeout/Google Photos/Mine/IMG_0001.jpg.json", "IMG_0001.jpg").
+		addImage("Takeout/Google Photos/Mine/IMG_0001.jpg", 10).
+		addJSONAlbum("Takeout/Google Photos/From Bob/metadata.json", "From Bob").
+		addJSONImage("Takeout/Google Photos/From Bob/IMG_0002.jpg.json", "IMG_0002.jpg", fromSharedAlbum).
+		addImage("Takeout/Google Photos/From Bob/IMG_0002.jpg", 20).
+		addJSONAlbum("Takeout/Google Photos/Together/metadata.json", "Together").
+		addJSONImage("Takeout/Google Photos/Together/IMG_0003.jpg.json", "IMG_0003.jpg", fromSharedAlbum).
+		addImage("Takeout/Google Photos/Together/IMG_0003.jpg", 30).
+		addJSONImage("Takeout/Google Photos/Together/IMG_0004.jpg.json", "IMG_0004.jpg").
+		addImage("Takeout/Google Photos/Together/IMG_0004.jpg", 40)
+	if fsys.err != nil {
+		t.Fatal(fsys.err)
+	}
+	ctx := context.Background()
+	to, err := NewTakeout(ctx, logger.NewJournal(logger.NoLogger{}), fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shared := map[string]bool{}
+	for a := range to.Browse(ctx) {
+		for _, al := range a.Albums {
+			shared[al.Name] = al.Shared
+		}
+	}
+	// the user's own albums, even shared with others, contain the user's assets
+	want := map[string]bool{"Mine": false, "From Bob": true, "Together": false}
+	if !reflect.DeepEqual(shared, want) {
+		t.Errorf("shared albums = %v, want %v", shared, want)
+	}
+}
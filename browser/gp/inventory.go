@@ -0,0 +1,51 @@
+package gp
+
+// Inventory gives the counts of the takeout's content, as understood by the browser.
+// The files repeated in several folders of the takeout, like in the albums, are counted once.
+type Inventory struct {
+	Matched     int // media files associated with their JSON
+	WithoutJSON int // media files without JSON, they aren't uploaded
+	Orphans     int // JSON files without media file
+	Albums      int // albums discovered
+	Partner     int // media files coming from a partner
+	Archived    int // archived media files
+	Trashed     int // trashed media files
+}
+
+// Inventory counts the content of the takeout, without browsing the files again
+func (to *Takeout) Inventory() Inventory {
+	inv := Inventory{
+		Orphans: to.orphans,
+		Albums:  len(to.albums),
+	}
+	seen := map[fileKey]bool{}
+	for _, catalog := range to.catalogs {
+		for _, dir := range catalog {
+			for base, f := range dir.files {
+				key := fileKey{base: base, length: f.length}
+				if f.md != nil {
+					key.year = f.md.PhotoTakenTime.Time().Year()
+				}
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				if f.md == nil {
+					inv.WithoutJSON++
+					continue
+				}
+				inv.Matched++
+				if f.md.isPartner() {
+					inv.Partner++
+				}
+				if f.md.Archived {
+					inv.Archived++
+				}
+				if f.md.Trashed {
+					inv.Trashed++
+				}
+			}
+		}
+	}
+	return inv
+}
@@ -16,12 +16,16 @@ type GoogleMetaData struct {
 	DatePresent        googIsPresent  `json:"date,omitempty"` // true when the file is a folder metadata
 	PhotoTakenTime     googTimeObject `json:"photoTakenTime"`
 	GeoDataExif        googGeoData    `json:"geoDataExif"`
+	GeoData            googGeoData    `json:"geoData"`
 	Trashed            bool           `json:"trashed,omitempty"`
 	Archived           bool           `json:"archived,omitempty"`
-	URLPresent         googIsPresent  `json:"url,omitempty"`       // true when the file is an asset metadata
-	Favorited          bool           `json:"favorited,omitempty"` // true when starred in GP
+	URLPresent         googIsPresent  `json:"url,omitempty"`        // true when the file is an asset metadata
+	Favorited          bool           `json:"favorited,omitempty"`  // true when starred in GP
+	People             []googPerson   `json:"people,omitempty"`     // people tagged on the asset
+	CoverPhoto         string         `json:"coverPhoto,omitempty"` // file name of the album's cover, when given
 	GooglePhotosOrigin struct {
 		FromPartnerSharing googIsPresent `json:"fromPartnerSharing,omitempty"` // true when this is a partner's asset
+		FromSharedAlbum    googIsPresent `json:"fromSharedAlbum,omitempty"`    // true when the asset comes from an album shared with the user
 	} `json:"googlePhotosOrigin"`
 	foundInPaths []string // Not in the JSON, keep track of paths where the json has been found
 }
@@ -34,6 +38,12 @@ func (gmd GoogleMetaData) isAsset() bool {
 	return bool(gmd.URLPresent)
 }
 
+// isShared is true for an asset added by another user to an album shared with the user.
+// The user's own albums, even shared, contain the user's assets.
+func (gmd GoogleMetaData) isShared() bool {
+	return bool(gmd.GooglePhotosOrigin.FromSharedAlbum)
+}
+
 func (gmd GoogleMetaData) isPartner() bool {
 	return bool(gmd.GooglePhotosOrigin.FromPartnerSharing)
 }
@@ -72,6 +82,28 @@ type googGeoData struct {
 	Altitude  float64 `json:"altitude"`
 }
 
+// isSet tells if the coordinates are given. The position 0,0 means no location.
+func (g googGeoData) isSet() bool {
+	return g.Latitude != 0 || g.Longitude != 0
+}
+
+// location returns the coordinates of the asset: the ones from the EXIF when given,
+// otherwise the ones edited in Google Photos. The zero value when none is given.
+func (gmd GoogleMetaData) location() googGeoData {
+	switch {
+	case gmd.GeoDataExif.isSet():
+		return gmd.GeoDataExif
+	case gmd.GeoData.isSet():
+		return gmd.GeoData
+	}
+	return googGeoData{}
+}
+
+// googPerson is a person tagged on the asset
+type googPerson struct {
+	Name string `json:"name"`
+}
+
 // googTimeObject to handle the epoch timestamp
 type googTimeObject struct {
 	Timestamp string `json:"timestamp"`
@@ -81,6 +113,10 @@ type googTimeObject struct {
 // Time return the time.Time of the epoch
 func (gt googTimeObject) Time() time.Time {
 	ts, _ := strconv.ParseInt(gt.Timestamp, 10, 64)
+	if ts == 0 {
+		// The date is unknown
+		return time.Time{}
+	}
 	t := time.Unix(ts, 0)
 	local, _ := tzone.Local()
 	//	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
@@ -13,6 +13,8 @@ func TestPresentFields(t *testing.T) {
 		json      string
 		isPartner bool
 		isAlbum   bool
+		isShared  bool
+		people    []string
 	}{
 		{
 			name: "regularJSON",
@@ -43,6 +45,14 @@ func TestPresentFields(t *testing.T) {
 				  "longitudeSpan": 0.0
 				},
 				"url": "https://photos.google.com/photo/AAMKMAKZMAZMKAZMKZMAK",
+				"people": [
+				  {
+					"name": "Alice"
+				  },
+				  {
+					"name": "Bob"
+				  }
+				],
 				"googlePhotosOrigin": {
 				  "mobileUpload": {
 					"deviceFolder": {
@@ -54,6 +64,7 @@ func TestPresentFields(t *testing.T) {
 			  }`,
 			isPartner: false,
 			isAlbum:   false,
+			people:    []string{"Alice", "Bob"},
 		},
 		{
 			name: "albumJson",
@@ -114,6 +125,50 @@ func TestPresentFields(t *testing.T) {
 			isPartner: true,
 			isAlbum:   false,
 		},
+		{
+			name: "sharedAlbumJson",
+			json: `{
+				"title": "Holidays with friends",
+				"description": "",
+				"access": "protected",
+				"date": {
+				  "timestamp": "1687791968",
+				  "formatted": "26 juin 2023, 15:06:08 UTC"
+				},
+				"sharedAlbumComments": [
+				  {
+				    "text": "Nice!",
+				    "creationTime": {
+				      "timestamp": "1687792236",
+				      "formatted": "26 juin 2023, 15:10:36 UTC"
+				    },
+				    "contentOwnerName": "Bob"
+				  }
+				]
+			  }`,
+			isPartner: false,
+			isAlbum:   true,
+			isShared:  false,
+		},
+		{
+			name: "sharedAssetJson",
+			json: `{
+				"title": "IMG_0420.jpg",
+				"description": "",
+				"photoTakenTime": {
+				  "timestamp": "1687791968",
+				  "formatted": "26 juin 2023, 15:06:08 UTC"
+				},
+				"url": "https://photos.google.com/photo/AF1QipMiih4bHng7H2JcBe32Z70f86FWJxz3WwLjhc75",
+				"googlePhotosOrigin": {
+				  "fromSharedAlbum": {
+				  }
+				}
+			  }`,
+			isPartner: false,
+			isAlbum:   false,
+			isShared:  true,
+		},
 	}
 
 	for _, c := range tcs {
@@ -130,7 +185,74 @@ func TestPresentFields(t *testing.T) {
 			if c.isPartner != md.isPartner() {
 				t.Errorf("expected isPartner to be %t, got %t", c.isPartner, md.isPartner())
 			}
+			if c.isShared != md.isShared() {
+				t.Errorf("expected isShared to be %t, got %t", c.isShared, md.isShared())
+			}
+			people := []string{}
+			for _, p := range md.People {
+				people = append(people, p.Name)
+			}
+			if strings.Join(c.people, ",") != strings.Join(people, ",") {
+				t.Errorf("expected people to be %v, got %v", c.people, people)
+			}
 		})
 	}
 
 }
+
+func TestLocation(t *testing.T) {
+	const zero = `{"latitude": 0.0, "longitude": 0.0, "altitude": 0.0, "latitudeSpan": 0.0, "longitudeSpan": 0.0}`
+	const paris = `{"latitude": 48.8583, "longitude": 2.2945, "altitude": 35.0, "latitudeSpan": 0.0, "longitudeSpan": 0.0}`
+	const rome = `{"latitude": 41.8902, "longitude": 12.4922, "altitude": 20.0, "latitudeSpan": 0.0, "longitudeSpan": 0.0}`
+
+	tcs := []struct {
+		name    string
+		json    string
+		wantLat float64
+		wantLon float64
+		wantAlt float64
+	}{
+		{
+			name:    "exif only",
+			json:    `{"title": "IMG.jpg", "geoData": ` + zero + `, "geoDataExif": ` + paris + `}`,
+			wantLat: 48.8583, wantLon: 2.2945, wantAlt: 35,
+		},
+		{
+			name:    "geoData only",
+			json:    `{"title": "IMG.jpg", "geoData": ` + rome + `, "geoDataExif": ` + zero + `}`,
+			wantLat: 41.8902, wantLon: 12.4922, wantAlt: 20,
+		},
+		{
+			name:    "geoData without geoDataExif",
+			json:    `{"title": "IMG.jpg", "geoData": ` + rome + `}`,
+			wantLat: 41.8902, wantLon: 12.4922, wantAlt: 20,
+		},
+		{
+			name:    "both",
+			json:    `{"title": "IMG.jpg", "geoData": ` + rome + `, "geoDataExif": ` + paris + `}`,
+			wantLat: 48.8583, wantLon: 2.2945, wantAlt: 35,
+		},
+		{
+			name: "neither",
+			json: `{"title": "IMG.jpg", "geoData": ` + zero + `, "geoDataExif": ` + zero + `}`,
+		},
+		{
+			name: "missing",
+			json: `{"title": "IMG.jpg"}`,
+		},
+	}
+	for _, c := range tcs {
+		t.Run(c.name, func(t *testing.T) {
+			var md GoogleMetaData
+			err := json.NewDecoder(strings.NewReader(c.json)).Decode(&md)
+			if err != nil {
+				t.Errorf("unexpected error: %s", err)
+				return
+			}
+			l := md.location()
+			if l.Latitude != c.wantLat || l.Longitude != c.wantLon || l.Altitude != c.wantAlt {
+				t.Errorf("location()=%v, want %v,%v,%v", l, c.wantLat, c.wantLon, c.wantAlt)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package gp
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io/fs"
+	"path"
+	"reflect"
+	"testing"
+
+	"github.com/simulot/immich-go/logger"
+
+	"github.com/kr/pretty"
+)
+
+// zipOf writes the content of the in memory file system into a zip archive
+func zipOf(mfs *inMemFS) (fs.FS, error) {
+	if mfs.err != nil {
+		return nil, mfs.err
+	}
+	buf := bytes.NewBuffer(nil)
+	zw := zip.NewWriter(buf)
+	err := fs.WalkDir(mfs, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		b, err := fs.ReadFile(mfs, name)
+		if err != nil {
+			return err
+		}
+		w, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(b)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = zw.Close()
+	if err != nil {
+		return nil, err
+	}
+	return zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+}
+
+func TestSplitArchives(t *testing.T) {
+	// takeout-001.zip gets most of the metadata, takeout-002.zip gets the medias.
+	// The album's metadata of IMG_8172.jpg is in both archives.
+	part1 := newInMemFS().
+		addJSONImage("Takeout/Google Photos/Photos from 2023/PXL_20230922_144936660.jpg.json", "PXL_20230922_144936660.jpg", takenTime("PXL_20230922_144936660")).
+		addJSONImage("Takeout/Google Photos/Photos from 2023/IMG_8172.jpg.json", "IMG_8172.jpg", takenTime("20230922102100")).
+		addJSONAlbum("Takeout/Google Photos/Album/metadata.json", "Album").
+		addJSONImage("Takeout/Google Photos/Album/IMG_8172.jpg.json", "IMG_8172.jpg", takenTime("20230922102100")).
+		addImage("Takeout/Google Photos/Photos from 2023/PXL_20230922_144934440.jpg", 15)
+	part2 := newInMemFS().
+		addImage("Takeout/Google Photos/Photos from 2023/PXL_20230922_144936660.jpg", 10).
+		addImage("Takeout/Google Photos/Photos from 2023/IMG_8172.jpg", 52).
+		addImage("Takeout/Google Photos/Album/IMG_8172.jpg", 52).
+		addJSONImage("Takeout/Google Photos/Album/IMG_8172.jpg.json", "IMG_8172.jpg", takenTime("20230922102100")).
+		addJSONImage("Takeout/Google Photos/Photos from 2023/PXL_20230922_144934440.jpg.json", "PXL_20230922_144934440.jpg", takenTime("PXL_20230922_144934440"))
+
+	z1, err := zipOf(part1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	z2, err := zipOf(part2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	for _, order := range [][]fs.FS{{z1, z2}, {z2, z1}} {
+		b, err := NewTakeout(ctx, logger.NewJournal(logger.NoLogger{}), order...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		results := []fileResult{}
+		albums := map[string][]string{}
+		for a := range b.Browse(ctx) {
+			if a.Err != nil {
+				t.Fatal(a.Err)
+			}
+			results = append(results, fileResult{name: path.Base(a.FileName), size: a.FileSize, title: a.Title})
+			for _, al := range a.Albums {
+				albums[al.Name] = append(albums[al.Name], path.Base(a.FileName))
+			}
+		}
+		results = sortFileResult(results)
+		want := sortFileResult([]fileResult{
+			{name: "IMG_8172.jpg", size: 52, title: "IMG_8172.jpg"},
+			{name: "PXL_20230922_144934440.jpg", size: 15, title: "PXL_20230922_144934440.jpg"},
+			{name: "PXL_20230922_144936660.jpg", size: 10, title: "PXL_20230922_144936660.jpg"},
+		})
+		if !reflect.DeepEqual(results, want) {
+			t.Errorf("difference\n")
+			pretty.Ldiff(t, want, results)
+		}
+		wantAlbums := map[string][]string{"Album": {"IMG_8172.jpg"}}
+		if !reflect.DeepEqual(albums, wantAlbums) {
+			t.Errorf("albums = %v, want %v", albums, wantAlbums)
+		}
+	}
+}
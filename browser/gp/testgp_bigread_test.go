@@ -26,7 +26,7 @@ func TestReadBigTakeout(t *testing.T) {
 		return
 	}
 	cnt := 0
-	fsyss, err := fshelper.ParsePath(m, true)
+	fsyss, err := fshelper.ParsePath(m, true, nil)
 	to, err := NewTakeout(context.Background(), j, fsyss...)
 	if err != nil {
 		t.Error(err)
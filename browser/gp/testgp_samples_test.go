@@ -53,6 +53,12 @@ func takenTime(date string) func(md *GoogleMetaData) {
 	}
 }
 
+func favorited() jsonFn {
+	return func(md *GoogleMetaData) {
+		md.Favorited = true
+	}
+}
+
 func descriptionField(description string) jsonFn {
 	return func(md *GoogleMetaData) {
 		md.Description = description
@@ -184,6 +190,19 @@ func imagesEditedJSON() *inMemFS {
 		addImage("Takeout/Google Photos/Photos from 2023/PXL_20220405_090200110.PORTRAIT-modifié.jpg", 12)
 }
 
+func editedInAlbum() *inMemFS {
+	return newInMemFS().
+		addJSONAlbum("Takeout/Google Photos/Holidays/metadata.json", "Holidays").
+		addJSONImage("Takeout/Google Photos/Holidays/IMG_1234.jpg.json", "IMG_1234.jpg", takenTime("20230101")).
+		addImage("Takeout/Google Photos/Holidays/IMG_1234.jpg", 10).
+		addImage("Takeout/Google Photos/Holidays/IMG_1234-edited.jpg", 12).
+		addJSONImage("Takeout/Google Photos/Photos from 2023/IMG_1234.jpg.json", "IMG_1234.jpg", takenTime("20230101")).
+		addImage("Takeout/Google Photos/Photos from 2023/IMG_1234.jpg", 10).
+		addImage("Takeout/Google Photos/Photos from 2023/IMG_1234-edited.jpg", 12).
+		addJSONImage("Takeout/Google Photos/Photos from 2023/IMG_5678.jpg.json", "IMG_5678.jpg", takenTime("20230101")).
+		addImage("Takeout/Google Photos/Photos from 2023/IMG_5678.jpg", 20)
+}
+
 func titlesWithForbiddenChars() *inMemFS {
 	return newInMemFS().
 		addJSONImage("Takeout/Google Photos/Photos from 2012/27_06_12 - 1.mov.json", "27/06/12 - 1.mov").
@@ -57,7 +57,6 @@ func TestBrowse(t *testing.T) {
 
 		{"imagesWithoutJSON", imagesEditedJSON,
 			sortFileResult([]fileResult{
-				{name: "PXL_20220405_090123740.PORTRAIT.jpg", size: 41, title: "PXL_20220405_090123740.PORTRAIT.jpg"},
 				{name: "PXL_20220405_090123740.PORTRAIT-modifié.jpg", size: 21, title: "PXL_20220405_090123740.PORTRAIT.jpg"},
 			}),
 		},
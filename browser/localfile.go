@@ -29,8 +29,11 @@ import (
 */
 
 type LocalAlbum struct {
-	Path string // As found in the files
-	Name string // As found in metadata
+	Path        string // As found in the files
+	Name        string // As found in metadata
+	Description string // As found in metadata
+	Shared      bool   // The album is shared with the user
+	Cover       string // File name of the album's cover, as found in metadata
 }
 
 type LocalAssetFile struct {
@@ -41,18 +44,26 @@ type LocalAssetFile struct {
 	Albums      []LocalAlbum // The asset's album, if any
 	Err         error        // keep errors encountered
 	SideCar     *metadata.SideCar
+	JSONSideCar string // Filename of the JSON sidecar read for the asset, empty when none
+	MimeType    string // Content type of the upload, guessed from the extension when empty
 
 	// Common metadata
 	DateTaken time.Time // the date of capture
+	ForceDate bool      // DateTaken replaces the date of capture the server finds in the file
 	Latitude  float64   // GPS Latitude
 	Longitude float64   // GPS Longitude
 	Altitude  float64   // GPS Altitude
+	Width     int       // Width of the image as displayed, 0 when unknown
+	Height    int       // Height of the image as displayed, 0 when unknown
+	Rating    int       // Star rating found in the XMP sidecar, 0 when unknown, -1 for rejected
+	Tags      []string  // Keywords found in the metadata, like Places/Europe/Italy
 
 	// Google Photos flags
 	Trashed     bool // The asset is trashed
 	Archived    bool // The asset is archived
 	FromPartner bool // the asset comes from a partner
 	Favorite    bool
+	People      []string // Names of the people on the asset
 
 	// Live Photos
 	LivePhotoData string // Filename of MP4 file associated
@@ -65,6 +76,9 @@ type LocalAssetFile struct {
 	tempFile   *os.File  // buffer that keep partial reads available for the full file reading
 	teeReader  io.Reader // write each read from it into the tempWriter
 	reader     io.Reader // the reader that combines the partial read and original file for full file reading
+
+	checksum string                   // checksum of the file content, empty until computed
+	hasher   *fshelper.ChecksumReader // computes the checksum during a full reading
 }
 
 func (l LocalAssetFile) DebugObject() any {
@@ -112,7 +126,7 @@ func (l *LocalAssetFile) PartialSourceReader() (reader io.Reader, err error) {
 			return nil, err
 		}
 		tempDir = filepath.Join(tempDir, "github.com/simulot/immich-go")
-		os.Mkdir(tempDir, 0700)
+		os.MkdirAll(tempDir, 0700)
 		l.tempFile, err = os.CreateTemp(tempDir, "")
 		if err != nil {
 			return nil, err
@@ -128,6 +142,7 @@ func (l *LocalAssetFile) PartialSourceReader() (reader io.Reader, err error) {
 // Open return fs.File that reads previously read bytes followed by the actual file content.
 func (l *LocalAssetFile) Open() (fs.File, error) {
 	var err error
+	fromStart := l.sourceFile == nil || l.tempFile != nil
 	if l.sourceFile == nil {
 		l.sourceFile, err = l.FSys.Open(l.FileName)
 		if err != nil {
@@ -140,12 +155,58 @@ func (l *LocalAssetFile) Open() (fs.File, error) {
 	} else {
 		l.reader = l.sourceFile
 	}
+	l.hasher = nil
+	if l.checksum == "" && fromStart {
+		size := int64(l.FileSize)
+		if size == 0 {
+			if fi, err := l.sourceFile.Stat(); err == nil {
+				size = fi.Size()
+			}
+		}
+		l.hasher = fshelper.NewChecksumReader(l.reader, size)
+		l.reader = l.hasher
+	}
 	return l, nil
 }
 
-// Read
+// Read reads the file content. The checksum is captured as soon as the whole file is read:
+// the upload reads only FileSize bytes and never gets the end of the file.
 func (l *LocalAssetFile) Read(b []byte) (int, error) {
-	return l.reader.Read(b)
+	n, err := l.reader.Read(b)
+	if l.hasher != nil {
+		if sum, ok := l.hasher.Sum(); ok {
+			l.checksum = sum
+			l.hasher = nil
+		}
+	}
+	return n, err
+}
+
+// Checksum gives the base64 encoded SHA1 of the file, as given by the server.
+// It is computed once, during the upload when the file is fully read, or
+// by reading the file when it isn't known yet.
+func (l *LocalAssetFile) Checksum() (string, error) {
+	if l.checksum != "" {
+		return l.checksum, nil
+	}
+	f, err := l.FSys.Open(l.FileName)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	l.checksum, err = fshelper.Checksum(f)
+	return l.checksum, err
+}
+
+// SetContent makes the asset read the file of fsys, like a converted copy of the original file.
+// The asset is closed, and its checksum is computed again from the new content.
+func (l *LocalAssetFile) SetContent(fsys fs.FS, size int) error {
+	err := l.Close()
+	l.FSys = fsys
+	l.FileSize = size
+	l.checksum = ""
+	l.hasher = nil
+	return err
 }
 
 // Close close the temporary file  and close the source
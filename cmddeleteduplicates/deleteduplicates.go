@@ -0,0 +1,191 @@
+// Command delete-duplicates
+
+package cmddeleteduplicates
+
+import (
+	"context"
+	"flag"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/simulot/immich-go/helpers/fshelper/myflag"
+	"github.com/simulot/immich-go/helpers/gen"
+	"github.com/simulot/immich-go/helpers/nfc"
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/logger"
+	"github.com/simulot/immich-go/ui"
+)
+
+type iClient interface {
+	GetAllAssetsWithFilter(context.Context, *immich.GetAssetOptions, func(*immich.Asset)) error
+	DeleteAssets(context.Context, []string, bool) error
+}
+
+type DeleteDuplicatesCmd struct {
+	client iClient       // Immich client
+	log    logger.Logger // Program's logger
+
+	DryRun    bool             // Display actions but don't change anything
+	Confirm   bool             // Must be set to really delete assets
+	DateRange immich.DateRange // Set capture date range
+
+	assets []*immich.Asset // List of assets present on the server
+}
+
+// duplicateGroup is a set of server assets having the same name and the same date of capture
+type duplicateGroup struct {
+	Keep   *immich.Asset   // the best copy
+	Delete []*immich.Asset // inferior copies
+}
+
+func NewDeleteDuplicatesCmd(ctx context.Context, ic iClient, log logger.Logger, args []string) (*DeleteDuplicatesCmd, error) {
+	cmd := flag.NewFlagSet("delete-duplicates", flag.ExitOnError)
+	validRange := immich.DateRange{}
+	validRange.Set("1850-01-04,2030-01-01")
+
+	app := DeleteDuplicatesCmd{
+		client:    ic,
+		log:       log,
+		DateRange: validRange,
+	}
+	cmd.BoolFunc(
+		"dry-run",
+		"display actions but don't touch the server",
+		myflag.BoolFlagFn(&app.DryRun, false))
+	cmd.BoolFunc(
+		"confirm",
+		"Confirm the deletion of the inferior copies (default: FALSE)",
+		myflag.BoolFlagFn(&app.Confirm, false))
+	cmd.Var(&app.DateRange,
+		"date",
+		"Process only assets having a capture date in that range.")
+
+	err := cmd.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+
+	log.OK("Ask for server's assets...")
+	var list []*immich.Asset
+	err = app.client.GetAllAssetsWithFilter(ctx, nil, func(a *immich.Asset) {
+		if a.IsTrashed {
+			return
+		}
+		if !app.DateRange.InRange(a.ExifInfo.DateTimeOriginal.Time) {
+			return
+		}
+		list = append(list, a)
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.OK("%d asset(s) received", len(list))
+
+	app.assets = list
+	return &app, nil
+}
+
+func DeleteDuplicatesCommand(ctx context.Context, ic iClient, log logger.Logger, args []string) error {
+	app, err := NewDeleteDuplicatesCmd(ctx, ic, log, args)
+	if err != nil {
+		return err
+	}
+	return app.Run(ctx)
+}
+
+func (app *DeleteDuplicatesCmd) Run(ctx context.Context) error {
+	groups := duplicateGroups(app.assets)
+	app.log.OK("%d duplicate group(s) found", len(groups))
+
+	ids := []string{}
+	freed := 0
+	for _, g := range groups {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+		app.log.OK("%d copies of %s, taken on %s", len(g.Delete)+1, g.Keep.OriginalFileName, g.Keep.ExifInfo.DateTimeOriginal.Format(time.DateTime))
+		app.log.OK("  keep   %s, %s", g.Keep.OriginalPath, ui.FormatBytes(g.Keep.ExifInfo.FileSizeInByte))
+		for _, a := range g.Delete {
+			app.log.OK("  delete %s, %s", a.OriginalPath, ui.FormatBytes(a.ExifInfo.FileSizeInByte))
+			ids = append(ids, a.ID)
+			freed += a.ExifInfo.FileSizeInByte
+		}
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	switch {
+	case app.DryRun:
+		app.log.Warning("%d server assets to delete (%s). skipped dry-run mode", len(ids), ui.FormatBytes(freed))
+		return nil
+	case !app.Confirm:
+		app.log.Warning("%d server assets to delete (%s). Use the -confirm option to proceed", len(ids), ui.FormatBytes(freed))
+		return nil
+	}
+
+	app.log.Warning("%d server assets to delete (%s).", len(ids), ui.FormatBytes(freed))
+	return app.client.DeleteAssets(ctx, ids, false)
+}
+
+// duplicateGroups returns the groups of assets having the same name and the same date of capture.
+// In each group, the best copy is kept, as the upload advice does: the highest resolution, then the biggest size.
+// The assets without date of capture aren't grouped, the same name doesn't make them copies.
+func duplicateGroups(assets []*immich.Asset) []duplicateGroup {
+	groups := []duplicateGroup{}
+
+	// The names are compared in NFC form: macOS writes them decomposed
+	byName := map[string][]*immich.Asset{}
+	for _, a := range assets {
+		if a.ExifInfo.DateTimeOriginal.IsZero() {
+			continue
+		}
+		n := nfc.String(a.OriginalFileName + path.Ext(a.OriginalPath))
+		byName[n] = append(byName[n], a)
+	}
+
+	names := gen.MapKeys(byName)
+	sort.Strings(names)
+	for _, n := range names {
+		l := byName[n]
+		if len(l) < 2 {
+			continue
+		}
+
+		// split the assets having the same name by date of capture
+		var buckets [][]*immich.Asset
+	nextAsset:
+		for _, a := range l {
+			for i := range buckets {
+				if immich.CompareDate(buckets[i][0].ExifInfo.DateTimeOriginal.Time, a.ExifInfo.DateTimeOriginal.Time) == 0 {
+					buckets[i] = append(buckets[i], a)
+					continue nextAsset
+				}
+			}
+			buckets = append(buckets, []*immich.Asset{a})
+		}
+
+		for _, b := range buckets {
+			if len(b) < 2 {
+				continue
+			}
+			sort.SliceStable(b, func(i, j int) bool {
+				ei, ej := b[i].ExifInfo, b[j].ExifInfo
+				if c := immich.CompareCopies(ei.ExifImageWidth, ei.ExifImageHeight, ei.FileSizeInByte, ej.ExifImageWidth, ej.ExifImageHeight, ej.FileSizeInByte); c != 0 {
+					return c > 0
+				}
+				return strings.Compare(b[i].ID, b[j].ID) < 0
+			})
+			groups = append(groups, duplicateGroup{
+				Keep:   b[0],
+				Delete: b[1:],
+			})
+		}
+	}
+	return groups
+}
@@ -0,0 +1,107 @@
+package cmddeleteduplicates
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/logger"
+)
+
+func serverAsset(id string, name string, date string, size int) *immich.Asset {
+	d, _ := time.Parse(time.DateTime, date)
+	return &immich.Asset{
+		ID:               id,
+		OriginalFileName: name,
+		OriginalPath:     "upload/" + id + ".jpg",
+		ExifInfo: immich.ExifInfo{
+			FileSizeInByte:   size,
+			DateTimeOriginal: immich.ImmichTime{Time: d},
+		},
+	}
+}
+
+type icCatchDeletes struct {
+	assets  []*immich.Asset
+	deleted []string
+}
+
+func (c *icCatchDeletes) GetAllAssetsWithFilter(ctx context.Context, opt *immich.GetAssetOptions, filter func(*immich.Asset)) error {
+	for _, a := range c.assets {
+		if opt.Match(a) {
+			filter(a)
+		}
+	}
+	return nil
+}
+
+func (c *icCatchDeletes) DeleteAssets(ctx context.Context, ids []string, force bool) error {
+	c.deleted = append(c.deleted, ids...)
+	return nil
+}
+
+func TestDeleteDuplicates(t *testing.T) {
+	assets := []*immich.Asset{
+		serverAsset("1", "IMG_0001", "2023-10-01 10:00:00", 1000),
+		serverAsset("2", "IMG_0001", "2023-10-01 10:00:01", 3000),
+		serverAsset("3", "IMG_0001", "2023-10-01 10:00:00", 2000),
+		serverAsset("4", "IMG_0001", "2021-05-01 08:00:00", 1000), // same name, other date
+		serverAsset("5", "IMG_0002", "2023-10-01 10:00:00", 1000),
+	}
+
+	tests := []struct {
+		name        string
+		args        []string
+		wantDeleted []string
+	}{
+		{
+			name:        "no confirmation",
+			args:        []string{},
+			wantDeleted: nil,
+		},
+		{
+			name:        "dry run",
+			args:        []string{"-dry-run", "-confirm"},
+			wantDeleted: nil,
+		},
+		{
+			name:        "confirmed",
+			args:        []string{"-confirm"},
+			wantDeleted: []string{"3", "1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ic := &icCatchDeletes{assets: assets}
+			err := DeleteDuplicatesCommand(context.Background(), ic, logger.NoLogger{}, tt.args)
+			if err != nil {
+				t.Errorf("unexpected error: %s", err)
+				return
+			}
+			if !reflect.DeepEqual(ic.deleted, tt.wantDeleted) {
+				t.Errorf("deleted assets = %v, want %v", ic.deleted, tt.wantDeleted)
+			}
+		})
+	}
+}
+
+func TestDuplicateGroups(t *testing.T) {
+	noDate1 := serverAsset("1", "image", "", 1000)
+	noDate2 := serverAsset("2", "image", "", 2000)
+	small := serverAsset("3", "IMG_0001", "2023-10-01 10:00:00", 3000)
+	small.ExifInfo.ExifImageWidth, small.ExifInfo.ExifImageHeight = 4000, 3000
+	big := serverAsset("4", "IMG_0001", "2023-10-01 10:00:00", 2000)
+	big.ExifInfo.ExifImageWidth, big.ExifInfo.ExifImageHeight = 8000, 6000
+
+	groups := duplicateGroups([]*immich.Asset{noDate1, noDate2, small, big})
+	// the assets without date of capture aren't copies of each other
+	if len(groups) != 1 {
+		t.Fatalf("got %d groups, want 1", len(groups))
+	}
+	// the highest resolution is kept, even when its file is smaller
+	if groups[0].Keep.ID != "4" || len(groups[0].Delete) != 1 || groups[0].Delete[0].ID != "3" {
+		t.Errorf("keep %s, delete %v, want to keep 4 and delete 3", groups[0].Keep.ID, groups[0].Delete)
+	}
+}
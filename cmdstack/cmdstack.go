@@ -6,6 +6,7 @@ import (
 	"path"
 	"sort"
 	"strconv"
+	"time"
 
 	"github.com/simulot/immich-go/helpers/stacking"
 	"github.com/simulot/immich-go/immich"
@@ -17,8 +18,9 @@ type StackCmd struct {
 	Immich *immich.ImmichClient // Immich client
 	logger *logger.Log
 
-	AssumeYes bool
-	DateRange immich.DateRange // Set capture date range
+	AssumeYes   bool
+	DateRange   immich.DateRange // Set capture date range
+	BurstWindow time.Duration    // Maximal interval between two frames of a burst
 }
 
 func initSack(xtx context.Context, ic *immich.ImmichClient, log *logger.Log, args []string) (*StackCmd, error) {
@@ -38,6 +40,7 @@ func initSack(xtx context.Context, ic *immich.ImmichClient, log *logger.Log, arg
 		return err
 	})
 	cmd.Var(&app.DateRange, "date", "Process only documents having a capture date in that range.")
+	cmd.DurationVar(&app.BurstWindow, "burst-window", stacking.DefaultBurstWindow, "Maximal interval between two frames of a burst, like 500ms")
 	err := cmd.Parse(args)
 	return &app, err
 }
@@ -47,7 +50,7 @@ func NewStackCommand(ctx context.Context, ic *immich.ImmichClient, log *logger.L
 		return err
 	}
 
-	sb := stacking.NewStackBuilder()
+	sb := stacking.NewStackBuilder().SetBurstWindow(app.BurstWindow)
 	log.MessageContinue(logger.OK, "Get server's assets...")
 	assetCount := 0
 
@@ -0,0 +1,119 @@
+package cmdupload
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/simulot/immich-go/logger"
+)
+
+// albumAddResult counts the outcome of the additions to an album
+type albumAddResult struct {
+	added      int // assets added
+	duplicates int // assets already in the album
+	failed     int // assets not added, even after the retries
+}
+
+// albumResults gives the outcome of the additions, by album
+type albumResults map[string]albumAddResult
+
+// failures gives the number of assets not added to their albums
+func (ar albumResults) failures() int {
+	n := 0
+	for _, r := range ar {
+		n += r.failed
+	}
+	return n
+}
+
+// report the outcome of each album, after the journal's report
+func (ar albumResults) report(j *logger.Journal) {
+	if len(ar) == 0 {
+		return
+	}
+	albums := make([]string, 0, len(ar))
+	for a := range ar {
+		albums = append(albums, a)
+	}
+	sort.Strings(albums)
+	j.Summary("Albums:")
+	for _, a := range albums {
+		r := ar[a]
+		j.Summary("%6d added, %d already in, %d failed: %s", r.added, r.duplicates, r.failed, a)
+	}
+}
+
+// recordAlbum keeps the outcome of the additions to the album for the report
+func (app *UpCmd) recordAlbum(album string, r albumAddResult) {
+	if app.albumResults == nil {
+		app.albumResults = albumResults{}
+	}
+	app.albumResults[album] = r
+}
+
+// albumAddPermanent tells if the error given by the server for an asset isn't worth a retry.
+// The duplicates are already in the album, and the permission won't change during the run.
+func albumAddPermanent(e string) bool {
+	return e == "duplicate" || e == "no_permission"
+}
+
+// addToAlbumByBatch adds the assets into the album by batches of AlbumBatchSize IDs
+// The assets failing with a transient error are tried again up to UploadRetries times.
+func (app *UpCmd) addToAlbumByBatch(ctx context.Context, albumID string, ids []string) (albumAddResult, error) {
+	size := app.AlbumBatchSize
+	if size <= 0 {
+		size = len(ids)
+	}
+	res := albumAddResult{}
+	for len(ids) > 0 {
+		batch := ids[:min(size, len(ids))]
+		ids = ids[len(batch):]
+		retryIDs, errs, err := app.addBatchToAlbum(ctx, albumID, batch, &res)
+		if err != nil {
+			return res, err
+		}
+		for retry := 1; len(retryIDs) > 0 && retry <= app.UploadRetries; retry++ {
+			app.Journal.Warning("%d asset(s) not added to the album, retry %d/%d", len(retryIDs), retry, app.UploadRetries)
+			select {
+			case <-ctx.Done():
+				return res, ctx.Err()
+			case <-time.After(app.albumRetryDelay):
+			}
+			retryIDs, errs, err = app.addBatchToAlbum(ctx, albumID, retryIDs, &res)
+			if err != nil {
+				return res, err
+			}
+		}
+		for i, id := range retryIDs {
+			app.Journal.Warning("%s: %s", id, errs[i])
+		}
+		res.failed += len(retryIDs)
+	}
+	return res, nil
+}
+
+// addBatchToAlbum makes one call to the server, counts the added assets and the permanent failures,
+// and returns the assets to be tried again with their errors.
+func (app *UpCmd) addBatchToAlbum(ctx context.Context, albumID string, ids []string, res *albumAddResult) ([]string, []string, error) {
+	rr, err := app.client.AddAssetToAlbum(ctx, albumID, ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	var retryIDs, errs []string
+	for _, r := range rr {
+		switch {
+		case r.Success:
+			res.added++
+		case r.Error == "duplicate":
+			res.duplicates++
+		case albumAddPermanent(r.Error):
+			app.Journal.Warning("%s: %s", r.ID, r.Error)
+			res.failed++
+		default:
+			retryIDs = append(retryIDs, r.ID)
+			errs = append(errs, r.Error)
+		}
+	}
+	return retryIDs, errs, nil
+}
@@ -0,0 +1,117 @@
+package cmdupload
+
+import (
+	"context"
+	"testing"
+
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/logger"
+)
+
+// icAlbumFailures fails the additions of some assets, a number of times
+type icAlbumFailures struct {
+	stubIC
+	errors map[string]string // asset ID -> error given by the server
+	fails  map[string]int    // asset ID -> number of failures before the success
+	calls  int
+}
+
+func (c *icAlbumFailures) GetAllAlbums(ctx context.Context) ([]immich.AlbumSimplified, error) {
+	return []immich.AlbumSimplified{{ID: "id-album", AlbumName: "Album"}}, nil
+}
+
+func (c *icAlbumFailures) AddAssetToAlbum(ctx context.Context, album string, ids []string) ([]immich.UpdateAlbumResult, error) {
+	c.calls++
+	r := []immich.UpdateAlbumResult{}
+	for _, id := range ids {
+		if e, ok := c.errors[id]; ok && c.fails[id] != 0 {
+			c.fails[id]--
+			r = append(r, immich.UpdateAlbumResult{ID: id, Error: e})
+			continue
+		}
+		r = append(r, immich.UpdateAlbumResult{ID: id, Success: true})
+	}
+	return r, nil
+}
+
+func TestAlbumAddFailures(t *testing.T) {
+	tc := []struct {
+		name     string
+		errors   map[string]string
+		fails    map[string]int
+		retries  int
+		expected albumAddResult
+		calls    int
+	}{
+		{
+			name:     "all added",
+			expected: albumAddResult{added: 3},
+			calls:    1,
+		},
+		{
+			name:     "duplicate",
+			errors:   map[string]string{"2": "duplicate"},
+			fails:    map[string]int{"2": -1},
+			retries:  2,
+			expected: albumAddResult{added: 2, duplicates: 1},
+			calls:    1,
+		},
+		{
+			name:     "no permission",
+			errors:   map[string]string{"2": "no_permission"},
+			fails:    map[string]int{"2": -1},
+			retries:  2,
+			expected: albumAddResult{added: 2, failed: 1},
+			calls:    1,
+		},
+		{
+			name:     "transient, retried",
+			errors:   map[string]string{"2": "unknown", "3": "unknown"},
+			fails:    map[string]int{"2": 1, "3": 2},
+			retries:  2,
+			expected: albumAddResult{added: 3},
+			calls:    3,
+		},
+		{
+			name:     "transient, too many failures",
+			errors:   map[string]string{"2": "unknown"},
+			fails:    map[string]int{"2": -1},
+			retries:  2,
+			expected: albumAddResult{added: 2, failed: 1},
+			calls:    3,
+		},
+		{
+			name:     "transient, no retry",
+			errors:   map[string]string{"2": "unknown"},
+			fails:    map[string]int{"2": 1},
+			expected: albumAddResult{added: 2, failed: 1},
+			calls:    1,
+		},
+	}
+	for _, c := range tc {
+		t.Run(c.name, func(t *testing.T) {
+			ic := &icAlbumFailures{errors: c.errors, fails: c.fails}
+			app := UpCmd{
+				client:        ic,
+				Journal:       logger.NewJournal(logger.NoLogger{}),
+				UploadRetries: c.retries,
+				updateAlbums: map[string]map[string]any{
+					"Album": {"1": nil, "2": nil, "3": nil},
+				},
+			}
+			err := app.ManageAlbums(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if got := app.albumResults["Album"]; got != c.expected {
+				t.Errorf("result = %+v, want %+v", got, c.expected)
+			}
+			if ic.calls != c.calls {
+				t.Errorf("calls = %d, want %d", ic.calls, c.calls)
+			}
+			if got := app.albumResults.failures(); got != c.expected.failed {
+				t.Errorf("failures = %d, want %d", got, c.expected.failed)
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package cmdupload
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/simulot/immich-go/browser"
+)
+
+// parseAlbumByDate checks the Go time layout given to -album-by-date
+func parseAlbumByDate(s string) (string, error) {
+	ref := time.Date(1999, 12, 31, 23, 59, 58, 0, time.UTC)
+	if s == "" || ref.Format(s) == s {
+		return "", fmt.Errorf("the -album-by-date layout %q must contain date elements, like 2006-01", s)
+	}
+	return s, nil
+}
+
+// dateAlbum gives the album of the asset with -album-by-date, the NoDateAlbum when
+// the asset has no date of capture, or "" when the asset isn't put in an album
+func (app *UpCmd) dateAlbum(a *browser.LocalAssetFile) string {
+	if a.DateTaken.IsZero() {
+		return app.NoDateAlbum
+	}
+	return a.DateTaken.Format(app.AlbumByDate)
+}
@@ -0,0 +1,94 @@
+package cmdupload
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/logger"
+)
+
+func TestParseAlbumByDate(t *testing.T) {
+	for _, c := range []struct {
+		layout string
+		ok     bool
+	}{
+		{layout: "2006-01", ok: true},
+		{layout: "January 2006", ok: true},
+		{layout: "Photos", ok: false},
+		{layout: "", ok: false},
+	} {
+		_, err := parseAlbumByDate(c.layout)
+		if (err == nil) != c.ok {
+			t.Errorf("%q: unexpected error %v", c.layout, err)
+		}
+	}
+}
+
+func TestAlbumByDate(t *testing.T) {
+	fsys := fstest.MapFS{
+		"trip/photo_01.cr3": {Data: []byte("photo 01")},
+		"home/photo_02.cr3": {Data: []byte("photo 02")},
+		"home/photo_03.cr3": {Data: []byte("photo 03")},
+		"scan/photo_04.cr3": {Data: []byte("photo 04")},
+	}
+	files := map[string]time.Time{
+		"trip/photo_01.cr3": time.Date(2023, 7, 14, 10, 0, 0, 0, time.UTC),
+		"home/photo_02.cr3": time.Date(2023, 7, 30, 18, 0, 0, 0, time.UTC),
+		"home/photo_03.cr3": time.Date(2023, 8, 1, 9, 0, 0, 0, time.UTC),
+		"scan/photo_04.cr3": {},
+	}
+	tests := []struct {
+		name   string
+		noDate string
+		want   map[string]map[string]any
+	}{
+		{
+			name: "without date, skipped",
+			want: map[string]map[string]any{
+				"2023-07": {"trip/photo_01.cr3": nil, "home/photo_02.cr3": nil},
+				"2023-08": {"home/photo_03.cr3": nil},
+			},
+		},
+		{
+			name:   "without date, fallback album",
+			noDate: "Undated",
+			want: map[string]map[string]any{
+				"2023-07": {"trip/photo_01.cr3": nil, "home/photo_02.cr3": nil},
+				"2023-08": {"home/photo_03.cr3": nil},
+				"Undated": {"scan/photo_04.cr3": nil},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := UpCmd{
+				client:       &icCatchUploadsAssets{},
+				Journal:      logger.NewJournal(logger.NoLogger{}),
+				AlbumByDate:  "2006-01",
+				NoDateAlbum:  tt.noDate,
+				updateAlbums: map[string]map[string]any{},
+				AssetIndex:   &AssetIndex{},
+			}
+			app.AssetIndex.ReIndex()
+			for name, d := range files {
+				a := &browser.LocalAssetFile{
+					FSys:      fsys,
+					FileName:  name,
+					Title:     name[len("trip/"):],
+					FileSize:  len(fsys[name].Data),
+					DateTaken: d,
+				}
+				if err := app.handleAsset(context.Background(), a); err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+			}
+			if !reflect.DeepEqual(app.updateAlbums, tt.want) {
+				t.Errorf("albums = %v, want %v", app.updateAlbums, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package cmdupload
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/helpers/gen"
+)
+
+// albumList gives the albums allowed and denied by the -album-list file.
+// A denied album is never imported. When the file has included albums,
+// only the assets from these albums are imported.
+type albumList struct {
+	include map[string]bool
+	deny    map[string]bool
+}
+
+// readAlbumList reads a file with lines like +Album to include the album, or -Album to deny it.
+// Empty lines and lines starting with # are ignored.
+func readAlbumList(name string) (*albumList, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseAlbumList(f)
+}
+
+func parseAlbumList(r io.Reader) (*albumList, error) {
+	l := &albumList{include: map[string]bool{}, deny: map[string]bool{}}
+	s := bufio.NewScanner(r)
+	for line := 1; s.Scan(); line++ {
+		t := strings.TrimSpace(s.Text())
+		if t == "" || strings.HasPrefix(t, "#") {
+			continue
+		}
+		album := strings.TrimSpace(t[1:])
+		if album == "" {
+			return nil, fmt.Errorf("can't read the album list, line %d: no album name", line)
+		}
+		switch t[0] {
+		case '+':
+			l.include[album] = true
+		case '-':
+			l.deny[album] = true
+		default:
+			return nil, fmt.Errorf("can't read the album list, line %d: expecting +Album or -Album, got %q", line, t)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("can't read the album list: %w", err)
+	}
+	return l, nil
+}
+
+// allowed tells if the assets of the album can be imported. The denied albums win over the included ones.
+func (l *albumList) allowed(album string) bool {
+	if l.deny[album] {
+		return false
+	}
+	return len(l.include) == 0 || l.include[album]
+}
+
+// filterAlbumList removes the albums not allowed from the asset, and tells if the asset is imported.
+// An asset without album is imported only when the list has no included album.
+func (app *UpCmd) filterAlbumList(a *browser.LocalAssetFile) bool {
+	if len(a.Albums) == 0 {
+		return len(app.albumList.include) == 0
+	}
+	a.Albums = gen.Filter(a.Albums, func(al browser.LocalAlbum) bool {
+		return app.albumList.allowed(app.albumName(al))
+	})
+	return len(a.Albums) > 0
+}
@@ -0,0 +1,97 @@
+package cmdupload
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/logger"
+)
+
+func TestReadAlbumList(t *testing.T) {
+	l, err := readAlbumList("TEST_DATA/albumlist.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &albumList{
+		include: map[string]bool{"Holidays": true, "Family": true, "Birthdays": true},
+		deny:    map[string]bool{"Family": true, "Screenshots": true},
+	}
+	if !reflect.DeepEqual(l, expected) {
+		t.Errorf("list = %+v, want %+v", l, expected)
+	}
+
+	for _, s := range []string{"Holidays", "+", "- "} {
+		if _, err := parseAlbumList(strings.NewReader(s)); err == nil {
+			t.Errorf("expecting an error for %q", s)
+		}
+	}
+}
+
+func TestAlbumList(t *testing.T) {
+	withInclude, err := readAlbumList("TEST_DATA/albumlist.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	denyOnly, err := parseAlbumList(strings.NewReader("-Screenshots\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name       string
+		list       *albumList
+		albums     []string
+		wantAlbums []string
+		excluded   bool
+	}{
+		{name: "included", list: withInclude, albums: []string{"Holidays"}, wantAlbums: []string{"Holidays"}},
+		{name: "not included", list: withInclude, albums: []string{"Work"}, excluded: true},
+		{name: "no album, with includes", list: withInclude, excluded: true},
+		{name: "included and denied", list: withInclude, albums: []string{"Family"}, excluded: true},
+		{name: "partly included", list: withInclude, albums: []string{"Family", "Birthdays", "Work"}, wantAlbums: []string{"Birthdays"}},
+		{name: "denied", list: denyOnly, albums: []string{"Screenshots"}, excluded: true},
+		{name: "not denied", list: denyOnly, albums: []string{"Screenshots", "Work"}, wantAlbums: []string{"Work"}},
+		{name: "no album, deny only", list: denyOnly},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := UpCmd{
+				client:       &stubIC{},
+				Journal:      logger.NewJournal(logger.NoLogger{}),
+				albumList:    tt.list,
+				AssetIndex:   &AssetIndex{},
+				updateAlbums: map[string]map[string]any{},
+			}
+			app.AssetIndex.ReIndex()
+			a := &browser.LocalAssetFile{
+				FSys:     fstest.MapFS{"photo.cr3": {Data: []byte("content")}},
+				FileName: "photo.cr3",
+				Title:    "photo.cr3",
+				FileSize: 7,
+			}
+			for _, al := range tt.albums {
+				a.Albums = append(a.Albums, browser.LocalAlbum{Path: al, Name: al})
+			}
+			err := app.handleAsset(context.Background(), a)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if got := app.Journal.Count(logger.NOT_SELECTED) == 1; got != tt.excluded {
+				t.Errorf("asset excluded: %v, want %v", got, tt.excluded)
+			}
+			if tt.excluded {
+				return
+			}
+			got := []string(nil)
+			for _, al := range a.Albums {
+				got = append(got, al.Name)
+			}
+			if !reflect.DeepEqual(got, tt.wantAlbums) {
+				t.Errorf("albums = %v, want %v", got, tt.wantAlbums)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package cmdupload
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// readAlbumMap reads a file with lines like source=target to import the assets of the album
+// source into the album target. Empty lines and lines starting with # are ignored.
+func readAlbumMap(name string) (map[string]string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseAlbumMap(f)
+}
+
+func parseAlbumMap(r io.Reader) (map[string]string, error) {
+	m := map[string]string{}
+	s := bufio.NewScanner(r)
+	for line := 1; s.Scan(); line++ {
+		t := strings.TrimSpace(s.Text())
+		if t == "" || strings.HasPrefix(t, "#") {
+			continue
+		}
+		source, target, found := strings.Cut(t, "=")
+		source, target = strings.TrimSpace(source), strings.TrimSpace(target)
+		if !found || source == "" || target == "" {
+			return nil, fmt.Errorf("can't read the album map, line %d: expecting source=target, got %q", line, t)
+		}
+		if other, exists := m[source]; exists && other != target {
+			return nil, fmt.Errorf("can't read the album map, line %d: the album %q is already mapped to %q", line, source, other)
+		}
+		m[source] = target
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("can't read the album map: %w", err)
+	}
+	return m, nil
+}
+
+// mapAlbum gives the name of the album receiving the assets of the album, given by the -album-map file
+func (app *UpCmd) mapAlbum(album string) string {
+	if target, ok := app.albumMap[album]; ok {
+		return target
+	}
+	return album
+}
@@ -0,0 +1,44 @@
+package cmdupload
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadAlbumMap(t *testing.T) {
+	m, err := readAlbumMap("TEST_DATA/albummap.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{"IMG folder": "Phone Photos", "Camera": "Phone Photos", "Trip 2023": "Italy 2023"}
+	if !reflect.DeepEqual(m, expected) {
+		t.Errorf("map = %v, want %v", m, expected)
+	}
+
+	for _, s := range []string{"Camera", "=Phone Photos", "Camera=", "Camera=Phone\nCamera=Other"} {
+		if _, err := parseAlbumMap(strings.NewReader(s)); err == nil {
+			t.Errorf("expecting an error for %q", s)
+		}
+	}
+}
+
+func TestAlbumMap(t *testing.T) {
+	m, err := readAlbumMap("TEST_DATA/albummap.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := UpCmd{albumMap: m, updateAlbums: map[string]map[string]any{}}
+	app.AddToAlbum("1", "IMG folder")
+	app.AddToAlbum("2", "Camera")
+	app.AddToAlbum("3", "Work")
+	app.AddToAlbum("4", "Phone Photos")
+
+	expected := map[string]map[string]any{
+		"Phone Photos": {"1": nil, "2": nil, "4": nil},
+		"Work":         {"3": nil},
+	}
+	if !reflect.DeepEqual(app.updateAlbums, expected) {
+		t.Errorf("albums = %v, want %v", app.updateAlbums, expected)
+	}
+}
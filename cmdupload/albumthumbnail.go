@@ -0,0 +1,55 @@
+package cmdupload
+
+import (
+	"path"
+	"time"
+
+	"github.com/simulot/immich-go/browser"
+)
+
+// albumCover tracks the assets of an album that can be its thumbnail
+type albumCover struct {
+	name     string    // file name of the cover given by the album's metadata
+	coverID  string    // ID of the asset named as the cover
+	earliest string    // ID of the earliest asset of the album
+	date     time.Time // date of capture of the earliest asset
+}
+
+// coverCandidates gives the thumbnail's candidates by album
+type coverCandidates map[string]*albumCover
+
+// recordAlbumCover keeps the asset added to the album when it is the album's cover,
+// or the earliest asset of the album
+func (app *UpCmd) recordAlbumCover(a *browser.LocalAssetFile, ID string, album string, cover string) {
+	if app.albumCovers == nil {
+		app.albumCovers = coverCandidates{}
+	}
+	c := app.albumCovers[album]
+	if c == nil {
+		c = &albumCover{}
+		app.albumCovers[album] = c
+	}
+	if cover != "" {
+		c.name = cover
+	}
+	if c.name != "" && (path.Base(a.Title) == c.name || path.Base(a.FileName) == c.name) {
+		c.coverID = ID
+	}
+	if c.earliest == "" || (!a.DateTaken.IsZero() && (c.date.IsZero() || a.DateTaken.Before(c.date))) {
+		c.earliest = ID
+		c.date = a.DateTaken
+	}
+}
+
+// albumThumbnail gives the ID of the asset named as the album's cover, or of the
+// earliest asset of the album when the cover isn't uploaded. Empty when unknown.
+func (app *UpCmd) albumThumbnail(album string) string {
+	c := app.albumCovers[album]
+	switch {
+	case c == nil:
+		return ""
+	case c.coverID != "":
+		return c.coverID
+	}
+	return c.earliest
+}
@@ -0,0 +1,55 @@
+package cmdupload
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/logger"
+)
+
+func TestAlbumThumbnail(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2023, 6, d, 12, 0, 0, 0, time.UTC) }
+	ic := &icCatchAlbumBatches{batches: map[string][]int{}}
+	app := UpCmd{
+		client:                 ic,
+		Journal:                logger.NewJournal(logger.NoLogger{}),
+		AlbumComments:          true,
+		AlbumThumbnailFromMeta: true,
+		updateAlbums:           map[string]map[string]any{},
+	}
+	assets := []struct {
+		ID    string
+		title string
+		date  time.Time
+		album string
+		cover string
+	}{
+		{ID: "id-1", title: "IMG_0001.jpg", date: day(3), album: "Holidays", cover: "IMG_0002.jpg"},
+		{ID: "id-2", title: "IMG_0002.jpg", date: day(5), album: "Holidays", cover: "IMG_0002.jpg"},
+		{ID: "id-3", title: "IMG_0003.jpg", date: day(4), album: "Party", cover: "IMG_0009.jpg"},
+		{ID: "id-4", title: "IMG_0004.jpg", date: day(2), album: "Party", cover: "IMG_0009.jpg"},
+		{ID: "id-5", title: "IMG_0005.jpg", date: day(1), album: "Family"},
+	}
+	for _, a := range assets {
+		la := &browser.LocalAssetFile{FileName: a.title, Title: a.title, DateTaken: a.date}
+		app.recordAlbumCover(la, a.ID, a.album, a.cover)
+		app.AddToAlbum(a.ID, a.album)
+	}
+	err := app.ManageAlbums(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"Holidays": "id-2", // the cover given by the takeout
+		"Party":    "id-4", // the cover isn't uploaded, the earliest asset
+		"Family":   "id-5",
+	}
+	for album, id := range want {
+		info := ic.infos[album]
+		if info.ThumbnailAssetID == nil || *info.ThumbnailAssetID != id {
+			t.Errorf("thumbnail of %s: %v, want %s", album, info.ThumbnailAssetID, id)
+		}
+	}
+}
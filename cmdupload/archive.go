@@ -0,0 +1,27 @@
+package cmdupload
+
+import (
+	"context"
+	"fmt"
+)
+
+// archiveBatchSize is the maximum number of assets archived per call
+const archiveBatchSize = 1000
+
+// ArchiveUploaded archives the assets uploaded with -archive-all, by batches of archiveBatchSize
+func (app *UpCmd) ArchiveUploaded(ctx context.Context) error {
+	ids := app.archiveList
+	for len(ids) > 0 {
+		batch := ids[:min(archiveBatchSize, len(ids))]
+		ids = ids[len(batch):]
+		if app.DryRun {
+			continue
+		}
+		err := app.client.ArchiveAssets(ctx, batch, true)
+		if err != nil {
+			return fmt.Errorf("can't archive the uploaded assets: %w", err)
+		}
+	}
+	app.Journal.OK("%d asset(s) archived", len(app.archiveList))
+	return nil
+}
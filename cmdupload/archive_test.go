@@ -0,0 +1,119 @@
+package cmdupload
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/logger"
+)
+
+// icArchive records the archived assets
+type icArchive struct {
+	stubIC
+	batches []int
+	ids     []string
+}
+
+func (c *icArchive) AssetUpload(ctx context.Context, a *browser.LocalAssetFile) (immich.AssetResponse, error) {
+	return immich.AssetResponse{ID: "new-" + a.FileName}, nil
+}
+
+func (c *icArchive) ArchiveAssets(ctx context.Context, IDs []string, isArchived bool) error {
+	if !isArchived {
+		return fmt.Errorf("unexpected unarchive")
+	}
+	c.batches = append(c.batches, len(IDs))
+	c.ids = append(c.ids, IDs...)
+	return nil
+}
+
+func TestArchiveAll(t *testing.T) {
+	date := time.Date(2023, 6, 23, 13, 32, 52, 0, time.UTC)
+	fsys := fstest.MapFS{
+		"scans/scan_01.cr3": {Data: []byte("scan 01")},
+		"scans/scan_02.cr3": {Data: []byte("scan 02")},
+	}
+	server := []*immich.Asset{
+		{
+			ID:               "server-scan_02",
+			OriginalFileName: "scan_02",
+			OriginalPath:     "upload/scan_02.cr3",
+			ExifInfo: immich.ExifInfo{
+				FileSizeInByte:   len(fsys["scans/scan_02.cr3"].Data),
+				DateTimeOriginal: immich.ImmichTime{Time: date},
+			},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		archive     bool
+		wantArchive []string
+	}{
+		{name: "not archived", archive: false},
+		{name: "archived", archive: true, wantArchive: []string{"new-scans/scan_01.cr3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ic := &icArchive{}
+			app := UpCmd{
+				client:          ic,
+				Journal:         logger.NewJournal(logger.NoLogger{}),
+				ArchiveAll:      tt.archive,
+				ImportIntoAlbum: "Scans",
+				updateAlbums:    map[string]map[string]any{},
+				AssetIndex:      &AssetIndex{assets: server},
+			}
+			app.AssetIndex.ReIndex()
+			for _, f := range []string{"scans/scan_01.cr3", "scans/scan_02.cr3"} {
+				a := &browser.LocalAssetFile{
+					FSys:      fsys,
+					FileName:  f,
+					Title:     f[len("scans/"):],
+					FileSize:  len(fsys[f].Data),
+					DateTaken: date,
+				}
+				if err := app.handleAsset(context.Background(), a); err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+			}
+			if err := app.ArchiveUploaded(context.Background()); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(ic.ids, tt.wantArchive) {
+				t.Errorf("archived = %v, want %v", ic.ids, tt.wantArchive)
+			}
+			wantAlbum := map[string]any{"new-scans/scan_01.cr3": nil, "server-scan_02": nil}
+			if !reflect.DeepEqual(app.updateAlbums["Scans"], wantAlbum) {
+				t.Errorf("album = %v, want %v", app.updateAlbums["Scans"], wantAlbum)
+			}
+		})
+	}
+}
+
+func TestArchiveUploadedByBatch(t *testing.T) {
+	ic := &icArchive{}
+	app := UpCmd{
+		client:  ic,
+		Journal: logger.NewJournal(logger.NoLogger{}),
+	}
+	for i := 0; i < 2*archiveBatchSize+500; i++ {
+		app.archiveList = append(app.archiveList, fmt.Sprintf("id-%d", i))
+	}
+	if err := app.ArchiveUploaded(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	want := []int{archiveBatchSize, archiveBatchSize, 500}
+	if !reflect.DeepEqual(ic.batches, want) {
+		t.Errorf("batches = %v, want %v", ic.batches, want)
+	}
+	if !reflect.DeepEqual(ic.ids, app.archiveList) {
+		t.Errorf("all the assets must be archived")
+	}
+}
@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/helpers/nfc"
 	"github.com/simulot/immich-go/immich"
 )
 
@@ -13,35 +15,101 @@ type AssetIndex struct {
 	assets []*immich.Asset
 	byHash map[string][]*immich.Asset
 	byName map[string][]*immich.Asset
+	byBase map[string][]*immich.Asset // by name without extension, for the assets stored without one
 	byID   map[string]*immich.Asset
 	// albums []immich.AlbumSimplified
+
+	fetchDay func(day time.Time) ([]*immich.Asset, error) // When set, the server's assets are fetched by day of capture
+	days     map[time.Time]bool                           // Days already fetched
+	explain  func(format string, args ...any)             // When set, the steps of ShouldUpload are reported
+}
+
+// newAssetIndexByDate gives an index that fetches the server's assets of a day when
+// an asset taken this day is checked
+func newAssetIndexByDate(fetchDay func(day time.Time) ([]*immich.Asset, error)) *AssetIndex {
+	ai := &AssetIndex{
+		fetchDay: fetchDay,
+		days:     map[time.Time]bool{},
+	}
+	ai.ReIndex()
+	return ai
 }
 
 func (ai *AssetIndex) ReIndex() {
 	ai.byHash = map[string][]*immich.Asset{}
 	ai.byName = map[string][]*immich.Asset{}
+	ai.byBase = map[string][]*immich.Asset{}
 	ai.byID = map[string]*immich.Asset{}
 
 	for _, a := range ai.assets {
-		ext := path.Ext(a.OriginalPath)
-		ID := fmt.Sprintf("%s-%d", strings.ToUpper(path.Base(a.OriginalFileName)+ext), a.ExifInfo.FileSizeInByte)
-		l := ai.byHash[a.Checksum]
-		l = append(l, a)
-		ai.byHash[a.Checksum] = l
-
-		n := a.OriginalFileName + ext
-		l = ai.byName[n]
-		l = append(l, a)
-		ai.byName[n] = l
-		ai.byID[ID] = a
+		ai.index(a)
+	}
+}
+
+func (ai *AssetIndex) index(a *immich.Asset) {
+	ext := path.Ext(a.OriginalPath)
+	// The names are compared in NFC form: macOS writes them decomposed
+	ID := nfc.String(fmt.Sprintf("%s-%d", strings.ToUpper(path.Base(a.OriginalFileName)+ext), a.ExifInfo.FileSizeInByte))
+	l := ai.byHash[a.Checksum]
+	l = append(l, a)
+	ai.byHash[a.Checksum] = l
+
+	n := nfc.String(a.OriginalFileName + ext)
+	l = ai.byName[n]
+	l = append(l, a)
+	ai.byName[n] = l
+	ai.indexBase(n, a)
+	ai.byID[ID] = a
+}
+
+// indexBase indexes the asset by its name without extension
+func (ai *AssetIndex) indexBase(n string, a *immich.Asset) {
+	b := strings.TrimSuffix(n, path.Ext(n))
+	ai.byBase[b] = append(ai.byBase[b], a)
+}
+
+// sameBaseName gives the assets having the name n once the extensions are removed,
+// with the same date and the same size. Some servers have assets stored without
+// their extension, they are missed by the lookup by name.
+func (ai *AssetIndex) sameBaseName(n string, dateTaken time.Time, size int) []*immich.Asset {
+	var l []*immich.Asset
+	for _, sa := range ai.byBase[strings.TrimSuffix(n, path.Ext(n))] {
+		if sa.ExifInfo.FileSizeInByte == size && immich.CompareDate(dateTaken, sa.ExifInfo.DateTimeOriginal.Time) == 0 {
+			l = append(l, sa)
+		}
+	}
+	return l
+}
+
+// loadDays fetches the server's assets taken around the date, when not yet done.
+// The days before and after are fetched too when the date is close to midnight.
+func (ai *AssetIndex) loadDays(d time.Time) error {
+	if ai.fetchDay == nil || d.IsZero() {
+		return nil
+	}
+	for _, t := range []time.Time{d.Add(-5 * time.Minute), d, d.Add(5 * time.Minute)} {
+		day := t.UTC().Truncate(24 * time.Hour)
+		if ai.days[day] {
+			continue
+		}
+		list, err := ai.fetchDay(day)
+		if err != nil {
+			return fmt.Errorf("can't get the server's assets of %s: %w", day.Format(time.DateOnly), err)
+		}
+		ai.days[day] = true
+		for _, a := range list {
+			ai.assets = append(ai.assets, a)
+			ai.index(a)
+		}
 	}
+	return nil
 }
 
 func (ai *AssetIndex) Len() int {
 	return len(ai.assets)
 }
 
-func (ai *AssetIndex) AddLocalAsset(la *browser.LocalAssetFile, ImmichID string) {
+func (ai *AssetIndex) AddLocalAsset(la *browser.LocalAssetFile, ImmichID string) *immich.Asset {
 	sa := &immich.Asset{
 		ID:               ImmichID,
 		DeviceAssetID:    la.DeviceAssetID(),
@@ -51,12 +119,17 @@ func (ai *AssetIndex) AddLocalAsset(la *browser.LocalAssetFile, ImmichID string)
 			DateTimeOriginal: immich.ImmichTime{Time: la.DateTaken},
 			Latitude:         la.Latitude,
 			Longitude:        la.Longitude,
+			ExifImageWidth:   la.Width,
+			ExifImageHeight:  la.Height,
 		},
 		JustUploaded: true,
 	}
 	ai.assets = append(ai.assets, sa)
-	ai.byID[sa.DeviceAssetID] = sa
-	l := ai.byName[sa.OriginalFileName]
+	ai.byID[nfc.String(sa.DeviceAssetID)] = sa
+	n := nfc.String(sa.OriginalFileName)
+	l := ai.byName[n]
 	l = append(l, sa)
-	ai.byName[sa.OriginalFileName] = l
+	ai.byName[n] = l
+	ai.indexBase(nfc.String(path.Base(la.Title)), sa)
+	return sa
 }
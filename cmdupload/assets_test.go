@@ -0,0 +1,210 @@
+package cmdupload
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/immich"
+)
+
+func TestAssetIndexByDate(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2023, 6, d, 0, 0, 0, 0, time.UTC) }
+	serverAssets := map[time.Time][]*immich.Asset{
+		day(23): {{
+			ID:               "server-1",
+			OriginalFileName: "IMG_0001",
+			OriginalPath:     "upload/IMG_0001.jpg",
+			ExifInfo: immich.ExifInfo{
+				FileSizeInByte:   100,
+				DateTimeOriginal: immich.ImmichTime{Time: day(23).Add(10 * time.Hour)},
+			},
+		}},
+	}
+	var fetched []string
+	ai := newAssetIndexByDate(func(d time.Time) ([]*immich.Asset, error) {
+		fetched = append(fetched, d.Format(time.DateOnly))
+		return serverAssets[d], nil
+	})
+
+	tests := []struct {
+		name        string
+		date        time.Time
+		size        int
+		want        AdviceCode
+		wantFetched []string
+	}{
+		{name: "same day", date: day(23).Add(10 * time.Hour), size: 100, want: SameOnServer, wantFetched: []string{"2023-06-23"}},
+		{name: "cached day", date: day(23).Add(10 * time.Hour), size: 200, want: SmallerOnServer, wantFetched: []string{"2023-06-23"}},
+		{name: "near midnight", date: day(24).Add(-2 * time.Minute), size: 300, want: NotOnServer, wantFetched: []string{"2023-06-23", "2023-06-24"}},
+		{name: "no date", size: 300, want: NotOnServer, wantFetched: []string{"2023-06-23", "2023-06-24"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			advice, err := ai.ShouldUpload(&browser.LocalAssetFile{
+				FileName:  "IMG_0001.jpg",
+				Title:     "IMG_0001.jpg",
+				FileSize:  tt.size,
+				DateTaken: tt.date,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if advice.Advice != tt.want {
+				t.Errorf("advice = %s, want %s", advice.Advice, tt.want)
+			}
+			if !reflect.DeepEqual(fetched, tt.wantFetched) {
+				t.Errorf("fetched days = %v, want %v", fetched, tt.wantFetched)
+			}
+		})
+	}
+}
+
+func TestShouldUploadResolution(t *testing.T) {
+	date := time.Date(2023, 6, 23, 10, 0, 0, 0, time.UTC)
+	ai := &AssetIndex{
+		assets: []*immich.Asset{{
+			ID:               "server-1",
+			OriginalFileName: "IMG_0001",
+			OriginalPath:     "upload/IMG_0001.jpg",
+			ExifInfo: immich.ExifInfo{
+				FileSizeInByte:   1000,
+				ExifImageWidth:   4000,
+				ExifImageHeight:  3000,
+				DateTimeOriginal: immich.ImmichTime{Time: date},
+			},
+		}},
+	}
+	ai.ReIndex()
+
+	tests := []struct {
+		name          string
+		size          int
+		width, height int
+		want          AdviceCode
+	}{
+		{name: "same size", size: 1000, width: 2000, height: 1500, want: SameOnServer},
+		{name: "bigger but lower resolution", size: 2000, width: 2000, height: 1500, want: BetterOnServer},
+		{name: "smaller but higher resolution", size: 500, width: 6000, height: 4000, want: SmallerOnServer},
+		{name: "rotated, bigger", size: 2000, width: 3000, height: 4000, want: SmallerOnServer},
+		{name: "rotated, smaller", size: 500, width: 3000, height: 4000, want: BetterOnServer},
+		{name: "unknown resolution, bigger", size: 2000, want: SmallerOnServer},
+		{name: "unknown resolution, smaller", size: 500, want: BetterOnServer},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			advice, err := ai.ShouldUpload(&browser.LocalAssetFile{
+				FSys:      fstest.MapFS{"IMG_0001.jpg": {Data: []byte("no exif")}},
+				FileName:  "IMG_0001.jpg",
+				Title:     "IMG_0001.jpg",
+				FileSize:  tt.size,
+				Width:     tt.width,
+				Height:    tt.height,
+				DateTaken: date,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if advice.Advice != tt.want {
+				t.Errorf("advice = %s, want %s: %s", advice.Advice, tt.want, advice.Message)
+			}
+		})
+	}
+}
+
+func TestShouldUploadUnicodeNames(t *testing.T) {
+	const (
+		composed   = "Caf\u00e9"  // NFC, as stored by most systems
+		decomposed = "Cafe\u0301" // NFD, as written by macOS
+	)
+	date := time.Date(2023, 6, 23, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name          string
+		server, local string
+		size          int
+		want          AdviceCode
+	}{
+		{name: "NFC on server, NFD locally, same size", server: composed, local: decomposed, size: 1000, want: SameOnServer},
+		{name: "NFD on server, NFC locally, same size", server: decomposed, local: composed, size: 1000, want: SameOnServer},
+		{name: "NFC on server, NFD locally, bigger", server: composed, local: decomposed, size: 2000, want: SmallerOnServer},
+		{name: "NFD on server, NFC locally, bigger", server: decomposed, local: composed, size: 2000, want: SmallerOnServer},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ai := &AssetIndex{
+				assets: []*immich.Asset{{
+					ID:               "server-1",
+					OriginalFileName: tt.server,
+					OriginalPath:     "upload/" + tt.server + ".jpg",
+					ExifInfo: immich.ExifInfo{
+						FileSizeInByte:   1000,
+						DateTimeOriginal: immich.ImmichTime{Time: date},
+					},
+				}},
+			}
+			ai.ReIndex()
+			advice, err := ai.ShouldUpload(&browser.LocalAssetFile{
+				FSys:      fstest.MapFS{tt.local + ".jpg": {Data: []byte("no exif")}},
+				FileName:  tt.local + ".jpg",
+				Title:     tt.local + ".jpg",
+				FileSize:  tt.size,
+				DateTaken: date,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if advice.Advice != tt.want {
+				t.Errorf("advice = %s, want %s: %s", advice.Advice, tt.want, advice.Message)
+			}
+		})
+	}
+}
+
+func TestShouldUploadWithoutExtension(t *testing.T) {
+	date := time.Date(2023, 6, 23, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		size int
+		date time.Time
+		want AdviceCode
+	}{
+		{name: "same date and size", size: 1000, date: date, want: SameOnServer},
+		{name: "date within 5 minutes", size: 1000, date: date.Add(time.Minute), want: SameOnServer},
+		{name: "different size", size: 2000, date: date, want: NotOnServer},
+		{name: "different date", size: 1000, date: date.Add(time.Hour), want: NotOnServer},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ai := &AssetIndex{
+				assets: []*immich.Asset{{
+					ID:               "server-1",
+					OriginalFileName: "IMG_0001",
+					OriginalPath:     "upload/library/IMG_0001",
+					ExifInfo: immich.ExifInfo{
+						FileSizeInByte:   1000,
+						DateTimeOriginal: immich.ImmichTime{Time: date},
+					},
+				}},
+			}
+			ai.ReIndex()
+			advice, err := ai.ShouldUpload(&browser.LocalAssetFile{
+				FSys:      fstest.MapFS{"IMG_0001.jpg": {Data: []byte("no exif")}},
+				FileName:  "IMG_0001.jpg",
+				Title:     "IMG_0001.jpg",
+				FileSize:  tt.size,
+				DateTaken: tt.date,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if advice.Advice != tt.want {
+				t.Errorf("advice = %s, want %s: %s", advice.Advice, tt.want, advice.Message)
+			}
+			if tt.want == SameOnServer && advice.ServerAsset.ID != "server-1" {
+				t.Errorf("server asset = %q, want server-1", advice.ServerAsset.ID)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package cmdupload
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/simulot/immich-go/browser"
+)
+
+// bulkUpdateBatchSize is the maximum number of assets updated per call
+const bulkUpdateBatchSize = 1000
+
+// bulkUpdate gives the values set with a single call on all the assets sharing them
+type bulkUpdate struct {
+	archived bool
+	favorite bool
+}
+
+// bulkQueue gives the IDs of the assets by values to be set
+type bulkQueue map[bulkUpdate][]string
+
+// updateMetadata sets the metadata of the uploaded asset that aren't given by the upload.
+// The description, the GPS coordinates and the forced date are proper to the asset and need a call for it.
+// The archived and favorite flags are set by batches, sent when full and at the end of the run.
+func (app *UpCmd) updateMetadata(ctx context.Context, a *browser.LocalAssetFile, ID string) {
+	if app.DryRun {
+		return
+	}
+	if a.Description != "" || a.Latitude != 0 || a.Longitude != 0 || a.ForceDate {
+		_, err := app.client.UpdateAsset(ctx, ID, a)
+		if err != nil {
+			app.Journal.Error("can't update the asset '%s': %s", a.FileName, err)
+		}
+		return
+	}
+	if !a.Favorite && !a.Archived {
+		return
+	}
+	if app.bulkUpdates == nil {
+		app.bulkUpdates = bulkQueue{}
+	}
+	u := bulkUpdate{archived: a.Archived, favorite: a.Favorite}
+	app.bulkUpdates[u] = append(app.bulkUpdates[u], ID)
+	if len(app.bulkUpdates[u]) >= bulkUpdateBatchSize {
+		err := app.sendBulkUpdate(ctx, u, app.bulkUpdates[u])
+		delete(app.bulkUpdates, u)
+		if err != nil {
+			app.Journal.Error(err.Error())
+		}
+	}
+}
+
+// sendBulkUpdate sets the values u on the assets ids with a single call
+func (app *UpCmd) sendBulkUpdate(ctx context.Context, u bulkUpdate, ids []string) error {
+	err := app.client.UpdateAssets(ctx, ids, u.archived, u.favorite, 0, 0, false, "")
+	if err != nil {
+		return fmt.Errorf("can't update the assets: %w", err)
+	}
+	app.bulkUpdated += len(ids)
+	return nil
+}
+
+// UpdateMetadataByBatch sets the archived and favorite flags of the uploaded assets
+// left in the queue, with one call per batch of assets sharing the same values
+func (app *UpCmd) UpdateMetadataByBatch(ctx context.Context) error {
+	for u, ids := range app.bulkUpdates {
+		for len(ids) > 0 {
+			batch := ids[:min(bulkUpdateBatchSize, len(ids))]
+			ids = ids[len(batch):]
+			err := app.sendBulkUpdate(ctx, u, batch)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	app.bulkUpdates = nil
+	app.Journal.OK("%d asset(s) updated", app.bulkUpdated)
+	return nil
+}
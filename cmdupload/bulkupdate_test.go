@@ -0,0 +1,109 @@
+package cmdupload
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/logger"
+)
+
+// icBulkUpdate records the updates of the assets
+type icBulkUpdate struct {
+	stubIC
+	updated []string   // assets updated alone
+	batches [][]string // assets updated by batch
+}
+
+func (c *icBulkUpdate) UpdateAsset(ctx context.Context, ID string, a *browser.LocalAssetFile) (*immich.Asset, error) {
+	c.updated = append(c.updated, ID)
+	return &immich.Asset{}, nil
+}
+
+func (c *icBulkUpdate) UpdateAssets(ctx context.Context, IDs []string, isArchived bool, isFavorite bool, latitude float64, longitude float64, removeParent bool, stackParentId string) error {
+	c.batches = append(c.batches, slices.Clone(IDs))
+	return nil
+}
+
+func TestUpdateMetadataByBatch(t *testing.T) {
+	ic := &icBulkUpdate{}
+	app := UpCmd{
+		client:  ic,
+		Journal: logger.NewJournal(logger.NoLogger{}),
+	}
+	ctx := context.Background()
+	assets := map[string]*browser.LocalAssetFile{
+		"fav1":  {Favorite: true},
+		"fav2":  {Favorite: true},
+		"arch":  {Archived: true},
+		"gps":   {Latitude: 48.8, Longitude: 2.3},
+		"desc":  {Description: "a description", Favorite: true},
+		"plain": {},
+	}
+	for _, ID := range []string{"fav1", "fav2", "arch", "gps", "desc", "plain"} {
+		app.updateMetadata(ctx, assets[ID], ID)
+	}
+	if !slices.Equal(ic.updated, []string{"gps", "desc"}) {
+		t.Errorf("assets updated alone: %v, want [gps desc]", ic.updated)
+	}
+	if len(ic.batches) != 0 {
+		t.Errorf("the batches are sent before they are full")
+	}
+	err := app.UpdateMetadataByBatch(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, b := range ic.batches {
+		got = append(got, b...)
+	}
+	slices.Sort(got)
+	if len(ic.batches) != 2 || !slices.Equal(got, []string{"arch", "fav1", "fav2"}) {
+		t.Errorf("batches: %v, want [fav1 fav2] and [arch]", ic.batches)
+	}
+}
+
+func TestUpdateMetadataGPS(t *testing.T) {
+	const n = 5
+	ic := &icBulkUpdate{}
+	app := UpCmd{
+		client:  ic,
+		Journal: logger.NewJournal(logger.NoLogger{}),
+	}
+	ctx := context.Background()
+	for i := 0; i < n; i++ {
+		app.updateMetadata(ctx, &browser.LocalAssetFile{Favorite: true, Latitude: 48 + float64(i), Longitude: 2.3}, strconv.Itoa(i))
+	}
+	err := app.UpdateMetadataByBatch(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ic.updated) != n || len(ic.batches) != 0 {
+		t.Errorf("%d geotagged favorites: %d call(s) by asset and %d batch(es), want %d and 0", n, len(ic.updated), len(ic.batches), n)
+	}
+}
+
+func TestUpdateMetadataFlush(t *testing.T) {
+	ic := &icBulkUpdate{}
+	app := UpCmd{
+		client:  ic,
+		Journal: logger.NewJournal(logger.NoLogger{}),
+	}
+	ctx := context.Background()
+	for i := 0; i < 2*bulkUpdateBatchSize+1; i++ {
+		app.updateMetadata(ctx, &browser.LocalAssetFile{Favorite: true}, strconv.Itoa(i))
+	}
+	if len(ic.batches) != 2 {
+		t.Errorf("%d batch(es) sent during the run, want 2", len(ic.batches))
+	}
+	err := app.UpdateMetadataByBatch(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ic.batches) != 3 || len(ic.batches[2]) != 1 || app.bulkUpdated != 2*bulkUpdateBatchSize+1 {
+		t.Errorf("%d batch(es), %d asset(s) updated, want 3 batches and %d assets", len(ic.batches), app.bulkUpdated, 2*bulkUpdateBatchSize+1)
+	}
+}
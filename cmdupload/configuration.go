@@ -78,3 +78,37 @@ func (sl StringList) Exclude(s string) bool {
 	}
 	return slices.Contains(sl, strings.ToLower(s))
 }
+
+// MimeOverrides gives the content type to be used for the upload of the files having the extension
+type MimeOverrides map[string]string
+
+// Set parses a value like cr3=image/x-canon-cr3
+func (mo *MimeOverrides) Set(s string) error {
+	ext, mtype, ok := strings.Cut(s, "=")
+	ext = strings.ToLower(strings.TrimSpace(ext))
+	mtype = strings.ToLower(strings.TrimSpace(mtype))
+	if !ok || ext == "" || ext == "." {
+		return fmt.Errorf("invalid mime type override %q, expecting ext=type", s)
+	}
+	if !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	main, sub, ok := strings.Cut(mtype, "/")
+	if !ok || (main != "image" && main != "video") || sub == "" || strings.ContainsAny(sub, " ;/") {
+		return fmt.Errorf("invalid mime type %q for the extension %s, expecting image/... or video/...", mtype, ext)
+	}
+	if *mo == nil {
+		*mo = MimeOverrides{}
+	}
+	(*mo)[ext] = mtype
+	return nil
+}
+
+func (mo MimeOverrides) String() string {
+	l := []string{}
+	for ext, mtype := range mo {
+		l = append(l, ext+"="+mtype)
+	}
+	slices.Sort(l)
+	return strings.Join(l, ", ")
+}
@@ -0,0 +1,64 @@
+package cmdupload
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/simulot/immich-go/browser"
+)
+
+// dryRunPlan sums up the changes a dry run would make on the server
+type dryRunPlan struct {
+	uploads       int // new assets
+	uploadBytes   int64
+	replaced      int // server's assets replaced by a better local file
+	replacedBytes int64
+	duplicates    int // assets already on the server, not uploaded
+	albumsCreated int
+	createdAssets int // assets put in the created albums
+	albumsUpdated int
+	updatedAssets int // assets added to albums already on the server
+}
+
+// record the decision taken for the asset
+func (p *dryRunPlan) record(advice *Advice, a *browser.LocalAssetFile) {
+	switch advice.Advice {
+	case NotOnServer:
+		p.uploads++
+		p.uploadBytes += a.Size()
+	case SmallerOnServer, ReplaceOnServer:
+		p.replaced++
+		p.replacedBytes += a.Size()
+	case SameOnServer, BetterOnServer, TrashedOnServer, LocalCopy:
+		p.duplicates++
+	}
+}
+
+// lines gives the plan, one change per line
+func (p *dryRunPlan) lines() []string {
+	return []string{
+		"Plan of the changes on the server:",
+		fmt.Sprintf("%6d new upload(s), %s", p.uploads, formatBytes(int(p.uploadBytes))),
+		fmt.Sprintf("%6d asset(s) replaced, %s", p.replaced, formatBytes(int(p.replacedBytes))),
+		fmt.Sprintf("%6d duplicate(s) skipped", p.duplicates),
+		fmt.Sprintf("%6d album(s) created, with %d asset(s)", p.albumsCreated, p.createdAssets),
+		fmt.Sprintf("%6d asset(s) added to %d existing album(s)", p.updatedAssets, p.albumsUpdated),
+	}
+}
+
+// reportPlan prints the plan of a dry run after the report, and writes it into the DryRunOutput file
+func (app *UpCmd) reportPlan() error {
+	lines := app.plan.lines()
+	for _, l := range lines {
+		app.Journal.Summary("%s", l)
+	}
+	if app.DryRunOutput == "" {
+		return nil
+	}
+	err := os.WriteFile(app.DryRunOutput, []byte(strings.Join(lines, "\n")+"\n"), 0o644)
+	if err != nil {
+		return fmt.Errorf("can't write the -dry-run-output file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package cmdupload
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/logger"
+)
+
+func TestDryRunPlan(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "plan.txt")
+	app := UpCmd{
+		client:       &icAlbumFailures{},
+		Journal:      logger.NewJournal(logger.NoLogger{}),
+		DryRun:       true,
+		DryRunOutput: output,
+		updateAlbums: map[string]map[string]any{
+			"Album":     {"1": nil, "2": nil},
+			"New album": {"3": nil},
+		},
+	}
+	for _, c := range []struct {
+		advice AdviceCode
+		size   int
+	}{
+		{NotOnServer, 1024},
+		{NotOnServer, 2048},
+		{SmallerOnServer, 512},
+		{ReplaceOnServer, 512},
+		{SameOnServer, 10},
+		{BetterOnServer, 10},
+	} {
+		app.plan.record(&Advice{Advice: c.advice}, &browser.LocalAssetFile{FileSize: c.size})
+	}
+	if err := app.ManageAlbums(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.reportPlan(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := dryRunPlan{
+		uploads:       2,
+		uploadBytes:   3072,
+		replaced:      2,
+		replacedBytes: 1024,
+		duplicates:    2,
+		albumsCreated: 1,
+		createdAssets: 1,
+		albumsUpdated: 1,
+		updatedAssets: 2,
+	}
+	if app.plan != expected {
+		t.Errorf("plan = %+v, want %+v", app.plan, expected)
+	}
+
+	b, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `Plan of the changes on the server:
+     2 new upload(s), 3.0 KB
+     2 asset(s) replaced, 1.0 KB
+     2 duplicate(s) skipped
+     1 album(s) created, with 1 asset(s)
+     2 asset(s) added to 1 existing album(s)
+`
+	if string(b) != want {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", b, want)
+	}
+}
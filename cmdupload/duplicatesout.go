@@ -0,0 +1,48 @@
+package cmdupload
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+
+	"github.com/simulot/immich-go/browser"
+)
+
+// serverDuplicate is a file skipped because it is already on the server
+type serverDuplicate struct {
+	file   string // The local file
+	id     string // ID of the server's asset
+	name   string // Name of the server's asset, empty when unknown
+	reason string // Why the file is the same as the server's asset
+}
+
+func (app *UpCmd) recordDuplicate(a *browser.LocalAssetFile, id string, name string, reason string) {
+	app.duplicates = append(app.duplicates, serverDuplicate{file: a.FileName, id: id, name: name, reason: reason})
+}
+
+// reportDuplicates gives the number of files skipped as duplicates,
+// and writes them into the DuplicatesOut file to check the matches before using -delete
+func (app *UpCmd) reportDuplicates() error {
+	if app.DuplicatesOut == "" {
+		return nil
+	}
+	f, err := os.Create(app.DuplicatesOut)
+	if err != nil {
+		return fmt.Errorf("can't write the -duplicates-out file: %w", err)
+	}
+	w := csv.NewWriter(f)
+	_ = w.Write([]string{"file", "server_id", "server_name", "reason"})
+	for _, d := range app.duplicates {
+		_ = w.Write([]string{d.file, d.id, d.name, d.reason})
+	}
+	w.Flush()
+	err = w.Error()
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("can't write the -duplicates-out file: %w", err)
+	}
+	app.Journal.Summary("%6d files skipped as duplicates of server's assets, listed in %s", len(app.duplicates), app.DuplicatesOut)
+	return nil
+}
@@ -0,0 +1,73 @@
+package cmdupload
+
+import (
+	"context"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/logger"
+)
+
+func TestDuplicatesOut(t *testing.T) {
+	fsys := fstest.MapFS{
+		"trip/photo_01.cr3": {Data: []byte("photo 01")},
+		"trip/photo_02.cr3": {Data: []byte("photo 02")},
+		"trip/photo_03.cr3": {Data: []byte("photo 03")},
+	}
+	out := filepath.Join(t.TempDir(), "duplicates.csv")
+	app := UpCmd{
+		client:        &icServerDedup{existing: map[string]string{"trip/photo_02.cr3": "server-02"}},
+		Journal:       logger.NewJournal(logger.NoLogger{}),
+		DuplicatesOut: out,
+		updateAlbums:  map[string]map[string]any{},
+		AssetIndex: &AssetIndex{assets: []*immich.Asset{{
+			ID:               "server-01",
+			OriginalFileName: "photo_01",
+			OriginalPath:     "upload/photo_01.cr3",
+			ExifInfo:         immich.ExifInfo{FileSizeInByte: len(fsys["trip/photo_01.cr3"].Data)},
+		}}},
+	}
+	app.AssetIndex.ReIndex()
+	for _, name := range []string{"trip/photo_01.cr3", "trip/photo_02.cr3", "trip/photo_03.cr3"} {
+		a := &browser.LocalAssetFile{
+			FSys:     fsys,
+			FileName: name,
+			Title:    name[len("trip/"):],
+			FileSize: len(fsys[name].Data),
+		}
+		if err := app.handleAsset(context.Background(), a); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	if err := app.reportDuplicates(); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"file", "server_id", "server_name"},
+		{"trip/photo_01.cr3", "server-01", "photo_01.cr3"},
+		{"trip/photo_02.cr3", "server-02", ""},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("records = %v, want %d lines", records, len(want))
+	}
+	for i, r := range records {
+		if !reflect.DeepEqual(r[:3], want[i]) || r[3] == "" {
+			t.Errorf("line %d = %v, want %v and a reason", i+1, r, want[i])
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package cmdupload
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/logger"
+)
+
+func TestSkipEmpty(t *testing.T) {
+	fsys := fstest.MapFS{
+		"photo_01.cr3": {Data: []byte("photo 01")},
+		"photo_02.cr3": {Data: []byte{}},
+		"photo_03.cr3": {Data: []byte("photo 03")},
+	}
+	tc := []struct {
+		name      string
+		skipEmpty bool
+		want      []string
+	}{
+		{name: "skipped", skipEmpty: true, want: []string{"photo_01.cr3", "photo_03.cr3"}},
+		{name: "uploaded", skipEmpty: false, want: []string{"photo_01.cr3", "photo_03.cr3", "photo_02.cr3"}},
+	}
+	for _, c := range tc {
+		t.Run(c.name, func(t *testing.T) {
+			ic := &icCatchUploadsAssets{albums: map[string][]string{}}
+			app := UpCmd{
+				client:       ic,
+				Journal:      logger.NewJournal(logger.NoLogger{}),
+				SkipEmpty:    c.skipEmpty,
+				updateAlbums: map[string]map[string]any{},
+				AssetIndex:   &AssetIndex{},
+			}
+			app.AssetIndex.ReIndex()
+			assets := []*browser.LocalAssetFile{
+				// the size given by the browser
+				{FSys: fsys, FileName: "photo_01.cr3", Title: "photo_01.cr3", FileSize: 8},
+				// the size is unknown
+				{FSys: fsys, FileName: "photo_03.cr3", Title: "photo_03.cr3"},
+				{FSys: fsys, FileName: "photo_02.cr3", Title: "photo_02.cr3"},
+			}
+			for _, a := range assets {
+				if err := app.handleAsset(context.Background(), a); err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+			}
+			if !reflect.DeepEqual(ic.assets, c.want) {
+				t.Errorf("uploads = %v, want %v", ic.assets, c.want)
+			}
+		})
+	}
+}
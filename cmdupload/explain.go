@@ -0,0 +1,82 @@
+package cmdupload
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/helpers/gen"
+	"github.com/simulot/immich-go/immich"
+)
+
+// describeAsset gives the fields of the server's asset used by ShouldUpload
+func describeAsset(sa *immich.Asset) string {
+	return fmt.Sprintf("%s name:%q date:%s size:%d pixels:%dx%d checksum:%s path:%q",
+		sa.ID, sa.OriginalFileName, sa.ExifInfo.DateTimeOriginal.Format(time.DateTime), sa.ExifInfo.FileSizeInByte,
+		sa.ExifInfo.ExifImageWidth, sa.ExifInfo.ExifImageHeight, sa.Checksum, sa.OriginalPath)
+}
+
+// Dump writes the byID and byName maps of the index into the file
+func (ai *AssetIndex) Dump(name string) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return fmt.Errorf("can't write the -dump-index file: %w", err)
+	}
+	w := bufio.NewWriter(f)
+
+	fmt.Fprintf(w, "# %d server's assets\n", len(ai.assets))
+	fmt.Fprintf(w, "\n# byID: %d keys, made of the upper case name and the size\n", len(ai.byID))
+	keys := gen.MapKeys(ai.byID)
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(w, "%s\n\t%s\n", k, describeAsset(ai.byID[k]))
+	}
+	fmt.Fprintf(w, "\n# byName: %d keys, made of the name and the extension\n", len(ai.byName))
+	keys = gen.MapKeys(ai.byName)
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(w, "%s\n", k)
+		for _, sa := range ai.byName[k] {
+			fmt.Fprintf(w, "\t%s\n", describeAsset(sa))
+		}
+	}
+
+	err = w.Flush()
+	if err == nil {
+		err = f.Close()
+	} else {
+		f.Close()
+	}
+	if err != nil {
+		return fmt.Errorf("can't write the -dump-index file: %w", err)
+	}
+	return nil
+}
+
+// explainf reports a step of ShouldUpload when the file is explained
+func (ai *AssetIndex) explainf(format string, args ...any) {
+	if ai.explain != nil {
+		ai.explain(format, args...)
+	}
+}
+
+// isExplained tells if the file is the one given by -explain, by its path or its name
+func (app *UpCmd) isExplained(a *browser.LocalAssetFile) bool {
+	return app.Explain != "" && (a.FileName == app.Explain || path.Base(a.FileName) == app.Explain)
+}
+
+// startExplain prints the decisions of ShouldUpload for the file given by -explain.
+// The returned function stops the explanation.
+func (app *UpCmd) startExplain(a *browser.LocalAssetFile) func() {
+	if !app.isExplained(a) {
+		return func() {}
+	}
+	app.AssetIndex.explain = func(format string, args ...any) {
+		app.Journal.OK("explain %s: "+format, append([]any{a.FileName}, args...)...)
+	}
+	return func() { app.AssetIndex.explain = nil }
+}
@@ -0,0 +1,105 @@
+package cmdupload
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/immich"
+)
+
+func TestDumpIndex(t *testing.T) {
+	date := time.Date(2023, 6, 23, 13, 32, 52, 0, time.UTC)
+	ai := &AssetIndex{
+		assets: []*immich.Asset{
+			{ID: "id-1", OriginalFileName: "IMG_0001", OriginalPath: "upload/IMG_0001.jpg", Checksum: "sum1", ExifInfo: immich.ExifInfo{FileSizeInByte: 100, DateTimeOriginal: immich.ImmichTime{Time: date}}},
+			{ID: "id-2", OriginalFileName: "IMG_0001", OriginalPath: "upload/IMG_0001.jpg", Checksum: "sum2", ExifInfo: immich.ExifInfo{FileSizeInByte: 200, DateTimeOriginal: immich.ImmichTime{Time: date}}},
+		},
+	}
+	ai.ReIndex()
+	name := filepath.Join(t.TempDir(), "index.txt")
+	if err := ai.Dump(name); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		"# byID: 2 keys",
+		"IMG_0001.JPG-100\n\tid-1 name:\"IMG_0001\" date:2023-06-23 13:32:52 size:100",
+		"IMG_0001.JPG-200\n\tid-2 ",
+		"# byName: 1 keys",
+		"IMG_0001.jpg\n\tid-1 ",
+		"checksum:sum2",
+	} {
+		if !strings.Contains(string(b), s) {
+			t.Errorf("the dump doesn't contain %q:\n%s", s, b)
+		}
+	}
+}
+
+func TestExplain(t *testing.T) {
+	date := time.Date(2023, 6, 23, 13, 32, 52, 0, time.UTC)
+	ai := &AssetIndex{
+		assets: []*immich.Asset{
+			{ID: "id-1", OriginalFileName: "IMG_0001", OriginalPath: "upload/IMG_0001.jpg", ExifInfo: immich.ExifInfo{FileSizeInByte: 100, DateTimeOriginal: immich.ImmichTime{Time: date.Add(time.Hour)}}},
+			{ID: "id-2", OriginalFileName: "IMG_0001", OriginalPath: "upload/IMG_0001.jpg", ExifInfo: immich.ExifInfo{FileSizeInByte: 200, DateTimeOriginal: immich.ImmichTime{Time: date}}},
+		},
+	}
+	ai.ReIndex()
+	steps := []string{}
+	ai.explain = func(format string, args ...any) {
+		steps = append(steps, fmt.Sprintf(format, args...))
+	}
+	advice, err := ai.ShouldUpload(&browser.LocalAssetFile{FileName: "photos/IMG_0001.jpg", Title: "IMG_0001.jpg", FileSize: 150, DateTaken: date})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if advice.Advice != BetterOnServer {
+		t.Errorf("advice: %s, want %s", advice.Advice, BetterOnServer)
+	}
+	want := []string{
+		"byID[\"IMG_0001.JPG-150\"] not found",
+		"byName[\"IMG_0001.jpg\"] found 2 candidate(s)",
+		"candidate id-1 ",
+		"  dates differ by -1h0m0s, 5 minutes or more: not the same asset",
+		"candidate id-2 ",
+		"  same date, sizes differ by -50 bytes, local pixels:0x0",
+		"  bigger on server",
+	}
+	got := []string{}
+	for _, s := range steps[1:] {
+		for _, w := range want {
+			if strings.HasPrefix(s, w) {
+				got = append(got, w)
+				break
+			}
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("steps:\n%s", strings.Join(steps, "\n"))
+	}
+}
+
+func TestIsExplained(t *testing.T) {
+	app := UpCmd{Explain: "IMG_0001.jpg"}
+	for name, want := range map[string]bool{
+		"photos/IMG_0001.jpg": true,
+		"IMG_0001.jpg":        true,
+		"photos/IMG_0002.jpg": false,
+	} {
+		if got := app.isExplained(&browser.LocalAssetFile{FileName: name}); got != want {
+			t.Errorf("isExplained(%q)=%v, want %v", name, got, want)
+		}
+	}
+	app.Explain = "photos/IMG_0002.jpg"
+	if !app.isExplained(&browser.LocalAssetFile{FileName: "photos/IMG_0002.jpg"}) {
+		t.Errorf("isExplained by path must be true")
+	}
+}
@@ -0,0 +1,79 @@
+package cmdupload
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/logger"
+)
+
+// readFileList reads the list of the files to be imported, one path per line
+func readFileList(name string) (map[string]bool, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("can't read the -only-files list: %w", err)
+	}
+	defer f.Close()
+	return parseFileList(f)
+}
+
+func parseFileList(r io.Reader) (map[string]bool, error) {
+	list := map[string]bool{}
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		l := strings.TrimSpace(s.Text())
+		if l != "" {
+			list[l] = true
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("can't read the -only-files list: %w", err)
+	}
+	return list, nil
+}
+
+// recordFailure keeps the path of the assets that failed, for the -failures-out file.
+// An asset failing before succeeding, like uploaded again after a VERIFY_FAILED, is removed from the list.
+func (app *UpCmd) recordFailure(a *browser.LocalAssetFile, action logger.Action) {
+	if app.FailuresOut == "" {
+		return
+	}
+	switch action {
+	case logger.UPLOADED, logger.UPGRADED, logger.SERVER_DUPLICATE:
+		if app.failures[a.FileName] {
+			delete(app.failures, a.FileName)
+			app.failureList = slices.DeleteFunc(app.failureList, func(name string) bool { return name == a.FileName })
+		}
+		return
+	case logger.SERVER_ERROR, logger.VERIFY_FAILED:
+	default:
+		return
+	}
+	if app.failures == nil {
+		app.failures = map[string]bool{}
+	}
+	if !app.failures[a.FileName] {
+		app.failures[a.FileName] = true
+		app.failureList = append(app.failureList, a.FileName)
+	}
+}
+
+// writeFailures writes the paths of the failed assets, one per line.
+// The file is usable as the -only-files list of the next run.
+func writeFailures(name string, list []string) error {
+	b := strings.Builder{}
+	for _, l := range list {
+		b.WriteString(l)
+		b.WriteString("\n")
+	}
+	err := os.WriteFile(name, []byte(b.String()), 0o644)
+	if err != nil {
+		return fmt.Errorf("can't write the -failures-out file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package cmdupload
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/logger"
+)
+
+// icFailingUploads fails the upload of the given files
+type icFailingUploads struct {
+	stubIC
+	failing  map[string]bool
+	uploaded []string
+}
+
+func (c *icFailingUploads) AssetUpload(ctx context.Context, a *browser.LocalAssetFile) (immich.AssetResponse, error) {
+	if c.failing[a.FileName] {
+		return immich.AssetResponse{}, errors.New("server error")
+	}
+	c.uploaded = append(c.uploaded, a.FileName)
+	return immich.AssetResponse{ID: "id-" + a.FileName}, nil
+}
+
+func TestParseFileList(t *testing.T) {
+	list, err := parseFileList(strings.NewReader("a/photo.jpg\n\n  b/video.mp4  \r\nc d/photo.jpg\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]bool{"a/photo.jpg": true, "b/video.mp4": true, "c d/photo.jpg": true}
+	if !reflect.DeepEqual(list, want) {
+		t.Errorf("parseFileList() = %v, want %v", list, want)
+	}
+}
+
+func TestFailuresRetry(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a/1.cr3": {Data: []byte("one")},
+		"a/2.cr3": {Data: []byte("two")},
+		"b/3.cr3": {Data: []byte("three")},
+	}
+	names := []string{"a/1.cr3", "a/2.cr3", "b/3.cr3"}
+	failuresFile := filepath.Join(t.TempDir(), "failures.txt")
+
+	run := func(ic iClient, onlyFiles map[string]bool) *UpCmd {
+		app := &UpCmd{
+			client:       ic,
+			Journal:      logger.NewJournal(logger.NoLogger{}),
+			FailuresOut:  failuresFile,
+			onlyFiles:    onlyFiles,
+			AssetIndex:   &AssetIndex{},
+			updateAlbums: map[string]map[string]any{},
+		}
+		app.AssetIndex.ReIndex()
+		for _, n := range names {
+			err := app.handleAsset(context.Background(), &browser.LocalAssetFile{
+				FSys:     fsys,
+				FileName: n,
+				Title:    filepath.Base(n),
+				FileSize: len(fsys[n].Data),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		err := writeFailures(app.FailuresOut, app.failureList)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return app
+	}
+
+	// first run, two failures
+	run(&icFailingUploads{failing: map[string]bool{"a/2.cr3": true, "b/3.cr3": true}}, nil)
+	b, err := os.ReadFile(failuresFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "a/2.cr3\nb/3.cr3\n" {
+		t.Errorf("failures file = %q", string(b))
+	}
+
+	// second run, only the failed files
+	only, err := readFileList(failuresFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ic := &icFailingUploads{}
+	app := run(ic, only)
+	if !reflect.DeepEqual(ic.uploaded, []string{"a/2.cr3", "b/3.cr3"}) {
+		t.Errorf("uploaded = %v", ic.uploaded)
+	}
+	if app.Journal.Count(logger.NOT_SELECTED) != 1 {
+		t.Errorf("expecting 1 NOT_SELECTED entry, got %d", app.Journal.Count(logger.NOT_SELECTED))
+	}
+	b, err = os.ReadFile(failuresFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) != 0 {
+		t.Errorf("failures file = %q, expecting an empty file", string(b))
+	}
+}
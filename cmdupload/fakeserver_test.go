@@ -0,0 +1,58 @@
+package cmdupload
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/simulot/immich-go/immich/immichtest"
+	"github.com/simulot/immich-go/logger"
+)
+
+var _ iClient = (*immichtest.FakeServer)(nil)
+
+func TestUploadFakeServer(t *testing.T) {
+	s, err := immichtest.ParseScenario(strings.NewReader(`{
+		"albums": [{"id": "album-low", "albumName": "low"}]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := immichtest.NewFakeServer(s)
+	ctx := context.Background()
+	args := []string{"-create-album-folder", "-create-stacks=false", "TEST_DATA/folder"}
+
+	err = UploadCommand(ctx, srv, logger.NoLogger{}, args)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	uploaded := len(srv.Uploaded())
+	if uploaded == 0 {
+		t.Fatal("no file uploaded")
+	}
+	// the album low exists, the other ones are created
+	albums := map[string]int{}
+	total := 0
+	for _, al := range srv.Albums() {
+		albums[al.AlbumName]++
+		total += al.AssetCount
+		if al.AlbumName == "low" && (al.ID != "album-low" || al.AssetCount != 8) {
+			t.Errorf("album low = %+v, want the 8 assets in the existing album", al)
+		}
+	}
+	if albums["low"] != 1 || total != uploaded {
+		t.Errorf("albums = %v with %d assets, want %d assets and one album low", albums, total, uploaded)
+	}
+
+	// the second run finds the assets on the server
+	err = UploadCommand(ctx, srv, logger.NoLogger{}, args)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got := len(srv.Uploaded()); got != uploaded {
+		t.Errorf("%d files uploaded again", got-uploaded)
+	}
+	if got := srv.Calls("AssetUpload"); got != uploaded {
+		t.Errorf("AssetUpload called %d times, want %d", got, uploaded)
+	}
+}
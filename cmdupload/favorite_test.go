@@ -0,0 +1,149 @@
+package cmdupload
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/logger"
+)
+
+// icFavorite records the favorite status sent after the upload, alone or by batch
+type icFavorite struct {
+	stubIC
+	favorites map[string]bool
+}
+
+func (c *icFavorite) AssetUpload(ctx context.Context, a *browser.LocalAssetFile) (immich.AssetResponse, error) {
+	return immich.AssetResponse{ID: a.FileName}, nil
+}
+
+func (c *icFavorite) UpdateAsset(ctx context.Context, ID string, a *browser.LocalAssetFile) (*immich.Asset, error) {
+	c.favorites[ID] = a.Favorite
+	return &immich.Asset{}, nil
+}
+
+func (c *icFavorite) UpdateAssets(ctx context.Context, IDs []string, isArchived bool, isFavorite bool, latitude float64, longitude float64, removeParent bool, stackParentId string) error {
+	for _, ID := range IDs {
+		c.favorites[ID] = isFavorite
+	}
+	return nil
+}
+
+func TestFavoriteMinRating(t *testing.T) {
+	date := time.Date(2023, 6, 23, 13, 32, 52, 0, time.UTC)
+	ratings := map[string]int{
+		"unrated.cr3":  0,
+		"rejected.cr3": -1,
+		"three.cr3":    3,
+		"four.cr3":     4,
+		"five.cr3":     5,
+	}
+	fsys := fstest.MapFS{}
+	for name := range ratings {
+		fsys[name] = &fstest.MapFile{Data: []byte(name)}
+	}
+
+	tests := []struct {
+		name      string
+		minRating int
+		want      map[string]bool
+	}{
+		{
+			name:      "disabled",
+			minRating: 0,
+			want:      map[string]bool{"unrated.cr3": false, "rejected.cr3": false, "three.cr3": false, "four.cr3": false, "five.cr3": false},
+		},
+		{
+			name:      "4 stars",
+			minRating: 4,
+			want:      map[string]bool{"unrated.cr3": false, "rejected.cr3": false, "three.cr3": false, "four.cr3": true, "five.cr3": true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ic := &icFavorite{favorites: map[string]bool{}}
+			app := UpCmd{
+				client:            ic,
+				Journal:           logger.NewJournal(logger.NoLogger{}),
+				FavoriteMinRating: tt.minRating,
+				updateAlbums:      map[string]map[string]any{},
+				AssetIndex:        &AssetIndex{},
+			}
+			app.AssetIndex.ReIndex()
+			for name, r := range ratings {
+				a := &browser.LocalAssetFile{
+					FSys:      fsys,
+					FileName:  name,
+					Title:     name,
+					FileSize:  len(name),
+					DateTaken: date,
+					Rating:    r,
+				}
+				if err := app.handleAsset(context.Background(), a); err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+			}
+			if err := app.UpdateMetadataByBatch(context.Background()); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			// the assets without favorite aren't updated
+			got := map[string]bool{}
+			for name := range ratings {
+				got[name] = ic.favorites[name]
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("favorites = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFavoriteKeptOnUpgrade(t *testing.T) {
+	date := time.Date(2023, 6, 23, 13, 32, 52, 0, time.UTC)
+	fsys := fstest.MapFS{
+		"photo.cr3": {Data: []byte("the bigger local photo")},
+	}
+	for _, fav := range []bool{false, true} {
+		ic := &icFavorite{favorites: map[string]bool{}}
+		app := UpCmd{
+			client:       ic,
+			Journal:      logger.NewJournal(logger.NoLogger{}),
+			updateAlbums: map[string]map[string]any{},
+			AssetIndex: &AssetIndex{assets: []*immich.Asset{{
+				ID:               "server-id",
+				OriginalFileName: "photo",
+				OriginalPath:     "upload/photo.cr3",
+				IsFavorite:       fav,
+				ExifInfo: immich.ExifInfo{
+					FileSizeInByte:   5,
+					DateTimeOriginal: immich.ImmichTime{Time: date},
+				},
+			}}},
+		}
+		app.AssetIndex.ReIndex()
+		a := &browser.LocalAssetFile{
+			FSys:      fsys,
+			FileName:  "photo.cr3",
+			Title:     "photo.cr3",
+			FileSize:  len(fsys["photo.cr3"].Data),
+			DateTaken: date,
+		}
+		if err := app.handleAsset(context.Background(), a); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if err := app.UpdateMetadataByBatch(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if app.Journal.Count(logger.UPGRADED) != 1 {
+			t.Fatalf("the server's asset should be upgraded")
+		}
+		if ic.favorites["photo.cr3"] != fav {
+			t.Errorf("server favorite %v: new asset favorite %v", fav, ic.favorites["photo.cr3"])
+		}
+	}
+}
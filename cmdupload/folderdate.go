@@ -0,0 +1,44 @@
+package cmdupload
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/simulot/immich-go/helpers/tzone"
+)
+
+// parseDateFromFolder checks the Go time layout given to -date-from-folder
+func parseDateFromFolder(s string) (string, error) {
+	if !strings.Contains(s, "06") {
+		return "", fmt.Errorf("the -date-from-folder layout %q must contain the year, like 2006 or 2006-01", s)
+	}
+	ref := time.Date(1999, 12, 31, 0, 0, 0, 0, time.UTC)
+	if _, err := time.Parse(s, ref.Format(s)); err != nil {
+		return "", fmt.Errorf("invalid -date-from-folder layout %q: %w", s, err)
+	}
+	return s, nil
+}
+
+// folderDate gives the date parsed from the name of the closest folder of the file
+// matching the -date-from-folder layout. The folder name can continue after the date,
+// like "1987 Summer" with the layout 2006.
+func (app *UpCmd) folderDate(name string) time.Time {
+	loc, err := tzone.Naive()
+	if err != nil {
+		loc = time.Local
+	}
+	for dir := path.Dir(name); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		base := path.Base(dir)
+		if t, err := time.ParseInLocation(app.DateFromFolder, base, loc); err == nil {
+			return t
+		}
+		if len(base) > len(app.DateFromFolder) {
+			if t, err := time.ParseInLocation(app.DateFromFolder, base[:len(app.DateFromFolder)], loc); err == nil {
+				return t
+			}
+		}
+	}
+	return time.Time{}
+}
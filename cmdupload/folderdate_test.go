@@ -0,0 +1,166 @@
+package cmdupload
+
+import (
+	"context"
+	"path"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/helpers/tzone"
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/immich/immichtest"
+	"github.com/simulot/immich-go/logger"
+)
+
+func TestParseDateFromFolder(t *testing.T) {
+	for s, ok := range map[string]bool{
+		"2006":     true,
+		"2006-01":  true,
+		"01.02.06": true,
+		"Jan 2006": true,
+		"01-02":    false,
+		"holidays": false,
+	} {
+		_, err := parseDateFromFolder(s)
+		if (err == nil) != ok {
+			t.Errorf("parseDateFromFolder(%q): %v, want valid: %v", s, err, ok)
+		}
+	}
+}
+
+func TestFolderDate(t *testing.T) {
+	tzone.SetNaive(time.UTC)
+	defer tzone.SetNaive(nil)
+	tests := []struct {
+		layout string
+		name   string
+		want   time.Time
+	}{
+		{layout: "2006", name: "scans/1987/scan_001.tif", want: time.Date(1987, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{layout: "2006", name: "scans/1987 Summer/scan_001.tif", want: time.Date(1987, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{layout: "2006", name: "scans/1987/roll 2/scan_001.tif", want: time.Date(1987, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{layout: "2006-01", name: "1987-06/roll 2/scan_001.tif", want: time.Date(1987, 6, 1, 0, 0, 0, 0, time.UTC)},
+		{layout: "2006", name: "scans/roll 2/scan_001.tif"},
+		{layout: "2006", name: "scan_001.tif"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := UpCmd{DateFromFolder: tt.layout}
+			if got := app.folderDate(tt.name); !got.Equal(tt.want) {
+				t.Errorf("folderDate() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// icDates records the dates of capture of the uploaded assets
+type icDates struct {
+	stubIC
+	dates map[string]time.Time
+}
+
+func (c *icDates) AssetUpload(ctx context.Context, a *browser.LocalAssetFile) (immich.AssetResponse, error) {
+	c.dates[a.FileName] = a.DateTaken
+	return immich.AssetResponse{ID: a.FileName}, nil
+}
+
+func TestDateFromFolder(t *testing.T) {
+	tzone.SetNaive(time.UTC)
+	defer tzone.SetNaive(nil)
+	scanDate := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	folderDate := time.Date(1987, 1, 1, 0, 0, 0, 0, time.UTC)
+	fsys := fstest.MapFS{
+		"1987/scan_001.cr3": {Data: []byte("scan 001")},
+		"1987/scan_002.cr3": {Data: []byte("scan 002")},
+	}
+	files := map[string]time.Time{
+		"1987/scan_001.cr3": {},
+		"1987/scan_002.cr3": scanDate,
+	}
+	tests := []struct {
+		name  string
+		force bool
+		want  map[string]time.Time
+	}{
+		{name: "when missing", want: map[string]time.Time{"1987/scan_001.cr3": folderDate, "1987/scan_002.cr3": scanDate}},
+		{name: "forced", force: true, want: map[string]time.Time{"1987/scan_001.cr3": folderDate, "1987/scan_002.cr3": folderDate}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ic := &icDates{dates: map[string]time.Time{}}
+			app := UpCmd{
+				client:              ic,
+				Journal:             logger.NewJournal(logger.NoLogger{}),
+				DateFromFolder:      "2006",
+				DateFromFolderForce: tt.force,
+				updateAlbums:        map[string]map[string]any{},
+				AssetIndex:          &AssetIndex{},
+			}
+			app.AssetIndex.ReIndex()
+			for name, d := range files {
+				a := &browser.LocalAssetFile{
+					FSys:      fsys,
+					FileName:  name,
+					Title:     name[len("1987/"):],
+					FileSize:  len(fsys[name].Data),
+					DateTaken: d,
+				}
+				if err := app.handleAsset(context.Background(), a); err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+			}
+			for name, want := range tt.want {
+				if !ic.dates[name].Equal(want) {
+					t.Errorf("%s: date %s, want %s", name, ic.dates[name], want)
+				}
+			}
+		})
+	}
+}
+
+func TestDateFromFolderSentToServer(t *testing.T) {
+	tzone.SetNaive(time.UTC)
+	defer tzone.SetNaive(nil)
+	folderDate := time.Date(1987, 1, 1, 0, 0, 0, 0, time.UTC)
+	fsys := fstest.MapFS{
+		"1987/scan_001.cr3": {Data: []byte("scan 001")},
+		"scan_002.cr3":      {Data: []byte("scan 002")},
+	}
+	srv := immichtest.NewFakeServer(&immichtest.Scenario{})
+	app := UpCmd{
+		client:              srv,
+		Journal:             logger.NewJournal(logger.NoLogger{}),
+		DateFromFolder:      "2006",
+		DateFromFolderForce: true,
+		updateAlbums:        map[string]map[string]any{},
+		AssetIndex:          &AssetIndex{},
+	}
+	app.AssetIndex.ReIndex()
+	scanDate := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	for _, name := range []string{"1987/scan_001.cr3", "scan_002.cr3"} {
+		a := &browser.LocalAssetFile{
+			FSys:      fsys,
+			FileName:  name,
+			Title:     path.Base(name),
+			FileSize:  len(fsys[name].Data),
+			DateTaken: scanDate,
+		}
+		if err := app.handleAsset(context.Background(), a); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	if got := srv.Calls("UpdateAsset"); got != 1 {
+		t.Errorf("UpdateAsset called %d times, want 1", got)
+	}
+	for _, a := range srv.Assets() {
+		want := scanDate
+		if a.OriginalFileName == "scan_001" {
+			want = folderDate
+		}
+		if !a.ExifInfo.DateTimeOriginal.Equal(want) {
+			t.Errorf("%s: dateTimeOriginal %s, want %s", a.OriginalFileName, a.ExifInfo.DateTimeOriginal, want)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package cmdupload
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// parseFolderDepth parses the -album-folder-depth value: a depth like 2, or a range of depths like 2-3
+func parseFolderDepth(s string) (int, int, error) {
+	from, to, isRange := strings.Cut(strings.TrimSpace(s), "-")
+	f, err := strconv.Atoi(strings.TrimSpace(from))
+	if err != nil || f < 1 {
+		return 0, 0, fmt.Errorf("invalid folder depth %q, expecting a depth like 2 or a range like 2-3", s)
+	}
+	t := f
+	if isRange {
+		t, err = strconv.Atoi(strings.TrimSpace(to))
+		if err != nil || t < f {
+			return 0, 0, fmt.Errorf("invalid folder depth %q, expecting a depth like 2 or a range like 2-3", s)
+		}
+	}
+	return f, t, nil
+}
+
+// folderAlbum gives the album's name of the folder: its name, or with -album-folder-depth,
+// the names of the folders at these depths joined by the separator.
+// The folders above the depth keep their name.
+func (app *UpCmd) folderAlbum(dir string) string {
+	if dir == "." || dir == "/" || dir == "" {
+		return ""
+	}
+	parts := strings.Split(dir, "/")
+	if app.albumDepthFrom == 0 || app.albumDepthFrom > len(parts) {
+		return path.Base(dir)
+	}
+	to := min(app.albumDepthTo, len(parts))
+	return strings.Join(parts[app.albumDepthFrom-1:to], app.AlbumFolderSeparator)
+}
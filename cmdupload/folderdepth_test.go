@@ -0,0 +1,63 @@
+package cmdupload
+
+import (
+	"testing"
+
+	"github.com/simulot/immich-go/browser"
+)
+
+func TestFolderAlbum(t *testing.T) {
+	tests := []struct {
+		depth string
+		dir   string
+		want  string
+	}{
+		{depth: "", dir: "Trips/2023/Italy/Rome", want: "Rome"},
+		{depth: "2", dir: "Trips/2023/Italy/Rome", want: "2023"},
+		{depth: "2-3", dir: "Trips/2023/Italy/Rome", want: "2023 - Italy"},
+		{depth: "1-9", dir: "Trips/2023/Italy/Rome", want: "Trips - 2023 - Italy - Rome"},
+		{depth: "2-3", dir: "Trips/2023", want: "2023"},
+		{depth: "2", dir: "Trips", want: "Trips"}, // above the depth
+		{depth: "2", dir: ".", want: ""},
+	}
+	for _, tt := range tests {
+		app := UpCmd{AlbumFolderSeparator: " - "}
+		if tt.depth != "" {
+			var err error
+			app.albumDepthFrom, app.albumDepthTo, err = parseFolderDepth(tt.depth)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		if got := app.folderAlbum(tt.dir); got != tt.want {
+			t.Errorf("depth %q, folder %q: got %q, want %q", tt.depth, tt.dir, got, tt.want)
+		}
+	}
+}
+
+func TestParseFolderDepth(t *testing.T) {
+	for _, s := range []string{"0", "-1", "3-2", "a", "2-", "2-b"} {
+		if _, _, err := parseFolderDepth(s); err == nil {
+			t.Errorf("parseFolderDepth(%q): expecting an error", s)
+		}
+	}
+	if f, to, err := parseFolderDepth(" 2 - 3 "); err != nil || f != 2 || to != 3 {
+		t.Errorf("parseFolderDepth(\" 2 - 3 \") = %d, %d, %v", f, to, err)
+	}
+}
+
+func TestFolderAlbumNameGroup(t *testing.T) {
+	tmpl, err := parseAlbumNameTemplate("{{.Group}} / {{.Dir}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := UpCmd{AlbumFolderSeparator: " - ", albumDepthFrom: 2, albumDepthTo: 2}
+	a := &browser.LocalAssetFile{FileName: "Trips/2023/Italy/Rome/photo.jpg"}
+	got, err := folderAlbumName(tmpl, a, app.folderAlbum("Trips/2023/Italy/Rome"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "2023 / Rome" {
+		t.Errorf("got %q, want %q", got, "2023 / Rome")
+	}
+}
@@ -0,0 +1,131 @@
+package cmdupload
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/simulot/immich-go/browser"
+)
+
+// heicConverter is an external tool making a JPEG from a HEIC file
+type heicConverter struct {
+	name string
+	path string                        // path of the executable
+	args func(in, out string) []string // command line converting in into out
+}
+
+// heicConverters lists the known tools, by order of preference
+var heicConverters = []heicConverter{
+	{name: "heif-convert", args: func(in, out string) []string { return []string{"-q", "92", in, out} }},
+	{name: "ffmpeg", args: func(in, out string) []string {
+		return []string{"-loglevel", "error", "-y", "-i", in, "-frames:v", "1", "-q:v", "2", out}
+	}},
+}
+
+// findHEICConverter gives the first known tool found with lookPath, or nil
+func findHEICConverter(lookPath func(string) (string, error)) *heicConverter {
+	for _, c := range heicConverters {
+		if p, err := lookPath(c.name); err == nil {
+			c.path = p
+			return &c
+		}
+	}
+	return nil
+}
+
+// isHEIC tells if the file is transcoded with -transcode-heic-to-jpeg
+func isHEIC(name string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	return ext == ".heic" || ext == ".heif"
+}
+
+// renditionFS gives the converted file in place of the original one, the other files
+// like the sidecar are read from the original file system
+type renditionFS struct {
+	fs.FS
+	name string // name of the converted file
+	file string // path of the converted file on the disk
+}
+
+func (r renditionFS) Open(name string) (fs.File, error) {
+	if name == r.name {
+		return os.Open(r.file)
+	}
+	return r.FS.Open(name)
+}
+
+// transcodeHEIC converts the HEIC file into a JPEG with the converter, and makes the asset
+// give the JPEG file. The date and the GPS location are given by a sidecar when the asset
+// has none. The returned function gives back the original file to the asset.
+func (app *UpCmd) transcodeHEIC(ctx context.Context, a *browser.LocalAssetFile) (func(), error) {
+	dir, err := os.MkdirTemp("", "immich-go-heic")
+	if err != nil {
+		return nil, err
+	}
+	in := filepath.Join(dir, "original"+path.Ext(a.FileName))
+	out := filepath.Join(dir, "rendition.jpg")
+	err = copyToFile(a.FSys, a.FileName, in)
+	if err == nil {
+		var b []byte
+		b, err = exec.CommandContext(ctx, app.heicConverter.path, app.heicConverter.args(in, out)...).CombinedOutput()
+		if err != nil {
+			err = fmt.Errorf("%s: %w: %s", app.heicConverter.name, err, strings.TrimSpace(string(b)))
+		}
+	}
+	var fi os.FileInfo
+	if err == nil {
+		fi, err = os.Stat(out)
+	}
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	original := *a
+	name := strings.TrimSuffix(a.FileName, path.Ext(a.FileName)) + ".jpg"
+	err = a.SetContent(renditionFS{FS: a.FSys, name: name, file: out}, int(fi.Size()))
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	a.FileName = name
+	a.Title = strings.TrimSuffix(a.Title, path.Ext(a.Title)) + ".jpg"
+	a.MimeType = "image/jpeg"
+	if a.SideCar == nil {
+		a.SideCar = dateGPSSidecar(a)
+	}
+
+	return func() {
+		a.SetContent(original.FSys, original.FileSize)
+		a.FileName = original.FileName
+		a.Title = original.Title
+		a.MimeType = original.MimeType
+		a.SideCar = original.SideCar
+		os.RemoveAll(dir)
+	}, nil
+}
+
+// copyToFile copies the file of fsys on the disk
+func copyToFile(fsys fs.FS, name string, dest string) error {
+	r, err := fsys.Open(name)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	w, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, r)
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,92 @@
+package cmdupload
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os/exec"
+	"testing"
+	"testing/fstest"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/logger"
+)
+
+func TestFindHEICConverter(t *testing.T) {
+	tc := []struct {
+		name     string
+		found    []string
+		expected string
+	}{
+		{name: "none"},
+		{name: "ffmpeg", found: []string{"ffmpeg"}, expected: "ffmpeg"},
+		{name: "both", found: []string{"ffmpeg", "heif-convert"}, expected: "heif-convert"},
+	}
+	for _, c := range tc {
+		t.Run(c.name, func(t *testing.T) {
+			lookPath := func(name string) (string, error) {
+				for _, f := range c.found {
+					if f == name {
+						return "/usr/bin/" + name, nil
+					}
+				}
+				return "", exec.ErrNotFound
+			}
+			got := findHEICConverter(lookPath)
+			switch {
+			case got == nil && c.expected != "":
+				t.Errorf("no converter, want %s", c.expected)
+			case got != nil && got.name != c.expected:
+				t.Errorf("converter = %s, want %q", got.name, c.expected)
+			case got != nil && got.path != "/usr/bin/"+c.expected:
+				t.Errorf("path = %s", got.path)
+			}
+		})
+	}
+}
+
+func TestTranscodeHEIC(t *testing.T) {
+	cp, err := exec.LookPath("cp")
+	if err != nil {
+		t.Skip("cp is needed to fake the converter")
+	}
+	app := UpCmd{
+		Journal: logger.NewJournal(logger.NoLogger{}),
+		// the fake converter copies the file
+		heicConverter: &heicConverter{name: "cp", path: cp, args: func(in, out string) []string { return []string{in, out} }},
+	}
+	fsys := fstest.MapFS{"photos/IMG_1.HEIC": &fstest.MapFile{Data: []byte("converted content")}}
+	a := &browser.LocalAssetFile{FSys: fsys, FileName: "photos/IMG_1.HEIC", Title: "IMG_1.HEIC", FileSize: 17, Latitude: 48.8}
+
+	restore, err := app.transcodeHEIC(context.Background(), a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.FileName != "photos/IMG_1.jpg" || a.Title != "IMG_1.jpg" || a.MimeType != "image/jpeg" {
+		t.Errorf("unexpected rendition: %s, %s, %s", a.FileName, a.Title, a.MimeType)
+	}
+	if a.SideCar == nil || a.SideCar.Latitude != 48.8 {
+		t.Errorf("the sidecar doesn't give the GPS location: %+v", a.SideCar)
+	}
+	f, err := a.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(f)
+	a.Close()
+	if err != nil || string(b) != "converted content" {
+		t.Errorf("unexpected content %q, %v", b, err)
+	}
+
+	restore()
+	if a.FileName != "photos/IMG_1.HEIC" || a.Title != "IMG_1.HEIC" || a.SideCar != nil {
+		t.Errorf("the original file isn't given back: %s, %s, %+v", a.FileName, a.Title, a.SideCar)
+	}
+
+	app.heicConverter.path = "false"
+	_, err = app.transcodeHEIC(context.Background(), a)
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		t.Errorf("expecting the converter's failure, got %v", err)
+	}
+}
@@ -0,0 +1,53 @@
+package cmdupload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/simulot/immich-go/browser"
+)
+
+var errHookFailed = errors.New("the -on-upload command has failed")
+
+// Environment variables given to the -on-upload command
+const (
+	hookEnvPath   = "IMMICH_GO_ASSET_PATH"  // path of the file, relative to the imported folder or archive
+	hookEnvTitle  = "IMMICH_GO_ASSET_TITLE" // name of the asset on the server
+	hookEnvID     = "IMMICH_GO_ASSET_ID"    // ID of the asset on the server
+	hookEnvAction = "IMMICH_GO_ACTION"      // UPLOADED, or UPGRADED when the asset replaces a server's asset
+)
+
+// hookCommand gives the shell running the -on-upload command
+func hookCommand(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	return exec.CommandContext(ctx, "sh", "-c", command)
+}
+
+// runUploadHook runs the -on-upload command after the upload of the asset.
+// A failure is logged, and stops the import with -hook-fatal.
+func (app *UpCmd) runUploadHook(ctx context.Context, a *browser.LocalAssetFile, ID string, action string) error {
+	cmd := hookCommand(ctx, app.OnUpload)
+	cmd.Env = append(os.Environ(),
+		hookEnvPath+"="+a.FileName,
+		hookEnvTitle+"="+a.Title,
+		hookEnvID+"="+ID,
+		hookEnvAction+"="+action,
+	)
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		return nil
+	}
+	err = fmt.Errorf("%w: %w: %s", errHookFailed, err, strings.TrimSpace(string(out)))
+	if app.HookFatal {
+		return err
+	}
+	app.Journal.Error("%s: %s", a.FileName, err)
+	return nil
+}
@@ -0,0 +1,90 @@
+package cmdupload
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"testing/fstest"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/logger"
+)
+
+func TestOnUpload(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test commands need sh")
+	}
+	out := filepath.Join(t.TempDir(), "hook.txt")
+	tc := []struct {
+		name    string
+		command string
+		fatal   bool
+		dryRun  bool
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "variables",
+			command: `echo "$IMMICH_GO_ACTION $IMMICH_GO_ASSET_ID $IMMICH_GO_ASSET_PATH $IMMICH_GO_ASSET_TITLE" >> "` + out + `"`,
+			want:    "UPLOADED trip/photo 1.cr3 trip/photo 1.cr3 photo 1.cr3\n",
+		},
+		{
+			name:    "dry run",
+			command: `echo "$IMMICH_GO_ASSET_ID" >> "` + out + `"`,
+			dryRun:  true,
+		},
+		{
+			name:    "failure",
+			command: "exit 3",
+		},
+		{
+			name:    "fatal failure",
+			command: "exit 3",
+			fatal:   true,
+			wantErr: true,
+		},
+	}
+	for _, c := range tc {
+		t.Run(c.name, func(t *testing.T) {
+			os.Remove(out)
+			fsys := fstest.MapFS{
+				"trip/photo 1.cr3": {Data: []byte("photo 1")},
+				"trip/photo 2.cr3": {Data: []byte("photo 2")},
+			}
+			app := UpCmd{
+				client:       &icCatchUploadsAssets{},
+				Journal:      logger.NewJournal(logger.NoLogger{}),
+				OnUpload:     c.command,
+				HookFatal:    c.fatal,
+				DryRun:       c.dryRun,
+				updateAlbums: map[string]map[string]any{},
+				// photo 2 is already on the server
+				AssetIndex: &AssetIndex{assets: []*immich.Asset{{
+					ID:               "server-2",
+					OriginalFileName: "photo 2",
+					OriginalPath:     "upload/photo 2.cr3",
+					ExifInfo:         immich.ExifInfo{FileSizeInByte: 7},
+				}}},
+			}
+			app.AssetIndex.ReIndex()
+			var err error
+			for _, name := range []string{"trip/photo 1.cr3", "trip/photo 2.cr3"} {
+				a := &browser.LocalAssetFile{FSys: fsys, FileName: name, Title: filepath.Base(name), FileSize: 7}
+				if err = app.handleAsset(context.Background(), a); err != nil {
+					break
+				}
+			}
+			if (err != nil) != c.wantErr || (err != nil && !errors.Is(err, errHookFailed)) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			b, _ := os.ReadFile(out)
+			if string(b) != c.want {
+				t.Errorf("hook output = %q, want %q", b, c.want)
+			}
+		})
+	}
+}
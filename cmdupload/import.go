@@ -0,0 +1,86 @@
+package cmdupload
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/helpers/fshelper"
+	"github.com/simulot/immich-go/immich"
+)
+
+// pathMapping translates a local folder into the path of the same folder seen by the server
+type pathMapping struct {
+	local  string
+	server string
+}
+
+// PathMap is the list of -path-map translations
+type PathMap []pathMapping
+
+func (pm *PathMap) String() string {
+	l := []string{}
+	for _, m := range *pm {
+		l = append(l, m.local+"="+m.server)
+	}
+	return strings.Join(l, ",")
+}
+
+// Set adds a translation given as local=server
+func (pm *PathMap) Set(s string) error {
+	local, server, ok := strings.Cut(s, "=")
+	local, server = strings.TrimSpace(local), strings.TrimSpace(server)
+	if !ok || local == "" || server == "" {
+		return fmt.Errorf("invalid -path-map %q, expecting local=server", s)
+	}
+	local, err := filepath.Abs(local)
+	if err != nil {
+		return err
+	}
+	*pm = append(*pm, pathMapping{
+		local:  filepath.ToSlash(local),
+		server: strings.TrimSuffix(server, "/"),
+	})
+	return nil
+}
+
+// serverPath gives the server's path of the local file. The longest matching local folder is used.
+func (pm PathMap) serverPath(local string) (string, error) {
+	local = filepath.ToSlash(local)
+	best := -1
+	for i, m := range pm {
+		if (local == m.local || strings.HasPrefix(local, strings.TrimSuffix(m.local, "/")+"/")) &&
+			(best < 0 || len(m.local) > len(pm[best].local)) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return "", fmt.Errorf("no -path-map for the file %s", local)
+	}
+	return pm[best].server + strings.TrimPrefix(local, strings.TrimSuffix(pm[best].local, "/")), nil
+}
+
+// importAsset registers the file in place on the server, with its path translated by the -path-map
+func (app *UpCmd) importAsset(ctx context.Context, a *browser.LocalAssetFile) (immich.AssetResponse, error) {
+	local, err := fshelper.DiskPath(a.FSys, a.FileName)
+	if err != nil {
+		return immich.AssetResponse{}, fmt.Errorf("can't import %s: %w", a.FileName, err)
+	}
+	assetPath, err := app.PathMap.serverPath(local)
+	if err != nil {
+		return immich.AssetResponse{}, err
+	}
+	sidecarPath := ""
+	if a.SideCar != nil && a.SideCar.OnFSsys {
+		local, err := fshelper.DiskPath(a.FSys, a.SideCar.FileName)
+		if err == nil {
+			sidecarPath, err = app.PathMap.serverPath(local)
+		}
+		if err != nil {
+			return immich.AssetResponse{}, err
+		}
+	}
+	return app.client.AssetImport(ctx, a, assetPath, sidecarPath)
+}
@@ -0,0 +1,38 @@
+package cmdupload
+
+import (
+	"testing"
+)
+
+func TestPathMap(t *testing.T) {
+	var pm PathMap
+	for _, s := range []string{"/mnt/nas=/data", "/mnt/nas/photos=/photos/", "/mnt/nas/pho=/pho"} {
+		if err := pm.Set(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tc := []struct {
+		local string
+		want  string
+		err   bool
+	}{
+		{local: "/mnt/nas/doc/a.jpg", want: "/data/doc/a.jpg"},
+		{local: "/mnt/nas/photos/2023/b.jpg", want: "/photos/2023/b.jpg"},
+		{local: "/mnt/nas/photos2/c.jpg", want: "/data/photos2/c.jpg"},
+		{local: "/mnt/other/d.jpg", err: true},
+	}
+	for _, c := range tc {
+		got, err := pm.serverPath(c.local)
+		if (err != nil) != c.err {
+			t.Errorf("serverPath(%s) error: %v", c.local, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("serverPath(%s)=%q, want %q", c.local, got, c.want)
+		}
+	}
+
+	if err := pm.Set("/mnt/nas"); err == nil {
+		t.Errorf("expecting an error for a mapping without server path")
+	}
+}
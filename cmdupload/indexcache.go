@@ -0,0 +1,142 @@
+package cmdupload
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/simulot/immich-go/immich"
+)
+
+// indexCache is the content of the -index-cache file
+type indexCache struct {
+	UpdatedAt time.Time       `json:"updatedAt"` // Most recent update of the cached assets, as given by the server
+	Assets    []*immich.Asset `json:"assets"`
+}
+
+// cachedServerAssets gives the server's assets kept into the -index-cache file, updated with the
+// assets changed on the server since the last run. All the assets are requested when the cache is
+// missing or reset, and when the number of assets differs from the server's count.
+func (app *UpCmd) cachedServerAssets(ctx context.Context) ([]*immich.Asset, error) {
+	var list []*immich.Asset
+	valid := false
+	if !app.IndexCacheReset {
+		cache, err := readIndexCache(app.IndexCache)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+		case err != nil:
+			app.Journal.Warning("Can't use the index cache: %s", err)
+		default:
+			list, err = app.updateIndexCache(ctx, cache)
+			if err != nil {
+				return nil, err
+			}
+			valid = app.checkIndexCache(ctx, len(list))
+		}
+	}
+	if !valid {
+		var err error
+		list, err = app.getServerAssets(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err := writeIndexCache(app.IndexCache, list)
+	if err != nil {
+		app.Journal.Warning("%s", err)
+	}
+	return list, nil
+}
+
+// updateIndexCache merges the assets changed on the server since the cache was written
+func (app *UpCmd) updateIndexCache(ctx context.Context, cache indexCache) ([]*immich.Asset, error) {
+	byID := map[string]*immich.Asset{}
+	for _, a := range cache.Assets {
+		byID[a.ID] = a
+	}
+	changes := 0
+	err := app.client.GetAssetsUpdatedAfter(ctx, cache.UpdatedAt, func(a *immich.Asset) {
+		changes++
+		if a.IsTrashed {
+			delete(byID, a.ID)
+			return
+		}
+		byID[a.ID] = a
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]*immich.Asset, 0, len(byID))
+	for _, a := range cache.Assets {
+		if byID[a.ID] != nil {
+			list = append(list, byID[a.ID])
+			delete(byID, a.ID)
+		}
+	}
+	for _, a := range byID {
+		list = append(list, a)
+	}
+	app.Journal.OK("%d asset(s) read from the index cache, %d changed on the server since %s", len(cache.Assets), changes, cache.UpdatedAt.Format(time.DateTime))
+	return list, nil
+}
+
+// checkIndexCache compares the number of cached assets with the server's count
+func (app *UpCmd) checkIndexCache(ctx context.Context, count int) bool {
+	stats, err := app.client.GetAssetStatistics(ctx)
+	if err != nil {
+		app.Journal.Warning("Can't check the index cache: %s", err)
+		return false
+	}
+	if stats.Total != count {
+		app.Journal.Warning("The index cache is stale: %d asset(s) in the cache, %d on the server", count, stats.Total)
+		return false
+	}
+	return true
+}
+
+func readIndexCache(name string) (indexCache, error) {
+	var cache indexCache
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return cache, err
+	}
+	err = json.Unmarshal(b, &cache)
+	if err != nil {
+		return cache, fmt.Errorf("can't read the index cache %s: %w", name, err)
+	}
+	return cache, nil
+}
+
+// writeIndexCache writes the assets into the cache, through a temporary file to never leave a partial cache
+func writeIndexCache(name string, list []*immich.Asset) error {
+	cache := indexCache{Assets: list}
+	for _, a := range list {
+		if a.UpdatedAt.After(cache.UpdatedAt) {
+			cache.UpdatedAt = a.UpdatedAt.Time
+		}
+	}
+	b, err := json.Marshal(cache)
+	if err != nil {
+		return fmt.Errorf("can't write the index cache: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".*")
+	if err != nil {
+		return fmt.Errorf("can't write the index cache: %w", err)
+	}
+	_, err = tmp.Write(b)
+	err = errors.Join(err, tmp.Close())
+	if err == nil {
+		err = os.Rename(tmp.Name(), name)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("can't write the index cache: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package cmdupload
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/logger"
+)
+
+type icIndexCache struct {
+	stubIC
+	all       []*immich.Asset
+	updated   []*immich.Asset
+	total     int
+	fullReads int
+	since     time.Time
+}
+
+func (c *icIndexCache) GetAllAssetsWithFilter(ctx context.Context, opt *immich.GetAssetOptions, filter func(*immich.Asset)) error {
+	c.fullReads++
+	for _, a := range c.all {
+		if opt.Match(a) {
+			filter(a)
+		}
+	}
+	return nil
+}
+
+func (c *icIndexCache) GetAssetsUpdatedAfter(ctx context.Context, after time.Time, filter func(*immich.Asset)) error {
+	c.since = after
+	for _, a := range c.updated {
+		filter(a)
+	}
+	return nil
+}
+
+func (c *icIndexCache) GetAssetStatistics(ctx context.Context) (immich.AssetStatistics, error) {
+	return immich.AssetStatistics{Total: c.total}, nil
+}
+
+func TestIndexCache(t *testing.T) {
+	t0 := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	asset := func(id string, updated time.Time, trashed bool) *immich.Asset {
+		return &immich.Asset{ID: id, OriginalFileName: id, UpdatedAt: immich.ImmichTime{Time: updated}, IsTrashed: trashed}
+	}
+	cacheFile := filepath.Join(t.TempDir(), "index.json")
+
+	run := func(ic *icIndexCache, reset bool) []string {
+		app := UpCmd{
+			client:          ic,
+			Journal:         logger.NewJournal(logger.NoLogger{}),
+			IndexCache:      cacheFile,
+			IndexCacheReset: reset,
+		}
+		list, err := app.cachedServerAssets(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids := []string{}
+		for _, a := range list {
+			ids = append(ids, a.ID)
+		}
+		sort.Strings(ids)
+		return ids
+	}
+
+	// no cache yet
+	ic := &icIndexCache{all: []*immich.Asset{asset("a", t0, false), asset("b", t0.Add(time.Hour), false), asset("t", t0, true)}}
+	if got := run(ic, false); !reflect.DeepEqual(got, []string{"a", "b"}) || ic.fullReads != 1 {
+		t.Errorf("first run: assets %v, %d full read(s)", got, ic.fullReads)
+	}
+
+	// changes since the last run
+	ic = &icIndexCache{
+		updated: []*immich.Asset{asset("b", t0.Add(2*time.Hour), true), asset("c", t0.Add(2*time.Hour), false)},
+		total:   2,
+	}
+	if got := run(ic, false); !reflect.DeepEqual(got, []string{"a", "c"}) || ic.fullReads != 0 {
+		t.Errorf("second run: assets %v, %d full read(s)", got, ic.fullReads)
+	}
+	if !ic.since.Equal(t0.Add(time.Hour)) {
+		t.Errorf("second run: changes requested since %s, want %s", ic.since, t0.Add(time.Hour))
+	}
+
+	// stale cache
+	ic = &icIndexCache{all: []*immich.Asset{asset("a", t0, false)}, total: 1}
+	if got := run(ic, false); !reflect.DeepEqual(got, []string{"a"}) || ic.fullReads != 1 {
+		t.Errorf("stale cache: assets %v, %d full read(s)", got, ic.fullReads)
+	}
+
+	// reset
+	ic = &icIndexCache{all: []*immich.Asset{asset("d", t0, false)}, total: 1}
+	if got := run(ic, true); !reflect.DeepEqual(got, []string{"d"}) || ic.fullReads != 1 {
+		t.Errorf("reset: assets %v, %d full read(s)", got, ic.fullReads)
+	}
+}
@@ -0,0 +1,51 @@
+package cmdupload
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/logger"
+)
+
+func TestJXLServerVersion(t *testing.T) {
+	fsys := fstest.MapFS{
+		"photos/photo.jxl": {Data: []byte("\xff\x0ajxl photo")},
+	}
+	tests := []struct {
+		name     string
+		version  immich.ServerVersion
+		uploaded bool
+	}{
+		{name: "old server", version: immich.ServerVersion{Major: 1, Minor: 85}, uploaded: false},
+		{name: "recent server", version: immich.ServerVersion{Major: 1, Minor: 100}, uploaded: true},
+		{name: "unknown version", uploaded: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ic := &icCatchUploadsAssets{}
+			app := UpCmd{
+				client:        ic,
+				Journal:       logger.NewJournal(logger.NoLogger{}),
+				serverVersion: tt.version,
+				updateAlbums:  map[string]map[string]any{},
+				AssetIndex:    &AssetIndex{},
+			}
+			app.AssetIndex.ReIndex()
+			a := &browser.LocalAssetFile{
+				FSys:     fsys,
+				FileName: "photos/photo.jxl",
+				Title:    "photo.jxl",
+				FileSize: len(fsys["photos/photo.jxl"].Data),
+			}
+			if err := app.handleAsset(context.Background(), a); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if got := len(ic.assets) == 1; got != tt.uploaded {
+				t.Errorf("uploaded = %v, want %v", got, tt.uploaded)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package cmdupload
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/logger"
+)
+
+func TestImportKeywords(t *testing.T) {
+	tc := []struct {
+		name     string
+		keywords bool
+		want     map[string]map[string]any
+	}{
+		{
+			name:     "keywords",
+			keywords: true,
+			want: map[string]map[string]any{
+				"Trip":                {"photo.cr3": nil},
+				"Places/Europe/Italy": {"photo.cr3": nil},
+			},
+		},
+		{
+			name: "no keywords",
+			want: map[string]map[string]any{"Trip": {"photo.cr3": nil}},
+		},
+	}
+	for _, c := range tc {
+		t.Run(c.name, func(t *testing.T) {
+			app := UpCmd{
+				client:         &icCatchUploadsAssets{},
+				Journal:        logger.NewJournal(logger.NoLogger{}),
+				Tags:           []string{"Trip"},
+				ImportKeywords: c.keywords,
+				AssetIndex:     &AssetIndex{},
+				updateAlbums:   map[string]map[string]any{},
+			}
+			app.AssetIndex.ReIndex()
+			a := &browser.LocalAssetFile{
+				FSys:     fstest.MapFS{"photo.cr3": {Data: []byte("content")}},
+				FileName: "photo.cr3",
+				Title:    "photo.cr3",
+				FileSize: 7,
+				Tags:     []string{"Places/Europe/Italy", "Trip"},
+			}
+			if err := app.handleAsset(context.Background(), a); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(app.updateTags, c.want) {
+				t.Errorf("tags = %v, want %v", app.updateTags, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package cmdupload
+
+import (
+	"context"
+	"fmt"
+)
+
+// setLibrary directs the uploads into the library given by its ID or by its name
+func (app *UpCmd) setLibrary(ctx context.Context) error {
+	libraries, err := app.client.GetAllLibraries(ctx)
+	if err != nil {
+		return fmt.Errorf("can't get the libraries from the server: %w", err)
+	}
+	ID := ""
+	for _, l := range libraries {
+		if l.ID == app.Library {
+			ID = l.ID
+			break
+		}
+		if l.Name == app.Library {
+			if ID != "" {
+				return fmt.Errorf("several libraries are named %q, give the ID of the library", app.Library)
+			}
+			ID = l.ID
+		}
+	}
+	if ID == "" {
+		return fmt.Errorf("the library %q doesn't exist on the server", app.Library)
+	}
+	app.client.SetLibrary(ID)
+	app.Journal.OK("The assets are uploaded into the library %s", app.Library)
+	return nil
+}
@@ -0,0 +1,55 @@
+package cmdupload
+
+import (
+	"context"
+	"testing"
+
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/logger"
+)
+
+// icLibraries gives the server's libraries and records the library set for the uploads
+type icLibraries struct {
+	stubIC
+	libraries []immich.Library
+	library   string
+}
+
+func (c *icLibraries) GetAllLibraries(context.Context) ([]immich.Library, error) {
+	return c.libraries, nil
+}
+
+func (c *icLibraries) SetLibrary(ID string) {
+	c.library = ID
+}
+
+func TestSetLibrary(t *testing.T) {
+	libraries := []immich.Library{
+		{ID: "id-1", Name: "Family"},
+		{ID: "id-2", Name: "Archive"},
+		{ID: "id-3", Name: "Archive"},
+	}
+	tc := []struct {
+		library string
+		want    string
+		wantErr bool
+	}{
+		{library: "Family", want: "id-1"},
+		{library: "id-3", want: "id-3"},
+		{library: "Work", wantErr: true},
+		{library: "Archive", wantErr: true},
+	}
+	for _, c := range tc {
+		t.Run(c.library, func(t *testing.T) {
+			ic := &icLibraries{libraries: libraries}
+			app := UpCmd{client: ic, Journal: logger.NewJournal(logger.NoLogger{}), Library: c.library}
+			err := app.setLibrary(context.Background())
+			if (err != nil) != c.wantErr {
+				t.Fatalf("error = %v, want error %v", err, c.wantErr)
+			}
+			if ic.library != c.want {
+				t.Errorf("library = %q, want %q", ic.library, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package cmdupload
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/helpers/nfc"
+	"github.com/simulot/immich-go/immich"
+)
+
+// localCopy is the first file of the source having a given content, and the ID of its asset
+type localCopy struct {
+	fileName string
+	ID       string
+}
+
+// localCopies gives the first file of the source by content key
+type localCopies map[string]localCopy
+
+// localCopyKey identifies the content of the file: its checksum with -checksum,
+// otherwise its name, its date of capture and its size.
+// An empty key means the file can't be compared. With -rename-on-conflict, the files
+// having the same name, date and size may differ: only their checksums are compared.
+func (app *UpCmd) localCopyKey(a *browser.LocalAssetFile) string {
+	if app.Checksum {
+		sum, err := a.Checksum()
+		if err != nil {
+			app.Journal.Warning("%s: can't compute the checksum: %s", a.FileName, err)
+			return ""
+		}
+		return "sum:" + sum
+	}
+	if a.DateTaken.IsZero() || app.RenameOnConflict {
+		return ""
+	}
+	name := nfc.String(strings.ToUpper(path.Base(a.Title)))
+	return fmt.Sprintf("name:%s-%d-%d", name, a.DateTaken.Unix(), a.Size())
+}
+
+// localCopyAdvice gives the advice for a copy of a file already handled in this run:
+// the copy isn't uploaded, it only brings its albums to the asset of the first file.
+func (app *UpCmd) localCopyAdvice(a *browser.LocalAssetFile, key string) *Advice {
+	if key == "" {
+		return nil
+	}
+	first, ok := app.localCopies[key]
+	if !ok {
+		return nil
+	}
+	app.localCopyCount++
+	return &Advice{
+		Advice:      LocalCopy,
+		Message:     "same file as " + first.fileName,
+		ServerAsset: &immich.Asset{ID: first.ID, JustUploaded: true},
+		LocalAsset:  a,
+	}
+}
+
+// recordLocalCopy gives the asset of the file to its next copies
+func (app *UpCmd) recordLocalCopy(a *browser.LocalAssetFile, key string, ID string) {
+	if key == "" || ID == "" {
+		return
+	}
+	if _, ok := app.localCopies[key]; ok {
+		return
+	}
+	if app.localCopies == nil {
+		app.localCopies = localCopies{}
+	}
+	app.localCopies[key] = localCopy{fileName: a.FileName, ID: ID}
+}
+
+// reportLocalCopies gives the number of copies found in the source
+func (app *UpCmd) reportLocalCopies() {
+	if app.localCopyCount > 0 {
+		app.Journal.Summary("%6d copies of files of the source handled once", app.localCopyCount)
+	}
+}
@@ -0,0 +1,99 @@
+package cmdupload
+
+import (
+	"context"
+	"path"
+	"reflect"
+	"sort"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/helpers/gen"
+	"github.com/simulot/immich-go/logger"
+)
+
+func TestLocalCopies(t *testing.T) {
+	date := time.Date(2023, 6, 23, 13, 32, 52, 0, time.UTC)
+	fsys := fstest.MapFS{
+		"2023/trip/IMG_0001.cr3":    {Data: []byte("photo 01")},
+		"backup/trip/IMG_0001.cr3":  {Data: []byte("photo 01")},
+		"backup/other/copy of.cr3":  {Data: []byte("photo 01")},
+		"backup/other/IMG_0002.cr3": {Data: []byte("photo 02")},
+	}
+	files := []string{"2023/trip/IMG_0001.cr3", "backup/trip/IMG_0001.cr3", "backup/other/copy of.cr3", "backup/other/IMG_0002.cr3"}
+
+	tests := []struct {
+		name       string
+		checksum   bool
+		wantUpload []string
+		wantAlbums map[string][]string
+		wantCopies int
+	}{
+		{
+			name:       "name, date and size",
+			wantUpload: []string{"2023/trip/IMG_0001.cr3", "backup/other/IMG_0002.cr3", "backup/other/copy of.cr3"},
+			wantAlbums: map[string][]string{
+				"trip":  {"2023/trip/IMG_0001.cr3"},
+				"other": {"backup/other/IMG_0002.cr3", "backup/other/copy of.cr3"},
+			},
+			wantCopies: 1,
+		},
+		{
+			name:       "checksum",
+			checksum:   true,
+			wantUpload: []string{"2023/trip/IMG_0001.cr3", "backup/other/IMG_0002.cr3"},
+			wantAlbums: map[string][]string{
+				"trip":  {"2023/trip/IMG_0001.cr3"},
+				"other": {"2023/trip/IMG_0001.cr3", "backup/other/IMG_0002.cr3"},
+			},
+			wantCopies: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ic := &icCatchUploadsAssets{}
+			app := UpCmd{
+				client:                 ic,
+				Journal:                logger.NewJournal(logger.NoLogger{}),
+				TrustServerDedup:       true,
+				CreateAlbumAfterFolder: true,
+				Checksum:               tt.checksum,
+				updateAlbums:           map[string]map[string]any{},
+				AssetIndex:             &AssetIndex{},
+			}
+			app.AssetIndex.ReIndex()
+			for _, name := range files {
+				a := &browser.LocalAssetFile{
+					FSys:      fsys,
+					FileName:  name,
+					Title:     path.Base(name),
+					FileSize:  len(fsys[name].Data),
+					DateTaken: date,
+				}
+				if err := app.handleAsset(context.Background(), a); err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+			}
+			sort.Strings(ic.assets)
+			if !reflect.DeepEqual(ic.assets, tt.wantUpload) {
+				t.Errorf("uploads = %v, want %v", ic.assets, tt.wantUpload)
+			}
+			albums := map[string][]string{}
+			for album, ids := range app.updateAlbums {
+				albums[album] = gen.MapKeys(ids)
+				sort.Strings(albums[album])
+			}
+			if !reflect.DeepEqual(albums, tt.wantAlbums) {
+				t.Errorf("albums = %v, want %v", albums, tt.wantAlbums)
+			}
+			if app.localCopyCount != tt.wantCopies {
+				t.Errorf("copies = %d, want %d", app.localCopyCount, tt.wantCopies)
+			}
+			if got := app.Journal.Count(logger.LOCAL_DUPLICATE); got != tt.wantCopies {
+				t.Errorf("local duplicates = %d, want %d", got, tt.wantCopies)
+			}
+		})
+	}
+}
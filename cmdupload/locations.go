@@ -0,0 +1,101 @@
+package cmdupload
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/helpers/fshelper/myflag"
+	"github.com/simulot/immich-go/immich/metadata"
+)
+
+// folderLocation gives the GPS coordinates of the assets of a folder and its sub-folders
+type folderLocation struct {
+	Folder    string
+	Latitude  float64
+	Longitude float64
+}
+
+// readLocations reads a CSV file with lines like: folder,latitude,longitude
+// Folders are relative to the imported path. A first line that doesn't give coordinates is taken as a header.
+func readLocations(name string) ([]folderLocation, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseLocations(f)
+}
+
+func parseLocations(r io.Reader) ([]folderLocation, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = 3
+	cr.Comment = '#'
+	cr.TrimLeadingSpace = true
+
+	var locations []folderLocation
+	for line := 1; ; line++ {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("can't read the locations: %w", err)
+		}
+		lat, long, err := myflag.ParseLatLong(record[1] + "," + record[2])
+		if err != nil {
+			if line == 1 {
+				continue
+			}
+			return nil, fmt.Errorf("can't read the locations, line %d: %w", line, err)
+		}
+		folder := path.Clean("/" + strings.TrimSpace(record[0]))[1:] // "" for the root
+		locations = append(locations, folderLocation{
+			Folder:    folder,
+			Latitude:  lat,
+			Longitude: long,
+		})
+	}
+	return locations, nil
+}
+
+// locationFor gives the coordinates to be set to an asset: the ones of the nearest folder
+// listed in the locations file, or the ones of the -gps option.
+func (app *UpCmd) locationFor(a *browser.LocalAssetFile) (float64, float64, bool) {
+	dir := path.Dir(a.FileName)
+	best := -1
+	for i, l := range app.locations {
+		if l.Folder != "" && dir != l.Folder && !strings.HasPrefix(dir, l.Folder+"/") {
+			continue
+		}
+		if best < 0 || len(l.Folder) > len(app.locations[best].Folder) {
+			best = i
+		}
+	}
+	if best >= 0 {
+		return app.locations[best].Latitude, app.locations[best].Longitude, true
+	}
+	if app.GPS.IsSet() {
+		return app.GPS.Latitude, app.GPS.Longitude, true
+	}
+	return 0, 0, false
+}
+
+// hasEmbeddedGPS checks the sidecar and the file's metadata for GPS coordinates.
+// When the file can't be read, the coordinates are considered as present to not override them.
+func (app *UpCmd) hasEmbeddedGPS(a *browser.LocalAssetFile) bool {
+	if a.SideCar != nil && a.SideCar.HasGPS(a.FSys) {
+		return true
+	}
+	r, err := a.PartialSourceReader()
+	if err != nil {
+		return true
+	}
+	md, _ := metadata.GetFromReader(r, path.Ext(a.FileName))
+	return md.Latitude != 0 || md.Longitude != 0
+}
@@ -0,0 +1,70 @@
+package cmdupload
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/helpers/fshelper/myflag"
+)
+
+func TestParseLocations(t *testing.T) {
+	csv := `folder,latitude,longitude
+# scanned photos
+Paris, 48.8584, 2.2945
+/Paris/Sydney/,-33.8568,151.2153
+,45,5
+`
+	got, err := parseLocations(strings.NewReader(csv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []folderLocation{
+		{Folder: "Paris", Latitude: 48.8584, Longitude: 2.2945},
+		{Folder: "Paris/Sydney", Latitude: -33.8568, Longitude: 151.2153},
+		{Folder: "", Latitude: 45, Longitude: 5},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseLocations()=%v, want %v", got, want)
+	}
+
+	_, err = parseLocations(strings.NewReader("Paris,48.8584,2.2945\nRome,north,east\n"))
+	if err == nil {
+		t.Error("expecting an error for invalid coordinates")
+	}
+}
+
+func TestLocationFor(t *testing.T) {
+	app := UpCmd{
+		locations: []folderLocation{
+			{Folder: "Paris", Latitude: 1, Longitude: 1},
+			{Folder: "Paris/Sydney", Latitude: 2, Longitude: 2},
+		},
+	}
+	app.GPS.Set("3,3")
+
+	tc := []struct {
+		file string
+		want float64
+	}{
+		{file: "Paris/photo.jpg", want: 1},
+		{file: "Paris/2020/photo.jpg", want: 1},
+		{file: "Paris/Sydney/photo.jpg", want: 2},
+		{file: "Parisian/photo.jpg", want: 3},
+		{file: "photo.jpg", want: 3},
+	}
+	for _, c := range tc {
+		t.Run(c.file, func(t *testing.T) {
+			lat, long, ok := app.locationFor(&browser.LocalAssetFile{FileName: c.file})
+			if !ok || lat != c.want || long != c.want {
+				t.Errorf("locationFor()=%v,%v,%v, want %v", lat, long, ok, c.want)
+			}
+		})
+	}
+
+	app.GPS = myflag.LatLong{}
+	if _, _, ok := app.locationFor(&browser.LocalAssetFile{FileName: "photo.jpg"}); ok {
+		t.Errorf("locationFor() without -gps and location should give nothing")
+	}
+}
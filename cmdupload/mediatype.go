@@ -0,0 +1,42 @@
+package cmdupload
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/helpers/fshelper"
+)
+
+// Values of the -only filter
+const (
+	OnlyPhoto = "photo"
+	OnlyVideo = "video"
+	OnlyAll   = "all"
+)
+
+func parseOnly(s string) (string, error) {
+	switch o := strings.ToLower(s); o {
+	case OnlyPhoto, OnlyVideo, OnlyAll:
+		return o, nil
+	}
+	return "", fmt.Errorf("unknown type of media %q, expecting %s, %s or %s", s, OnlyPhoto, OnlyVideo, OnlyAll)
+}
+
+// matchMediaType checks the type of the asset against the -only filter.
+// The type is given by the -mime-override of the extension when any.
+func (app *UpCmd) matchMediaType(a *browser.LocalAssetFile) bool {
+	ext := strings.ToLower(path.Ext(a.FileName))
+	t := fshelper.TypeFromExt(ext)
+	if mtype, ok := app.MimeOverrides[ext]; ok {
+		t = fshelper.TypeFromMime(mtype)
+	}
+	switch app.Only {
+	case OnlyPhoto:
+		return t == fshelper.TypeImage
+	case OnlyVideo:
+		return t == fshelper.TypeVideo
+	}
+	return true
+}
@@ -0,0 +1,38 @@
+package cmdupload
+
+import (
+	"testing"
+
+	"github.com/simulot/immich-go/browser"
+)
+
+func TestMatchMediaType(t *testing.T) {
+	tests := []struct {
+		only     string
+		file     string
+		override MimeOverrides
+		want     bool
+	}{
+		{only: OnlyPhoto, file: "a/photo.JPG", want: true},
+		{only: OnlyPhoto, file: "a/video.mp4", want: false},
+		{only: OnlyVideo, file: "a/video.mp4", want: true},
+		{only: OnlyVideo, file: "a/photo.heic", want: false},
+		{only: OnlyAll, file: "a/video.mp4", want: true},
+		{only: OnlyPhoto, file: "a/video.mp4", override: MimeOverrides{".mp4": "image/heic"}, want: true},
+	}
+	for _, tt := range tests {
+		app := UpCmd{Only: tt.only, MimeOverrides: tt.override}
+		if got := app.matchMediaType(&browser.LocalAssetFile{FileName: tt.file}); got != tt.want {
+			t.Errorf("-only=%s %s: got %v, want %v", tt.only, tt.file, got, tt.want)
+		}
+	}
+}
+
+func TestParseOnly(t *testing.T) {
+	if o, err := parseOnly("Video"); err != nil || o != OnlyVideo {
+		t.Errorf("parseOnly(\"Video\") = %q, %v", o, err)
+	}
+	if _, err := parseOnly("audio"); err == nil {
+		t.Errorf("parseOnly(\"audio\"): expecting an error")
+	}
+}
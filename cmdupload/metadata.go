@@ -0,0 +1,92 @@
+package cmdupload
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/logger"
+)
+
+// Policies for the metadata of the assets already on the server
+const (
+	MetadataReplace   = "replace"   // Send all the local values, as the earlier versions
+	MetadataSkip      = "skip"      // Never touch the server's metadata
+	MetadataFillOnly  = "fill-only" // Set the server's empty fields only
+	MetadataOverwrite = "overwrite" // Replace the server's fields by the local non-empty values
+)
+
+func parseMetadataPolicy(s string) (string, error) {
+	switch p := strings.ToLower(s); p {
+	case MetadataReplace, MetadataSkip, MetadataFillOnly, MetadataOverwrite:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown metadata policy %q, expecting %s, %s, %s or %s", s, MetadataReplace, MetadataSkip, MetadataFillOnly, MetadataOverwrite)
+}
+
+// updateExistingMetadata sets the local description, GPS coordinates and favorite flag on an asset already
+// on the server, according to the UpdateExistingMetadata policy. With the fill-only and overwrite policies,
+// local empty values are never sent.
+func (app *UpCmd) updateExistingMetadata(ctx context.Context, a *browser.LocalAssetFile, sa *immich.Asset) {
+	switch app.UpdateExistingMetadata {
+	case MetadataSkip:
+		return
+	case "", MetadataReplace:
+		app.replaceMetadata(ctx, a, sa.ID)
+		return
+	}
+	overwrite := app.UpdateExistingMetadata == MetadataOverwrite
+
+	// Start from the server's values, the update call sets all of them
+	upd := browser.LocalAssetFile{
+		Description: sa.ExifInfo.Description,
+		Latitude:    sa.ExifInfo.Latitude,
+		Longitude:   sa.ExifInfo.Longitude,
+		Favorite:    sa.IsFavorite,
+		Archived:    sa.IsArchived,
+	}
+	changes := []string{}
+	if a.Description != "" && a.Description != upd.Description && (overwrite || upd.Description == "") {
+		upd.Description = a.Description
+		changes = append(changes, "description")
+	}
+	if (a.Latitude != 0 || a.Longitude != 0) && (a.Latitude != upd.Latitude || a.Longitude != upd.Longitude) &&
+		(overwrite || (upd.Latitude == 0 && upd.Longitude == 0)) {
+		upd.Latitude, upd.Longitude = a.Latitude, a.Longitude
+		changes = append(changes, "GPS")
+	}
+	if a.Favorite && !upd.Favorite {
+		upd.Favorite = true
+		changes = append(changes, "favorite")
+	}
+	if len(changes) == 0 {
+		return
+	}
+
+	app.journalAsset(a, logger.INFO, "Metadata updated on the server: "+strings.Join(changes, ", "))
+	if app.DryRun {
+		return
+	}
+	_, err := app.client.UpdateAsset(ctx, sa.ID, &upd)
+	if err != nil {
+		app.Journal.Error("can't update the asset '%s': %s", a.FileName, err)
+	}
+}
+
+// replaceMetadata sends all the local values of the asset, when it has some
+func (app *UpCmd) replaceMetadata(ctx context.Context, a *browser.LocalAssetFile, ID string) {
+	shouldUpdate := len(a.Description) > 0
+	shouldUpdate = shouldUpdate || a.Favorite
+	shouldUpdate = shouldUpdate || a.Longitude != 0 || a.Latitude != 0
+	shouldUpdate = shouldUpdate || !a.DateTaken.IsZero()
+	shouldUpdate = shouldUpdate || a.Archived
+
+	if !app.DryRun && shouldUpdate {
+		_, err := app.client.UpdateAsset(ctx, ID, a)
+		if err != nil {
+			app.Journal.Error("can't update the asset '%s': %s", a.FileName, err)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package cmdupload
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/logger"
+)
+
+type icUpdateMeta struct {
+	stubIC
+	updates map[string]browser.LocalAssetFile
+}
+
+func (c *icUpdateMeta) UpdateAsset(ctx context.Context, ID string, a *browser.LocalAssetFile) (*immich.Asset, error) {
+	if c.updates == nil {
+		c.updates = map[string]browser.LocalAssetFile{}
+	}
+	c.updates[ID] = *a
+	return nil, nil
+}
+
+func TestUpdateExistingMetadata(t *testing.T) {
+	date := time.Date(2023, 6, 23, 13, 32, 52, 0, time.UTC)
+	content := []byte("canonical raw content")
+
+	tests := []struct {
+		name   string
+		policy string
+		server immich.ExifInfo
+		fav    bool
+		want   map[string]browser.LocalAssetFile
+	}{
+		{
+			name:   "skip",
+			policy: MetadataSkip,
+		},
+		{
+			name:   "fill empty fields",
+			policy: MetadataFillOnly,
+			want: map[string]browser.LocalAssetFile{
+				"server-id": {Description: "local", Latitude: 48.8, Longitude: 2.3, Favorite: true},
+			},
+		},
+		{
+			name:   "fill keeps the server's fields",
+			policy: MetadataFillOnly,
+			server: immich.ExifInfo{Description: "server", Latitude: 45.7, Longitude: 4.8},
+			want: map[string]browser.LocalAssetFile{
+				"server-id": {Description: "server", Latitude: 45.7, Longitude: 4.8, Favorite: true},
+			},
+		},
+		{
+			name:   "fill, nothing to change",
+			policy: MetadataFillOnly,
+			server: immich.ExifInfo{Description: "server", Latitude: 45.7, Longitude: 4.8},
+			fav:    true,
+		},
+		{
+			name:   "overwrite",
+			policy: MetadataOverwrite,
+			server: immich.ExifInfo{Description: "server", Latitude: 45.7, Longitude: 4.8},
+			fav:    true,
+			want: map[string]browser.LocalAssetFile{
+				"server-id": {Description: "local", Latitude: 48.8, Longitude: 2.3, Favorite: true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ic := &icUpdateMeta{}
+			tt.server.FileSizeInByte = len(content)
+			tt.server.DateTimeOriginal = immich.ImmichTime{Time: date}
+			app := UpCmd{
+				client:                 ic,
+				Journal:                logger.NewJournal(logger.NoLogger{}),
+				UpdateExistingMetadata: tt.policy,
+				updateAlbums:           map[string]map[string]any{},
+				AssetIndex: &AssetIndex{
+					assets: []*immich.Asset{{
+						ID:               "server-id",
+						OriginalFileName: "photo",
+						OriginalPath:     "upload/photo.cr3",
+						IsFavorite:       tt.fav,
+						ExifInfo:         tt.server,
+					}},
+				},
+			}
+			app.AssetIndex.ReIndex()
+			a := &browser.LocalAssetFile{
+				FSys:        fstest.MapFS{"photo.cr3": {Data: content}},
+				FileName:    "photo.cr3",
+				Title:       "photo.cr3",
+				FileSize:    len(content),
+				DateTaken:   date,
+				Description: "local",
+				Latitude:    48.8,
+				Longitude:   2.3,
+				Favorite:    true,
+			}
+			err := app.handleAsset(context.Background(), a)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(ic.updates, tt.want) {
+				t.Errorf("updates = %+v, want %+v", ic.updates, tt.want)
+			}
+		})
+	}
+}
+
+// TestUpdateExistingMetadataReplace checks that the default policy sends the local values, as the earlier versions
+func TestUpdateExistingMetadataReplace(t *testing.T) {
+	date := time.Date(2023, 6, 23, 13, 32, 52, 0, time.UTC)
+	content := []byte("canonical raw content")
+
+	for _, policy := range []string{"", MetadataReplace} {
+		ic := &icUpdateMeta{}
+		app := UpCmd{
+			client:                 ic,
+			Journal:                logger.NewJournal(logger.NoLogger{}),
+			UpdateExistingMetadata: policy,
+			updateAlbums:           map[string]map[string]any{},
+			AssetIndex: &AssetIndex{
+				assets: []*immich.Asset{{
+					ID:               "server-id",
+					OriginalFileName: "photo",
+					OriginalPath:     "upload/photo.cr3",
+					ExifInfo: immich.ExifInfo{
+						Description:      "server",
+						FileSizeInByte:   len(content),
+						DateTimeOriginal: immich.ImmichTime{Time: date},
+					},
+				}},
+			},
+		}
+		app.AssetIndex.ReIndex()
+		a := &browser.LocalAssetFile{
+			FSys:        fstest.MapFS{"photo.cr3": {Data: content}},
+			FileName:    "photo.cr3",
+			Title:       "photo.cr3",
+			FileSize:    len(content),
+			DateTaken:   date,
+			Description: "local",
+			Archived:    true,
+		}
+		err := app.handleAsset(context.Background(), a)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		u, ok := ic.updates["server-id"]
+		if !ok || u.Description != "local" || !u.Archived {
+			t.Errorf("policy %q: updates = %+v, want the local values", policy, ic.updates)
+		}
+	}
+}
+
+func TestParseMetadataPolicy(t *testing.T) {
+	for _, s := range []string{"replace", "skip", "Fill-Only", "overwrite"} {
+		if _, err := parseMetadataPolicy(s); err != nil {
+			t.Errorf("parseMetadataPolicy(%q): unexpected error %s", s, err)
+		}
+	}
+	if _, err := parseMetadataPolicy("always"); err == nil {
+		t.Errorf("parseMetadataPolicy(\"always\"): expecting an error")
+	}
+}
@@ -0,0 +1,82 @@
+package cmdupload
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/simulot/immich-go/logger"
+)
+
+// metrics exposes the counters of the run at /metrics, in the Prometheus text format.
+// The counters of assets come from the journal, as the report.
+type metrics struct {
+	journal       *logger.Journal
+	bytesUploaded atomic.Int64
+	inProgress    atomic.Int64
+	server        *http.Server
+	addr          net.Addr
+}
+
+// startMetrics starts the HTTP server of the metrics
+func startMetrics(addr string, journal *logger.Journal) (*metrics, error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("can't start the metrics server: %w", err)
+	}
+	m := &metrics{
+		journal: journal,
+		addr:    l.Addr(),
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m)
+	m.server = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		_ = m.server.Serve(l)
+	}()
+	return m, nil
+}
+
+// stop shuts down the server, letting the scrape in progress finish
+func (m *metrics) stop() {
+	if m == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_ = m.server.Shutdown(ctx)
+}
+
+// uploadStarted and uploadDone surround each upload
+func (m *metrics) uploadStarted() {
+	if m != nil {
+		m.inProgress.Add(1)
+	}
+}
+
+func (m *metrics) uploadDone(size int64) {
+	if m != nil {
+		m.inProgress.Add(-1)
+		m.bytesUploaded.Add(size)
+	}
+}
+
+func (m *metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	j := m.journal
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	write := func(name, kind, help string, value int64) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %d\n", name, help, name, kind, name, value)
+	}
+	// An upgrade is recorded as an upload, then removed from the uploads
+	write("immichgo_uploaded_total", "counter", "Number of assets uploaded, upgrades included.",
+		int64(j.Count(logger.UPLOADED)+j.Count(logger.UPGRADED)))
+	write("immichgo_skipped_total", "counter", "Number of assets not uploaded because of the options, or already on the server.",
+		int64(j.Count(logger.NOT_SELECTED)+j.Count(logger.LOCAL_DUPLICATE)+j.Count(logger.SERVER_DUPLICATE)+j.Count(logger.SERVER_BETTER)))
+	write("immichgo_errors_total", "counter", "Number of errors.",
+		int64(j.Count(logger.ERROR)+j.Count(logger.SERVER_ERROR)))
+	write("immichgo_bytes_uploaded_total", "counter", "Size of the uploaded files.", m.bytesUploaded.Load())
+	write("immichgo_uploads_in_progress", "gauge", "Number of uploads in progress.", m.inProgress.Load())
+}
@@ -0,0 +1,56 @@
+package cmdupload
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/simulot/immich-go/logger"
+)
+
+func TestMetrics(t *testing.T) {
+	j := logger.NewJournal(logger.NoLogger{})
+	m, err := startMetrics("127.0.0.1:0", j)
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := "http://" + m.addr.String() + "/metrics"
+
+	j.AddEntry("a.jpg", logger.UPLOADED)
+	j.AddEntry("b.jpg", logger.UPLOADED)
+	j.AddEntry("b.jpg", logger.UPGRADED)
+	j.AddEntry("c.jpg", logger.SERVER_DUPLICATE)
+	j.AddEntry("d.jpg", logger.NOT_SELECTED)
+	j.AddEntry("e.jpg", logger.SERVER_ERROR)
+	m.uploadStarted()
+	m.uploadDone(1000)
+	m.uploadStarted()
+	m.uploadDone(500)
+	m.uploadStarted()
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	for _, want := range []string{
+		"immichgo_uploaded_total 2\n",
+		"immichgo_skipped_total 2\n",
+		"immichgo_errors_total 1\n",
+		"immichgo_bytes_uploaded_total 1500\n",
+		"immichgo_uploads_in_progress 1\n",
+		"# TYPE immichgo_uploads_in_progress gauge\n",
+	} {
+		if !strings.Contains(string(b), want) {
+			t.Errorf("missing %q in the metrics:\n%s", want, b)
+		}
+	}
+
+	m.stop()
+	if resp, err := http.Get(url); err == nil {
+		resp.Body.Close()
+		t.Errorf("the metrics server is still running")
+	}
+}
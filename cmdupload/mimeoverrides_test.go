@@ -0,0 +1,33 @@
+package cmdupload
+
+import "testing"
+
+func TestMimeOverrides(t *testing.T) {
+	tc := []struct {
+		value   string
+		ext     string
+		want    string
+		wantErr bool
+	}{
+		{value: "cr3=image/x-canon-cr3", ext: ".cr3", want: "image/x-canon-cr3"},
+		{value: ".DNG = image/x-adobe-dng", ext: ".dng", want: "image/x-adobe-dng"},
+		{value: "mts=video/mp2t", ext: ".mts", want: "video/mp2t"},
+		{value: "cr3", wantErr: true},
+		{value: "=image/jpeg", wantErr: true},
+		{value: "cr3=application/octet-stream", wantErr: true},
+		{value: "cr3=image/", wantErr: true},
+	}
+	for _, c := range tc {
+		t.Run(c.value, func(t *testing.T) {
+			var mo MimeOverrides
+			err := mo.Set(c.value)
+			if (err != nil) != c.wantErr {
+				t.Errorf("Set(%q)=%v, expecting error: %v", c.value, err, c.wantErr)
+				return
+			}
+			if !c.wantErr && mo[c.ext] != c.want {
+				t.Errorf("Set(%q) gives %v, expecting %s=%s", c.value, mo, c.ext, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package cmdupload
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/simulot/immich-go/logger"
+)
+
+func TestMinAlbumSize(t *testing.T) {
+	tc := []struct {
+		name    string
+		min     int
+		created []string
+	}{
+		{name: "no minimum", created: []string{"Album 1", "Album 3"}},
+		{name: "minimum 1", min: 1, created: []string{"Album 1", "Album 3"}},
+		{name: "minimum 2", min: 2, created: []string{"Album 3"}},
+		{name: "minimum 4", min: 4},
+	}
+	for _, c := range tc {
+		t.Run(c.name, func(t *testing.T) {
+			ic := &icCatchUploadsAssets{}
+			app := UpCmd{
+				client:       ic,
+				Journal:      logger.NewJournal(logger.NoLogger{}),
+				MinAlbumSize: c.min,
+				updateAlbums: map[string]map[string]any{
+					"Album 1": {"1": nil},
+					"Album 3": {"1": nil, "2": nil, "3": nil},
+					"Empty":   {}, // all the assets have been filtered out
+				},
+			}
+			if err := app.ManageAlbums(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			created := []string{}
+			for album := range ic.albums {
+				created = append(created, album)
+			}
+			sort.Strings(created)
+			if len(c.created) == 0 {
+				c.created = []string{}
+			}
+			if !reflect.DeepEqual(created, c.created) {
+				t.Errorf("created albums = %v, want %v", created, c.created)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package cmdupload
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/helpers/fshelper"
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/logger"
+)
+
+func TestMoveTo(t *testing.T) {
+	for _, dryRun := range []bool{false, true} {
+		t.Run(map[bool]string{false: "move", true: "dry run"}[dryRun], func(t *testing.T) {
+			src := t.TempDir()
+			moveTo := filepath.Join(t.TempDir(), "done")
+			files := []string{"trip/photo_01.cr3", "trip/photo_02.cr3", "trip/photo_03.cr3"}
+			// the video of the live photo and the JSON sidecar follow photo_03
+			companions := []string{"trip/photo_03.mp4", "trip/photo_03.json"}
+			for _, f := range append(files, companions...) {
+				p := filepath.Join(src, filepath.FromSlash(f))
+				if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(p, []byte(f), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			fsyss, err := fshelper.ParsePath([]string{src}, false, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// photo_01 is in the server's index, photo_02 is a duplicate found by the server
+			ic := &icServerDedup{existing: map[string]string{"trip/photo_02.cr3": "server-02"}}
+			app := UpCmd{
+				client:       ic,
+				Journal:      logger.NewJournal(logger.NoLogger{}),
+				MoveTo:       moveTo,
+				DryRun:       dryRun,
+				updateAlbums: map[string]map[string]any{},
+				AssetIndex: &AssetIndex{assets: []*immich.Asset{{
+					ID:               "server-01",
+					OriginalFileName: "photo_01",
+					OriginalPath:     "upload/photo_01.cr3",
+					ExifInfo:         immich.ExifInfo{FileSizeInByte: len(files[0])},
+				}}},
+			}
+			app.AssetIndex.ReIndex()
+			for _, f := range files {
+				a := &browser.LocalAssetFile{FSys: fsyss[0], FileName: f, Title: filepath.Base(f), FileSize: len(f)}
+				if f == "trip/photo_03.cr3" {
+					a.LivePhotoData, a.JSONSideCar = companions[0], companions[1]
+				}
+				if err := app.handleAsset(context.Background(), a); err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+			}
+			app.MoveLocalAssets()
+
+			for _, f := range append(files, companions...) {
+				moved := !dryRun && strings.HasPrefix(f, "trip/photo_03.")
+				_, err := os.Stat(filepath.Join(moveTo, filepath.FromSlash(f)))
+				if (err == nil) != moved {
+					t.Errorf("%s: moved = %v, want %v", f, err == nil, moved)
+				}
+				_, err = os.Stat(filepath.Join(src, filepath.FromSlash(f)))
+				if (err == nil) == moved {
+					t.Errorf("%s: still in the source = %v, want %v", f, err == nil, !moved)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package cmdupload
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/immich/metadata"
+)
+
+// Values of the -orientation filter
+const (
+	OrientationLandscape = "landscape"
+	OrientationPortrait  = "portrait"
+	OrientationSquare    = "square"
+)
+
+func parseOrientation(s string) (string, error) {
+	switch o := strings.ToLower(s); o {
+	case OrientationLandscape, OrientationPortrait, OrientationSquare:
+		return o, nil
+	}
+	return "", fmt.Errorf("unknown orientation %q, expecting %s, %s or %s", s, OrientationLandscape, OrientationPortrait, OrientationSquare)
+}
+
+// matchOrientation checks the orientation of the image.
+// Assets without known dimensions, like videos, are accepted.
+func (app *UpCmd) matchOrientation(a *browser.LocalAssetFile) bool {
+	if a.Width == 0 || a.Height == 0 {
+		readDimensions(a)
+	}
+	w, h := a.Width, a.Height
+	if w == 0 || h == 0 {
+		return true
+	}
+	switch app.Orientation {
+	case OrientationLandscape:
+		return w > h
+	case OrientationPortrait:
+		return w < h
+	case OrientationSquare:
+		return w == h
+	}
+	return true
+}
+
+// readDimensions gets the size of the image from its metadata, when not given by the browser
+func readDimensions(a *browser.LocalAssetFile) {
+	if a.FSys == nil {
+		return
+	}
+	r, err := a.PartialSourceReader()
+	if err != nil {
+		return
+	}
+	md, _ := metadata.GetFromReader(r, path.Ext(a.FileName))
+	a.Width, a.Height = md.Width, md.Height
+}
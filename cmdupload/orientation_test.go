@@ -0,0 +1,63 @@
+package cmdupload
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/logger"
+)
+
+func TestOrientation(t *testing.T) {
+	tests := []struct {
+		name          string
+		orientation   string
+		width, height int
+		want          bool
+	}{
+		{name: "landscape", orientation: OrientationLandscape, width: 4000, height: 3000, want: true},
+		{name: "portrait as landscape", orientation: OrientationLandscape, width: 3000, height: 4000},
+		{name: "square as landscape", orientation: OrientationLandscape, width: 3000, height: 3000},
+		{name: "portrait", orientation: OrientationPortrait, width: 3000, height: 4000, want: true},
+		{name: "landscape as portrait", orientation: OrientationPortrait, width: 4000, height: 3000},
+		{name: "square", orientation: OrientationSquare, width: 3000, height: 3000, want: true},
+		{name: "unknown dimensions", orientation: OrientationPortrait, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ic := &icReplace{}
+			app := UpCmd{
+				client:       ic,
+				Journal:      logger.NewJournal(logger.NoLogger{}),
+				Orientation:  tt.orientation,
+				updateAlbums: map[string]map[string]any{},
+				AssetIndex:   &AssetIndex{},
+			}
+			app.AssetIndex.ReIndex()
+			a := &browser.LocalAssetFile{
+				FSys:     fstest.MapFS{"photo.cr3": {Data: []byte("raw content")}},
+				FileName: "photo.cr3",
+				Title:    "photo.cr3",
+				Width:    tt.width,
+				Height:   tt.height,
+			}
+			err := app.handleAsset(context.Background(), a)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if got := len(ic.uploads) > 0; got != tt.want {
+				t.Errorf("uploaded: %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseOrientation(t *testing.T) {
+	if o, err := parseOrientation("Landscape"); err != nil || o != OrientationLandscape {
+		t.Errorf("parseOrientation(\"Landscape\") = %q, %v", o, err)
+	}
+	if _, err := parseOrientation("panorama"); err == nil {
+		t.Errorf("parseOrientation(\"panorama\"): expecting an error")
+	}
+}
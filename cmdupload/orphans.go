@@ -0,0 +1,25 @@
+package cmdupload
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/simulot/immich-go/logger"
+)
+
+// writeOrphans writes the sidecar files without media file into the OrphansOut file, one per line
+func (app *UpCmd) writeOrphans() error {
+	if app.OrphansOut == "" {
+		return nil
+	}
+	var b strings.Builder
+	for _, f := range app.Journal.Files(logger.ORPHAN_SIDECAR) {
+		b.WriteString(f + "\n")
+	}
+	err := os.WriteFile(app.OrphansOut, []byte(b.String()), 0o644)
+	if err != nil {
+		return fmt.Errorf("can't write the -orphans-out file: %w", err)
+	}
+	return nil
+}
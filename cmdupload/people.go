@@ -0,0 +1,103 @@
+package cmdupload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/logger"
+)
+
+// peopleTagging is an asset waiting for its people
+type peopleTagging struct {
+	ID    string   // Immich asset ID
+	File  string   // Local file name, for the journal
+	Names []string // People's names
+}
+
+// AddToPeople queues the asset for being tagged with its people after the upload
+func (app *UpCmd) AddToPeople(ID string, a *browser.LocalAssetFile) {
+	if !app.ImportPeople || len(a.People) == 0 {
+		return
+	}
+	app.peopleQueue = append(app.peopleQueue, peopleTagging{ID: ID, File: a.FileName, Names: a.People})
+}
+
+// ManagePeople associates the uploaded assets with their people. A missing person is created
+// only when a face of the asset is given to them.
+// The face detection runs on the server after the upload. Assets without detected faces
+// are retried later.
+func (app *UpCmd) ManagePeople(ctx context.Context) error {
+	if len(app.peopleQueue) == 0 {
+		return nil
+	}
+	if app.DryRun {
+		for _, q := range app.peopleQueue {
+			app.Journal.AddEntry(q.File, logger.PEOPLE, strings.Join(q.Names, ", "), "skipped - dry run mode")
+		}
+		return nil
+	}
+
+	serverPeople, err := app.client.GetAllPeople(ctx)
+	if err != nil {
+		return fmt.Errorf("can't get the people list from the server: %w", err)
+	}
+	people := map[string]immich.Person{}
+	for _, p := range serverPeople {
+		if p.Name != "" {
+			people[p.Name] = p
+		}
+	}
+
+	queue := app.peopleQueue
+	for attempt := 0; len(queue) > 0; attempt++ {
+		if attempt > 0 {
+			if attempt > app.PeopleRetries {
+				break
+			}
+			app.Journal.OK("Waiting for the face detection of %d asset(s)...", len(queue))
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(app.PeopleRetryDelay):
+			}
+		}
+
+		var retry []peopleTagging
+		for _, q := range queue {
+			list := []immich.Person{}
+			for _, name := range q.Names {
+				p, ok := people[name]
+				if !ok {
+					// created by AddPeopleToAsset when it gets a face
+					p = immich.Person{Name: name}
+				}
+				list = append(list, p)
+			}
+			created, err := app.client.AddPeopleToAsset(ctx, q.ID, list)
+			for _, p := range created {
+				people[p.Name] = p
+			}
+			switch {
+			case errors.Is(err, immich.ErrNoFaceDetected):
+				retry = append(retry, q)
+			case errors.Is(err, immich.ErrAmbiguousFaces):
+				app.Journal.AddEntry(q.File, logger.INFO, "not tagged with "+strings.Join(q.Names, ", ")+": "+err.Error())
+			case err != nil:
+				app.Journal.AddEntry(q.File, logger.SERVER_ERROR, err.Error())
+			default:
+				app.Journal.AddEntry(q.File, logger.PEOPLE, strings.Join(q.Names, ", "))
+			}
+		}
+		queue = retry
+	}
+
+	for _, q := range queue {
+		app.Journal.Warning("%s: can't tag with %s: %s", q.File, strings.Join(q.Names, ", "), immich.ErrNoFaceDetected)
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package cmdupload
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/logger"
+)
+
+type icCatchPeople struct {
+	stubIC
+	people     []immich.Person
+	created    []string
+	noFaceLeft map[string]int      // number of calls before faces are detected
+	failCreate bool                // the creation of the people fails
+	tagged     map[string][]string // asset ID -> people IDs
+}
+
+func (c *icCatchPeople) GetAllPeople(ctx context.Context) ([]immich.Person, error) {
+	return c.people, nil
+}
+
+func (c *icCatchPeople) AddPeopleToAsset(ctx context.Context, assetID string, people []immich.Person) ([]immich.Person, error) {
+	if c.noFaceLeft[assetID] > 0 {
+		c.noFaceLeft[assetID]--
+		return nil, immich.ErrNoFaceDetected
+	}
+	var created []immich.Person
+	for _, p := range people {
+		if p.ID == "" && c.failCreate {
+			return nil, errors.New("500 Internal Server Error")
+		}
+	}
+	for _, p := range people {
+		if p.ID == "" {
+			c.created = append(c.created, p.Name)
+			p.ID = "new-" + p.Name
+			created = append(created, p)
+		}
+		c.tagged[assetID] = append(c.tagged[assetID], p.ID)
+	}
+	return created, nil
+}
+
+func TestManagePeople(t *testing.T) {
+	tests := []struct {
+		name        string
+		noFace      map[string]int
+		failCreate  bool
+		wantCreated []string
+		wantTagged  map[string][]string
+	}{
+		{
+			name:        "faces detected",
+			noFace:      map[string]int{},
+			wantCreated: []string{"Bob"},
+			wantTagged: map[string][]string{
+				"1": {"alice", "new-Bob"},
+				"2": {"alice"},
+				"4": {"new-Bob"},
+			},
+		},
+		{
+			name:        "detection delayed",
+			noFace:      map[string]int{"1": 2},
+			wantCreated: []string{"Bob"},
+			wantTagged: map[string][]string{
+				"1": {"alice", "new-Bob"},
+				"2": {"alice"},
+				"4": {"new-Bob"},
+			},
+		},
+		{
+			name:   "detection never done",
+			noFace: map[string]int{"1": 10, "4": 10},
+			wantTagged: map[string][]string{
+				"2": {"alice"},
+			},
+		},
+		{
+			name:       "creation failed",
+			noFace:     map[string]int{},
+			failCreate: true,
+			wantTagged: map[string][]string{
+				"2": {"alice"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ic := &icCatchPeople{
+				people:     []immich.Person{{ID: "alice", Name: "Alice"}},
+				noFaceLeft: tt.noFace,
+				failCreate: tt.failCreate,
+				tagged:     map[string][]string{},
+			}
+			app := UpCmd{
+				client:           ic,
+				Journal:          logger.NewJournal(logger.NoLogger{}),
+				ImportPeople:     true,
+				PeopleRetries:    3,
+				PeopleRetryDelay: time.Millisecond,
+			}
+			app.AddToPeople("1", &browser.LocalAssetFile{FileName: "a.jpg", People: []string{"Alice", "Bob"}})
+			app.AddToPeople("2", &browser.LocalAssetFile{FileName: "b.jpg", People: []string{"Alice"}})
+			app.AddToPeople("3", &browser.LocalAssetFile{FileName: "c.jpg"})
+			app.AddToPeople("4", &browser.LocalAssetFile{FileName: "d.jpg", People: []string{"Bob"}})
+
+			err := app.ManagePeople(context.Background())
+			if err != nil {
+				t.Errorf("unexpected error: %s", err)
+				return
+			}
+			if !reflect.DeepEqual(ic.created, tt.wantCreated) {
+				t.Errorf("created people = %v, want %v", ic.created, tt.wantCreated)
+			}
+			if !reflect.DeepEqual(ic.tagged, tt.wantTagged) {
+				t.Errorf("tagged assets = %v, want %v", ic.tagged, tt.wantTagged)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package cmdupload
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/logger"
+)
+
+// uploadProgress displays the progress of the upload of large files
+type uploadProgress struct {
+	log       logger.Logger
+	threshold int64         // Minimal size of the files
+	interval  time.Duration // Minimal interval between updates
+	file      string        // File being uploaded
+	start     time.Time     // Start of its upload
+	last      time.Time     // Last displayed progress
+}
+
+// update is called by the client during the upload of each file
+func (p *uploadProgress) update(a *browser.LocalAssetFile, sent, size int64) {
+	if size < p.threshold || size == 0 {
+		return
+	}
+	now := time.Now()
+	if a.FileName != p.file {
+		p.file = a.FileName
+		p.start = now
+		p.last = time.Time{}
+	}
+	if sent < size && now.Sub(p.last) < p.interval {
+		return
+	}
+	p.last = now
+	rate := 0.0
+	if d := now.Sub(p.start).Seconds(); d > 0 {
+		rate = float64(sent) / d
+	}
+	p.log.Progress(logger.OK, "%s: %d%% of %s, %s/s", a.FileName, sent*100/size, formatBytes(int(size)), formatBytes(int(rate)))
+}
+
+// phaseProgress counts the items of the last phases of the run, like the stacks
+// and the albums, and estimates the remaining time from the time spent per item
+type phaseProgress struct {
+	total int
+	done  int
+	start time.Time
+	now   func() time.Time
+}
+
+func newPhaseProgress(total int) *phaseProgress {
+	return &phaseProgress{total: total, start: time.Now(), now: time.Now}
+}
+
+// next counts the item being processed, and gives like 37/412, ETA 1m20s
+func (p *phaseProgress) next() string {
+	if p.done == 0 {
+		p.start = p.now()
+	}
+	p.done++
+	s := fmt.Sprintf("%d/%d", p.done, p.total)
+	if p.done == 1 {
+		return s
+	}
+	perItem := p.now().Sub(p.start) / time.Duration(p.done-1)
+	eta := (perItem * time.Duration(p.total-p.done+1)).Round(time.Second)
+	return s + ", ETA " + eta.String()
+}
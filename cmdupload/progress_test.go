@@ -0,0 +1,60 @@
+package cmdupload
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/logger"
+)
+
+// progressLogger records the progress messages
+type progressLogger struct {
+	logger.NoLogger
+	messages []string
+}
+
+func (l *progressLogger) Progress(level logger.Level, f string, v ...any) {
+	l.messages = append(l.messages, fmt.Sprintf(f, v...))
+}
+
+func TestUploadProgress(t *testing.T) {
+	log := &progressLogger{}
+	p := uploadProgress{log: log, threshold: 1000}
+
+	small := &browser.LocalAssetFile{FileName: "small.jpg"}
+	p.update(small, 10, 100)
+	p.update(small, 100, 100)
+
+	big := &browser.LocalAssetFile{FileName: "big.mp4"}
+	for sent := int64(0); sent <= 2000; sent += 500 {
+		p.update(big, sent, 2000)
+	}
+
+	want := []string{"big.mp4: 0% of", "big.mp4: 25% of", "big.mp4: 50% of", "big.mp4: 75% of", "big.mp4: 100% of"}
+	if len(log.messages) != len(want) {
+		t.Fatalf("progress messages = %q, want %q", log.messages, want)
+	}
+	for i := range want {
+		if !strings.HasPrefix(log.messages[i], want[i]) {
+			t.Errorf("progress message %q, want %q...", log.messages[i], want[i])
+		}
+	}
+}
+
+func TestPhaseProgress(t *testing.T) {
+	now := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	p := newPhaseProgress(4)
+	p.now = func() time.Time { return now }
+
+	// each item takes 10 seconds
+	want := []string{"1/4", "2/4, ETA 30s", "3/4, ETA 20s", "4/4, ETA 10s"}
+	for i := range want {
+		if got := p.next(); got != want[i] {
+			t.Errorf("item %d: %q, want %q", i+1, got, want[i])
+		}
+		now = now.Add(10 * time.Second)
+	}
+}
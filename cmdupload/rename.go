@@ -0,0 +1,50 @@
+package cmdupload
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"path"
+	"strings"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/logger"
+)
+
+// renameOnConflict gives a new name to the file when the asset found as the same or as better
+// has the same name and date, but a different checksum. The advice is given again for the new name,
+// as the renamed file may be on the server since a previous run.
+func (app *UpCmd) renameOnConflict(a *browser.LocalAssetFile, advice *Advice) (*Advice, error) {
+	if advice.Advice != SameOnServer && advice.Advice != BetterOnServer {
+		return advice, nil
+	}
+	sa := advice.ServerAsset
+	if sa.Checksum == "" {
+		return advice, nil
+	}
+	sum, err := a.Checksum()
+	if err != nil {
+		app.journalAsset(a, logger.ERROR, "can't compute the checksum: "+err.Error())
+		return advice, nil
+	}
+	if sum == sa.Checksum {
+		return advice, nil
+	}
+	name := conflictName(a, sum)
+	app.journalAsset(a, logger.INFO, "renamed "+name+": an asset with the same name and date but a different content exists")
+	a.Title = name
+	return app.AssetIndex.ShouldUpload(a)
+}
+
+// conflictName appends the beginning of the checksum to the name of the file
+func conflictName(a *browser.LocalAssetFile, sum string) string {
+	ext := path.Ext(a.Title)
+	if ext == "" {
+		ext = path.Ext(a.FileName)
+	}
+	base := strings.TrimSuffix(a.Title, ext)
+	h, err := base64.StdEncoding.DecodeString(sum)
+	if err != nil || len(h) < 4 {
+		return base + "_" + sum[:min(8, len(sum))] + ext
+	}
+	return base + "_" + hex.EncodeToString(h[:4]) + ext
+}
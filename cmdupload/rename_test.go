@@ -0,0 +1,135 @@
+package cmdupload
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/logger"
+)
+
+// icRename records the names given to the uploaded assets
+type icRename struct {
+	stubIC
+	titles []string
+}
+
+func (c *icRename) AssetUpload(ctx context.Context, a *browser.LocalAssetFile) (immich.AssetResponse, error) {
+	c.titles = append(c.titles, a.Title)
+	return immich.AssetResponse{ID: "new-" + a.Title}, nil
+}
+
+func TestRenameOnConflict(t *testing.T) {
+	date := time.Date(2023, 6, 23, 13, 32, 52, 0, time.UTC)
+	content1 := []byte("first camera.")
+	content2 := []byte("second camera")
+	h := sha1.Sum(content1)
+	checksum1 := base64.StdEncoding.EncodeToString(h[:])
+	h = sha1.Sum(content2)
+	renamed2 := "IMG_0001_" + hex.EncodeToString(h[:4]) + ".cr3"
+
+	serverAsset := func(name string, sum string) *immich.Asset {
+		return &immich.Asset{
+			ID:               "server-" + name,
+			OriginalFileName: name,
+			OriginalPath:     "upload/" + name + ".cr3",
+			Checksum:         sum,
+			ExifInfo: immich.ExifInfo{
+				FileSizeInByte:   len(content1),
+				DateTimeOriginal: immich.ImmichTime{Time: date},
+			},
+		}
+	}
+
+	tests := []struct {
+		name        string
+		rename      bool
+		server      []*immich.Asset
+		files       []string
+		wantUploads []string
+		wantAlbum   map[string]any
+	}{
+		{
+			name:      "not renamed",
+			server:    []*immich.Asset{serverAsset("IMG_0001", checksum1)},
+			files:     []string{"cam2/IMG_0001.cr3"},
+			wantAlbum: map[string]any{"server-IMG_0001": nil},
+		},
+		{
+			name:        "renamed",
+			rename:      true,
+			server:      []*immich.Asset{serverAsset("IMG_0001", checksum1)},
+			files:       []string{"cam2/IMG_0001.cr3"},
+			wantUploads: []string{renamed2},
+			wantAlbum:   map[string]any{"new-" + renamed2: nil},
+		},
+		{
+			name:      "same content",
+			rename:    true,
+			server:    []*immich.Asset{serverAsset("IMG_0001", checksum1)},
+			files:     []string{"cam1/IMG_0001.cr3"},
+			wantAlbum: map[string]any{"server-IMG_0001": nil},
+		},
+		{
+			name:   "renamed during a previous run",
+			rename: true,
+			server: []*immich.Asset{
+				serverAsset("IMG_0001", checksum1),
+				serverAsset("IMG_0001_"+hex.EncodeToString(h[:4]), "whatever"),
+			},
+			files:     []string{"cam2/IMG_0001.cr3"},
+			wantAlbum: map[string]any{"server-IMG_0001_" + hex.EncodeToString(h[:4]): nil},
+		},
+		{
+			name:        "both in the same run",
+			rename:      true,
+			files:       []string{"cam1/IMG_0001.cr3", "cam2/IMG_0001.cr3", "cam1/IMG_0001.cr3"},
+			wantUploads: []string{"IMG_0001.cr3", renamed2},
+			wantAlbum:   map[string]any{"new-IMG_0001.cr3": nil, "new-" + renamed2: nil},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ic := &icRename{}
+			app := UpCmd{
+				client:           ic,
+				Journal:          logger.NewJournal(logger.NoLogger{}),
+				RenameOnConflict: tt.rename,
+				ImportIntoAlbum:  "Holidays",
+				updateAlbums:     map[string]map[string]any{},
+				AssetIndex:       &AssetIndex{assets: tt.server},
+			}
+			app.AssetIndex.ReIndex()
+			fsys := fstest.MapFS{
+				"cam1/IMG_0001.cr3": {Data: content1},
+				"cam2/IMG_0001.cr3": {Data: content2},
+			}
+			for _, f := range tt.files {
+				a := &browser.LocalAssetFile{
+					FSys:      fsys,
+					FileName:  f,
+					Title:     "IMG_0001.cr3",
+					FileSize:  len(fsys[f].Data),
+					DateTaken: date,
+				}
+				err := app.handleAsset(context.Background(), a)
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+			}
+			if !reflect.DeepEqual(ic.titles, tt.wantUploads) {
+				t.Errorf("uploads = %v, want %v", ic.titles, tt.wantUploads)
+			}
+			if !reflect.DeepEqual(app.updateAlbums["Holidays"], tt.wantAlbum) {
+				t.Errorf("album = %v, want %v", app.updateAlbums["Holidays"], tt.wantAlbum)
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package cmdupload
+
+import (
+	"os"
+
+	"github.com/simulot/immich-go/logger"
+)
+
+// report gives the final report of the journal, into the ReportFile when given
+func (app *UpCmd) report() {
+	if app.ReportFile == "" {
+		app.Journal.Report()
+		return
+	}
+	f, err := os.Create(app.ReportFile)
+	if err != nil {
+		app.Journal.Error("can't write the -report-file: %s", err)
+		return
+	}
+	defer f.Close()
+	app.Journal.SetReportFormat(logger.ReportCSV, f)
+	app.Journal.Report()
+}
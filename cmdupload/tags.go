@@ -0,0 +1,83 @@
+package cmdupload
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/simulot/immich-go/helpers/gen"
+	"github.com/simulot/immich-go/immich"
+)
+
+// AddToTag queues the asset for being associated with the tag
+func (app *UpCmd) AddToTag(ID string, tag string) {
+	if app.updateTags == nil {
+		app.updateTags = map[string]map[string]any{}
+	}
+	l := app.updateTags[tag]
+	if l == nil {
+		l = map[string]any{}
+	}
+	l[ID] = nil
+	app.updateTags[tag] = l
+}
+
+// ManageTags associates the assets with their tags, creating missing tags.
+// Assets are tagged by batches of AlbumBatchSize IDs.
+func (app *UpCmd) ManageTags(ctx context.Context) error {
+	if len(app.updateTags) == 0 {
+		return nil
+	}
+	if app.DryRun {
+		for tag, list := range app.updateTags {
+			app.Journal.OK("Tag %s on %d asset(s) skipped - dry run mode", tag, len(list))
+		}
+		return nil
+	}
+
+	serverTags, err := app.client.GetAllTags(ctx)
+	if err != nil {
+		return fmt.Errorf("can't get the tag list from the server: %w", err)
+	}
+	byName := map[string]immich.Tag{}
+	for _, t := range serverTags {
+		byName[t.Name] = t
+	}
+
+	for tag, list := range app.updateTags {
+		t, found := byName[tag]
+		if !found {
+			app.Journal.OK("Create the tag %s", tag)
+			t, err = app.client.CreateTag(ctx, tag)
+			if err != nil {
+				return fmt.Errorf("can't create the tag %q: %w", tag, err)
+			}
+			byName[tag] = t
+		}
+		ids := gen.MapKeys(list)
+		size := app.AlbumBatchSize
+		if size <= 0 {
+			size = len(ids)
+		}
+		tagged := 0
+		for len(ids) > 0 {
+			batch := ids[:min(size, len(ids))]
+			ids = ids[len(batch):]
+			rr, err := app.client.TagAssets(ctx, t.ID, batch)
+			if err != nil {
+				return fmt.Errorf("can't tag the assets with %q: %w", tag, err)
+			}
+			for _, r := range rr {
+				if r.Success {
+					tagged++
+				}
+				if !r.Success && r.Error != "duplicate" {
+					app.Journal.Warning("%s: %s", r.AssetID, r.Error)
+				}
+			}
+		}
+		if tagged > 0 {
+			app.Journal.OK("%d asset(s) tagged with %q", tagged, tag)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package cmdupload
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/logger"
+)
+
+type icTags struct {
+	stubIC
+	created []string
+	tagged  map[string][]string // asset IDs by tag ID
+	calls   int
+}
+
+func (c *icTags) AssetUpload(ctx context.Context, a *browser.LocalAssetFile) (immich.AssetResponse, error) {
+	return immich.AssetResponse{ID: "new-" + a.FileName}, nil
+}
+
+func (c *icTags) GetAllTags(ctx context.Context) ([]immich.Tag, error) {
+	return []immich.Tag{{ID: "tag-1", Name: "scanned-2024"}}, nil
+}
+
+func (c *icTags) CreateTag(ctx context.Context, name string) (immich.Tag, error) {
+	c.created = append(c.created, name)
+	return immich.Tag{ID: "new-" + name, Name: name}, nil
+}
+
+func (c *icTags) TagAssets(ctx context.Context, tagID string, assets []string) ([]immich.TagAssetResult, error) {
+	c.calls++
+	if c.tagged == nil {
+		c.tagged = map[string][]string{}
+	}
+	c.tagged[tagID] = append(c.tagged[tagID], assets...)
+	var r []immich.TagAssetResult
+	for _, id := range assets {
+		r = append(r, immich.TagAssetResult{AssetID: id, Success: true})
+	}
+	return r, nil
+}
+
+func TestTags(t *testing.T) {
+	tests := []struct {
+		name         string
+		tags         []string
+		albumsAsTags bool
+		dryRun       bool
+		wantCreated  []string
+		wantTagged   map[string][]string
+		wantAlbums   map[string]map[string]any
+	}{
+		{
+			name:       "no tag",
+			wantAlbums: map[string]map[string]any{"Holidays": {"new-a.cr3": nil, "new-b.cr3": nil}},
+		},
+		{
+			name:        "tags",
+			tags:        []string{"scanned-2024", "family"},
+			wantCreated: []string{"family"},
+			wantTagged: map[string][]string{
+				"tag-1":      {"new-a.cr3", "new-b.cr3"},
+				"new-family": {"new-a.cr3", "new-b.cr3"},
+			},
+			wantAlbums: map[string]map[string]any{"Holidays": {"new-a.cr3": nil, "new-b.cr3": nil}},
+		},
+		{
+			name:         "albums as tags",
+			tags:         []string{"scanned-2024"},
+			albumsAsTags: true,
+			wantCreated:  []string{"Holidays"},
+			wantTagged: map[string][]string{
+				"tag-1":        {"new-a.cr3", "new-b.cr3"},
+				"new-Holidays": {"new-a.cr3", "new-b.cr3"},
+			},
+			wantAlbums: map[string]map[string]any{},
+		},
+		{
+			name:   "dry run",
+			tags:   []string{"scanned-2024"},
+			dryRun: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ic := &icTags{}
+			app := UpCmd{
+				client:         ic,
+				Journal:        logger.NewJournal(logger.NoLogger{}),
+				GooglePhotos:   true,
+				CreateAlbums:   true,
+				Tags:           tt.tags,
+				AlbumsAsTags:   tt.albumsAsTags,
+				DryRun:         tt.dryRun,
+				AlbumBatchSize: 1,
+				updateAlbums:   map[string]map[string]any{},
+				AssetIndex:     &AssetIndex{},
+			}
+			app.AssetIndex.ReIndex()
+			for _, name := range []string{"a.cr3", "b.cr3"} {
+				a := &browser.LocalAssetFile{
+					FSys:     fstest.MapFS{name: {Data: []byte("raw content " + name)}},
+					FileName: name,
+					Title:    name,
+					Albums:   []browser.LocalAlbum{{Path: "Holidays", Name: "Holidays"}},
+				}
+				err := app.handleAsset(context.Background(), a)
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+			}
+			err := app.ManageTags(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			slices.Sort(ic.created)
+			slices.Sort(tt.wantCreated)
+			if !reflect.DeepEqual(ic.created, tt.wantCreated) {
+				t.Errorf("created tags = %v, want %v", ic.created, tt.wantCreated)
+			}
+			for _, l := range ic.tagged {
+				slices.Sort(l)
+			}
+			if !reflect.DeepEqual(ic.tagged, tt.wantTagged) {
+				t.Errorf("tagged = %v, want %v", ic.tagged, tt.wantTagged)
+			}
+			if tt.wantTagged != nil && ic.calls != 2*len(tt.wantTagged) {
+				t.Errorf("%d calls, want batches of 1 asset", ic.calls)
+			}
+			if !tt.dryRun && !reflect.DeepEqual(app.updateAlbums, tt.wantAlbums) {
+				t.Errorf("albums = %v, want %v", app.updateAlbums, tt.wantAlbums)
+			}
+		})
+	}
+}
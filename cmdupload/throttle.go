@@ -0,0 +1,35 @@
+package cmdupload
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	minThrottleRetries = 3           // new attempts of a throttled upload, even without -upload-retries
+	maxThrottleDelay   = time.Minute // longest pause without Retry-After
+)
+
+// throttle pauses the uploads after a refusal of the server because of too many requests.
+// The delay given by the Retry-After header is honored, otherwise the pause doubles at each retry.
+// A single warning is given for the run, the count is given in the report.
+func (app *UpCmd) throttle(ctx context.Context, delay time.Duration, retry int) error {
+	if app.throttled == 0 {
+		app.Journal.Warning("The server refuses the requests because there are too many, the uploads are slowed down. Consider using -max-requests-per-second")
+	}
+	app.throttled++
+	if delay <= 0 {
+		delay = maxThrottleDelay
+		if retry < 16 {
+			delay = min(app.throttleDelay<<(retry-1), maxThrottleDelay)
+		}
+	}
+	t := time.NewTimer(delay)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
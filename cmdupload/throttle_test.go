@@ -0,0 +1,68 @@
+package cmdupload
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/logger"
+)
+
+func TestUploadThrottled(t *testing.T) {
+	tc := []struct {
+		name     string
+		refusals int
+		retries  int
+		uploaded bool
+	}{
+		{name: "retried without -upload-retries", refusals: 2, uploaded: true},
+		{name: "too many refusals", refusals: 4},
+		{name: "more retries", refusals: 4, retries: 5, uploaded: true},
+	}
+	for _, c := range tc {
+		t.Run(c.name, func(t *testing.T) {
+			calls := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				if calls <= c.refusals {
+					w.Header().Set("Retry-After", "0")
+					w.WriteHeader(http.StatusTooManyRequests)
+					return
+				}
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte(`{"id":"asset-1"}`))
+			}))
+			defer server.Close()
+			ic, err := immich.NewImmichClient(server.URL, "1234", false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			app := UpCmd{
+				client:        ic,
+				Journal:       logger.NewJournal(logger.NoLogger{}),
+				UploadRetries: c.retries,
+				AssetIndex:    &AssetIndex{},
+				throttleDelay: time.Millisecond,
+			}
+			app.AssetIndex.ReIndex()
+			a := &browser.LocalAssetFile{
+				FSys:     fstest.MapFS{"photo.jpg": {Data: []byte("content")}},
+				FileName: "photo.jpg",
+				Title:    "photo.jpg",
+				FileSize: 7,
+			}
+			_, err = app.UploadAsset(context.Background(), a)
+			if (err == nil) != c.uploaded {
+				t.Fatalf("uploaded: %v, want %v, error: %v", err == nil, c.uploaded, err)
+			}
+			if app.throttled != min(c.refusals, max(c.retries, minThrottleRetries)) {
+				t.Errorf("throttled = %d", app.throttled)
+			}
+		})
+	}
+}
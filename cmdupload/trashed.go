@@ -0,0 +1,41 @@
+package cmdupload
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/logger"
+)
+
+// adviceTrashedOnServer replaces the advice given for a trashed server's asset:
+// uploading the file again would conflict with the trashed asset when it is restored
+func adviceTrashedOnServer(sa *immich.Asset) *Advice {
+	return &Advice{
+		Advice:      TrashedOnServer,
+		Message:     fmt.Sprintf("An asset with the same name:%q and date:%q is in the server's trash.", sa.OriginalFileName, sa.ExifInfo.DateTimeOriginal.Format(time.DateTime)),
+		ServerAsset: sa,
+	}
+}
+
+// handleTrashed skips the file matching a trashed server's asset, or restores the server's
+// asset with -restore-trashed. It gives the ID of the restored asset, empty when skipped.
+func (app *UpCmd) handleTrashed(ctx context.Context, a *browser.LocalAssetFile, advice *Advice) (string, error) {
+	sa := advice.ServerAsset
+	if !app.RestoreTrashed {
+		app.journalAsset(a, logger.SERVER_TRASHED, advice.Message+" Not uploaded.")
+		return "", nil
+	}
+	if !app.DryRun {
+		err := app.client.RestoreAssets(ctx, []string{sa.ID})
+		if err != nil {
+			app.journalAsset(a, logger.SERVER_ERROR, "can't restore the server's asset from the trash: "+err.Error())
+			return "", err
+		}
+	}
+	sa.IsTrashed = false
+	app.journalAsset(a, logger.SERVER_TRASHED, advice.Message+" Restored.")
+	return sa.ID, nil
+}
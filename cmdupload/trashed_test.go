@@ -0,0 +1,78 @@
+package cmdupload
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/logger"
+)
+
+// icCatchRestores records the restored assets
+type icCatchRestores struct {
+	icCatchUploadsAssets
+	restored []string
+}
+
+func (c *icCatchRestores) RestoreAssets(ctx context.Context, IDs []string) error {
+	c.restored = append(c.restored, IDs...)
+	return nil
+}
+
+func TestReconcileTrashed(t *testing.T) {
+	tc := []struct {
+		name         string
+		restore      bool
+		wantRestored []string
+		wantAlbums   map[string]map[string]any
+	}{
+		{name: "skip", wantAlbums: map[string]map[string]any{}},
+		{name: "restore", restore: true, wantRestored: []string{"server-01"}, wantAlbums: map[string]map[string]any{"Trip": {"server-01": nil}}},
+	}
+	for _, c := range tc {
+		t.Run(c.name, func(t *testing.T) {
+			fsys := fstest.MapFS{"trip/photo_01.cr3": {Data: []byte("photo 01")}}
+			ic := &icCatchRestores{}
+			app := UpCmd{
+				client:           ic,
+				Journal:          logger.NewJournal(logger.NoLogger{}),
+				ReconcileTrashed: true,
+				RestoreTrashed:   c.restore,
+				ImportIntoAlbum:  "Trip",
+				updateAlbums:     map[string]map[string]any{},
+				AssetIndex: &AssetIndex{assets: []*immich.Asset{{
+					ID:               "server-01",
+					OriginalFileName: "photo_01",
+					OriginalPath:     "upload/photo_01.cr3",
+					IsTrashed:        true,
+					ExifInfo:         immich.ExifInfo{FileSizeInByte: len(fsys["trip/photo_01.cr3"].Data)},
+				}}},
+			}
+			app.AssetIndex.ReIndex()
+			a := &browser.LocalAssetFile{
+				FSys:     fsys,
+				FileName: "trip/photo_01.cr3",
+				Title:    "photo_01.cr3",
+				FileSize: len(fsys["trip/photo_01.cr3"].Data),
+			}
+			if err := app.handleAsset(context.Background(), a); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if len(ic.assets) != 0 {
+				t.Errorf("uploads = %v, want none", ic.assets)
+			}
+			if !reflect.DeepEqual(ic.restored, c.wantRestored) {
+				t.Errorf("restored = %v, want %v", ic.restored, c.wantRestored)
+			}
+			if !reflect.DeepEqual(app.updateAlbums, c.wantAlbums) {
+				t.Errorf("albums = %v, want %v", app.updateAlbums, c.wantAlbums)
+			}
+			if n := app.Journal.Count(logger.SERVER_TRASHED); n != 1 {
+				t.Errorf("trashed count = %d, want 1", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package cmdupload
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/logger"
+)
+
+// icServerDedup gives the ID of the server's asset having the same content
+type icServerDedup struct {
+	stubIC
+	existing map[string]string // file name -> ID of the server's asset
+	uploads  []string
+}
+
+func (c *icServerDedup) AssetUpload(ctx context.Context, a *browser.LocalAssetFile) (immich.AssetResponse, error) {
+	c.uploads = append(c.uploads, a.FileName)
+	if ID, ok := c.existing[a.FileName]; ok {
+		return immich.AssetResponse{ID: ID, Duplicate: true}, nil
+	}
+	return immich.AssetResponse{ID: "new-" + a.FileName}, nil
+}
+
+func TestTrustServerDedup(t *testing.T) {
+	fsys := fstest.MapFS{
+		"trip/photo_01.cr3": {Data: []byte("photo 01")},
+		"trip/photo_02.cr3": {Data: []byte("photo 02")},
+	}
+	ic := &icServerDedup{existing: map[string]string{"trip/photo_01.cr3": "server-01"}}
+	app := UpCmd{
+		client:           ic,
+		Journal:          logger.NewJournal(logger.NoLogger{}),
+		TrustServerDedup: true,
+		ImportIntoAlbum:  "Trip",
+		updateAlbums:     map[string]map[string]any{},
+		// without -trust-server-dedup, this asset is the same as photo_01.cr3
+		AssetIndex: &AssetIndex{assets: []*immich.Asset{{
+			ID:               "server-01",
+			OriginalFileName: "photo_01",
+			OriginalPath:     "upload/photo_01.cr3",
+			ExifInfo:         immich.ExifInfo{FileSizeInByte: len(fsys["trip/photo_01.cr3"].Data)},
+		}}},
+	}
+	app.AssetIndex.ReIndex()
+	for _, name := range []string{"trip/photo_01.cr3", "trip/photo_02.cr3"} {
+		a := &browser.LocalAssetFile{
+			FSys:     fsys,
+			FileName: name,
+			Title:    name[len("trip/"):],
+			FileSize: len(fsys[name].Data),
+		}
+		if err := app.handleAsset(context.Background(), a); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	if !reflect.DeepEqual(ic.uploads, []string{"trip/photo_01.cr3", "trip/photo_02.cr3"}) {
+		t.Errorf("uploads = %v", ic.uploads)
+	}
+	want := map[string]map[string]any{"Trip": {"server-01": nil, "new-trip/photo_02.cr3": nil}}
+	if !reflect.DeepEqual(app.updateAlbums, want) {
+		t.Errorf("albums = %v, want %v", app.updateAlbums, want)
+	}
+	if app.mediaUploaded != 1 {
+		t.Errorf("uploaded = %d, want 1", app.mediaUploaded)
+	}
+}
@@ -4,13 +4,19 @@ package cmdupload
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"io/fs"
 	"math"
+	"os"
+	"os/exec"
 	"path"
 	"path/filepath"
+	"slices"
 	"strings"
+	"text/template"
 	"time"
 
 	"github.com/google/uuid"
@@ -20,65 +26,217 @@ import (
 	"github.com/simulot/immich-go/helpers/fshelper"
 	"github.com/simulot/immich-go/helpers/fshelper/myflag"
 	"github.com/simulot/immich-go/helpers/gen"
+	"github.com/simulot/immich-go/helpers/nfc"
 	"github.com/simulot/immich-go/helpers/stacking"
+	"github.com/simulot/immich-go/helpers/tzone"
 	"github.com/simulot/immich-go/immich"
 	"github.com/simulot/immich-go/immich/metadata"
 
 	"github.com/simulot/immich-go/logger"
+	"github.com/simulot/immich-go/ui"
 )
 
 // iClient is an interface that implements the minimal immich client set of features for uploading
-// interface used to mock up the client
+// interface used to mock up the client, like the immichtest.FakeServer
 type iClient interface {
 	GetAllAssetsWithFilter(context.Context, *immich.GetAssetOptions, func(*immich.Asset)) error
+	GetAssetsTakenBetween(ctx context.Context, after time.Time, before time.Time, filter func(*immich.Asset)) error
+	GetAssetsUpdatedAfter(ctx context.Context, after time.Time, filter func(*immich.Asset)) error
+	GetAssetStatistics(ctx context.Context) (immich.AssetStatistics, error)
 	AssetUpload(context.Context, *browser.LocalAssetFile) (immich.AssetResponse, error)
+	AssetImport(ctx context.Context, la *browser.LocalAssetFile, assetPath string, sidecarPath string) (immich.AssetResponse, error)
 	DeleteAssets(context.Context, []string, bool) error
+	RestoreAssets(ctx context.Context, IDs []string) error
 
 	GetAllAlbums(context.Context) ([]immich.AlbumSimplified, error)
 	AddAssetToAlbum(context.Context, string, []string) ([]immich.UpdateAlbumResult, error)
 	CreateAlbum(context.Context, string, []string) (immich.AlbumSimplified, error)
+	UpdateAlbumInfo(ctx context.Context, id string, info immich.AlbumInfo) error
 	UpdateAssets(ctx context.Context, IDs []string, isArchived bool, isFavorite bool, latitude float64, longitude float64, removeParent bool, stackParentId string) error
+	ArchiveAssets(ctx context.Context, IDs []string, isArchived bool) error
 	StackAssets(ctx context.Context, cover string, IDs []string) error
 	UpdateAsset(ctx context.Context, ID string, a *browser.LocalAssetFile) (*immich.Asset, error)
+	GetAssetByID(ctx context.Context, id string) (*immich.Asset, error)
+	GetAssetAlbums(ctx context.Context, id string) ([]immich.AlbumSimplified, error)
+	SetUploadProgress(fn immich.UploadProgressFunc)
+	GetAllLibraries(ctx context.Context) ([]immich.Library, error)
+	SetLibrary(ID string)
+	GetServerVersion(ctx context.Context) (immich.ServerVersion, error)
+
+	GetAllPeople(ctx context.Context) ([]immich.Person, error)
+	AddPeopleToAsset(ctx context.Context, assetID string, people []immich.Person) ([]immich.Person, error)
+
+	GetAllTags(ctx context.Context) ([]immich.Tag, error)
+	CreateTag(ctx context.Context, name string) (immich.Tag, error)
+	TagAssets(ctx context.Context, tagID string, assets []string) ([]immich.TagAssetResult, error)
 }
 
 type UpCmd struct {
 	client  iClient         // Immich client
 	Journal *logger.Journal // Log and journal
 
-	fsys []fs.FS // pseudo file system to browse
+	fsys         []fs.FS               // pseudo file system to browse
+	ZipPasswords fshelper.ZipPasswords // Passwords of the encrypted archives
+	ZipPwFile    string                // File giving the passwords of the encrypted archives
 
 	GooglePhotos           bool             // For reading Google Photos takeout files
+	ValidateTakeout        bool             // Report the content of the takeout without uploading
 	Delete                 bool             // Delete original file after import
+	DeleteConfirmed        bool             // Delete the original files without asking
+	MoveTo                 string           // Folder receiving the uploaded files, under their relative path
+	OnUpload               string           // Command run after each upload
+	HookFatal              bool             // A failure of the OnUpload command stops the import
 	CreateAlbumAfterFolder bool             // Create albums for assets based on the parent folder or a given name
+	AlbumNameTemplate      string           // Template for the name of the folder's albums
+	AlbumByDate            string           // Go time layout giving the album of the assets from their date of capture
+	NoDateAlbum            string           // Album of the assets without date of capture with AlbumByDate
+	AlbumFolderDepth       string           // Depth of the folders giving the album's names, like 2 or 2-3
+	NestedAlbums           bool             // Nest the albums like the folders, when the server can. Flattened for now
+	AlbumFolderSeparator   string           // Separator of the folder's names when the depth spans several levels
 	ImportIntoAlbum        string           // All assets will be added to this album
+	SharedAlbumID          string           // All assets will be added to this existing album, given by its ID
+	Library                string           // Library receiving the uploads, given by its ID or its name
 	PartnerAlbum           string           // Partner's assets will be added to this album
+	SkipSharedAlbums       bool             // Don't add assets to the albums shared with the user
+	SharedAlbumPrefix      string           // Prefix for the names of the albums shared with the user
 	Import                 bool             // Import instead of upload
+	PathMap                PathMap          // Translation of the local paths into the server's paths for the Import
 	DeviceUUID             string           // Set a device UUID
 	Paths                  []string         // Path to explore
 	DateRange              immich.DateRange // Set capture date range
+	Since                  string           // Beginning of the capture date range, as an alternative to DateRange
+	Until                  string           // End of the capture date range, as an alternative to DateRange
 	ImportFromAlbum        string           // Import assets from this albums
+	ExcludeAlbums          []string         // Don't import assets found only in these albums
+	AlbumListFile          string           // File of the albums to include (+Album) and to deny (-Album)
+	AlbumMapFile           string           // File of the albums renamed on import, with source=target lines
 	CreateAlbums           bool             // Create albums when exists in the source
+	Tags                   []string         // Tags given to all the imported assets
+	AlbumsAsTags           bool             // Tag the assets with the names of their albums instead of adding them into albums
+	ImportKeywords         bool             // Tag the assets with the keywords of their metadata
 	KeepTrashed            bool             // Import trashed assets
+	ReconcileTrashed       bool             // Match the files with the trashed server's assets too
+	RestoreTrashed         bool             // Restore the trashed server's assets matching a file instead of skipping the file
 	KeepPartner            bool             // Import partner's assets
 	KeepUntitled           bool             // Keep untitled albums
 	UseFolderAsAlbumName   bool             // Use folder's name instead of metadata's title as Album name
 	DryRun                 bool             // Display actions but don't change anything
+	DryRunOutput           string           // File receiving the plan of the dry run
+	DuplicatesOut          string           // File receiving the list of the files skipped as duplicates of server's assets
+	OrphansOut             string           // File receiving the list of the sidecar files without media file
 	ForceSidecar           bool             // Generate a sidecar file for each file (default: TRUE)
 	CreateStacks           bool             // Stack jpg/raw/burst (Default: TRUE)
 	StackJpgRaws           bool             // Stack jpg/raw (Default: TRUE)
 	StackBurst             bool             // Stack burst (Default: TRUE)
+	BurstWindow            time.Duration    // Maximal interval between two frames of a burst
+	LivePhotos             bool             // Upload the photo and the video of Live Photos as one asset (Default: TRUE)
 	DiscardArchived        bool             // Don't import archived assets (Default: FALSE)
+	KeepEdited             string           // Policy for the pairs of edited and original files of a takeout
+	AlbumYearSuffix        bool             // Append the year to the titles of the takeout albums sharing the same title
+	ArchiveAll             bool             // Archive all the uploaded assets (Default: FALSE)
+	FavoriteMinRating      int              // Minimal XMP rating making the asset a favorite, 0 to disable
+	ImportPeople           bool             // Tag assets with the people found in the metadata (Default: FALSE)
+	PeopleRetries          int              // Number of new attempts when the faces of the assets aren't detected yet
+	PeopleRetryDelay       time.Duration    // Delay between the attempts to tag the people
+	MinSize                myflag.ByteSize  // Don't import assets smaller than this size
+	MaxSize                myflag.ByteSize  // Don't import assets bigger than this size
+	Orientation            string           // Import only the images having this orientation: landscape, portrait or square
+	Only                   string           // Import only the photos or only the videos
+	IfNewer                string           // File keeping the time of the last successful run
+	StartFrom              string           // Path of the folder or file where the walk of the folders starts
+	SidecarFormat          string           // Format of the JSON sidecar files: auto, exiftool or none
+	FailuresOut            string           // File receiving the paths of the assets that failed
+	OnlyFiles              string           // File listing the only paths to be imported
+	UploadRetries          int              // Number of new attempts when an upload times out or is throttled
+	ContinueOnQuota        bool             // Keep uploading when the server's storage quota is exceeded
+	MetricsAddr            string           // Address of the HTTP server exposing the metrics, like :9095
+	Verify                 bool             // Check the size and the checksum of new assets after their upload
+	ForceReplace           bool             // Replace the server's assets having the same name and date but a different checksum
+	RenameOnConflict       bool             // Upload under a new name the files having the name and date of a server's asset but a different checksum
+	UpdateExistingMetadata string           // Policy for the metadata of assets already on the server: replace, skip, fill-only or overwrite
+	StrictVersion          bool             // Refuse to run with a server out of the supported versions
+	IndexByDate            bool             // Request the server's assets by day of capture, when needed
+	TrustServerDedup       bool             // Upload all the files without requesting the server's assets, the server detects the duplicates
+	Checksum               bool             // Detect the copies of a file in the source by their checksum, even under another name
+	IndexCache             string           // File keeping the server's assets between runs
+	IndexCacheReset        bool             // Ignore the content of the IndexCache
+	DumpIndex              string           // File receiving the index of the server's assets
+	Explain                string           // Path or name of the file whose duplicate detection is explained
+	FetchPageSize          int              // Request the server's assets by pages of this size, 0 for a single request
+	ProgressThreshold      myflag.ByteSize  // Show the upload progress of files bigger than this size
+	AlbumBatchSize         int              // Maximum number of assets added to an album per call
+	MinAlbumSize           int              // Albums with fewer assets aren't created
+	AlbumFailStrict        bool             // Fail the run when assets couldn't be added to their albums
+	AlbumMatchFuzzy        bool             // Ignore case and surrounding spaces when searching existing albums
+	AlbumMatchLoose        bool             // Ignore the accents too when searching existing albums
+	AlbumComments          bool             // Enable the comments and likes on created albums (default: TRUE)
+	UpdateAlbumMeta        bool             // Set the description and the comments flag on existing albums too
+	PreserveAlbumOrder     bool             // Set the order of the assets in the albums
+	AlbumThumbnailFromMeta bool             // Set the album's thumbnail to the cover given by the takeout
+	SkipInvalid            bool             // Skip files with an invalid content instead of stopping (default: TRUE)
+	SkipEmpty              bool             // Skip the empty files (default: TRUE)
+	BrowserStrict          bool             // Stop at the first error of the browsing instead of skipping the file
+	MimeOverrides          MimeOverrides    // Content type forced for the upload, by extension
+	ReportNoDate           bool             // List uploaded assets without date of capture
+	DateFromFilename       bool             // Take the date of capture from the file name when missing
+	DateFromFolder         string           // Go time layout of the folder names giving the date of capture when missing
+	DateFromFolderForce    bool             // The date of the folder name replaces the date of capture of the file
+	GPS                    myflag.LatLong   // Coordinates given to assets without location
+	AssumeUTC              bool             // Dates of capture without offset are UTC (legacy behavior)
+	StatsByType            bool             // Add the counts by file type to the report
+	LogByAsset             bool             // Give the messages of each file as a block
+	ReportFormat           string           // Serialization of the final report: table or csv
+	ReportFile             string           // File receiving the CSV report, the standard output when empty
+	Quiet                  bool             // Only the warnings, the errors and the final report are displayed
+	Verbose                bool             // Display the debug dumps of the assets
+	LocationsFile          string           // CSV file giving the coordinates of assets without location per folder
+	TranscodeHEIC          bool             // Upload a JPEG rendition of the HEIC files
 
 	BrowserConfig Configuration
 
 	AssetIndex       *AssetIndex               // List of assets present on the server
 	deleteServerList []*immich.Asset           // List of server assets to remove
 	deleteLocalList  []*browser.LocalAssetFile // List of local assets to remove
+	moveLocalList    []*browser.LocalAssetFile // List of local assets to move into MoveTo
 	mediaUploaded    int                       // Count uploaded medias
 	mediaCount       int                       // Count of media on the source
 	updateAlbums     map[string]map[string]any // track immich albums changes
+	updateTags       map[string]map[string]any // Assets to be associated with tags, by tag
+	serverVersion    immich.ServerVersion      // Version of the server, zero when unknown
+	createdAssets    map[string]bool           // IDs of the assets created by the uploads of this run
 	stacks           *stacking.StackBuilder
+	noDateAssets     []string           // Uploaded assets without date of capture
+	invalidFiles     int                // Count of files skipped because of their content
+	newerThan        time.Time          // Time of the last successful run, read from IfNewer
+	onlyFiles        map[string]bool    // Paths to be imported, read from OnlyFiles
+	failures         map[string]bool    // Paths of the assets that failed
+	failureList      []string           // Same, in the order of the failures
+	runStart         time.Time          // Time of the beginning of the run
+	peopleQueue      []peopleTagging    // Assets waiting for their people
+	albumTemplate    *template.Template // Parsed AlbumNameTemplate
+	albumDepthFrom   int                // Parsed AlbumFolderDepth, 0 for the parent folder
+	albumDepthTo     int                // Last depth of the range
+	sharedAlbum      map[string]any     // Assets to be added to the SharedAlbumID
+	locations        []folderLocation   // Folders' coordinates read from LocationsFile
+	naiveZone        *time.Location     // Time zone of the dates of capture without offset
+	albumDescription map[string]string  // Descriptions of the albums found in the metadata
+	albumCovers      coverCandidates    // Candidates for the thumbnails of the albums
+	metrics          *metrics           // Started with MetricsAddr
+	archiveList      []string           // Uploaded assets to be archived with ArchiveAll
+	bulkUpdates      bulkQueue          // Uploaded assets by metadata values set by batches
+	bulkUpdated      int                // Assets updated by batches
+	albumResults     albumResults       // Outcome of the additions, by album
+	albumRetryDelay  time.Duration      // Delay before retrying the failed additions to an album
+	jxlWarned        bool               // The server can't process JPEG XL files, the warning is given
+	plan             dryRunPlan         // Changes the dry run would make
+	heicConverter    *heicConverter     // Tool found for TranscodeHEIC, nil when missing
+	albumList        *albumList         // Albums read from AlbumListFile
+	albumMap         map[string]string  // Target album by source album, read from AlbumMapFile
+	throttled        int                // Number of uploads refused because of too many requests, then retried
+	throttleDelay    time.Duration      // First pause after a refusal without Retry-After
+	duplicates       []serverDuplicate  // Files skipped as duplicates of server's assets
+	localCopies      localCopies        // First file of the source by content, with its asset
+	localCopyCount   int                // Number of copies of files of the source handled once
 }
 
 func NewUpCmd(ctx context.Context, ic iClient, log logger.Logger, args []string) (*UpCmd, error) {
@@ -86,21 +244,51 @@ func NewUpCmd(ctx context.Context, ic iClient, log logger.Logger, args []string)
 	cmd := flag.NewFlagSet("upload", flag.ExitOnError)
 
 	app := UpCmd{
-		updateAlbums: map[string]map[string]any{},
-		Journal:      logger.NewJournal(log),
-		client:       ic,
+		updateAlbums:    map[string]map[string]any{},
+		Journal:         logger.NewJournal(log),
+		client:          ic,
+		albumRetryDelay: 5 * time.Second,
+		throttleDelay:   2 * time.Second,
 	}
 	cmd.BoolFunc(
 		"dry-run",
 		"display actions but don't touch source or destination",
 		myflag.BoolFlagFn(&app.DryRun, false))
+	cmd.StringVar(&app.DryRunOutput,
+		"dry-run-output",
+		"",
+		"With -dry-run, write the plan of the changes on the server into this file")
+	cmd.StringVar(&app.DuplicatesOut,
+		"duplicates-out",
+		"",
+		"Write the list of the files skipped as duplicates of server's assets, with the matched server's asset, into this CSV file")
+	cmd.StringVar(&app.OrphansOut,
+		"orphans-out",
+		"",
+		"Write the list of the XMP and JSON sidecar files whose media file is missing into this file")
 	cmd.Var(&app.DateRange,
 		"date",
 		"Date of capture range.")
+	cmd.StringVar(&app.Since,
+		"since",
+		"",
+		"Select the assets taken since this date, included: YYYY, YYYY-MM or YYYY-MM-DD")
+	cmd.StringVar(&app.Until,
+		"until",
+		"",
+		"Select the assets taken until this date, included: YYYY, YYYY-MM or YYYY-MM-DD")
 	cmd.StringVar(&app.ImportIntoAlbum,
 		"album",
 		"",
 		"All assets will be added to this album.")
+	cmd.StringVar(&app.Library,
+		"library",
+		"",
+		"Upload the assets into this library, given by its ID or its name (default: the user's library)")
+	cmd.StringVar(&app.SharedAlbumID,
+		"shared-album-id",
+		"",
+		"All assets will be added to this existing album given by its ID, even when owned by another user. Other album options are ignored.")
 	cmd.BoolFunc(
 		"force-sidecar",
 		"Upload the photo and a sidecar file with known information like date and GPS coordinates. With google-photos, information comes from the metadata files. (DEFAULT false)",
@@ -109,10 +297,47 @@ func NewUpCmd(ctx context.Context, ic iClient, log logger.Logger, args []string)
 		"create-album-folder",
 		" folder import only: Create albums for assets based on the parent folder",
 		myflag.BoolFlagFn(&app.CreateAlbumAfterFolder, false))
+	cmd.Func(
+		"album-name-template",
+		" folder import only: Template for the album's name with -create-album-folder, like \"{{.Date.Year}} - {{.Dir}}\"",
+		func(s string) error {
+			var err error
+			app.AlbumNameTemplate = s
+			app.albumTemplate, err = parseAlbumNameTemplate(s)
+			return err
+		})
+	cmd.Func(
+		"album-folder-depth",
+		" folder import only: With -create-album-folder, group the assets by the folder at this depth, like 2. A range like 2-3 joins the names of the folders at these depths",
+		func(s string) error {
+			var err error
+			app.AlbumFolderDepth = s
+			app.albumDepthFrom, app.albumDepthTo, err = parseFolderDepth(s)
+			return err
+		})
+	cmd.BoolFunc(
+		"nested-albums",
+		" folder import only: With -create-album-folder, create the albums nested like the folders, 2023/Italy/Rome gives the album Rome in Italy in 2023. The Immich server can't nest the albums yet, they are flattened with a warning (default FALSE)",
+		myflag.BoolFlagFn(&app.NestedAlbums, false))
+	cmd.StringVar(&app.AlbumFolderSeparator,
+		"album-folder-separator",
+		" - ",
+		" folder import only: Separator of the folder's names joined by -album-folder-depth")
 	cmd.BoolFunc(
 		"google-photos",
 		"Import GooglePhotos takeout zip files",
 		myflag.BoolFlagFn(&app.GooglePhotos, false))
+	cmd.BoolFunc(
+		"validate-takeout",
+		" google-photos only: Report the content of the takeout as understood by immich-go, and exit without uploading (default: FALSE)",
+		myflag.BoolFlagFn(&app.ValidateTakeout, false))
+	cmd.Var(&app.ZipPasswords,
+		"zip-password",
+		"Password of the encrypted zip files. Use name.zip=password for the password of one archive, can be repeated. Visible in the process list, prefer -zip-password-file or the IMMICH_ZIP_PASSWORD environment variable")
+	cmd.StringVar(&app.ZipPwFile,
+		"zip-password-file",
+		"",
+		"Read the passwords of the encrypted zip files from this file, one password or name.zip=password per line")
 	cmd.BoolFunc(
 		"create-albums",
 		" google-photos only: Create albums like there were in the source (default: TRUE)",
@@ -124,10 +349,46 @@ func NewUpCmd(ctx context.Context, ic iClient, log logger.Logger, args []string)
 	cmd.BoolFunc(
 		"keep-partner",
 		" google-photos only: Import also partner's items (default: TRUE)", myflag.BoolFlagFn(&app.KeepPartner, true))
+	cmd.BoolFunc(
+		"skip-shared-albums",
+		" google-photos only: Don't create the albums shared with you by others, their assets are imported without album (default: FALSE)", myflag.BoolFlagFn(&app.SkipSharedAlbums, false))
+	cmd.StringVar(&app.SharedAlbumPrefix,
+		"shared-album-prefix",
+		"",
+		" google-photos only: Prefix the names of the albums shared with you by others, like \"Shared: \"")
 	cmd.StringVar(&app.ImportFromAlbum,
 		"from-album",
 		"",
 		" google-photos only: Import only from this album")
+	cmd.Func(
+		"exclude-album",
+		" google-photos only: Don't import the assets found only in this album. Repeat the option to exclude several albums",
+		func(s string) error {
+			app.ExcludeAlbums = append(app.ExcludeAlbums, s)
+			return nil
+		})
+	cmd.StringVar(&app.AlbumListFile,
+		"album-list",
+		"",
+		"File of the albums to import, with lines like +Album to include an album, or -Album to deny it. The denied albums win, and only the included albums are imported when the file has any")
+	cmd.StringVar(&app.AlbumMapFile,
+		"album-map",
+		"",
+		"File of the albums renamed on import, with lines like source=target. The assets of the album source go into the album target")
+
+	cmd.BoolFunc(
+		"albums-as-tags",
+		" google-photos only: Tag the assets with the names of their albums instead of adding them into albums (default: FALSE)", myflag.BoolFlagFn(&app.AlbumsAsTags, false))
+	cmd.Func(
+		"tag",
+		"Tag all the imported assets with this tag. Repeat the option to give several tags",
+		func(s string) error {
+			app.Tags = append(app.Tags, s)
+			return nil
+		})
+	cmd.BoolFunc(
+		"import-keywords",
+		"Tag the assets with the IPTC keywords and the XMP subjects of the images and their XMP sidecars. The hierarchical keywords like Places|Europe|Italy give nested tags (default TRUE)", myflag.BoolFlagFn(&app.ImportKeywords, true))
 
 	cmd.BoolFunc(
 		"keep-untitled-albums",
@@ -140,6 +401,40 @@ func NewUpCmd(ctx context.Context, ic iClient, log logger.Logger, args []string)
 	cmd.BoolFunc(
 		"discard-archived",
 		" google-photos only: Do not import archived photos (default FALSE)", myflag.BoolFlagFn(&app.DiscardArchived, false))
+	app.KeepEdited = gp.KeepEditedEdited
+	cmd.Func(
+		"keep-edited",
+		" google-photos only: Files uploaded when a photo has an edited copy: original, edited or both (default edited)",
+		func(s string) error {
+			var err error
+			app.KeepEdited, err = gp.ParseKeepEdited(s)
+			return err
+		})
+	cmd.BoolFunc(
+		"album-year-suffix",
+		" google-photos only: Append the year to the titles of the albums sharing the same title, like Birthday (2021) (default FALSE)", myflag.BoolFlagFn(&app.AlbumYearSuffix, false))
+	cmd.BoolFunc(
+		"album-thumbnail-from-metadata",
+		" google-photos only: Set the thumbnail of the created albums to the cover photo given by the takeout, or to the oldest photo when the cover isn't uploaded (default FALSE)", myflag.BoolFlagFn(&app.AlbumThumbnailFromMeta, false))
+	cmd.BoolFunc(
+		"archive-all",
+		"Archive all the uploaded assets, they stay in their albums (default FALSE)", myflag.BoolFlagFn(&app.ArchiveAll, false))
+	cmd.IntVar(&app.FavoriteMinRating,
+		"favorite-min-rating",
+		0,
+		"Mark as favorite the assets having at least this star rating, from 1 to 5, in their XMP sidecar. 0 disables")
+
+	cmd.BoolFunc(
+		"people",
+		" google-photos only: Tag assets with the people's names found in the metadata, create missing people (default FALSE)", myflag.BoolFlagFn(&app.ImportPeople, false))
+	cmd.IntVar(&app.PeopleRetries,
+		"people-retries",
+		5,
+		"Number of new attempts to tag the assets whose faces aren't detected yet by the server")
+	cmd.DurationVar(&app.PeopleRetryDelay,
+		"people-retry-delay",
+		30*time.Second,
+		"Delay between the attempts to tag the people, like 10s")
 
 	cmd.BoolFunc(
 		"create-stacks",
@@ -151,8 +446,297 @@ func NewUpCmd(ctx context.Context, ic iClient, log logger.Logger, args []string)
 	cmd.BoolFunc(
 		"stack-burst",
 		"Control the stacking bursts (default TRUE)", myflag.BoolFlagFn(&app.StackBurst, true))
+	cmd.DurationVar(&app.BurstWindow,
+		"burst-window",
+		stacking.DefaultBurstWindow,
+		"Maximal interval between two frames of a burst, like 500ms")
+	cmd.BoolFunc(
+		"live-photo",
+		"Upload the photo and the video of a Live Photo as one asset (default TRUE)", myflag.BoolFlagFn(&app.LivePhotos, true))
+
+	cmd.Var(&app.MinSize,
+		"min-size",
+		"Import only assets having at least this size (ex: 500K, 2M)")
+	cmd.Var(&app.MaxSize,
+		"max-size",
+		"Import only assets having at most this size (ex: 500M, 2G)")
+	cmd.Func(
+		"orientation",
+		"Import only the images having this orientation: landscape, portrait or square. Assets without known dimensions, like videos, are imported",
+		func(s string) error {
+			var err error
+			app.Orientation, err = parseOrientation(s)
+			return err
+		})
+	cmd.Func(
+		"only",
+		"Import only the photos or only the videos: photo, video or all (default all)",
+		func(s string) error {
+			var err error
+			app.Only, err = parseOnly(s)
+			return err
+		})
+
+	cmd.BoolFunc(
+		"skip-invalid",
+		"Skip files having a content not matching their type or truncated, instead of stopping (default TRUE)", myflag.BoolFlagFn(&app.SkipInvalid, true))
+
+	cmd.BoolFunc(
+		"skip-empty",
+		"Skip the empty files, like the ones of a failed export, instead of uploading them (default TRUE)", myflag.BoolFlagFn(&app.SkipEmpty, true))
+
+	cmd.BoolFunc(
+		"browser-strict",
+		"Stop at the first file that can't be read, like a damaged JSON file, instead of reporting it and continuing with the other files (default FALSE)", myflag.BoolFlagFn(&app.BrowserStrict, false))
+
+	cmd.BoolFunc(
+		"album-match-fuzzy",
+		"Ignore the case and the surrounding spaces when searching an existing album by its name (default FALSE)", myflag.BoolFlagFn(&app.AlbumMatchFuzzy, false))
+
+	cmd.BoolFunc(
+		"album-match-loose",
+		"Like -album-match-fuzzy, and ignore the accents too: Café, cafe and CAFE are the same album. The created albums keep their name (default FALSE)", myflag.BoolFlagFn(&app.AlbumMatchLoose, false))
+
+	cmd.BoolFunc(
+		"album-comments",
+		"Enable the comments and likes on the albums created by immich-go (default TRUE)", myflag.BoolFlagFn(&app.AlbumComments, true))
+
+	cmd.BoolFunc(
+		"update-album-meta",
+		"Also update the description and the comments flag of albums already present on the server (default FALSE)", myflag.BoolFlagFn(&app.UpdateAlbumMeta, false))
+
+	cmd.BoolFunc(
+		"preserve-album-order",
+		"Set the order of the assets in the albums. The server can't keep the manual order of Google Photos, the albums are sorted by date (default FALSE)", myflag.BoolFlagFn(&app.PreserveAlbumOrder, false))
+
+	cmd.IntVar(&app.AlbumBatchSize,
+		"album-batch-size",
+		1000,
+		"Maximum number of assets added to an album in one call to the server, including when the album is created")
+	cmd.IntVar(&app.MinAlbumSize,
+		"min-album-size",
+		0,
+		"Don't create the albums having fewer assets, their assets are left without album. The albums already on the server aren't concerned")
+
+	cmd.BoolFunc(
+		"album-fail-strict",
+		"Exit with an error when some assets couldn't be added to their albums (default FALSE)", myflag.BoolFlagFn(&app.AlbumFailStrict, false))
+
+	cmd.IntVar(&app.UploadRetries,
+		"upload-retries",
+		0,
+		"Number of new attempts when an upload exceeds the -upload-timeout, or is refused because of too many requests (at least 3)")
+
+	cmd.StringVar(&app.MetricsAddr,
+		"metrics-addr",
+		"",
+		"Expose the counters of the run in the Prometheus format at http://addr/metrics, like :9095 (default: no metrics)")
+
+	cmd.BoolFunc(
+		"continue-on-quota",
+		"Keep trying to upload the remaining assets when the server's storage quota is exceeded, instead of stopping (default FALSE)", myflag.BoolFlagFn(&app.ContinueOnQuota, false))
+
+	cmd.BoolFunc(
+		"verify",
+		"Check the size and the checksum of each new asset on the server after its upload, and upload it again when they differ (default FALSE)", myflag.BoolFlagFn(&app.Verify, false))
+	cmd.BoolFunc(
+		"checksum",
+		"Detect the copies of a file in the source by their checksum, even under another name. Each file is read once more (default FALSE)", myflag.BoolFlagFn(&app.Checksum, false))
+
+	cmd.BoolFunc(
+		"index-by-date",
+		"Request the server's assets of a day of capture only when an asset of this day is imported, instead of getting all the server's assets at the start. Assets without date of capture are checked by the server only (default FALSE)", myflag.BoolFlagFn(&app.IndexByDate, false))
+
+	cmd.BoolFunc(
+		"trust-server-dedup",
+		"Upload all the files without requesting the server's assets, the server ignores the files it already has. The files are never upgraded (default FALSE)", myflag.BoolFlagFn(&app.TrustServerDedup, false))
+
+	cmd.BoolFunc(
+		"reconcile-trashed",
+		"Match the files with the trashed server's assets too, the matching files aren't uploaded again (default FALSE)", myflag.BoolFlagFn(&app.ReconcileTrashed, false))
+
+	cmd.BoolFunc(
+		"restore-trashed",
+		"With -reconcile-trashed, restore the trashed server's asset matching a file instead of skipping the file (default FALSE)", myflag.BoolFlagFn(&app.RestoreTrashed, false))
+
+	cmd.StringVar(&app.IndexCache,
+		"index-cache",
+		"",
+		"Keep the list of the server's assets into this file. Next runs only request the assets changed since")
+
+	cmd.BoolFunc(
+		"index-cache-reset",
+		"Ignore the content of the -index-cache file and request all the server's assets (default FALSE)", myflag.BoolFlagFn(&app.IndexCacheReset, false))
+
+	cmd.StringVar(&app.DumpIndex,
+		"dump-index",
+		"",
+		"Write the index of the server's assets used to detect the duplicates into this file")
+
+	cmd.StringVar(&app.Explain,
+		"explain",
+		"",
+		"Print how the duplicate detection decides for the file having this path or name")
+
+	cmd.IntVar(&app.FetchPageSize,
+		"fetch-page-size",
+		0,
+		"Request the server's assets by pages of this size, several pages at the same time. 0 requests all the assets at once")
+
+	cmd.BoolFunc(
+		"strict-version",
+		"Stop when the server version is out of the range supported by immich-go, instead of a warning (default FALSE)", myflag.BoolFlagFn(&app.StrictVersion, false))
+
+	cmd.BoolFunc(
+		"force-replace",
+		"Upload the file even when the server has the same or a bigger asset, if their checksums differ. The server's asset is moved to the trash and the new one is added to its albums (default FALSE)", myflag.BoolFlagFn(&app.ForceReplace, false))
+
+	cmd.BoolFunc(
+		"rename-on-conflict",
+		"Upload the file under a new name, ending with the beginning of its checksum, when the server has an asset with the same name and date but a different checksum. Both assets are kept (default FALSE)", myflag.BoolFlagFn(&app.RenameOnConflict, false))
+
+	app.UpdateExistingMetadata = MetadataReplace
+	cmd.Func(
+		"update-existing-metadata",
+		"Set the local description, GPS coordinates and favorite flag on the assets already on the server: replace (all the local values), skip, fill-only (only the server's empty fields) or overwrite (only the local non-empty values) (default replace)",
+		func(s string) error {
+			var err error
+			app.UpdateExistingMetadata, err = parseMetadataPolicy(s)
+			return err
+		})
+
+	app.ProgressThreshold = 100 << 20
+	cmd.Var(&app.ProgressThreshold,
+		"progress-threshold",
+		"Show the progress of the upload of files bigger than this size (ex: 500M), 0 to disable")
+
+	cmd.StringVar(&app.FailuresOut,
+		"failures-out",
+		"",
+		"Write the paths of the files that failed to upload into this file, one per line")
+
+	cmd.StringVar(&app.OnlyFiles,
+		"only-files",
+		"",
+		"Import only the files listed in this file, one path per line, like the ones written by -failures-out")
+
+	cmd.StringVar(&app.IfNewer,
+		"if-newer",
+		"",
+		" folder import only: Import only files modified since the last successful run. The time of the run is kept into the given file")
+
+	cmd.StringVar(&app.StartFrom,
+		"start-from",
+		"",
+		" folder import only: Skip the files before this path, relative to the imported folder, in the walk order")
+
+	app.SidecarFormat = metadata.SidecarAuto
+	cmd.Func(
+		"sidecar-format",
+		" folder import only: Format of the JSON sidecar files: auto, exiftool or none (default auto)",
+		func(s string) error {
+			var err error
+			app.SidecarFormat, err = metadata.ParseSidecarFormat(s)
+			return err
+		})
+
+	cmd.BoolFunc(
+		"report-no-date",
+		"List the uploaded assets without date of capture (default FALSE)", myflag.BoolFlagFn(&app.ReportNoDate, false))
+	cmd.BoolFunc(
+		"date-from-filename",
+		"Take the date of capture from the file name when the asset hasn't any (default FALSE)", myflag.BoolFlagFn(&app.DateFromFilename, false))
+	cmd.Func(
+		"album-by-date",
+		"Add the assets into albums named after their date of capture. The value is a Go time layout, like 2006-01 for monthly albums",
+		func(s string) error {
+			var err error
+			app.AlbumByDate, err = parseAlbumByDate(s)
+			return err
+		})
+	cmd.StringVar(&app.NoDateAlbum,
+		"no-date-album",
+		"",
+		"With -album-by-date, album of the assets without date of capture (default: not added to an album)")
+
+	cmd.Func(
+		"date-from-folder",
+		"Take the date of capture from the folder name when the asset hasn't any. The value is a Go time layout, like 2006 or 2006-01-02",
+		func(s string) error {
+			var err error
+			app.DateFromFolder, err = parseDateFromFolder(s)
+			return err
+		})
+	cmd.BoolFunc(
+		"date-from-folder-force",
+		"The date of the folder name replaces the date of capture of the assets (default FALSE)", myflag.BoolFlagFn(&app.DateFromFolderForce, false))
+
+	cmd.BoolFunc(
+		"assume-utc",
+		"The dates of capture written without offset are UTC (default FALSE)", myflag.BoolFlagFn(&app.AssumeUTC, false))
+
+	cmd.Var(&app.GPS,
+		"gps",
+		"Set these coordinates (latitude,longitude) to assets without GPS location in their metadata or sidecar")
+	cmd.StringVar(&app.LocationsFile,
+		"locations",
+		"",
+		"CSV file giving the coordinates of assets without GPS location per folder, with lines like: folder,latitude,longitude")
 
-	// cmd.BoolVar(&app.Delete, "delete", false, "Delete local assets after upload")
+	cmd.BoolFunc(
+		"stats-by-type",
+		"Add to the report the counts of handled files by file type (default FALSE)", myflag.BoolFlagFn(&app.StatsByType, false))
+	cmd.BoolFunc(
+		"log-by-asset",
+		"Give the messages of each file as a block, once the file is handled, without other messages in between (default FALSE)", myflag.BoolFlagFn(&app.LogByAsset, false))
+	cmd.StringVar(&app.ReportFormat,
+		"report-format",
+		string(logger.ReportTable),
+		"Format of the final report: table, or csv for one line per file with its path, action, server ID, albums, size and error")
+	cmd.StringVar(&app.ReportFile,
+		"report-file",
+		"",
+		"Write the CSV report into this file instead of the standard output")
+
+	cmd.BoolFunc(
+		"transcode-heic-to-jpeg",
+		"Upload a JPEG rendition of the HEIC files made with heif-convert or ffmpeg, for the servers unable to make their thumbnails (default FALSE)", myflag.BoolFlagFn(&app.TranscodeHEIC, false))
+
+	cmd.BoolFunc(
+		"import",
+		"Register the files in place on the server with their server's path given by -path-map, instead of uploading their content. The server must read the files, like the ones of a shared NAS folder (default FALSE)", myflag.BoolFlagFn(&app.Import, false))
+	cmd.Var(&app.PathMap,
+		"path-map",
+		"Translation of a local folder into the same folder seen by the server for the -import, like /mnt/nas/photos=/data/photos. Can be repeated")
+
+	cmd.BoolFunc(
+		"quiet",
+		"Display only the warnings, the errors and the final report (default FALSE)", myflag.BoolFlagFn(&app.Quiet, false))
+	cmd.BoolFunc(
+		"verbose",
+		"Display the details of each asset for debugging (default FALSE)", myflag.BoolFlagFn(&app.Verbose, false))
+
+	cmd.BoolFunc(
+		"delete",
+		"Delete the local files once they are on the server, after a confirmation (default FALSE)", myflag.BoolFlagFn(&app.Delete, false))
+	cmd.BoolFunc(
+		"delete-confirmed",
+		"With -delete, delete the local files without asking for a confirmation (default FALSE)", myflag.BoolFlagFn(&app.DeleteConfirmed, false))
+	cmd.StringVar(&app.MoveTo,
+		"move-to",
+		"",
+		"Move the local files whose upload has created a new asset into this folder, under their relative path")
+	cmd.StringVar(&app.OnUpload,
+		"on-upload",
+		"",
+		"Command run by the shell after each upload, with the variables IMMICH_GO_ASSET_PATH, IMMICH_GO_ASSET_TITLE, IMMICH_GO_ASSET_ID and IMMICH_GO_ACTION")
+	cmd.BoolFunc(
+		"hook-fatal",
+		"Stop the import when the -on-upload command fails (default FALSE)", myflag.BoolFlagFn(&app.HookFatal, false))
+
+	cmd.Var(&app.MimeOverrides,
+		"mime-override",
+		"Force the content type of the upload for an extension, like cr3=image/x-canon-cr3. Can be repeated")
 
 	cmd.Var(&app.BrowserConfig.SelectExtensions, "select-types", "list of selected extensions separated by a comma")
 	cmd.Var(&app.BrowserConfig.ExcludeExtensions, "exclude-types", "list of excluded extensions separated by a comma")
@@ -166,9 +750,181 @@ func NewUpCmd(ctx context.Context, ic iClient, log logger.Logger, args []string)
 		return nil, err
 	}
 
+	if app.MinSize > 0 && app.MaxSize > 0 && app.MinSize > app.MaxSize {
+		return nil, fmt.Errorf("the -min-size %s is bigger than the -max-size %s", app.MinSize, app.MaxSize)
+	}
+
+	if app.ForceReplace && app.RenameOnConflict {
+		return nil, errors.New("the -force-replace can't be combined with -rename-on-conflict")
+	}
+
+	if app.FavoriteMinRating < 0 || app.FavoriteMinRating > 5 {
+		return nil, fmt.Errorf("the -favorite-min-rating %d must be between 0 and 5", app.FavoriteMinRating)
+	}
+
+	if app.AlbumByDate != "" && app.CreateAlbumAfterFolder {
+		return nil, errors.New("the -album-by-date can't be combined with -create-album-folder")
+	}
+
+	if app.NestedAlbums {
+		switch {
+		case !app.CreateAlbumAfterFolder:
+			return nil, errors.New("the -nested-albums needs the -create-album-folder")
+		case app.AlbumNameTemplate != "" || app.AlbumFolderDepth != "":
+			return nil, errors.New("the -nested-albums can't be combined with -album-name-template or -album-folder-depth")
+		}
+	}
+
+	reportFormat, err := logger.ParseReportFormat(app.ReportFormat)
+	if err != nil {
+		return nil, err
+	}
+	if app.ReportFile != "" && reportFormat != logger.ReportCSV {
+		return nil, errors.New("the -report-file needs the -report-format csv")
+	}
+
+	if app.NoDateAlbum != "" && app.AlbumByDate == "" {
+		return nil, errors.New("the -no-date-album needs the -album-by-date layout")
+	}
+
+	if app.Since != "" || app.Until != "" {
+		if app.DateRange.IsSet() {
+			return nil, errors.New("the -date can't be combined with -since or -until")
+		}
+		app.DateRange, err = immich.NewOpenDateRange(app.Since, app.Until)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if app.DateFromFolderForce && app.DateFromFolder == "" {
+		return nil, errors.New("the -date-from-folder-force needs the -date-from-folder layout")
+	}
+
+	if app.ValidateTakeout && !app.GooglePhotos {
+		return nil, errors.New("the -validate-takeout needs the -google-photos")
+	}
+
+	if app.DryRunOutput != "" && !app.DryRun {
+		return nil, errors.New("the -dry-run-output needs the -dry-run")
+	}
+
+	if app.Import {
+		switch {
+		case len(app.PathMap) == 0:
+			return nil, errors.New("the -import needs the -path-map")
+		case app.TranscodeHEIC, app.ForceSidecar, app.Verify:
+			return nil, errors.New("the -import can't be combined with -transcode-heic-to-jpeg, -force-sidecar or -verify")
+		case app.Delete, app.MoveTo != "":
+			return nil, errors.New("the -import can't be combined with -delete or -move-to, the server reads the files in place")
+		}
+	}
+
+	if app.MoveTo != "" && app.Delete {
+		return nil, errors.New("the -move-to can't be combined with -delete")
+	}
+
+	if app.Quiet && app.Verbose {
+		return nil, errors.New("the -quiet can't be combined with -verbose")
+	}
+
+	if app.BurstWindow < 0 {
+		return nil, fmt.Errorf("the -burst-window %s can't be negative", app.BurstWindow)
+	}
+
+	if app.IndexByDate && app.DumpIndex != "" {
+		return nil, errors.New("the -index-by-date can't be combined with -dump-index")
+	}
+
+	if app.TrustServerDedup {
+		switch {
+		case app.IndexByDate, app.IndexCache != "", app.DumpIndex != "", app.Explain != "":
+			return nil, errors.New("the -trust-server-dedup can't be combined with -index-by-date, -index-cache, -dump-index or -explain")
+		case app.ForceReplace, app.RenameOnConflict:
+			return nil, errors.New("the -trust-server-dedup can't be combined with -force-replace or -rename-on-conflict")
+		}
+	}
+
+	if app.IndexByDate && app.IndexCache != "" {
+		return nil, errors.New("the -index-by-date can't be combined with -index-cache")
+	}
+
+	if app.ReconcileTrashed && (app.TrustServerDedup || app.IndexCache != "") {
+		return nil, errors.New("the -reconcile-trashed can't be combined with -trust-server-dedup or -index-cache")
+	}
+
+	if app.RestoreTrashed && !app.ReconcileTrashed {
+		return nil, errors.New("the -restore-trashed needs the -reconcile-trashed")
+	}
+
+	if app.SharedAlbumID != "" {
+		if app.ImportIntoAlbum != "" || app.CreateAlbumAfterFolder {
+			return nil, errors.New("the -shared-album-id can't be combined with -album or -create-album-folder")
+		}
+		app.CreateAlbums = false
+		app.sharedAlbum = map[string]any{}
+	}
+
 	app.Journal = logger.NewJournal(log)
 
-	app.fsys, err = fshelper.ParsePath(cmd.Args(), app.GooglePhotos)
+	// The dates of capture without offset are in the time zone given by the global -time-zone option
+	if app.AssumeUTC {
+		tzone.SetNaive(time.UTC)
+	}
+	if app.naiveZone, err = tzone.Naive(); err == nil {
+		log.OK("Dates of capture without offset are taken in the time zone %s", app.naiveZone)
+		app.DateRange.SetLocation(app.naiveZone)
+	}
+
+	if app.LocationsFile != "" {
+		app.locations, err = readLocations(app.LocationsFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if app.AlbumListFile != "" {
+		app.albumList, err = readAlbumList(app.AlbumListFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if app.AlbumMapFile != "" {
+		app.albumMap, err = readAlbumMap(app.AlbumMapFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if app.IfNewer != "" {
+		app.newerThan, err = readLastRun(app.IfNewer)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if app.OnlyFiles != "" {
+		app.onlyFiles, err = readFileList(app.OnlyFiles)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if pw := os.Getenv("IMMICH_ZIP_PASSWORD"); pw != "" {
+		err = app.ZipPasswords.Set(pw)
+		if err != nil {
+			return nil, fmt.Errorf("IMMICH_ZIP_PASSWORD: %w", err)
+		}
+	}
+	if app.ZipPwFile != "" {
+		err = app.ZipPasswords.ReadFile(app.ZipPwFile)
+		if err != nil {
+			return nil, fmt.Errorf("can't read the -zip-password-file: %w", err)
+		}
+	}
+
+	app.fsys, err = fshelper.ParsePath(cmd.Args(), app.GooglePhotos, app.ZipPasswords)
 	if err != nil {
 		return nil, err
 	}
@@ -178,20 +934,91 @@ func NewUpCmd(ctx context.Context, ic iClient, log logger.Logger, args []string)
 	}
 
 	if app.CreateStacks || app.StackBurst || app.StackJpgRaws {
-		app.stacks = stacking.NewStackBuilder()
+		app.stacks = stacking.NewStackBuilder().SetBurstWindow(app.BurstWindow)
 	}
-	log.OK("Ask for server's assets...")
-	var list []*immich.Asset
-	err = app.client.GetAllAssetsWithFilter(ctx, nil, func(a *immich.Asset) {
-		if a.IsTrashed {
-			return
+
+	app.Journal.SetStatsByType(app.StatsByType)
+	app.Journal.SetGroupByAsset(app.LogByAsset)
+	app.Journal.SetReportFormat(reportFormat, os.Stdout)
+	if app.OrphansOut != "" {
+		app.Journal.KeepFiles(logger.ORPHAN_SIDECAR)
+	}
+	switch {
+	case app.Quiet:
+		app.Journal.SetVerbosity(logger.Quiet)
+	case app.Verbose:
+		app.Journal.SetVerbosity(logger.Verbose)
+	}
+
+	if app.TranscodeHEIC {
+		app.heicConverter = findHEICConverter(exec.LookPath)
+		if app.heicConverter == nil {
+			app.Journal.Warning("No HEIC converter found, install heif-convert or ffmpeg. The HEIC files are uploaded unchanged")
 		}
-		list = append(list, a)
-	})
+	}
+
+	if app.ProgressThreshold > 0 {
+		p := &uploadProgress{log: app.Journal, threshold: int64(app.ProgressThreshold), interval: time.Second}
+		app.client.SetUploadProgress(p.update)
+	}
+
+	if app.ValidateTakeout {
+		// the server's assets aren't needed
+		return &app, nil
+	}
+
+	err = app.checkUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = app.checkServerVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if app.NestedAlbums {
+		// The Immich API has no nested albums yet
+		app.Journal.Warning("The server can't nest the albums, the albums are named after their folder only")
+	}
+
+	if app.Library != "" {
+		err = app.setLibrary(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if app.IndexByDate {
+		app.Journal.OK("The server's assets are requested by day of capture")
+		app.AssetIndex = newAssetIndexByDate(func(day time.Time) ([]*immich.Asset, error) {
+			var list []*immich.Asset
+			err := app.client.GetAssetsTakenBetween(ctx, day, day.AddDate(0, 0, 1), func(a *immich.Asset) {
+				if !a.IsTrashed || app.ReconcileTrashed {
+					list = append(list, a)
+				}
+			})
+			return list, err
+		})
+		return &app, nil
+	}
+
+	if app.TrustServerDedup {
+		app.Journal.OK("The server's assets aren't requested, the server detects the duplicates")
+		app.AssetIndex = &AssetIndex{}
+		app.AssetIndex.ReIndex()
+		return &app, nil
+	}
+
+	var list []*immich.Asset
+	if app.IndexCache != "" {
+		list, err = app.cachedServerAssets(ctx)
+	} else {
+		list, err = app.getServerAssets(ctx)
+	}
 	if err != nil {
 		return nil, err
 	}
-	log.OK("%d asset(s) received", len(list))
 
 	app.AssetIndex = &AssetIndex{
 		assets: list,
@@ -199,6 +1026,14 @@ func NewUpCmd(ctx context.Context, ic iClient, log logger.Logger, args []string)
 
 	app.AssetIndex.ReIndex()
 
+	if app.DumpIndex != "" {
+		err = app.AssetIndex.Dump(app.DumpIndex)
+		if err != nil {
+			return nil, err
+		}
+		app.Journal.OK("Index of the server's assets written into %s", app.DumpIndex)
+	}
+
 	return &app, err
 
 }
@@ -214,6 +1049,7 @@ func UploadCommand(ctx context.Context, ic iClient, log logger.Logger, args []st
 
 func (app *UpCmd) journalAsset(a *browser.LocalAssetFile, action logger.Action, comment ...string) {
 	app.Journal.AddEntry(a.FileName, action, comment...)
+	app.recordFailure(a, action)
 }
 
 func (app *UpCmd) Run(ctx context.Context, fsyss []fs.FS) error {
@@ -221,7 +1057,21 @@ func (app *UpCmd) Run(ctx context.Context, fsyss []fs.FS) error {
 	var browser browser.Browser
 	var err error
 
+	app.runStart = time.Now()
+
+	if app.MetricsAddr != "" {
+		app.metrics, err = startMetrics(app.MetricsAddr, app.Journal)
+		if err != nil {
+			return err
+		}
+		defer app.metrics.stop()
+		app.Journal.OK("Metrics exposed at http://%s/metrics", app.metrics.addr)
+	}
+
 	switch {
+	case app.ValidateTakeout:
+		app.Journal.Message(logger.OK, "Validating google take out archive...")
+		return app.validateTakeout(ctx, fsyss)
 	case app.GooglePhotos:
 		app.Journal.Message(logger.OK, "Browsing google take out archive...")
 		browser, err = app.ReadGoogleTakeOut(ctx, fsyss)
@@ -236,41 +1086,92 @@ func (app *UpCmd) Run(ctx context.Context, fsyss []fs.FS) error {
 	}
 	app.Journal.Message(logger.OK, "Done.")
 
-	assetChan := browser.Browse(ctx)
+	interrupted := false
+	quotaExceeded := false
+	var stopErr error // error stopping the upload, the albums of the uploaded assets are finalized before returning it
+
+	// The browsing stops with the upload when the quota is exceeded
+	browseCtx, cancelBrowse := context.WithCancel(ctx)
+	defer cancelBrowse()
+	assetChan := browser.Browse(browseCtx)
 assetLoop:
 	for {
 		select {
 		case <-ctx.Done():
-			return ctx.Err()
+			interrupted = true
+			break assetLoop
 
 		case a, ok := <-assetChan:
 			if !ok {
 				break assetLoop
 			}
+			if ctx.Err() != nil {
+				interrupted = true
+				break assetLoop
+			}
 			if a.Err != nil {
 				app.journalAsset(a, logger.ERROR, a.Err.Error())
+				if app.BrowserStrict {
+					return a.Err
+				}
 			} else {
+				name := a.FileName
+				app.Journal.BeginAsset(name)
 				err = app.handleAsset(ctx, a)
+				app.Journal.EndAsset(name)
+				if errors.Is(err, immich.ErrQuotaExceeded) {
+					app.Journal.Error("The storage quota of the server is exceeded, the remaining assets are not uploaded")
+					quotaExceeded = true
+					cancelBrowse()
+					break assetLoop
+				}
 				if err != nil {
 					app.journalAsset(a, logger.ERROR, err.Error())
+					if errors.Is(err, fshelper.ErrInvalidContent) || errors.Is(err, errHookFailed) || errors.Is(err, immich.ErrImportNotSupported) {
+						stopErr = fmt.Errorf("%s: %w", a.FileName, err)
+						cancelBrowse()
+						break assetLoop
+					}
 				}
 			}
 		}
 	}
 
-	if app.CreateStacks {
+	runCtx := ctx
+	if interrupted {
+		// Finalize the albums of already uploaded assets, even if the context is cancelled
+		app.Journal.Warning("Interrupted: the albums are updated with the assets already uploaded")
+		ctx = context.WithoutCancel(ctx)
+	}
+
+	if len(app.bulkUpdates) > 0 {
+		// before the stacks, that take the values of their cover
+		app.Journal.OK("Updating the metadata of the uploaded assets")
+		err = app.UpdateMetadataByBatch(ctx)
+		if err != nil {
+			app.Journal.Error(err.Error())
+			err = nil
+		}
+	}
+
+	if app.CreateStacks && !interrupted {
 		stacks := app.stacks.Stacks()
-		if len(stacks) > 0 {
+		if len(stacks) > 0 && !app.serverVersion.IsZero() && !app.serverVersion.CanStack() {
+			app.Journal.Warning("The server %s can't stack assets, %d stack(s) not created", app.serverVersion, len(stacks))
+		} else if len(stacks) > 0 {
 			app.Journal.OK("Creating stacks")
-		nextStack:
-			for _, s := range stacks {
+			stacks = gen.Filter(stacks, func(s stacking.Stack) bool {
 				switch {
 				case !app.StackBurst && s.StackType == stacking.StackBurst:
-					continue nextStack
+					return false
 				case !app.StackJpgRaws && s.StackType == stacking.StackRawJpg:
-					continue nextStack
+					return false
 				}
-				app.Journal.OK("  Stacking %s...", strings.Join(s.Names, ", "))
+				return true
+			})
+			progress := newPhaseProgress(len(stacks))
+			for _, s := range stacks {
+				app.Journal.OK("  Creating stack %s: %s...", progress.next(), strings.Join(s.Names, ", "))
 				if !app.DryRun {
 					err = app.client.StackAssets(ctx, s.CoverID, s.IDs)
 					if err != nil {
@@ -281,7 +1182,7 @@ assetLoop:
 		}
 	}
 
-	if app.CreateAlbums || app.CreateAlbumAfterFolder || (app.KeepPartner && len(app.PartnerAlbum) > 0) || len(app.ImportIntoAlbum) > 0 {
+	if app.CreateAlbums || app.CreateAlbumAfterFolder || app.AlbumByDate != "" || (app.KeepPartner && len(app.PartnerAlbum) > 0) || len(app.ImportIntoAlbum) > 0 {
 		app.Journal.OK("Managing albums")
 		err = app.ManageAlbums(ctx)
 		if err != nil {
@@ -290,7 +1191,43 @@ assetLoop:
 		}
 	}
 
-	if len(app.deleteServerList) > 0 {
+	if len(app.archiveList) > 0 {
+		app.Journal.OK("Archiving the uploaded assets")
+		err = app.ArchiveUploaded(ctx)
+		if err != nil {
+			app.Journal.Error(err.Error())
+			err = nil
+		}
+	}
+
+	if len(app.updateTags) > 0 {
+		app.Journal.OK("Managing tags")
+		err = app.ManageTags(ctx)
+		if err != nil {
+			app.Journal.Error(err.Error())
+			err = nil
+		}
+	}
+
+	if app.SharedAlbumID != "" {
+		app.Journal.OK("Managing the shared album")
+		err = app.ManageSharedAlbum(ctx)
+		if err != nil {
+			app.Journal.Error(err.Error())
+			err = nil
+		}
+	}
+
+	if app.ImportPeople && !interrupted {
+		app.Journal.OK("Managing people")
+		err = app.ManagePeople(ctx)
+		if err != nil {
+			app.Journal.Error(err.Error())
+			err = nil
+		}
+	}
+
+	if len(app.deleteServerList) > 0 && !interrupted {
 		ids := []string{}
 		for _, da := range app.deleteServerList {
 			ids = append(ids, da.ID)
@@ -301,26 +1238,119 @@ assetLoop:
 		}
 	}
 
-	if len(app.deleteLocalList) > 0 {
+	if len(app.deleteLocalList) > 0 && !interrupted && app.confirmLocalDelete(ctx) {
 		err = app.DeleteLocalAssets()
 	}
 
-	app.Journal.Report()
+	// the files uploaded before an interruption are moved, the others are left for the next run
+	if len(app.moveLocalList) > 0 {
+		app.MoveLocalAssets()
+	}
+
+	app.report()
+	app.albumResults.report(app.Journal)
+	app.reportLocalCopies()
+	if app.DryRun {
+		if perr := app.reportPlan(); perr != nil {
+			app.Journal.Error(perr.Error())
+		}
+	}
+
+	if err := app.reportDuplicates(); err != nil {
+		app.Journal.Error(err.Error())
+	}
+	if err := app.writeOrphans(); err != nil {
+		app.Journal.Error(err.Error())
+	}
+
+	if app.throttled > 0 {
+		app.Journal.Summary("%6d uploads retried because the server refused too many requests", app.throttled)
+	}
+
+	if app.invalidFiles > 0 {
+		app.Journal.Summary("%6d discarded files because their content is invalid", app.invalidFiles)
+	}
+
+	if app.ReportNoDate {
+		app.Journal.Summary("%d uploaded asset(s) without date of capture", len(app.noDateAssets))
+		for _, f := range app.noDateAssets {
+			app.Journal.Summary("  %s", f)
+		}
+	}
+
+	if app.FailuresOut != "" {
+		if werr := writeFailures(app.FailuresOut, app.failureList); werr != nil {
+			app.Journal.Error(werr.Error())
+		} else if len(app.failureList) > 0 {
+			app.Journal.Summary("%d failed asset(s) listed into %s", len(app.failureList), app.FailuresOut)
+		}
+	}
 
+	if interrupted {
+		return runCtx.Err()
+	}
+	if quotaExceeded {
+		return immich.ErrQuotaExceeded
+	}
+	if stopErr != nil {
+		return stopErr
+	}
+	if err == nil && app.AlbumFailStrict {
+		if n := app.albumResults.failures(); n > 0 {
+			err = fmt.Errorf("%d asset(s) couldn't be added to their albums", n)
+		}
+	}
+
+	if err == nil && app.IfNewer != "" && !app.DryRun {
+		if app.Journal.Count(logger.ERROR)+app.Journal.Count(logger.SERVER_ERROR) > 0 {
+			app.Journal.Warning("The run had errors, %s is not updated", app.IfNewer)
+		} else {
+			err = writeLastRun(app.IfNewer, app.runStart)
+		}
+	}
 	return err
 }
 
+// isEmptyFile tells if the file has no content, without reading it.
+// The size of the asset is 0 when it is unknown, the file system gives it.
+func isEmptyFile(a *browser.LocalAssetFile) bool {
+	if a.Size() > 0 {
+		return false
+	}
+	fi, err := fs.Stat(a.FSys, a.FileName)
+	return err == nil && fi.Size() == 0
+}
+
 func (app *UpCmd) handleAsset(ctx context.Context, a *browser.LocalAssetFile) error {
 	defer func() {
 		a.Close()
 	}()
 	app.mediaCount++
+	app.Journal.RecordAsset(a.FileName, "", a.Size())
 
-	// ext := path.Ext(a.FileName)
-	// if _, err := fshelper.MimeFromExt(ext); err != nil {
-	// 	app.journalAsset(a, logger.NOT_SELECTED, "not recognized extension")
-	// 	return nil
-	// }
+	ext := path.Ext(a.FileName)
+	if !app.BrowserConfig.SelectExtensions.Include(ext) {
+		app.journalAsset(a, logger.NOT_SELECTED, "asset excluded because its extension isn't selected")
+		return nil
+	}
+	if app.BrowserConfig.ExcludeExtensions.Exclude(ext) {
+		app.journalAsset(a, logger.NOT_SELECTED, "asset excluded because its extension is excluded")
+		return nil
+	}
+	if app.Only != "" && !app.matchMediaType(a) {
+		app.journalAsset(a, logger.NOT_SELECTED, "asset excluded because it isn't a "+app.Only)
+		return nil
+	}
+
+	if app.SkipEmpty && isEmptyFile(a) {
+		app.journalAsset(a, logger.NOT_SELECTED, "asset excluded because it is an empty file")
+		return nil
+	}
+
+	if app.onlyFiles != nil && !app.onlyFiles[a.FileName] {
+		app.journalAsset(a, logger.NOT_SELECTED, "asset excluded because not in the -only-files list")
+		return nil
+	}
 
 	if !app.KeepPartner && a.FromPartner {
 		app.journalAsset(a, logger.NOT_SELECTED, "partners asset excluded")
@@ -337,11 +1367,57 @@ func (app *UpCmd) handleAsset(ctx context.Context, a *browser.LocalAssetFile) er
 		return nil
 	}
 
+	if len(app.ExcludeAlbums) > 0 && len(a.Albums) > 0 {
+		a.Albums = gen.Filter(a.Albums, func(al browser.LocalAlbum) bool {
+			return !slices.Contains(app.ExcludeAlbums, app.albumName(al))
+		})
+		if len(a.Albums) == 0 {
+			app.journalAsset(a, logger.NOT_SELECTED, "asset excluded because only in excluded albums")
+			return nil
+		}
+	}
+
+	if app.albumList != nil && !app.filterAlbumList(a) {
+		app.journalAsset(a, logger.NOT_SELECTED, "asset excluded by the album list")
+		return nil
+	}
+
 	if app.DiscardArchived && a.Archived {
 		app.journalAsset(a, logger.NOT_SELECTED, "asset excluded because archives are discarded")
 		return nil
 	}
 
+	if strings.ToLower(ext) == ".jxl" && !app.serverVersion.IsZero() && !app.serverVersion.CanJXL() {
+		if !app.jxlWarned {
+			app.Journal.Warning("The server %s can't process the JPEG XL files, they are not uploaded", app.serverVersion)
+			app.jxlWarned = true
+		}
+		app.journalAsset(a, logger.NOT_SELECTED, "asset excluded because the server can't process JPEG XL files")
+		return nil
+	}
+
+	if app.DateFromFilename && a.DateTaken.IsZero() {
+		a.DateTaken = metadata.TakeTimeFromName(path.Base(a.Title))
+		if a.DateTaken.IsZero() {
+			a.DateTaken = metadata.TakeTimeFromName(path.Base(a.FileName))
+		}
+		if !a.DateTaken.IsZero() {
+			app.journalAsset(a, logger.INFO, "date of capture taken from the file name")
+		}
+	}
+
+	if app.DateFromFolder != "" && (a.DateTaken.IsZero() || app.DateFromFolderForce) {
+		if d := app.folderDate(a.FileName); !d.IsZero() {
+			// the server takes the date of capture from the file, it's replaced after the upload
+			a.DateTaken, a.ForceDate = d, true
+			app.journalAsset(a, logger.INFO, "date of capture taken from the folder name")
+		}
+	}
+
+	if app.naiveZone != nil && !app.GooglePhotos && !a.DateTaken.IsZero() {
+		app.journalAsset(a, logger.INFO, "date of capture "+a.DateTaken.In(app.naiveZone).Format(time.DateTime)+" "+app.naiveZone.String())
+	}
+
 	if app.DateRange.IsSet() {
 		d := a.DateTaken
 		if d.IsZero() {
@@ -354,67 +1430,195 @@ func (app *UpCmd) handleAsset(ctx context.Context, a *browser.LocalAssetFile) er
 		}
 	}
 
+	if app.MinSize > 0 && a.Size() < int64(app.MinSize) {
+		app.journalAsset(a, logger.NOT_SELECTED, "asset excluded because its size is below "+app.MinSize.String())
+		return nil
+	}
+	if app.MaxSize > 0 && a.Size() > int64(app.MaxSize) {
+		app.journalAsset(a, logger.NOT_SELECTED, "asset excluded because its size is above "+app.MaxSize.String())
+		return nil
+	}
+
+	if app.Orientation != "" && !app.matchOrientation(a) {
+		app.journalAsset(a, logger.NOT_SELECTED, "asset excluded because its orientation isn't "+app.Orientation)
+		return nil
+	}
+
+	if a.Latitude == 0 && a.Longitude == 0 {
+		if lat, long, ok := app.locationFor(a); ok && !app.hasEmbeddedGPS(a) {
+			a.Latitude, a.Longitude = lat, long
+			app.journalAsset(a, logger.INFO, fmt.Sprintf("GPS coordinates set to %v,%v", lat, long))
+		}
+	}
+
+	if app.FavoriteMinRating > 0 && a.Rating >= app.FavoriteMinRating && !a.Favorite {
+		a.Favorite = true
+		app.journalAsset(a, logger.INFO, fmt.Sprintf("favorite because rated %d stars", a.Rating))
+	}
+
+	if app.SkipSharedAlbums || (a.FromPartner && app.PartnerAlbum != "") {
+		// The partner album takes precedence over the shared albums for partner's assets
+		a.Albums = gen.Filter(a.Albums, func(i browser.LocalAlbum) bool {
+			return !i.Shared
+		})
+	}
+
 	if !app.KeepUntitled {
 		a.Albums = gen.Filter(a.Albums, func(i browser.LocalAlbum) bool {
 			return i.Name != ""
 		})
 	}
 
-	app.Journal.DebugObject("handleAsset: LocalAssetFile=", a)
+	for _, al := range a.Albums {
+		if al.Description != "" {
+			if app.albumDescription == nil {
+				app.albumDescription = map[string]string{}
+			}
+			app.albumDescription[app.albumName(al)] = al.Description
+		}
+	}
+
+	app.Journal.DebugObject("handleAsset: LocalAssetFile=", a)
+
+	tags := slices.Clone(app.Tags)
+	if app.ImportKeywords {
+		for _, t := range a.Tags {
+			if !slices.Contains(tags, t) {
+				tags = append(tags, t)
+			}
+		}
+	}
+	if app.GooglePhotos && app.AlbumsAsTags {
+		for _, al := range a.Albums {
+			tags = append(tags, app.albumName(al))
+		}
+		a.Albums = nil
+	}
+
+	var err error
+	advice := &Advice{Advice: NotOnServer, Message: "The server detects the duplicates"}
+	copyKey := app.localCopyKey(a)
+	if local := app.localCopyAdvice(a, copyKey); local != nil {
+		advice = local
+	} else if !app.TrustServerDedup {
+		stopExplain := app.startExplain(a)
+		advice, err = app.AssetIndex.ShouldUpload(a)
+		stopExplain()
+		if err != nil {
+			return err
+		}
+	}
+	if advice.ServerAsset != nil && advice.ServerAsset.IsTrashed {
+		advice = adviceTrashedOnServer(advice.ServerAsset)
+	}
+	if app.ForceReplace {
+		advice = app.forceReplace(a, advice)
+	}
+	if app.RenameOnConflict {
+		advice, err = app.renameOnConflict(a, advice)
+		if err != nil {
+			return err
+		}
+	}
+
+	switch advice.Advice {
+	case NotOnServer, SmallerOnServer, ReplaceOnServer:
+		// only the files to be uploaded are read
+		if err := app.validateContent(a); err != nil {
+			if !app.SkipInvalid || !errors.Is(err, fshelper.ErrInvalidContent) {
+				return err
+			}
+			app.invalidFiles++
+			app.journalAsset(a, logger.NOT_SELECTED, "asset excluded because its content is invalid: "+err.Error())
+			return nil
+		}
+	}
 
-	advice, err := app.AssetIndex.ShouldUpload(a)
-	if err != nil {
-		return err
+	if (advice.Advice == SmallerOnServer || advice.Advice == ReplaceOnServer) && advice.ServerAsset.IsFavorite {
+		// the new asset stays a favorite
+		a.Favorite = true
 	}
 
 	var ID string
 	switch advice.Advice {
+	case TrashedOnServer:
+		ID, err = app.handleTrashed(ctx, a, advice)
+		if err == nil && ID == "" {
+			return nil
+		}
+	case LocalCopy:
+		// the file is handled already, only its albums and tags are given to the asset
+		app.journalAsset(a, logger.LOCAL_DUPLICATE, advice.Message)
+		ID = advice.ServerAsset.ID
 	case NotOnServer:
 		ID, err = app.UploadAsset(ctx, a)
-		if app.Delete && err == nil {
-			app.deleteLocalList = append(app.deleteLocalList, a)
+		if err == nil {
+			app.queueLocalDelete(a, ID)
+			app.queueLocalMove(a, ID)
 		}
 	case SmallerOnServer:
 		app.journalAsset(a, logger.UPGRADED, advice.Message)
 		// add the superior asset into albums of the original asset
 		for _, al := range advice.ServerAsset.Albums {
-			app.journalAsset(a, logger.INFO, "Added to album: "+al.AlbumName)
+			app.journalAsset(a, logger.ALBUM, al.AlbumName)
 			a.AddAlbum(browser.LocalAlbum{Name: al.AlbumName})
 		}
 		ID, err = app.UploadAsset(ctx, a)
 
-		if err != nil {
+		if err == nil {
 			app.deleteServerList = append(app.deleteServerList, advice.ServerAsset)
-			if app.Delete {
-				app.deleteLocalList = append(app.deleteLocalList, a)
+			app.queueLocalDelete(a, ID)
+			app.queueLocalMove(a, ID)
+		}
+	case ReplaceOnServer:
+		var albums []immich.AlbumSimplified
+		albums, err = app.client.GetAssetAlbums(ctx, advice.ServerAsset.ID)
+		if err != nil {
+			app.journalAsset(a, logger.SERVER_ERROR, "can't get the albums of the server's asset: "+err.Error())
+			break
+		}
+		ID, err = app.UploadAsset(ctx, a)
+		if err == nil {
+			app.journalAsset(a, logger.UPGRADED, advice.Message)
+			// the new asset takes the place of the server's one in its albums,
+			// the album is given by its ID, the name may be shared by several albums
+			for _, al := range albums {
+				app.journalAsset(a, logger.ALBUM, al.AlbumName)
+				if !app.DryRun {
+					if _, aerr := app.client.AddAssetToAlbum(ctx, al.ID, []string{ID}); aerr != nil {
+						app.journalAsset(a, logger.SERVER_ERROR, "can't add the asset to the album "+al.AlbumName+": "+aerr.Error())
+					}
+				}
 			}
+			app.deleteServerList = append(app.deleteServerList, advice.ServerAsset)
+			app.queueLocalDelete(a, ID)
+			app.queueLocalMove(a, ID)
 		}
 	case SameOnServer:
 		// Set add the server asset into albums determined locally
 		if !advice.ServerAsset.JustUploaded {
 			app.journalAsset(a, logger.SERVER_DUPLICATE, advice.Message)
+			app.recordDuplicate(a, advice.ServerAsset.ID, advice.ServerAsset.OriginalFileName+path.Ext(advice.ServerAsset.OriginalPath), advice.Message)
 		} else {
 			app.journalAsset(a, logger.LOCAL_DUPLICATE)
 		}
 		ID = advice.ServerAsset.ID
 		if app.CreateAlbums {
 			for _, al := range a.Albums {
-				app.journalAsset(a, logger.INFO, "Added to album: "+al.Name)
+				app.journalAsset(a, logger.ALBUM, al.Name)
 				app.AddToAlbum(advice.ServerAsset.ID, app.albumName(al))
 			}
 		}
 		if app.ImportIntoAlbum != "" {
-			app.journalAsset(a, logger.INFO, "Added to album: "+app.ImportIntoAlbum)
+			app.journalAsset(a, logger.ALBUM, app.ImportIntoAlbum)
 			app.AddToAlbum(advice.ServerAsset.ID, app.ImportIntoAlbum)
 		}
 		if app.PartnerAlbum != "" && a.FromPartner {
-			app.journalAsset(a, logger.INFO, "Added to album: "+app.PartnerAlbum)
+			app.journalAsset(a, logger.ALBUM, app.PartnerAlbum)
 			app.AddToAlbum(advice.ServerAsset.ID, app.PartnerAlbum)
 		}
 		if !advice.ServerAsset.JustUploaded {
-			if app.Delete {
-				app.deleteLocalList = append(app.deleteLocalList, a)
-			}
+			app.queueExistingLocalDelete(a, advice.ServerAsset)
 		} else {
 			return nil
 		}
@@ -424,19 +1628,55 @@ func (app *UpCmd) handleAsset(ctx context.Context, a *browser.LocalAssetFile) er
 		// keep the server version but update albums
 		if app.CreateAlbums {
 			for _, al := range a.Albums {
-				app.journalAsset(a, logger.INFO, "Added to album: "+al.Name)
+				app.journalAsset(a, logger.ALBUM, al.Name)
 				app.AddToAlbum(advice.ServerAsset.ID, app.albumName(al))
 			}
 		}
 		if app.PartnerAlbum != "" && a.FromPartner {
-			app.journalAsset(a, logger.INFO, "Added to album: "+app.PartnerAlbum)
+			app.journalAsset(a, logger.ALBUM, app.PartnerAlbum)
 			app.AddToAlbum(advice.ServerAsset.ID, app.PartnerAlbum)
 		}
 	}
 
 	if err != nil {
+		// The error is already journaled, the exceeded quota stops the run
+		if errors.Is(err, immich.ErrQuotaExceeded) && !app.ContinueOnQuota {
+			return err
+		}
+		if errors.Is(err, immich.ErrImportNotSupported) {
+			return err
+		}
 		return nil
 	}
+	app.recordLocalCopy(a, copyKey, ID)
+	app.Journal.RecordAsset(a.FileName, ID, 0)
+	if app.DryRun {
+		app.plan.record(advice, a)
+	}
+
+	if app.OnUpload != "" && !app.DryRun && app.createdAssets[ID] {
+		switch advice.Advice {
+		case NotOnServer:
+			err = app.runUploadHook(ctx, a, ID, "UPLOADED")
+		case SmallerOnServer, ReplaceOnServer:
+			err = app.runUploadHook(ctx, a, ID, "UPGRADED")
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(tags) > 0 {
+		app.journalAsset(a, logger.TAGGED, strings.Join(tags, ", "))
+		for _, t := range tags {
+			app.AddToTag(ID, t)
+		}
+	}
+
+	if app.SharedAlbumID != "" {
+		app.journalAsset(a, logger.ALBUM, "shared album "+app.SharedAlbumID)
+		app.sharedAlbum[ID] = nil
+	}
 
 	if app.ImportIntoAlbum != "" ||
 		(app.GooglePhotos && (app.CreateAlbums || app.PartnerAlbum != "")) ||
@@ -453,7 +1693,14 @@ func (app *UpCmd) handleAsset(ctx context.Context, a *browser.LocalAssetFile) er
 					albums = append(albums, browser.LocalAlbum{Path: app.PartnerAlbum, Name: app.PartnerAlbum})
 				}
 			case !app.GooglePhotos && app.CreateAlbumAfterFolder:
-				album := path.Base(path.Dir(a.FileName))
+				album := app.folderAlbum(path.Dir(a.FileName))
+				if app.albumTemplate != nil {
+					album, err = folderAlbumName(app.albumTemplate, a, album)
+					if err != nil {
+						app.journalAsset(a, logger.ERROR, err.Error())
+						album = ""
+					}
+				}
 				if album != "" && album != "." {
 					albums = append(albums, browser.LocalAlbum{Path: album, Name: album})
 				}
@@ -470,9 +1717,12 @@ func (app *UpCmd) handleAsset(ctx context.Context, a *browser.LocalAssetFile) er
 					continue
 				}
 				Names = append(Names, Name)
+				if app.AlbumThumbnailFromMeta {
+					app.recordAlbumCover(a, ID, app.mapAlbum(Name), al.Cover)
+				}
 			}
 			if len(Names) > 0 {
-				app.journalAsset(a, logger.ALBUM, strings.Join(Names, ", "))
+				app.journalAsset(a, logger.ALBUM, Names...)
 				for _, n := range Names {
 					app.AddToAlbum(ID, n)
 				}
@@ -480,23 +1730,35 @@ func (app *UpCmd) handleAsset(ctx context.Context, a *browser.LocalAssetFile) er
 		}
 	}
 
-	shouldUpdate := len(a.Description) > 0
-	shouldUpdate = shouldUpdate || a.Favorite
-	shouldUpdate = shouldUpdate || a.Longitude != 0 || a.Latitude != 0
-	shouldUpdate = shouldUpdate || !a.DateTaken.IsZero()
-	shouldUpdate = shouldUpdate || a.Archived
-
-	if !app.DryRun && shouldUpdate {
-		_, err := app.client.UpdateAsset(ctx, ID, a)
-		if err != nil {
-			app.Journal.Error("can't update the asset '%s': ", err)
+	if app.AlbumByDate != "" {
+		if album := app.dateAlbum(a); album != "" {
+			app.journalAsset(a, logger.ALBUM, album)
+			app.AddToAlbum(ID, album)
 		}
 	}
 
+	if advice.Advice == LocalCopy {
+		return nil
+	}
+	if advice.Advice == SameOnServer || advice.Advice == BetterOnServer || advice.Advice == TrashedOnServer {
+		app.updateExistingMetadata(ctx, a, advice.ServerAsset)
+		return nil
+	}
+
+	app.updateMetadata(ctx, a, ID)
 	return nil
 
 }
 
+// validateContent checks the beginning of the file. Bytes read are kept for the upload.
+func (app *UpCmd) validateContent(a *browser.LocalAssetFile) error {
+	r, err := a.PartialSourceReader()
+	if err != nil {
+		return err
+	}
+	return fshelper.ValidateContent(path.Ext(a.FileName), r)
+}
+
 func (app *UpCmd) isInAlbum(a *browser.LocalAssetFile, album string) bool {
 	for _, al := range a.Albums {
 		if app.albumName(al) == album {
@@ -508,11 +1770,140 @@ func (app *UpCmd) isInAlbum(a *browser.LocalAssetFile, album string) bool {
 
 func (a *UpCmd) ReadGoogleTakeOut(ctx context.Context, fsyss []fs.FS) (browser.Browser, error) {
 	a.Delete = false
-	return gp.NewTakeout(ctx, a.Journal, fsyss...)
+	to, err := gp.NewTakeout(ctx, a.Journal, fsyss...)
+	if err != nil {
+		return nil, err
+	}
+	if a.KeepEdited != "" {
+		to.KeepEdited = a.KeepEdited
+	}
+	to.AlbumYearSuffix = a.AlbumYearSuffix
+	to.Strict = a.BrowserStrict
+	return to, nil
 }
 
 func (a *UpCmd) ExploreLocalFolder(ctx context.Context, fsyss []fs.FS) (browser.Browser, error) {
-	return files.NewLocalFiles(ctx, a.Journal, fsyss...)
+	b, err := files.NewLocalFiles(ctx, a.Journal, fsyss...)
+	if err != nil {
+		return nil, err
+	}
+	b.NewerThan = a.newerThan
+	b.LivePhotos = a.LivePhotos
+	b.StartFrom = a.StartFrom
+	b.Keywords = a.ImportKeywords
+	b.Strict = a.BrowserStrict
+	if a.SidecarFormat != "" {
+		b.SidecarFormat = a.SidecarFormat
+	}
+	return b, nil
+}
+
+// fetchConcurrency is the number of pages of the server's assets requested at the same time with -fetch-page-size
+const fetchConcurrency = 4
+
+// getServerAssets gets the list of all the server's assets, trashed assets excluded
+func (app *UpCmd) getServerAssets(ctx context.Context) ([]*immich.Asset, error) {
+	app.Journal.OK("Ask for server's assets...")
+	var list []*immich.Asset
+	trashed := false
+	opt := &immich.GetAssetOptions{
+		IsTrashed:   &trashed,
+		PageSize:    app.FetchPageSize,
+		Concurrency: fetchConcurrency,
+	}
+	if app.ReconcileTrashed {
+		opt.IsTrashed = nil
+	}
+	err := app.client.GetAllAssetsWithFilter(ctx, opt, func(a *immich.Asset) {
+		list = append(list, a)
+	})
+	if err != nil {
+		return nil, err
+	}
+	app.Journal.OK("%d asset(s) received", len(list))
+	return list, nil
+}
+
+// connectionValidator is implemented by the clients able to tell who owns the API key
+type connectionValidator interface {
+	ValidateConnection(ctx context.Context) (immich.User, error)
+}
+
+// checkUser tells who is authenticated by the API key, and fails before the upload
+// when the server refuses the key or when the user's storage quota is full.
+// The permissions of the key aren't given by the server, only the quota is checked.
+func (app *UpCmd) checkUser(ctx context.Context) error {
+	cv, ok := app.client.(connectionValidator)
+	if !ok {
+		return nil
+	}
+	user, err := cv.ValidateConnection(ctx)
+	if err != nil {
+		return err
+	}
+	app.Journal.OK("Authenticated as %s", user.Email)
+	if user.QuotaSizeInBytes == nil {
+		return nil
+	}
+	if user.QuotaUsageInBytes < *user.QuotaSizeInBytes {
+		app.Journal.OK("Storage quota: %s used of %s", formatBytes(int(user.QuotaUsageInBytes)), formatBytes(int(*user.QuotaSizeInBytes)))
+		return nil
+	}
+	err = fmt.Errorf("the storage quota of %s is full (%s used), the key can't upload", user.Email, formatBytes(int(user.QuotaUsageInBytes)))
+	if app.DryRun {
+		app.Journal.Warning("%s", err)
+		return nil
+	}
+	return err
+}
+
+// checkServerVersion warns when the server version is out of the supported range,
+// or fails when -strict-version is given
+func (app *UpCmd) checkServerVersion(ctx context.Context) error {
+	v, err := app.client.GetServerVersion(ctx)
+	if err != nil {
+		if app.StrictVersion {
+			return fmt.Errorf("can't get the server version: %w", err)
+		}
+		app.Journal.Warning("Can't get the server version: %s", err)
+		return nil
+	}
+	app.serverVersion = v
+	app.Journal.OK("Server version: %s", v)
+	if v.IsSupported() {
+		return nil
+	}
+	err = fmt.Errorf("the server version %s is out of the range supported by immich-go: %s to v%d.%d.x", v, immich.MinServerVersion, immich.MaxServerVersion.Major, immich.MaxServerVersion.Minor)
+	if app.StrictVersion {
+		return err
+	}
+	app.Journal.Warning("%s", err)
+	return nil
+}
+
+// readLastRun reads the time of the last successful run. A missing file gives a zero time.
+func readLastRun(name string) (time.Time, error) {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, fmt.Errorf("can't read the -if-newer file: %w", err)
+	}
+	t, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(string(b)))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("can't read the -if-newer file: %w", err)
+	}
+	return t, nil
+}
+
+// writeLastRun records the time of the run
+func writeLastRun(name string, t time.Time) error {
+	err := os.WriteFile(name, []byte(t.Format(time.RFC3339Nano)+"\n"), 0o644)
+	if err != nil {
+		return fmt.Errorf("can't write the -if-newer file: %w", err)
+	}
+	return nil
 }
 
 // UploadAsset upload the asset on the server
@@ -523,40 +1914,163 @@ func (app *UpCmd) UploadAsset(ctx context.Context, a *browser.LocalAssetFile) (s
 	var resp immich.AssetResponse
 	var err error
 	if !app.DryRun {
+		if mtype, ok := app.MimeOverrides[strings.ToLower(path.Ext(a.FileName))]; ok {
+			a.MimeType = mtype
+		}
+
+		if app.ForceSidecar && a.SideCar != nil && a.SideCar.OnFSsys {
+			// Keep the original sidecar, only the date and GPS are updated
+			a.SideCar.DateTaken = a.DateTaken
+			a.SideCar.Latitude = a.Latitude
+			a.SideCar.Longitude = a.Longitude
+			a.SideCar.Elevation = a.Altitude
+			a.SideCar.Merge = true
+		} else if app.ForceSidecar {
+			a.SideCar = dateGPSSidecar(a)
+		}
 
-		if app.ForceSidecar {
-			sc := metadata.SideCar{}
-			sc.DateTaken = a.DateTaken
-			sc.Latitude = a.Latitude
-			sc.Longitude = a.Longitude
-			sc.Elevation = a.Altitude
-			sc.FileName = a.FileName + ".xmp"
-			a.SideCar = &sc
+		if app.heicConverter != nil && isHEIC(a.FileName) {
+			restore, err := app.transcodeHEIC(ctx, a)
+			if err != nil {
+				app.Journal.Warning("%s: can't convert the HEIC file into JPEG, the HEIC file is uploaded: %s", a.FileName, err)
+			} else {
+				app.journalAsset(a, logger.INFO, "HEIC file converted into JPEG")
+				defer restore()
+			}
 		}
 
-		resp, err = app.client.AssetUpload(ctx, a)
+		upload := app.client.AssetUpload
+		if app.Import {
+			upload = app.importAsset
+		}
+		app.metrics.uploadStarted()
+		resp, err = upload(ctx, a)
+		for retry := 1; err != nil; retry++ {
+			delay, throttled := immich.RetryAfter(err)
+			if throttled && retry <= max(app.UploadRetries, minThrottleRetries) {
+				if werr := app.throttle(ctx, delay, retry); werr != nil {
+					err = werr
+					break
+				}
+			} else if errors.Is(err, context.DeadlineExceeded) && retry <= app.UploadRetries {
+				app.Journal.Warning("%s: upload timeout, retry %d/%d", a.FileName, retry, app.UploadRetries)
+			} else {
+				break
+			}
+			a.Close()
+			resp, err = upload(ctx, a)
+		}
+		if err == nil && app.Verify && !resp.Duplicate {
+			resp, err = app.verifyUpload(ctx, a, resp)
+		}
+		uploaded := int64(0)
+		if err == nil && !resp.Duplicate && !app.Import {
+			uploaded = a.Size()
+		}
+		app.metrics.uploadDone(uploaded)
 	} else {
 		resp.ID = uuid.NewString()
 	}
 	if err != nil {
-		app.journalAsset(a, logger.SERVER_ERROR, err.Error())
+		if errors.Is(err, context.DeadlineExceeded) {
+			app.journalAsset(a, logger.SERVER_ERROR, "upload timeout, the file can be uploaded again: "+err.Error())
+		} else {
+			app.journalAsset(a, logger.SERVER_ERROR, err.Error())
+		}
 		return "", err
 	}
 	if !resp.Duplicate {
 		app.journalAsset(a, logger.UPLOADED, a.Title)
-		app.AssetIndex.AddLocalAsset(a, resp.ID)
+		sa := app.AssetIndex.AddLocalAsset(a, resp.ID)
+		if app.RenameOnConflict {
+			// the next files with the same name are compared to this one
+			sa.Checksum, _ = a.Checksum()
+		}
+		if app.createdAssets == nil {
+			app.createdAssets = map[string]bool{}
+		}
+		app.createdAssets[resp.ID] = true
+		app.AddToPeople(resp.ID, a)
+		if a.DateTaken.IsZero() {
+			app.noDateAssets = append(app.noDateAssets, a.FileName)
+		}
 		app.mediaUploaded += 1
-		if app.CreateStacks {
+		if app.ArchiveAll {
+			app.archiveList = append(app.archiveList, resp.ID)
+		}
+		if app.CreateStacks && a.LivePhotoData == "" {
+			// the video of a live photo is linked by the server, not stacked
 			app.stacks.ProcessAsset(resp.ID, a.FileName, a.DateTaken)
 		}
 
 	} else {
 		app.journalAsset(a, logger.SERVER_DUPLICATE, "already on the server")
+		app.recordDuplicate(a, resp.ID, "", "the server has a file with the same checksum")
 	}
 
 	return resp.ID, nil
 }
 
+// dateGPSSidecar generates a sidecar giving the date of capture and the GPS location of the asset
+func dateGPSSidecar(a *browser.LocalAssetFile) *metadata.SideCar {
+	sc := metadata.SideCar{}
+	sc.DateTaken = a.DateTaken
+	sc.Latitude = a.Latitude
+	sc.Longitude = a.Longitude
+	sc.Elevation = a.Altitude
+	sc.FileName = a.FileName + ".xmp"
+	return &sc
+}
+
+// albumNameData is given to the -album-name-template
+type albumNameData struct {
+	Path   string    // Folder of the asset, like 2023/Vacation/Italy
+	Dir    string    // Name of the folder, like Italy
+	Parent string    // Name of the folder above, like Vacation
+	Group  string    // Name of the folder at the -album-folder-depth, like 2023, or the folder name
+	Date   time.Time // Date of capture
+	Name   string    // File name without extension
+	Ext    string    // File extension
+}
+
+func parseAlbumNameTemplate(s string) (*template.Template, error) {
+	t, err := template.New("album").Option("missingkey=error").Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid album name template: %w", err)
+	}
+	// Check the fields used by the template
+	err = t.Execute(io.Discard, albumNameData{})
+	if err != nil {
+		return nil, fmt.Errorf("invalid album name template: %w", err)
+	}
+	return t, nil
+}
+
+// folderAlbumName gives the album's name of the asset using the template
+func folderAlbumName(t *template.Template, a *browser.LocalAssetFile, group string) (string, error) {
+	dir := path.Dir(a.FileName)
+	parent := path.Base(path.Dir(dir))
+	if parent == "." || parent == "/" {
+		parent = ""
+	}
+	ext := path.Ext(a.FileName)
+	data := albumNameData{
+		Path:   dir,
+		Dir:    path.Base(dir),
+		Parent: parent,
+		Group:  group,
+		Date:   a.DateTaken,
+		Name:   strings.TrimSuffix(path.Base(a.FileName), ext),
+		Ext:    ext,
+	}
+	b := strings.Builder{}
+	err := t.Execute(&b, data)
+	if err != nil {
+		return "", fmt.Errorf("can't generate the album name: %w", err)
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
 func (app *UpCmd) albumName(al browser.LocalAlbum) string {
 	Name := al.Name
 	if app.GooglePhotos {
@@ -566,11 +2080,15 @@ func (app *UpCmd) albumName(al browser.LocalAlbum) string {
 		case app.KeepUntitled && Name == "":
 			Name = path.Base(al.Path)
 		}
+		if al.Shared && Name != "" {
+			Name = app.SharedAlbumPrefix + Name
+		}
 	}
 	return Name
 }
 
 func (app *UpCmd) AddToAlbum(ID string, album string) {
+	album = app.mapAlbum(album)
 	l := app.updateAlbums[album]
 	if l == nil {
 		l = map[string]any{}
@@ -579,6 +2097,55 @@ func (app *UpCmd) AddToAlbum(ID string, album string) {
 	app.updateAlbums[album] = l
 }
 
+// queueLocalDelete adds the file to the files deleted at the end of the run with -delete,
+// only when its upload has created a new asset on the server
+func (app *UpCmd) queueLocalDelete(a *browser.LocalAssetFile, ID string) {
+	if !app.Delete {
+		return
+	}
+	if !app.createdAssets[ID] {
+		app.journalAsset(a, logger.INFO, "not deleted: the upload hasn't created a new asset")
+		return
+	}
+	app.deleteLocalList = append(app.deleteLocalList, a)
+}
+
+// queueLocalMove adds the file to the files moved at the end of the run with -move-to,
+// when its upload has created a new asset
+func (app *UpCmd) queueLocalMove(a *browser.LocalAssetFile, ID string) {
+	if app.MoveTo == "" || !app.createdAssets[ID] {
+		return
+	}
+	app.moveLocalList = append(app.moveLocalList, a)
+}
+
+// queueExistingLocalDelete adds the file already on the server to the files deleted at the end of the run with -delete.
+// The name, the date and the size aren't enough: the checksum of the server's asset must be the file's one.
+func (app *UpCmd) queueExistingLocalDelete(a *browser.LocalAssetFile, sa *immich.Asset) {
+	if !app.Delete {
+		return
+	}
+	sum, err := a.Checksum()
+	if err != nil || sa.Checksum == "" || sum != sa.Checksum {
+		app.journalAsset(a, logger.INFO, "not deleted: the checksum of the server's asset can't be verified")
+		return
+	}
+	app.deleteLocalList = append(app.deleteLocalList, a)
+}
+
+// confirmLocalDelete asks the user to confirm the deletion of the local files, unless -delete-confirmed is given
+func (app *UpCmd) confirmLocalDelete(ctx context.Context) bool {
+	if app.DryRun || app.DeleteConfirmed {
+		return true
+	}
+	r, err := ui.ConfirmYesNo(ctx, fmt.Sprintf("Delete the %d local files now on the server?", len(app.deleteLocalList)), "n")
+	if err != nil || r != "y" {
+		app.Journal.Warning("The local files are not deleted, use -delete-confirmed to delete them without confirmation")
+		return false
+	}
+	return true
+}
+
 func (app *UpCmd) DeleteLocalAssets() error {
 	app.Journal.OK("%d local assets to delete.", len(app.deleteLocalList))
 
@@ -597,6 +2164,37 @@ func (app *UpCmd) DeleteLocalAssets() error {
 	return nil
 }
 
+// MoveLocalAssets moves the uploaded files and their sidecars into MoveTo, under their relative path.
+// A file that can't be moved is left in place.
+func (app *UpCmd) MoveLocalAssets() {
+	app.Journal.OK("%d local assets to move into %s.", len(app.moveLocalList), app.MoveTo)
+	for _, a := range app.moveLocalList {
+		names := []string{a.FileName}
+		if a.LivePhotoData != "" {
+			names = append(names, a.LivePhotoData)
+		}
+		if a.SideCar != nil && a.SideCar.OnFSsys {
+			names = append(names, a.SideCar.FileName)
+		}
+		if a.JSONSideCar != "" {
+			names = append(names, a.JSONSideCar)
+		}
+		for _, name := range names {
+			dest := filepath.Join(app.MoveTo, filepath.FromSlash(name))
+			if app.DryRun {
+				app.Journal.Warning("file %q not moved to %q, dry run mode", name, dest)
+				continue
+			}
+			err := fshelper.Move(a.FSys, name, dest)
+			if err != nil {
+				app.Journal.Error("can't move the file %q: %s", name, err)
+				break
+			}
+			app.Journal.Info("file %q moved to %q", name, dest)
+		}
+	}
+}
+
 func (app *UpCmd) DeleteServerAssets(ctx context.Context, ids []string) error {
 	app.Journal.Warning("%d server assets to delete.", len(ids))
 
@@ -614,55 +2212,173 @@ func (app *UpCmd) ManageAlbums(ctx context.Context) error {
 		if err != nil {
 			return fmt.Errorf("can't get the album list from the server: %w", err)
 		}
-		for album, list := range app.updateAlbums {
-
-			found := false
-			for _, sal := range serverAlbums {
-				if sal.AlbumName == album {
-					found = true
-					if !app.DryRun {
-						app.Journal.OK("Update the album %s", album)
-						rr, err := app.client.AddAssetToAlbum(ctx, sal.ID, gen.MapKeys(list))
-						if err != nil {
-							return fmt.Errorf("can't update the album list from the server: %w", err)
-						}
-						added := 0
-						for _, r := range rr {
-							if r.Success {
-								added++
-							}
-							if !r.Success && r.Error != "duplicate" {
-								app.Journal.Warning("%s: %s", r.ID, r.Error)
-							}
-						}
-						if added > 0 {
-							app.Journal.OK("%d asset(s) added to the album %q", added, album)
-						}
-					} else {
-						app.Journal.OK("Update album %s skipped - dry run mode", album)
+		byName := map[string]immich.AlbumSimplified{}
+		for _, sal := range serverAlbums {
+			k := app.albumKey(sal.AlbumName)
+			if other, exists := byName[k]; exists {
+				if app.AlbumMatchFuzzy || app.AlbumMatchLoose {
+					app.Journal.Warning("The server albums %q and %q have similar names, the one with the most assets is used", other.AlbumName, sal.AlbumName)
+					if sal.AssetCount > other.AssetCount {
+						byName[k] = sal
 					}
 				}
+				continue
+			}
+			byName[k] = sal
+		}
+		albums := 0
+		for _, list := range app.updateAlbums {
+			if len(list) > 0 {
+				albums++
 			}
-			if found {
+		}
+		if app.PreserveAlbumOrder && albums > 0 {
+			app.Journal.Warning("The server can't set a manual order of the album's assets, the albums are sorted by date, oldest first")
+		}
+		progress := newPhaseProgress(albums)
+		// the names are sorted, the album created for similar names is always named after the first one
+		names := gen.MapKeys(app.updateAlbums)
+		slices.Sort(names)
+		for _, album := range names {
+			list := app.updateAlbums[album]
+			if len(list) == 0 {
+				// all the assets of the album have been filtered out
 				continue
 			}
-			if list != nil {
+			step := progress.next()
+			if sal, found := byName[app.albumKey(album)]; found {
+				if app.AlbumMatchLoose && sal.AlbumName != "" && sal.AlbumName != album {
+					app.Journal.Warning("The album %q is merged into the album %q, as their names differ only by the case or the accents", album, sal.AlbumName)
+				}
 				if !app.DryRun {
-					app.Journal.OK("Create the album %s", album)
-
-					_, err := app.client.CreateAlbum(ctx, album, gen.MapKeys(list))
+					app.Journal.OK("Update the album %s (%s)", album, step)
+					res, err := app.addToAlbumByBatch(ctx, sal.ID, gen.MapKeys(list))
+					app.recordAlbum(album, res)
 					if err != nil {
-						return fmt.Errorf("can't create the album list from the server: %w", err)
+						return fmt.Errorf("can't update the album list from the server: %w", err)
+					}
+					if res.added > 0 {
+						app.Journal.OK("%d asset(s) added to the album %q", res.added, album)
+					}
+					if app.UpdateAlbumMeta {
+						err = app.setAlbumInfo(ctx, sal.ID, album)
+						if err != nil {
+							return err
+						}
+					} else if app.PreserveAlbumOrder {
+						app.setAlbumOrder(ctx, sal.ID, album)
 					}
 				} else {
-					app.Journal.OK("Create the album %s skipped - dry run mode", album)
+					app.Journal.OK("Update album %s skipped - dry run mode", album)
+					app.plan.albumsUpdated++
+					app.plan.updatedAssets += len(list)
+				}
+				continue
+			}
+			if len(list) < app.MinAlbumSize {
+				app.Journal.Info("The album %s isn't created, it has only %d asset(s)", album, len(list))
+				continue
+			}
+			if !app.DryRun {
+				app.Journal.OK("Create the album %s (%s)", album, step)
+
+				// The album is created with the first batch, the server may reject a too large request.
+				ids := gen.MapKeys(list)
+				first := ids
+				if app.AlbumBatchSize > 0 && len(ids) > app.AlbumBatchSize {
+					first = ids[:app.AlbumBatchSize]
+				}
+				created, err := app.client.CreateAlbum(ctx, album, first)
+				if err != nil {
+					return fmt.Errorf("can't create the album list from the server: %w", err)
+				}
+				byName[app.albumKey(album)] = created
+				res, err := app.addToAlbumByBatch(ctx, created.ID, ids[len(first):])
+				res.added += len(first)
+				app.recordAlbum(album, res)
+				if err != nil {
+					return fmt.Errorf("can't update the album list from the server: %w", err)
+				}
+				err = app.setAlbumInfo(ctx, created.ID, album)
+				if err != nil {
+					return err
 				}
+			} else {
+				app.Journal.OK("Create the album %s skipped - dry run mode", album)
+				app.plan.albumsCreated++
+				app.plan.createdAssets += len(list)
 			}
 		}
 	}
 	return nil
 }
 
+// setAlbumInfo sets the album's description and the comments flag when needed, then its order
+func (app *UpCmd) setAlbumInfo(ctx context.Context, ID string, album string) error {
+	info := immich.AlbumInfo{}
+	if d, ok := app.albumDescription[album]; ok {
+		info.Description = &d
+	}
+	if !app.AlbumComments {
+		info.IsActivityEnabled = &app.AlbumComments
+	}
+	if id := app.albumThumbnail(album); id != "" {
+		info.ThumbnailAssetID = &id
+	}
+	if info.Description != nil || info.IsActivityEnabled != nil || info.ThumbnailAssetID != nil {
+		err := app.client.UpdateAlbumInfo(ctx, ID, info)
+		if err != nil {
+			return fmt.Errorf("can't update the album %q: %w", album, err)
+		}
+	}
+	if app.PreserveAlbumOrder {
+		app.setAlbumOrder(ctx, ID, album)
+	}
+	return nil
+}
+
+// setAlbumOrder sorts the album's assets by date, as the server can't keep the order of the source.
+// The servers without the order of the albums refuse it, the album is left as is with a warning.
+func (app *UpCmd) setAlbumOrder(ctx context.Context, ID string, album string) {
+	o := immich.AlbumOrderAsc
+	err := app.client.UpdateAlbumInfo(ctx, ID, immich.AlbumInfo{Order: &o})
+	if err != nil {
+		app.Journal.Warning("Can't set the order of the album %q: %s", album, err)
+	}
+}
+
+// albumKey gives the key used to find an existing album on the server
+func (app *UpCmd) albumKey(name string) string {
+	if app.AlbumMatchLoose {
+		return strings.ToLower(strings.TrimSpace(nfc.StripMarks(nfc.String(name))))
+	}
+	if app.AlbumMatchFuzzy {
+		return strings.ToLower(strings.TrimSpace(name))
+	}
+	return name
+}
+
+// ManageSharedAlbum adds the assets into the album given by -shared-album-id
+func (app *UpCmd) ManageSharedAlbum(ctx context.Context) error {
+	if len(app.sharedAlbum) == 0 {
+		return nil
+	}
+	if app.DryRun {
+		app.Journal.OK("Update the shared album %s skipped - dry run mode", app.SharedAlbumID)
+		return nil
+	}
+	res, err := app.addToAlbumByBatch(ctx, app.SharedAlbumID, gen.MapKeys(app.sharedAlbum))
+	app.recordAlbum(app.SharedAlbumID, res)
+	if err != nil {
+		if errors.Is(err, immich.ErrAlbumNoAccess) {
+			return fmt.Errorf("can't add assets to the shared album %s, check the album ID and that it is shared with you as editor: %w", app.SharedAlbumID, err)
+		}
+		return fmt.Errorf("can't update the shared album %s: %w", app.SharedAlbumID, err)
+	}
+	app.Journal.OK("%d asset(s) added to the shared album %s", res.added, app.SharedAlbumID)
+	return nil
+}
+
 // - - go:generate stringer -type=AdviceCode
 type AdviceCode int
 
@@ -680,6 +2396,12 @@ func (a AdviceCode) String() string {
 		return "SameOnServer"
 	case NotOnServer:
 		return "NotOnServer"
+	case ReplaceOnServer:
+		return "ReplaceOnServer"
+	case TrashedOnServer:
+		return "TrashedOnServer"
+	case LocalCopy:
+		return "LocalCopy"
 	}
 	return fmt.Sprintf("advice(%d)", a)
 }
@@ -690,6 +2412,9 @@ const (
 	BetterOnServer
 	SameOnServer
 	NotOnServer
+	ReplaceOnServer
+	TrashedOnServer
+	LocalCopy
 )
 
 type Advice struct {
@@ -745,6 +2470,20 @@ func (ai *AssetIndex) adviceBetterOnServer(sa *immich.Asset) *Advice {
 		ServerAsset: sa,
 	}
 }
+func (ai *AssetIndex) adviceLowerResolutionOnServer(sa *immich.Asset) *Advice {
+	return &Advice{
+		Advice:      SmallerOnServer,
+		Message:     fmt.Sprintf("An asset with the same name:%q and date:%q but with a lower resolution:%dx%d exists on the server. Replace it.", sa.OriginalFileName, sa.ExifInfo.DateTimeOriginal.Format(time.DateTime), sa.ExifInfo.ExifImageWidth, sa.ExifInfo.ExifImageHeight),
+		ServerAsset: sa,
+	}
+}
+func (ai *AssetIndex) adviceHigherResolutionOnServer(sa *immich.Asset) *Advice {
+	return &Advice{
+		Advice:      BetterOnServer,
+		Message:     fmt.Sprintf("An asset with the same name:%q and date:%q but with a higher resolution:%dx%d exists on the server. No need to upload.", sa.OriginalFileName, sa.ExifInfo.DateTimeOriginal.Format(time.DateTime), sa.ExifInfo.ExifImageWidth, sa.ExifInfo.ExifImageHeight),
+		ServerAsset: sa,
+	}
+}
 func (ai *AssetIndex) adviceNotOnServer() *Advice {
 	return &Advice{
 		Advice:  NotOnServer,
@@ -755,7 +2494,8 @@ func (ai *AssetIndex) adviceNotOnServer() *Advice {
 // ShouldUpload check if the server has this asset
 //
 // The server may have different assets with the same name. This happens with photos produced by digital cameras.
-// The server may have the asset, but in lower resolution. Compare the taken date and resolution
+// The server may have the asset, but in lower resolution. Compare the taken date and resolution.
+// The resolution is given by the EXIF dimensions, the byte size is used when they are unknown.
 
 func (ai *AssetIndex) ShouldUpload(la *browser.LocalAssetFile) (*Advice, error) {
 	filename := la.Title
@@ -763,23 +2503,32 @@ func (ai *AssetIndex) ShouldUpload(la *browser.LocalAssetFile) (*Advice, error)
 		filename += path.Ext(la.FileName)
 	}
 	var err error
-	ID := la.DeviceAssetID()
+	err = ai.loadDays(la.DateTaken)
+	if err != nil {
+		return nil, err
+	}
+	ID := nfc.String(la.DeviceAssetID())
+	ai.explainf("title:%q date:%s size:%d pixels:%dx%d", filename, la.DateTaken.Format(time.DateTime), la.Size(), la.Width, la.Height)
 
 	sa := ai.byID[ID]
 	if sa != nil {
 		// the same ID exist on the server
+		ai.explainf("byID[%q] found %s: same on server", ID, describeAsset(sa))
 		return ai.adviceSameOnServer(sa), nil
 	}
+	ai.explainf("byID[%q] not found", ID)
 
 	var l []*immich.Asset
 
 	// check all files with the same name
 
-	n := filepath.Base(filename)
+	n := nfc.String(filepath.Base(filename))
 	l = ai.byName[n]
+	ai.explainf("byName[%q] found %d candidate(s)", n, len(l))
 	if len(l) == 0 {
-		// n = strings.TrimSuffix(n, filepath.Ext(n))
-		l = ai.byName[n]
+		// The server may have the asset without its extension: only the same date and size are accepted
+		l = ai.sameBaseName(n, la.DateTaken, int(la.Size()))
+		ai.explainf("byBase[%q] found %d candidate(s) with the same date and size", strings.TrimSuffix(n, path.Ext(n)), len(l))
 	}
 
 	if len(l) > 0 {
@@ -790,30 +2539,65 @@ func (ai *AssetIndex) ShouldUpload(la *browser.LocalAssetFile) (*Advice, error)
 
 		}
 		for _, sa = range l {
-			compareDate := compareDate(dateTaken, sa.ExifInfo.DateTimeOriginal.Time)
+			ai.explainf("candidate %s", describeAsset(sa))
+			if immich.CompareDate(dateTaken, sa.ExifInfo.DateTimeOriginal.Time) != 0 {
+				ai.explainf("  dates differ by %s, 5 minutes or more: not the same asset", dateTaken.Sub(sa.ExifInfo.DateTimeOriginal.Time))
+				continue
+			}
+			// Same name and date: an equal size means the same file, then the higher resolution wins,
+			// then the bigger size when a resolution is unknown or when both are equal
 			compareSize := size - sa.ExifInfo.FileSizeInByte
-
-			switch {
-			case compareDate == 0 && compareSize == 0:
+			if compareSize == 0 {
+				ai.explainf("  same date and same size: same on server")
 				return ai.adviceSameOnServer(sa), nil
-			case compareDate == 0 && compareSize > 0:
+			}
+			serverPixels := sa.ExifInfo.ExifImageWidth * sa.ExifInfo.ExifImageHeight
+			if serverPixels > 0 && la.Width*la.Height == 0 {
+				readDimensions(la)
+			}
+			byPixels := immich.ComparePixels(la.Width, la.Height, sa.ExifInfo.ExifImageWidth, sa.ExifInfo.ExifImageHeight) != 0
+			ai.explainf("  same date, sizes differ by %d bytes, local pixels:%dx%d", compareSize, la.Width, la.Height)
+			switch c := immich.CompareCopies(la.Width, la.Height, size, sa.ExifInfo.ExifImageWidth, sa.ExifInfo.ExifImageHeight, sa.ExifInfo.FileSizeInByte); {
+			case c > 0 && byPixels:
+				ai.explainf("  lower resolution on server")
+				return ai.adviceLowerResolutionOnServer(sa), nil
+			case c < 0 && byPixels:
+				ai.explainf("  higher resolution on server")
+				return ai.adviceHigherResolutionOnServer(sa), nil
+			case c > 0:
+				ai.explainf("  smaller on server")
 				return ai.adviceSmallerOnServer(sa), nil
-			case compareDate == 0 && compareSize < 0:
+			default:
+				ai.explainf("  bigger on server")
 				return ai.adviceBetterOnServer(sa), nil
 			}
 		}
 	}
+	ai.explainf("no candidate matches: not on server")
 	return ai.adviceNotOnServer(), nil
 }
 
-func compareDate(d1 time.Time, d2 time.Time) int {
-	diff := d1.Sub(d2)
-
-	switch {
-	case diff < -5*time.Minute:
-		return -1
-	case diff >= 5*time.Minute:
-		return +1
+// forceReplace changes the advice into ReplaceOnServer when the server's asset
+// found as the same or as better than the file has a different checksum
+func (app *UpCmd) forceReplace(a *browser.LocalAssetFile, advice *Advice) *Advice {
+	if advice.Advice != SameOnServer && advice.Advice != BetterOnServer {
+		return advice
+	}
+	sa := advice.ServerAsset
+	if sa.JustUploaded || sa.Checksum == "" {
+		return advice
+	}
+	sum, err := a.Checksum()
+	if err != nil {
+		app.journalAsset(a, logger.ERROR, "can't compute the checksum: "+err.Error())
+		return advice
+	}
+	if sum == sa.Checksum {
+		return advice
+	}
+	return &Advice{
+		Advice:      ReplaceOnServer,
+		Message:     fmt.Sprintf("An asset with the same name:%q and date:%q but with a different checksum exists on the server. Replace it.", sa.OriginalFileName, sa.ExifInfo.DateTimeOriginal.Format(time.DateTime)),
+		ServerAsset: sa,
 	}
-	return 0
 }
@@ -3,13 +3,22 @@ package cmdupload
 import (
 	"cmp"
 	"context"
+	"crypto/sha1"
+	"encoding/base64"
 	"errors"
+	"fmt"
 	"io/fs"
+	"os"
+	"path/filepath"
 	"reflect"
 	"slices"
+	"strconv"
 	"testing"
+	"testing/fstest"
+	"time"
 
 	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/helpers/fshelper"
 	"github.com/simulot/immich-go/helpers/gen"
 	"github.com/simulot/immich-go/immich"
 	"github.com/simulot/immich-go/logger"
@@ -23,12 +32,35 @@ type stubIC struct {
 func (c *stubIC) GetAllAssetsWithFilter(context.Context, *immich.GetAssetOptions, func(*immich.Asset)) error {
 	return nil
 }
+func (c *stubIC) GetAssetsTakenBetween(ctx context.Context, after time.Time, before time.Time, filter func(*immich.Asset)) error {
+	return nil
+}
+
+func (c *stubIC) GetAssetsUpdatedAfter(ctx context.Context, after time.Time, filter func(*immich.Asset)) error {
+	return nil
+}
+
+func (c *stubIC) GetAssetStatistics(ctx context.Context) (immich.AssetStatistics, error) {
+	return immich.AssetStatistics{}, nil
+}
+
 func (c *stubIC) AssetUpload(context.Context, *browser.LocalAssetFile) (immich.AssetResponse, error) {
 	return immich.AssetResponse{}, nil
 }
+
+func (c *stubIC) AssetImport(context.Context, *browser.LocalAssetFile, string, string) (immich.AssetResponse, error) {
+	return immich.AssetResponse{}, nil
+}
 func (c *stubIC) DeleteAssets(context.Context, []string, bool) error {
 	return nil
 }
+func (c *stubIC) RestoreAssets(context.Context, []string) error {
+	return nil
+}
+func (c *stubIC) GetAllLibraries(context.Context) ([]immich.Library, error) {
+	return nil, nil
+}
+func (c *stubIC) SetLibrary(string) {}
 func (c *stubIC) GetAllAlbums(context.Context) ([]immich.AlbumSimplified, error) {
 	return nil, nil
 }
@@ -38,10 +70,17 @@ func (c *stubIC) AddAssetToAlbum(context.Context, string, []string) ([]immich.Up
 func (c *stubIC) CreateAlbum(context.Context, string, []string) (immich.AlbumSimplified, error) {
 	return immich.AlbumSimplified{}, nil
 }
+func (c *stubIC) UpdateAlbumInfo(ctx context.Context, id string, info immich.AlbumInfo) error {
+	return nil
+}
 func (c *stubIC) UpdateAssets(ctx context.Context, IDs []string, isArchived bool, isFavorite bool, latitude float64, longitude float64, removeParent bool, stackParentId string) error {
 	return nil
 }
 
+func (c *stubIC) ArchiveAssets(ctx context.Context, IDs []string, isArchived bool) error {
+	return nil
+}
+
 func (c *stubIC) StackAssets(ctx context.Context, cover string, IDs []string) error {
 	return nil
 }
@@ -50,6 +89,40 @@ func (c *stubIC) UpdateAsset(ctx context.Context, ID string, a *browser.LocalAss
 	return nil, nil
 }
 
+func (c *stubIC) GetAssetByID(ctx context.Context, id string) (*immich.Asset, error) {
+	return &immich.Asset{ID: id}, nil
+}
+
+func (c *stubIC) GetAssetAlbums(ctx context.Context, id string) ([]immich.AlbumSimplified, error) {
+	return nil, nil
+}
+
+func (c *stubIC) GetAllTags(ctx context.Context) ([]immich.Tag, error) {
+	return nil, nil
+}
+
+func (c *stubIC) CreateTag(ctx context.Context, name string) (immich.Tag, error) {
+	return immich.Tag{ID: name, Name: name}, nil
+}
+
+func (c *stubIC) TagAssets(ctx context.Context, tagID string, assets []string) ([]immich.TagAssetResult, error) {
+	return nil, nil
+}
+
+func (c *stubIC) SetUploadProgress(fn immich.UploadProgressFunc) {}
+
+func (c *stubIC) GetServerVersion(ctx context.Context) (immich.ServerVersion, error) {
+	return immich.ServerVersion{Major: 1, Minor: 94}, nil
+}
+
+func (c *stubIC) GetAllPeople(ctx context.Context) ([]immich.Person, error) {
+	return nil, nil
+}
+
+func (c *stubIC) AddPeopleToAsset(ctx context.Context, assetID string, people []immich.Person) ([]immich.Person, error) {
+	return nil, nil
+}
+
 // type mockedBrowser struct {
 // 	assets []assets.LocalAssetFile
 // }
@@ -79,7 +152,15 @@ func (c *icCatchUploadsAssets) AssetUpload(ctx context.Context, a *browser.Local
 	}, nil
 }
 func (c *icCatchUploadsAssets) AddAssetToAlbum(ctx context.Context, album string, ids []string) ([]immich.UpdateAlbumResult, error) {
-	return nil, nil
+	if c.albums == nil {
+		c.albums = map[string][]string{}
+	}
+	r := []immich.UpdateAlbumResult{}
+	for _, id := range ids {
+		c.albums[album] = append(c.albums[album], id)
+		r = append(r, immich.UpdateAlbumResult{ID: id, Success: true})
+	}
+	return r, nil
 }
 func (c *icCatchUploadsAssets) CreateAlbum(ctx context.Context, album string, ids []string) (immich.AlbumSimplified, error) {
 	if album == "" {
@@ -340,6 +421,47 @@ func TestUpload(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "folder into a shared album",
+			args: []string{
+				"-shared-album-id=shared-album-uuid",
+				"TEST_DATA/Takeout2",
+			},
+			expectedAssets: []string{
+				"Google\u00a0Photos/Photos from 2023/PXL_20231006_063528961.jpg",
+				"Google\u00a0Photos/Photos from 2023/PXL_20231006_063000139.jpg",
+				"Google\u00a0Photos/Sans titre(9)/PXL_20231006_063108407.jpg",
+			},
+			expectedAlbums: map[string][]string{
+				"shared-album-uuid": {
+					"Google\u00a0Photos/Photos from 2023/PXL_20231006_063000139.jpg",
+					"Google\u00a0Photos/Photos from 2023/PXL_20231006_063528961.jpg",
+					"Google\u00a0Photos/Sans titre(9)/PXL_20231006_063108407.jpg",
+				},
+			},
+		},
+		{
+			name: "folder and albums with a name template",
+			args: []string{
+				"-create-album-folder",
+				"-album-name-template={{.Date.Year}} - {{.Dir}}",
+				"TEST_DATA/Takeout2",
+			},
+			expectedAssets: []string{
+				"Google\u00a0Photos/Photos from 2023/PXL_20231006_063528961.jpg",
+				"Google\u00a0Photos/Photos from 2023/PXL_20231006_063000139.jpg",
+				"Google\u00a0Photos/Sans titre(9)/PXL_20231006_063108407.jpg",
+			},
+			expectedAlbums: map[string][]string{
+				"2023 - Photos from 2023": {
+					"Google\u00a0Photos/Photos from 2023/PXL_20231006_063000139.jpg",
+					"Google\u00a0Photos/Photos from 2023/PXL_20231006_063528961.jpg",
+				},
+				"2023 - Sans titre(9)": {
+					"Google\u00a0Photos/Sans titre(9)/PXL_20231006_063108407.jpg",
+				},
+			},
+		},
 		{
 			name: "google photo, includes .mp4",
 			args: []string{
@@ -400,6 +522,59 @@ func TestUpload(t *testing.T) {
 				"PXL_20231006_063851485.jpg",
 			},
 		},
+		{
+			name: "folder, only videos",
+			args: []string{
+				"-only=video",
+				"TEST_DATA/Takeout1/Google\u00a0Photos/Album test 6-10-23",
+			},
+			expectedErr: false,
+			expectedAssets: []string{
+				"PXL_20231006_063909898.LS.mp4",
+			},
+		},
+		{
+			name: "folder, only photos among the selected types",
+			args: []string{
+				"-only=photo",
+				"-select-types=.mp4,.jpg",
+				"-exclude-types=.jpg",
+				"TEST_DATA/Takeout1/Google\u00a0Photos/Album test 6-10-23",
+			},
+			expectedErr:    false,
+			expectedAssets: []string{},
+		},
+		{
+			name: "folder, only photos",
+			args: []string{
+				"-only=PHOTO",
+				"TEST_DATA/Takeout1/Google\u00a0Photos/Album test 6-10-23",
+			},
+			expectedErr: false,
+			expectedAssets: []string{
+				"PXL_20231006_063000139.jpg",
+				"PXL_20231006_063029647.jpg",
+				"PXL_20231006_063108407.jpg",
+				"PXL_20231006_063121958.jpg",
+				"PXL_20231006_063357420.jpg",
+				"PXL_20231006_063536303.jpg",
+				"PXL_20231006_063851485.jpg",
+			},
+		},
+		{
+			name: "folder, size range",
+			args: []string{
+				"-min-size=100K",
+				"-max-size=115K",
+				"TEST_DATA/folder/high",
+			},
+			expectedErr: false,
+			expectedAssets: []string{
+				"AlbumA/PXL_20231006_063108407.jpg",
+				"AlbumA/PXL_20231006_063121958.jpg",
+				"AlbumA/PXL_20231006_063357420.jpg",
+			},
+		},
 		{
 			name: "folder and albums creation",
 			args: []string{
@@ -470,6 +645,407 @@ func TestUpload(t *testing.T) {
 	}
 }
 
+// icCancelAfterUploads cancels the context after some uploads, like a Ctrl-C
+type icCancelAfterUploads struct {
+	icCatchUploadsAssets
+	cancel context.CancelFunc
+	after  int
+}
+
+func (c *icCancelAfterUploads) AssetUpload(ctx context.Context, a *browser.LocalAssetFile) (immich.AssetResponse, error) {
+	r, err := c.icCatchUploadsAssets.AssetUpload(ctx, a)
+	if len(c.assets) == c.after {
+		c.cancel()
+	}
+	return r, err
+}
+
+func TestUploadInterrupted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ic := &icCancelAfterUploads{cancel: cancel, after: 2}
+
+	app, err := NewUpCmd(ctx, ic, logger.NoLogger{}, []string{"-album=interrupted", "-create-stacks=false", "TEST_DATA/folder/high"})
+	if err != nil {
+		t.Errorf("can't instantiate the UploadCmd: %s", err)
+		return
+	}
+	err = app.Run(ctx, app.fsys)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected a context.Canceled error, got: %v", err)
+	}
+	if len(ic.assets) != 2 {
+		t.Errorf("expected 2 uploads, got: %v", ic.assets)
+	}
+	if !cmpSlices(ic.assets, ic.albums["interrupted"]) {
+		t.Errorf("uploaded assets should be in the album")
+		pretty.Ldiff(t, ic.assets, ic.albums["interrupted"])
+	}
+}
+
+func TestUploadStoppedByInvalidFile(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"a.cr3": "raw content",
+		"z.jpg": "not a JPEG file",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx := context.Background()
+	ic := &icCatchUploadsAssets{albums: map[string][]string{}}
+	app, err := NewUpCmd(ctx, ic, logger.NoLogger{}, []string{"-album=strict", "-skip-invalid=false", "-create-stacks=false", dir})
+	if err != nil {
+		t.Fatalf("can't instantiate the UploadCmd: %s", err)
+	}
+	err = app.Run(ctx, app.fsys)
+	if !errors.Is(err, fshelper.ErrInvalidContent) {
+		t.Errorf("expected an ErrInvalidContent error, got: %v", err)
+	}
+	// the album is finalized with the assets uploaded before the invalid file
+	if len(ic.assets) != 1 || !cmpSlices(ic.assets, ic.albums["strict"]) {
+		t.Errorf("uploaded assets %v, album %v", ic.assets, ic.albums["strict"])
+	}
+}
+
+// icQuotaAfterUploads refuses the uploads after some assets, as a server without storage
+type icQuotaAfterUploads struct {
+	icCatchUploadsAssets
+	after    int
+	attempts int
+}
+
+func (c *icQuotaAfterUploads) AssetUpload(ctx context.Context, a *browser.LocalAssetFile) (immich.AssetResponse, error) {
+	c.attempts++
+	if len(c.assets) == c.after {
+		return immich.AssetResponse{}, fmt.Errorf("%w: 400 Bad Request", immich.ErrQuotaExceeded)
+	}
+	return c.icCatchUploadsAssets.AssetUpload(ctx, a)
+}
+
+func TestUploadQuotaExceeded(t *testing.T) {
+	tests := []struct {
+		name             string
+		args             []string
+		expectedErr      error
+		expectedAttempts int
+	}{
+		{
+			name:             "stop",
+			expectedErr:      immich.ErrQuotaExceeded,
+			expectedAttempts: 3,
+		},
+		{
+			name:             "continue",
+			args:             []string{"-continue-on-quota"},
+			expectedAttempts: 8,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ic := &icQuotaAfterUploads{after: 2}
+			ctx := context.Background()
+			args := append(tt.args, "-album=quota", "-create-stacks=false", "TEST_DATA/folder/high")
+			app, err := NewUpCmd(ctx, ic, logger.NoLogger{}, args)
+			if err != nil {
+				t.Errorf("can't instantiate the UploadCmd: %s", err)
+				return
+			}
+			err = app.Run(ctx, app.fsys)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Errorf("expected error %v, got: %v", tt.expectedErr, err)
+			}
+			if ic.attempts != tt.expectedAttempts {
+				t.Errorf("expected %d upload attempts, got: %d", tt.expectedAttempts, ic.attempts)
+			}
+			if len(ic.assets) != 2 {
+				t.Errorf("expected 2 uploads, got: %v", ic.assets)
+			}
+			if !cmpSlices(ic.assets, ic.albums["quota"]) {
+				t.Errorf("uploaded assets should be in the album")
+				pretty.Ldiff(t, ic.assets, ic.albums["quota"])
+			}
+		})
+	}
+}
+
+// icTimeoutUploads simulates timeouts on the first uploads of each file
+type icTimeoutUploads struct {
+	icCatchUploadsAssets
+	timeouts map[string]int
+}
+
+func (c *icTimeoutUploads) AssetUpload(ctx context.Context, a *browser.LocalAssetFile) (immich.AssetResponse, error) {
+	if c.timeouts[a.FileName] > 0 {
+		c.timeouts[a.FileName]--
+		return immich.AssetResponse{}, fmt.Errorf("upload: %w", context.DeadlineExceeded)
+	}
+	return c.icCatchUploadsAssets.AssetUpload(ctx, a)
+}
+
+func TestUploadRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		retries  string
+		expected []string
+	}{
+		{
+			name:    "no retry",
+			retries: "0",
+			expected: []string{
+				"PXL_20231006_063000139.jpg",
+				"PXL_20231006_063029647.jpg",
+				"PXL_20231006_063121958.jpg",
+				"PXL_20231006_063357420.jpg",
+			},
+		},
+		{
+			name:    "retry",
+			retries: "2",
+			expected: []string{
+				"PXL_20231006_063000139.jpg",
+				"PXL_20231006_063029647.jpg",
+				"PXL_20231006_063108407.jpg",
+				"PXL_20231006_063121958.jpg",
+				"PXL_20231006_063357420.jpg",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ic := &icTimeoutUploads{timeouts: map[string]int{"PXL_20231006_063108407.jpg": 2}}
+			ctx := context.Background()
+			app, err := NewUpCmd(ctx, ic, logger.NoLogger{}, []string{"-upload-retries=" + tt.retries, "-create-stacks=false", "TEST_DATA/folder/high/AlbumA"})
+			if err != nil {
+				t.Errorf("can't instantiate the UploadCmd: %s", err)
+				return
+			}
+			err = app.Run(ctx, app.fsys)
+			if err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+			if !cmpSlices(tt.expected, ic.assets) {
+				t.Errorf("expected upload differs ")
+				pretty.Ldiff(t, tt.expected, ic.assets)
+			}
+		})
+	}
+}
+
+// icCatchAlbumBatches records calls to albums APIs
+type icCatchAlbumBatches struct {
+	stubIC
+	serverAlbums []immich.AlbumSimplified
+	batches      map[string][]int // album ID -> sizes of the batches
+	created      []string
+	infos        map[string]immich.AlbumInfo // album ID -> updated info
+}
+
+func (c *icCatchAlbumBatches) GetAllAlbums(ctx context.Context) ([]immich.AlbumSimplified, error) {
+	return c.serverAlbums, nil
+}
+
+func (c *icCatchAlbumBatches) AddAssetToAlbum(ctx context.Context, album string, ids []string) ([]immich.UpdateAlbumResult, error) {
+	c.batches[album] = append(c.batches[album], len(ids))
+	r := []immich.UpdateAlbumResult{}
+	for _, id := range ids {
+		r = append(r, immich.UpdateAlbumResult{ID: id, Success: true})
+	}
+	return r, nil
+}
+
+func (c *icCatchAlbumBatches) CreateAlbum(ctx context.Context, album string, ids []string) (immich.AlbumSimplified, error) {
+	c.created = append(c.created, album)
+	return immich.AlbumSimplified{ID: album, AlbumName: album}, nil
+}
+
+func (c *icCatchAlbumBatches) UpdateAlbumInfo(ctx context.Context, id string, info immich.AlbumInfo) error {
+	if c.infos == nil {
+		c.infos = map[string]immich.AlbumInfo{}
+	}
+	c.infos[id] = info
+	return nil
+}
+
+func TestManageAlbumsBatches(t *testing.T) {
+	ic := &icCatchAlbumBatches{
+		serverAlbums: []immich.AlbumSimplified{
+			{ID: "id-other", AlbumName: "Other"},
+			{ID: "id-existing", AlbumName: "Existing"},
+		},
+		batches: map[string][]int{},
+	}
+	app := UpCmd{
+		client:         ic,
+		Journal:        logger.NewJournal(logger.NoLogger{}),
+		AlbumBatchSize: 2,
+		updateAlbums: map[string]map[string]any{
+			"Existing": {"1": nil, "2": nil, "3": nil, "4": nil, "5": nil},
+			"New":      {"6": nil},
+		},
+	}
+	err := app.ManageAlbums(context.Background())
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+		return
+	}
+	expected := map[string][]int{"id-existing": {2, 2, 1}}
+	if !reflect.DeepEqual(ic.batches, expected) {
+		t.Errorf("batches = %v, want %v", ic.batches, expected)
+	}
+	if !reflect.DeepEqual(ic.created, []string{"New"}) {
+		t.Errorf("created albums = %v, want [New]", ic.created)
+	}
+}
+
+// icLimitedCreate rejects the creation of albums with more than limit assets, like a server with a request size limit
+type icLimitedCreate struct {
+	icCatchAlbumBatches
+	limit   int
+	initial []int
+}
+
+func (c *icLimitedCreate) CreateAlbum(ctx context.Context, album string, ids []string) (immich.AlbumSimplified, error) {
+	if len(ids) > c.limit {
+		return immich.AlbumSimplified{}, errors.New("413 request entity too large")
+	}
+	c.initial = append(c.initial, len(ids))
+	return c.icCatchAlbumBatches.CreateAlbum(ctx, album, ids)
+}
+
+func TestManageAlbumsChunkedCreate(t *testing.T) {
+	list := map[string]any{}
+	for i := 0; i < 25; i++ {
+		list[strconv.Itoa(i)] = nil
+	}
+	ic := &icLimitedCreate{
+		icCatchAlbumBatches: icCatchAlbumBatches{batches: map[string][]int{}},
+		limit:               10,
+	}
+	app := UpCmd{
+		client:         ic,
+		Journal:        logger.NewJournal(logger.NoLogger{}),
+		AlbumBatchSize: 10,
+		updateAlbums:   map[string]map[string]any{"Big": list},
+	}
+	err := app.ManageAlbums(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(ic.initial, []int{10}) {
+		t.Errorf("album created with %v assets, want [10]", ic.initial)
+	}
+	if want := map[string][]int{"Big": {10, 5}}; !reflect.DeepEqual(ic.batches, want) {
+		t.Errorf("batches = %v, want %v", ic.batches, want)
+	}
+	if r := app.albumResults["Big"]; r.added != 25 || r.failed != 0 {
+		t.Errorf("album result = %+v, want 25 added", r)
+	}
+}
+
+func TestManageAlbumsFuzzy(t *testing.T) {
+	tests := []struct {
+		name        string
+		fuzzy       bool
+		wantBatches map[string][]int
+		wantCreated []string
+	}{
+		{
+			name:        "exact",
+			fuzzy:       false,
+			wantBatches: map[string][]int{},
+			wantCreated: []string{"Summer 2023"},
+		},
+		{
+			name:        "fuzzy",
+			fuzzy:       true,
+			wantBatches: map[string][]int{"id-big": {1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ic := &icCatchAlbumBatches{
+				serverAlbums: []immich.AlbumSimplified{
+					{ID: "id-small", AlbumName: "Summer 2023 ", AssetCount: 3},
+					{ID: "id-big", AlbumName: "summer 2023", AssetCount: 10},
+				},
+				batches: map[string][]int{},
+			}
+			app := UpCmd{
+				client:          ic,
+				Journal:         logger.NewJournal(logger.NoLogger{}),
+				AlbumMatchFuzzy: tt.fuzzy,
+				updateAlbums: map[string]map[string]any{
+					"Summer 2023": {"1": nil},
+				},
+			}
+			err := app.ManageAlbums(context.Background())
+			if err != nil {
+				t.Errorf("unexpected error: %s", err)
+				return
+			}
+			if !reflect.DeepEqual(ic.batches, tt.wantBatches) {
+				t.Errorf("batches = %v, want %v", ic.batches, tt.wantBatches)
+			}
+			if !reflect.DeepEqual(ic.created, tt.wantCreated) {
+				t.Errorf("created albums = %v, want %v", ic.created, tt.wantCreated)
+			}
+		})
+	}
+}
+
+func TestManageAlbumsLoose(t *testing.T) {
+	ic := &icCatchAlbumBatches{
+		serverAlbums: []immich.AlbumSimplified{
+			{ID: "id-cafe", AlbumName: "Café", AssetCount: 3},
+		},
+		batches: map[string][]int{},
+	}
+	app := UpCmd{
+		client:          ic,
+		Journal:         logger.NewJournal(logger.NoLogger{}),
+		AlbumMatchLoose: true,
+		updateAlbums: map[string]map[string]any{
+			"Cafe":   {"1": nil},
+			"CAFÉ ":  {"2": nil, "3": nil},
+			"Crème":  {"4": nil},
+			"creme ": {"5": nil},
+		},
+	}
+	err := app.ManageAlbums(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got := ic.batches["id-cafe"]; len(got) != 2 {
+		t.Errorf("batches of the album Café = %v, want 2 batches", got)
+	}
+	// the created album takes the first name in alphabetical order
+	if !reflect.DeepEqual(ic.created, []string{"Crème"}) {
+		t.Errorf("created albums = %v, want [Crème]", ic.created)
+	}
+}
+
+func TestParseAlbumNameTemplate(t *testing.T) {
+	tests := []struct {
+		template string
+		wantErr  bool
+	}{
+		{template: "{{.Date.Year}} - {{.Dir}}"},
+		{template: "{{.Parent}}/{{.Dir}}"},
+		{template: "{{.Dir", wantErr: true},
+		{template: "{{.Unknown}}", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			_, err := parseAlbumNameTemplate(tt.template)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseAlbumNameTemplate(%q) error = %v, wantErr %v", tt.template, err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func cmpAlbums(a, b map[string][]string) bool {
 	ka := gen.MapKeys(a)
 	kb := gen.MapKeys(b)
@@ -494,3 +1070,507 @@ func cmpSlices[T cmp.Ordered](a, b []T) bool {
 	slices.Sort(b)
 	return reflect.DeepEqual(a, b)
 }
+
+func TestSharedAlbumName(t *testing.T) {
+	app := UpCmd{GooglePhotos: true, SharedAlbumPrefix: "Shared: "}
+	tc := []struct {
+		album browser.LocalAlbum
+		want  string
+	}{
+		{album: browser.LocalAlbum{Path: "Holidays", Name: "Holidays"}, want: "Holidays"},
+		{album: browser.LocalAlbum{Path: "Party", Name: "Party", Shared: true}, want: "Shared: Party"},
+		{album: browser.LocalAlbum{Path: "Untitled(1)", Shared: true}, want: ""},
+	}
+	for _, c := range tc {
+		if got := app.albumName(c.album); got != c.want {
+			t.Errorf("albumName(%v)=%q, want %q", c.album, got, c.want)
+		}
+	}
+}
+
+func TestManageAlbumsInfo(t *testing.T) {
+	tests := []struct {
+		name       string
+		comments   bool
+		updateMeta bool
+		want       map[string]string // album ID -> description and comments flag
+	}{
+		{
+			name:     "new album only",
+			comments: true,
+			want:     map[string]string{"New": "new album description, comments: <nil>"},
+		},
+		{
+			name:       "existing album too",
+			comments:   true,
+			updateMeta: true,
+			want: map[string]string{
+				"New":         "new album description, comments: <nil>",
+				"id-existing": "existing album description, comments: <nil>",
+			},
+		},
+		{
+			name:     "comments disabled",
+			comments: false,
+			want: map[string]string{
+				"New":     "new album description, comments: false",
+				"Nothing": "<nil>, comments: false",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ic := &icCatchAlbumBatches{
+				serverAlbums: []immich.AlbumSimplified{{ID: "id-existing", AlbumName: "Existing"}},
+				batches:      map[string][]int{},
+			}
+			app := UpCmd{
+				client:          ic,
+				Journal:         logger.NewJournal(logger.NoLogger{}),
+				AlbumComments:   tt.comments,
+				UpdateAlbumMeta: tt.updateMeta,
+				updateAlbums: map[string]map[string]any{
+					"Existing": {"1": nil},
+					"New":      {"2": nil},
+					"Nothing":  {"3": nil},
+				},
+				albumDescription: map[string]string{
+					"Existing": "existing album description",
+					"New":      "new album description",
+				},
+			}
+			err := app.ManageAlbums(context.Background())
+			if err != nil {
+				t.Errorf("unexpected error: %s", err)
+				return
+			}
+			got := map[string]string{}
+			for id, info := range ic.infos {
+				d, c := "<nil>", "<nil>"
+				if info.Description != nil {
+					d = *info.Description
+				}
+				if info.IsActivityEnabled != nil {
+					c = fmt.Sprint(*info.IsActivityEnabled)
+				}
+				got[id] = d + ", comments: " + c
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("updated albums = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestManageAlbumsOrder(t *testing.T) {
+	ic := &icCatchAlbumBatches{
+		serverAlbums: []immich.AlbumSimplified{{ID: "id-existing", AlbumName: "Existing"}},
+		batches:      map[string][]int{},
+	}
+	app := UpCmd{
+		client:             ic,
+		Journal:            logger.NewJournal(logger.NoLogger{}),
+		AlbumComments:      true,
+		PreserveAlbumOrder: true,
+		updateAlbums: map[string]map[string]any{
+			"Existing": {"1": nil},
+			"New":      {"2": nil},
+		},
+		albumDescription: map[string]string{"Existing": "not updated"},
+	}
+	err := app.ManageAlbums(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for _, id := range []string{"id-existing", "New"} {
+		info, ok := ic.infos[id]
+		if !ok || info.Order == nil || *info.Order != immich.AlbumOrderAsc {
+			t.Errorf("the order of the album %s isn't set: %+v", id, info)
+		}
+	}
+	if d := ic.infos["id-existing"].Description; d != nil {
+		t.Errorf("the description of the existing album is updated without -update-album-meta: %q", *d)
+	}
+}
+
+// icOrderRefused is a server without the order of the albums
+type icOrderRefused struct {
+	icCatchAlbumBatches
+}
+
+func (c *icOrderRefused) UpdateAlbumInfo(ctx context.Context, id string, info immich.AlbumInfo) error {
+	if info.Order != nil {
+		return errors.New("400 Bad Request: property order should not exist")
+	}
+	return c.icCatchAlbumBatches.UpdateAlbumInfo(ctx, id, info)
+}
+
+func TestManageAlbumsOrderRefused(t *testing.T) {
+	ic := &icOrderRefused{icCatchAlbumBatches{batches: map[string][]int{}}}
+	app := UpCmd{
+		client:             ic,
+		Journal:            logger.NewJournal(logger.NoLogger{}),
+		AlbumComments:      true,
+		PreserveAlbumOrder: true,
+		updateAlbums: map[string]map[string]any{
+			"First":  {"1": nil},
+			"Second": {"2": nil},
+		},
+		albumDescription: map[string]string{"First": "first", "Second": "second"},
+	}
+	err := app.ManageAlbums(context.Background())
+	if err != nil {
+		t.Fatalf("the refused order stops the albums: %s", err)
+	}
+	if len(ic.created) != 2 {
+		t.Errorf("created albums = %v, want 2", ic.created)
+	}
+	for _, id := range []string{"First", "Second"} {
+		if info := ic.infos[id]; info.Description == nil {
+			t.Errorf("the description of the album %s isn't set", id)
+		}
+	}
+}
+
+type icReplace struct {
+	stubIC
+	uploads []string
+	albums  map[string][]string
+}
+
+func (c *icReplace) AssetUpload(ctx context.Context, a *browser.LocalAssetFile) (immich.AssetResponse, error) {
+	c.uploads = append(c.uploads, a.FileName)
+	return immich.AssetResponse{ID: "new-" + a.FileName}, nil
+}
+
+func (c *icReplace) GetAssetAlbums(ctx context.Context, id string) ([]immich.AlbumSimplified, error) {
+	return []immich.AlbumSimplified{{ID: "album-1", AlbumName: "Holidays"}}, nil
+}
+
+func (c *icReplace) AddAssetToAlbum(ctx context.Context, albumID string, IDs []string) ([]immich.UpdateAlbumResult, error) {
+	if c.albums == nil {
+		c.albums = map[string][]string{}
+	}
+	c.albums[albumID] = append(c.albums[albumID], IDs...)
+	return nil, nil
+}
+
+func TestForceReplace(t *testing.T) {
+	content := []byte("canonical raw content")
+	h := sha1.Sum(content)
+	checksum := base64.StdEncoding.EncodeToString(h[:])
+	date := time.Date(2023, 6, 23, 13, 32, 52, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		force       bool
+		serverSize  int
+		serverSum   string
+		wantUploads []string
+		wantDeleted []string
+		wantAlbums  map[string][]string
+	}{
+		{
+			name:       "better on server, not forced",
+			serverSize: 100,
+			serverSum:  "re-encoded",
+		},
+		{
+			name:        "better on server, forced",
+			force:       true,
+			serverSize:  100,
+			serverSum:   "re-encoded",
+			wantUploads: []string{"photo.cr3"},
+			wantDeleted: []string{"server-id"},
+			wantAlbums:  map[string][]string{"album-1": {"new-photo.cr3"}},
+		},
+		{
+			name:        "same on server, forced",
+			force:       true,
+			serverSize:  len(content),
+			serverSum:   "re-encoded",
+			wantUploads: []string{"photo.cr3"},
+			wantDeleted: []string{"server-id"},
+			wantAlbums:  map[string][]string{"album-1": {"new-photo.cr3"}},
+		},
+		{
+			name:       "same checksum, forced",
+			force:      true,
+			serverSize: 100,
+			serverSum:  checksum,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ic := &icReplace{}
+			app := UpCmd{
+				client:       ic,
+				Journal:      logger.NewJournal(logger.NoLogger{}),
+				ForceReplace: tt.force,
+				updateAlbums: map[string]map[string]any{},
+				AssetIndex: &AssetIndex{
+					assets: []*immich.Asset{{
+						ID:               "server-id",
+						OriginalFileName: "photo",
+						OriginalPath:     "upload/photo.cr3",
+						Checksum:         tt.serverSum,
+						ExifInfo: immich.ExifInfo{
+							FileSizeInByte:   tt.serverSize,
+							DateTimeOriginal: immich.ImmichTime{Time: date},
+						},
+					}},
+				},
+			}
+			app.AssetIndex.ReIndex()
+			a := &browser.LocalAssetFile{
+				FSys:      fstest.MapFS{"photo.cr3": {Data: content}},
+				FileName:  "photo.cr3",
+				Title:     "photo.cr3",
+				FileSize:  len(content),
+				DateTaken: date,
+			}
+			err := app.handleAsset(context.Background(), a)
+			if err != nil {
+				t.Errorf("unexpected error: %s", err)
+				return
+			}
+			if !reflect.DeepEqual(ic.uploads, tt.wantUploads) {
+				t.Errorf("uploads = %v, want %v", ic.uploads, tt.wantUploads)
+			}
+			deleted := []string(nil)
+			for _, sa := range app.deleteServerList {
+				deleted = append(deleted, sa.ID)
+			}
+			if !reflect.DeepEqual(deleted, tt.wantDeleted) {
+				t.Errorf("deleted = %v, want %v", deleted, tt.wantDeleted)
+			}
+			if !reflect.DeepEqual(ic.albums, tt.wantAlbums) {
+				t.Errorf("albums = %v, want %v", ic.albums, tt.wantAlbums)
+			}
+			if len(app.updateAlbums) > 0 {
+				t.Errorf("albums by name = %v, want none", app.updateAlbums)
+			}
+		})
+	}
+}
+
+type icDeleteLocal struct {
+	stubIC
+	duplicate bool
+	err       error
+}
+
+func (c *icDeleteLocal) AssetUpload(ctx context.Context, a *browser.LocalAssetFile) (immich.AssetResponse, error) {
+	return immich.AssetResponse{ID: "new-" + a.FileName, Duplicate: c.duplicate}, c.err
+}
+
+func TestDeleteLocal(t *testing.T) {
+	content := []byte("canonical raw content")
+	h := sha1.Sum(content)
+	checksum := base64.StdEncoding.EncodeToString(h[:])
+	date := time.Date(2023, 6, 23, 13, 32, 52, 0, time.UTC)
+
+	tests := []struct {
+		name              string
+		serverSize        int
+		serverSum         string
+		duplicate         bool
+		uploadErr         error
+		verify            bool
+		wantLocalDeleted  bool
+		wantServerDeleted bool
+	}{
+		{name: "uploaded", wantLocalDeleted: true},
+		{name: "duplicate upload", duplicate: true},
+		{name: "upload error", uploadErr: errors.New("server error")},
+		{name: "smaller on server", serverSize: 10, wantLocalDeleted: true, wantServerDeleted: true},
+		{name: "smaller on server, upload error", serverSize: 10, uploadErr: errors.New("server error")},
+		{name: "same on server, no checksum", serverSize: len(content)},
+		{name: "same on server", serverSize: len(content), serverSum: checksum, wantLocalDeleted: true},
+		{name: "same on server, checksum differs", serverSize: len(content), serverSum: "other"},
+		{name: "same on server, verified", serverSize: len(content), serverSum: checksum, verify: true, wantLocalDeleted: true},
+		{name: "same on server, checksum differs, verified", serverSize: len(content), serverSum: "other", verify: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ic := &icDeleteLocal{duplicate: tt.duplicate, err: tt.uploadErr}
+			app := UpCmd{
+				client:       ic,
+				Journal:      logger.NewJournal(logger.NoLogger{}),
+				Delete:       true,
+				Verify:       tt.verify,
+				updateAlbums: map[string]map[string]any{},
+				AssetIndex:   &AssetIndex{},
+			}
+			if tt.serverSize > 0 {
+				app.AssetIndex.assets = []*immich.Asset{{
+					ID:               "server-id",
+					OriginalFileName: "photo",
+					OriginalPath:     "upload/photo.cr3",
+					Checksum:         tt.serverSum,
+					ExifInfo: immich.ExifInfo{
+						FileSizeInByte:   tt.serverSize,
+						DateTimeOriginal: immich.ImmichTime{Time: date},
+					},
+				}}
+			}
+			app.AssetIndex.ReIndex()
+			a := &browser.LocalAssetFile{
+				FSys:      fstest.MapFS{"photo.cr3": {Data: content}},
+				FileName:  "photo.cr3",
+				Title:     "photo.cr3",
+				FileSize:  len(content),
+				DateTaken: date,
+			}
+			err := app.handleAsset(context.Background(), a)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if got := len(app.deleteLocalList) > 0; got != tt.wantLocalDeleted {
+				t.Errorf("local file deleted: %v, want %v", got, tt.wantLocalDeleted)
+			}
+			if got := len(app.deleteServerList) > 0; got != tt.wantServerDeleted {
+				t.Errorf("server asset deleted: %v, want %v", got, tt.wantServerDeleted)
+			}
+		})
+	}
+}
+
+func TestExcludeAlbums(t *testing.T) {
+	tests := []struct {
+		name       string
+		albums     []string
+		wantAlbums []string
+		excluded   bool
+	}{
+		{name: "no album", albums: nil},
+		{name: "only excluded", albums: []string{"Screenshots"}, excluded: true},
+		{name: "all excluded", albums: []string{"Screenshots", "Downloads"}, excluded: true},
+		{name: "also in another album", albums: []string{"Screenshots", "Holidays"}, wantAlbums: []string{"Holidays"}},
+		{name: "not excluded", albums: []string{"Holidays"}, wantAlbums: []string{"Holidays"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := UpCmd{
+				client:        &stubIC{},
+				Journal:       logger.NewJournal(logger.NoLogger{}),
+				GooglePhotos:  true,
+				ExcludeAlbums: []string{"Screenshots", "Downloads"},
+				AssetIndex:    &AssetIndex{},
+				updateAlbums:  map[string]map[string]any{},
+			}
+			app.AssetIndex.ReIndex()
+			a := &browser.LocalAssetFile{
+				FSys:     fstest.MapFS{"photo.cr3": {Data: []byte("content")}},
+				FileName: "photo.cr3",
+				Title:    "photo.cr3",
+				FileSize: 7,
+			}
+			for _, al := range tt.albums {
+				a.Albums = append(a.Albums, browser.LocalAlbum{Path: al, Name: al})
+			}
+			err := app.handleAsset(context.Background(), a)
+			if err != nil {
+				t.Errorf("unexpected error: %s", err)
+				return
+			}
+			if got := app.Journal.Count(logger.NOT_SELECTED) == 1; got != tt.excluded {
+				t.Errorf("asset excluded: %v, want %v", got, tt.excluded)
+			}
+			if tt.excluded {
+				return
+			}
+			got := []string(nil)
+			for _, al := range a.Albums {
+				got = append(got, al.Name)
+			}
+			if !reflect.DeepEqual(got, tt.wantAlbums) {
+				t.Errorf("albums = %v, want %v", got, tt.wantAlbums)
+			}
+		})
+	}
+}
+
+type icVersion struct {
+	stubIC
+	version immich.ServerVersion
+	err     error
+}
+
+func (c *icVersion) GetServerVersion(ctx context.Context) (immich.ServerVersion, error) {
+	return c.version, c.err
+}
+
+func TestCheckServerVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		version immich.ServerVersion
+		err     error
+		strict  bool
+		wantErr bool
+	}{
+		{name: "supported", version: immich.ServerVersion{Major: 1, Minor: 94, Patch: 1}},
+		{name: "supported, strict", version: immich.ServerVersion{Major: 1, Minor: 94, Patch: 1}, strict: true},
+		{name: "too old", version: immich.ServerVersion{Major: 1, Minor: 70}},
+		{name: "too old, strict", version: immich.ServerVersion{Major: 1, Minor: 70}, strict: true, wantErr: true},
+		{name: "too new, strict", version: immich.ServerVersion{Major: 1, Minor: 106}, strict: true, wantErr: true},
+		{name: "unknown", err: errors.New("not found")},
+		{name: "unknown, strict", err: errors.New("not found"), strict: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := UpCmd{
+				client:        &icVersion{version: tt.version, err: tt.err},
+				Journal:       logger.NewJournal(logger.NoLogger{}),
+				StrictVersion: tt.strict,
+			}
+			err := app.checkServerVersion(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkServerVersion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && app.serverVersion != tt.version {
+				t.Errorf("server version = %s, want %s", app.serverVersion, tt.version)
+			}
+		})
+	}
+}
+
+type icUser struct {
+	stubIC
+	user immich.User
+	err  error
+}
+
+func (c *icUser) ValidateConnection(ctx context.Context) (immich.User, error) {
+	return c.user, c.err
+}
+
+func TestCheckUser(t *testing.T) {
+	quota := int64(1000)
+	tests := []struct {
+		name    string
+		client  iClient
+		dryRun  bool
+		wantErr bool
+	}{
+		{name: "no validation", client: &stubIC{}},
+		{name: "authenticated", client: &icUser{user: immich.User{Email: "me@example.com"}}},
+		{name: "quota available", client: &icUser{user: immich.User{Email: "me@example.com", QuotaSizeInBytes: &quota, QuotaUsageInBytes: 10}}},
+		{name: "quota full", client: &icUser{user: immich.User{Email: "me@example.com", QuotaSizeInBytes: &quota, QuotaUsageInBytes: 1000}}, wantErr: true},
+		{name: "quota full, dry run", client: &icUser{user: immich.User{Email: "me@example.com", QuotaSizeInBytes: &quota, QuotaUsageInBytes: 1000}}, dryRun: true},
+		{name: "refused key", client: &icUser{err: immich.ErrUnauthorized}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := UpCmd{
+				client:  tt.client,
+				Journal: logger.NewJournal(logger.NoLogger{}),
+				DryRun:  tt.dryRun,
+			}
+			err := app.checkUser(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkUser() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
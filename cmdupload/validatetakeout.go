@@ -0,0 +1,29 @@
+package cmdupload
+
+import (
+	"context"
+	"io/fs"
+
+	"github.com/simulot/immich-go/browser/gp"
+)
+
+// validateTakeout reads the takeout and reports its content, without uploading anything
+func (app *UpCmd) validateTakeout(ctx context.Context, fsyss []fs.FS) error {
+	to, err := gp.NewTakeout(ctx, app.Journal, fsyss...)
+	if err != nil {
+		return err
+	}
+	inv := to.Inventory()
+	app.Journal.OK("Takeout content:")
+	app.Journal.OK("%6d media files with their JSON", inv.Matched)
+	app.Journal.OK("%6d media files without JSON, not uploaded", inv.WithoutJSON)
+	app.Journal.OK("%6d JSON files without media file", inv.Orphans)
+	app.Journal.OK("%6d albums", inv.Albums)
+	app.Journal.OK("%6d partner's assets", inv.Partner)
+	app.Journal.OK("%6d archived assets", inv.Archived)
+	app.Journal.OK("%6d trashed assets", inv.Trashed)
+	if inv.WithoutJSON > 0 || inv.Orphans > 0 {
+		app.Journal.Warning("Some media files and JSON files aren't paired, check that all the parts of the takeout are given")
+	}
+	return nil
+}
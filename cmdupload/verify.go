@@ -0,0 +1,56 @@
+package cmdupload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/logger"
+)
+
+var errVerifyFailed = errors.New("the asset on the server differs from the file")
+
+// verifyUpload checks the asset just uploaded. When it differs from the file,
+// the server's asset is deleted and the file is uploaded again, once.
+func (app *UpCmd) verifyUpload(ctx context.Context, a *browser.LocalAssetFile, resp immich.AssetResponse) (immich.AssetResponse, error) {
+	err := app.checkServerAsset(ctx, a, resp.ID)
+	if !errors.Is(err, errVerifyFailed) {
+		return resp, err
+	}
+	app.journalAsset(a, logger.VERIFY_FAILED, err.Error())
+	err = app.client.DeleteAssets(ctx, []string{resp.ID}, true)
+	if err != nil {
+		return resp, err
+	}
+	a.Close()
+	resp, err = app.client.AssetUpload(ctx, a)
+	if err != nil || resp.Duplicate {
+		return resp, err
+	}
+	return resp, app.checkServerAsset(ctx, a, resp.ID)
+}
+
+// checkServerAsset compares the size and the checksum given by the server with the file ones
+func (app *UpCmd) checkServerAsset(ctx context.Context, a *browser.LocalAssetFile, ID string) error {
+	sa, err := app.client.GetAssetByID(ctx, ID)
+	if err != nil {
+		return fmt.Errorf("can't verify the upload: %w", err)
+	}
+	// The size is known once the server has extracted the metadata
+	if size := sa.ExifInfo.FileSizeInByte; size != 0 && a.FileSize != 0 && size != a.FileSize {
+		return fmt.Errorf("%w: size on the server %d, file size %d", errVerifyFailed, size, a.FileSize)
+	}
+	if sa.Checksum == "" {
+		return nil
+	}
+	sum, err := a.Checksum()
+	if err != nil {
+		return fmt.Errorf("can't verify the upload: %w", err)
+	}
+	if sum != sa.Checksum {
+		return fmt.Errorf("%w: checksum on the server %s, file checksum %s", errVerifyFailed, sa.Checksum, sum)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package cmdupload
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/logger"
+)
+
+// icTruncatedUploads gives a wrong checksum for the first uploads
+type icTruncatedUploads struct {
+	stubIC
+	uploads   int
+	truncated int // number of truncated uploads
+	checksum  string
+	deleted   []string
+}
+
+func (c *icTruncatedUploads) AssetUpload(ctx context.Context, a *browser.LocalAssetFile) (immich.AssetResponse, error) {
+	c.uploads++
+	return immich.AssetResponse{ID: fmt.Sprintf("id-%d", c.uploads)}, nil
+}
+
+func (c *icTruncatedUploads) GetAssetByID(ctx context.Context, id string) (*immich.Asset, error) {
+	sum := c.checksum
+	if c.uploads <= c.truncated {
+		sum = "truncated"
+	}
+	return &immich.Asset{ID: id, Checksum: sum}, nil
+}
+
+func (c *icTruncatedUploads) DeleteAssets(ctx context.Context, ids []string, force bool) error {
+	c.deleted = append(c.deleted, ids...)
+	return nil
+}
+
+func TestUploadVerify(t *testing.T) {
+	content := []byte("photo content")
+	h := sha1.Sum(content)
+	checksum := base64.StdEncoding.EncodeToString(h[:])
+
+	tests := []struct {
+		name         string
+		truncated    int
+		wantID       string
+		wantErr      bool
+		wantDeleted  []string
+		wantFailures []string
+	}{
+		{name: "verified", wantID: "id-1"},
+		{name: "uploaded again", truncated: 1, wantID: "id-2", wantDeleted: []string{"id-1"}},
+		{name: "failed twice", truncated: 2, wantErr: true, wantDeleted: []string{"id-1"}, wantFailures: []string{"photo.jpg"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ic := &icTruncatedUploads{truncated: tt.truncated, checksum: checksum}
+			app := UpCmd{
+				client:      ic,
+				Journal:     logger.NewJournal(logger.NoLogger{}),
+				Verify:      true,
+				AssetIndex:  &AssetIndex{},
+				FailuresOut: "failures.txt",
+			}
+			app.AssetIndex.ReIndex()
+			a := &browser.LocalAssetFile{
+				FSys:     fstest.MapFS{"photo.jpg": {Data: content}},
+				FileName: "photo.jpg",
+				Title:    "photo.jpg",
+				FileSize: len(content),
+			}
+			ID, err := app.UploadAsset(context.Background(), a)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("UploadAsset()=%v, expecting error: %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && ID != tt.wantID {
+				t.Errorf("UploadAsset() gives the ID %q, want %q", ID, tt.wantID)
+			}
+			if !reflect.DeepEqual(ic.deleted, tt.wantDeleted) {
+				t.Errorf("deleted assets = %v, want %v", ic.deleted, tt.wantDeleted)
+			}
+			if tt.truncated > 0 && app.Journal.Count(logger.VERIFY_FAILED) != 1 {
+				t.Errorf("expecting one VERIFY_FAILED entry, got %d", app.Journal.Count(logger.VERIFY_FAILED))
+			}
+			if !slices.Equal(app.failureList, tt.wantFailures) || len(app.failures) != len(tt.wantFailures) {
+				t.Errorf("failures = %v, want %v", app.failureList, tt.wantFailures)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package fshelper
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"hash"
+	"io"
+)
+
+// ChecksumReader computes the checksum of the data read through it,
+// so the file is hashed while it is read for another purpose, like its upload.
+type ChecksumReader struct {
+	r    io.Reader
+	h    hash.Hash
+	size int64 // expected size of the data, 0 when unknown
+	read int64 // bytes read so far
+	done bool  // the end of the data is reached
+}
+
+// NewChecksumReader hashes the data read from r. When size is known, the data is
+// complete once size bytes are read, even if the reader, like an io.LimitReader,
+// is never read until its end.
+func NewChecksumReader(r io.Reader, size int64) *ChecksumReader {
+	return &ChecksumReader{r: r, h: sha1.New(), size: size}
+}
+
+func (cr *ChecksumReader) Read(b []byte) (int, error) {
+	n, err := cr.r.Read(b)
+	cr.h.Write(b[:n])
+	cr.read += int64(n)
+	if err == io.EOF || (cr.size > 0 && cr.read >= cr.size) {
+		cr.done = true
+	}
+	return n, err
+}
+
+// Sum gives the checksum once all the data is read
+func (cr *ChecksumReader) Sum() (string, bool) {
+	if !cr.done {
+		return "", false
+	}
+	return encodeChecksum(cr.h), true
+}
+
+// Checksum reads r until its end, and gives the base64 encoded SHA1 of its content,
+// as given by the server
+func Checksum(r io.Reader) (string, error) {
+	h := sha1.New()
+	_, err := io.Copy(h, r)
+	if err != nil {
+		return "", err
+	}
+	return encodeChecksum(h), nil
+}
+
+func encodeChecksum(h hash.Hash) string {
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,41 @@
+package fshelper
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestChecksum(t *testing.T) {
+	// base64 encoded SHA1, as given by the server
+	const want = "qZk+NkcGgWq6PiVxeFDCbJzQ2J0="
+	sum, err := Checksum(bytes.NewReader([]byte("abc")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum != want {
+		t.Errorf("Checksum = %s, want %s", sum, want)
+	}
+
+	cr := NewChecksumReader(bytes.NewReader([]byte("abc")), 0)
+	if _, ok := cr.Sum(); ok {
+		t.Error("the checksum is given before the end of the data")
+	}
+	_, err = io.Copy(io.Discard, cr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum, ok := cr.Sum(); !ok || sum != want {
+		t.Errorf("ChecksumReader.Sum = %s, %v, want %s", sum, ok, want)
+	}
+
+	// the size is read without reaching the end of the reader
+	cr = NewChecksumReader(bytes.NewReader([]byte("abc")), 3)
+	b := make([]byte, 3)
+	if _, err = io.ReadFull(cr, b); err != nil {
+		t.Fatal(err)
+	}
+	if sum, ok := cr.Sum(); !ok || sum != want {
+		t.Errorf("ChecksumReader.Sum after the size = %s, %v, want %s", sum, ok, want)
+	}
+}
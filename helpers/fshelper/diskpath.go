@@ -0,0 +1,33 @@
+package fshelper
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+)
+
+var ErrNotOnDisk = errors.New("the file isn't in a folder of the disk")
+
+// DiskPather is a file system whose files are files of the disk
+type DiskPather interface {
+	DiskPath(name string) (string, error)
+}
+
+// DiskPath gives the absolute path on the disk of the file of the file system
+func DiskPath(fsys fs.FS, name string) (string, error) {
+	if fsys, ok := fsys.(DiskPather); ok {
+		return fsys.DiskPath(name)
+	}
+	return "", ErrNotOnDisk
+}
+
+func (fsys dirFS) DiskPath(name string) (string, error) {
+	return filepath.Abs(filepath.Join(fsys.dir, filepath.FromSlash(name)))
+}
+
+func (fsys pathFS) DiskPath(name string) (string, error) {
+	if !fsys.listed(name) {
+		return "", fs.ErrNotExist
+	}
+	return filepath.Abs(filepath.Join(fsys.dir, filepath.FromSlash(name)))
+}
@@ -80,6 +80,32 @@ func MimeFromExt(ext string) ([]string, error) {
 	return nil, fmt.Errorf("unsupported extension %s", ext)
 }
 
+// Types of media handled by the server
+const (
+	TypeImage = "image"
+	TypeVideo = "video"
+)
+
+// TypeFromExt gives the type of media of the extension, TypeImage or TypeVideo.
+// Return an empty string when the extension is not handled by the server.
+func TypeFromExt(ext string) string {
+	m, err := MimeFromExt(ext)
+	if err != nil {
+		return ""
+	}
+	return TypeFromMime(m[0])
+}
+
+// TypeFromMime gives the type of media of the mime type, TypeImage or TypeVideo.
+func TypeFromMime(mtype string) string {
+	t, _, _ := strings.Cut(mtype, "/")
+	switch t {
+	case TypeImage, TypeVideo:
+		return t
+	}
+	return ""
+}
+
 // IsExtensionPrefix
 // Check if the string is first part of an known extension as needed for Google Takeout
 
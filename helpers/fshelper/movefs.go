@@ -0,0 +1,96 @@
+package fshelper
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+var ErrNotMovable = errors.New("the file isn't in a folder and can't be moved")
+
+// Mover is a file system whose files can be moved to another place of the disk
+type Mover interface {
+	Move(name string, dest string) error
+}
+
+// Move the file of the file system to the path dest on the disk
+func Move(fsys fs.FS, name string, dest string) error {
+	if fsys, ok := fsys.(Mover); ok {
+		return fsys.Move(name, dest)
+	}
+	return ErrNotMovable
+}
+
+// dirFS is a folder of the disk whose files can be moved
+type dirFS struct {
+	fs.FS
+	dir string
+}
+
+func newDirFS(dir string) fs.FS {
+	return &dirFS{FS: os.DirFS(dir), dir: dir}
+}
+
+func (fsys dirFS) Move(name string, dest string) error {
+	return MoveFile(filepath.Join(fsys.dir, filepath.FromSlash(name)), dest)
+}
+
+func (fsys pathFS) Move(name string, dest string) error {
+	if !fsys.listed(name) {
+		return fs.ErrNotExist
+	}
+	return MoveFile(filepath.Join(fsys.dir, filepath.FromSlash(name)), dest)
+}
+
+// MoveFile moves the file src to dest, creating the folders of dest when needed.
+// An existing dest is never overwritten. When the file can't be renamed, like when
+// dest is on another file system, the file is copied and then removed.
+func MoveFile(src string, dest string) error {
+	if _, err := os.Lstat(dest); err == nil {
+		return fmt.Errorf("can't move %s: %s %w", src, dest, fs.ErrExist)
+	}
+	err := os.MkdirAll(filepath.Dir(dest), 0o755)
+	if err != nil {
+		return err
+	}
+	if os.Rename(src, dest) == nil {
+		return nil
+	}
+	err = copyFile(src, dest)
+	if err != nil {
+		return fmt.Errorf("can't move %s: %w", src, err)
+	}
+	return os.Remove(src)
+}
+
+// copyFile copies the content, the permissions and the modification time of the file.
+// The incomplete copy is removed on error.
+func copyFile(src string, dest string) error {
+	r, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	fi, err := r.Stat()
+	if err != nil {
+		return err
+	}
+	w, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, fi.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, r)
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chtimes(dest, fi.ModTime(), fi.ModTime())
+	}
+	if err != nil {
+		os.Remove(dest)
+	}
+	return err
+}
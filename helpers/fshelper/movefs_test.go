@@ -0,0 +1,72 @@
+package fshelper
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestMove(t *testing.T) {
+	src := t.TempDir()
+	dest := t.TempDir()
+	for _, f := range []string{"a/photo.jpg", "a/other.jpg"} {
+		p := filepath.Join(src, filepath.FromSlash(f))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(f), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dest, "other.jpg"), []byte("existing"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fsys := newDirFS(src)
+
+	err := Move(fsys, "a/photo.jpg", filepath.Join(dest, "b", "photo.jpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, err := os.ReadFile(filepath.Join(dest, "b", "photo.jpg")); err != nil || string(b) != "a/photo.jpg" {
+		t.Errorf("unexpected moved file %q, %v", b, err)
+	}
+	if _, err := os.Stat(filepath.Join(src, "a", "photo.jpg")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("the source is still there: %v", err)
+	}
+
+	err = Move(fsys, "a/other.jpg", filepath.Join(dest, "other.jpg"))
+	if !errors.Is(err, fs.ErrExist) {
+		t.Errorf("expecting fs.ErrExist, got %v", err)
+	}
+	if b, _ := os.ReadFile(filepath.Join(dest, "other.jpg")); string(b) != "existing" {
+		t.Errorf("the existing file is overwritten: %q", b)
+	}
+
+	err = Move(fstest.MapFS{"photo.jpg": {}}, "photo.jpg", filepath.Join(dest, "photo.jpg"))
+	if !errors.Is(err, ErrNotMovable) {
+		t.Errorf("expecting ErrNotMovable, got %v", err)
+	}
+}
+
+func TestCopyFile(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.jpg")
+	if err := os.WriteFile(src, []byte("content"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	dest := filepath.Join(dir, "dest.jpg")
+	if err := copyFile(src, dest); err != nil {
+		t.Fatal(err)
+	}
+	si, _ := os.Stat(src)
+	di, err := os.Stat(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if di.Size() != si.Size() || !di.ModTime().Equal(si.ModTime()) {
+		t.Errorf("unexpected copy: %d %s, want %d %s", di.Size(), di.ModTime(), si.Size(), si.ModTime())
+	}
+}
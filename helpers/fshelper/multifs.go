@@ -1,17 +1,16 @@
 package fshelper
 
 import (
-	"archive/zip"
 	"io/fs"
 
 	"github.com/yalue/merged_fs"
 )
 
-func multiZip(names ...string) (fs.FS, error) {
+func multiZip(passwords ZipPasswords, names ...string) (fs.FS, error) {
 	fss := []fs.FS{}
 
 	for _, p := range names {
-		fsys, err := zip.OpenReader(p)
+		fsys, err := openZip(p, passwords.Get(p))
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,50 @@
+package myflag
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ByteSize is a flag.Value for sizes given in bytes, with an optional unit suffix.
+// Accepted values are like 1024, 500K, 500KB, 1.5M, 2G. Units are powers of 1024.
+type ByteSize int64
+
+var byteSizeUnits = []struct {
+	suffix string
+	factor float64
+}{
+	{"TB", 1 << 40}, {"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10},
+	{"T", 1 << 40}, {"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10},
+	{"B", 1},
+}
+
+func (bs *ByteSize) Set(s string) error {
+	v := strings.ToUpper(strings.TrimSpace(s))
+	factor := 1.0
+	for _, u := range byteSizeUnits {
+		if strings.HasSuffix(v, u.suffix) {
+			v = strings.TrimSpace(strings.TrimSuffix(v, u.suffix))
+			factor = u.factor
+			break
+		}
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || f < 0 {
+		return fmt.Errorf("invalid size %q", s)
+	}
+	*bs = ByteSize(f * factor)
+	return nil
+}
+
+func (bs ByteSize) String() string {
+	if bs == 0 {
+		return ""
+	}
+	for _, u := range byteSizeUnits[4:] {
+		if int64(bs)%int64(u.factor) == 0 {
+			return strconv.FormatInt(int64(bs)/int64(u.factor), 10) + u.suffix
+		}
+	}
+	return strconv.FormatInt(int64(bs), 10)
+}
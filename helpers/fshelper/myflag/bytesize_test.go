@@ -0,0 +1,34 @@
+package myflag
+
+import "testing"
+
+func TestByteSize(t *testing.T) {
+	tc := []struct {
+		value   string
+		want    ByteSize
+		wantErr bool
+	}{
+		{value: "1024", want: 1024},
+		{value: "500K", want: 500 * 1024},
+		{value: "500kb", want: 500 * 1024},
+		{value: "1.5M", want: 1536 * 1024},
+		{value: "2G", want: 2 * 1024 * 1024 * 1024},
+		{value: "10 MB", want: 10 * 1024 * 1024},
+		{value: "12B", want: 12},
+		{value: "big", wantErr: true},
+		{value: "-1K", wantErr: true},
+	}
+	for _, c := range tc {
+		t.Run(c.value, func(t *testing.T) {
+			var bs ByteSize
+			err := bs.Set(c.value)
+			if (err == nil && c.wantErr) || (err != nil && !c.wantErr) {
+				t.Errorf("Set(%q)=%v, expecting error: %v", c.value, err, c.wantErr)
+				return
+			}
+			if bs != c.want {
+				t.Errorf("Set(%q) gives %d, expecting: %d", c.value, bs, c.want)
+			}
+		})
+	}
+}
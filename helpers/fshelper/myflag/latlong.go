@@ -0,0 +1,55 @@
+package myflag
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// LatLong is a flag.Value for GPS coordinates given as "latitude,longitude" in decimal degrees
+type LatLong struct {
+	Latitude  float64
+	Longitude float64
+	set       bool
+}
+
+func (ll *LatLong) Set(s string) error {
+	lat, long, err := ParseLatLong(s)
+	if err != nil {
+		return err
+	}
+	ll.Latitude, ll.Longitude, ll.set = lat, long, true
+	return nil
+}
+
+func (ll LatLong) String() string {
+	if !ll.set {
+		return ""
+	}
+	return strconv.FormatFloat(ll.Latitude, 'f', -1, 64) + "," + strconv.FormatFloat(ll.Longitude, 'f', -1, 64)
+}
+
+// IsSet tells if the coordinates have been given
+func (ll LatLong) IsSet() bool {
+	return ll.set
+}
+
+// ParseLatLong parses coordinates given as "latitude,longitude" in decimal degrees
+func ParseLatLong(s string) (float64, float64, error) {
+	lat, long, ok := strings.Cut(s, ",")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid coordinates %q, expecting latitude,longitude", s)
+	}
+	la, err := strconv.ParseFloat(strings.TrimSpace(lat), 64)
+	if err != nil || la < -90 || la > 90 {
+		return 0, 0, fmt.Errorf("invalid latitude in %q", s)
+	}
+	lo, err := strconv.ParseFloat(strings.TrimSpace(long), 64)
+	if err != nil || lo < -180 || lo > 180 {
+		return 0, 0, fmt.Errorf("invalid longitude in %q", s)
+	}
+	if la == 0 && lo == 0 {
+		return 0, 0, fmt.Errorf("invalid coordinates %q, 0,0 means no location", s)
+	}
+	return la, lo, nil
+}
@@ -0,0 +1,36 @@
+package myflag
+
+import "testing"
+
+func TestLatLong(t *testing.T) {
+	tc := []struct {
+		value    string
+		wantLat  float64
+		wantLong float64
+		wantErr  bool
+	}{
+		{value: "48.8584,2.2945", wantLat: 48.8584, wantLong: 2.2945},
+		{value: "-33.8568, 151.2153", wantLat: -33.8568, wantLong: 151.2153},
+		{value: "48.8584", wantErr: true},
+		{value: "91,2", wantErr: true},
+		{value: "48,181", wantErr: true},
+		{value: "north,east", wantErr: true},
+		{value: "0,0", wantErr: true},
+	}
+	for _, c := range tc {
+		t.Run(c.value, func(t *testing.T) {
+			var ll LatLong
+			err := ll.Set(c.value)
+			if (err == nil && c.wantErr) || (err != nil && !c.wantErr) {
+				t.Errorf("Set(%q)=%v, expecting error: %v", c.value, err, c.wantErr)
+				return
+			}
+			if ll.IsSet() == c.wantErr {
+				t.Errorf("Set(%q): IsSet()=%v", c.value, ll.IsSet())
+			}
+			if ll.Latitude != c.wantLat || ll.Longitude != c.wantLong {
+				t.Errorf("Set(%q) gives %v,%v, expecting: %v,%v", c.value, ll.Latitude, ll.Longitude, c.wantLat, c.wantLong)
+			}
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/simulot/immich-go/helpers/gen"
@@ -21,7 +22,7 @@ type argParser struct {
 	err          error
 }
 
-func ParsePath(args []string, googlePhoto bool) ([]fs.FS, error) {
+func ParsePath(args []string, googlePhoto bool, passwords ZipPasswords) ([]fs.FS, error) {
 	p := argParser{
 		googlePhotos: googlePhoto,
 		unsupported:  map[string]any{},
@@ -61,7 +62,11 @@ func ParsePath(args []string, googlePhoto bool) ([]fs.FS, error) {
 		p.paths[d] = l
 	}
 
-	for pa, l := range p.paths {
+	// Browse the folders in a predictable order
+	folders := gen.MapKeys(p.paths)
+	sort.Strings(folders)
+	for _, pa := range folders {
+		l := p.paths[pa]
 		if len(l) > 0 {
 			f, err := newPathFS(pa, l)
 			if err != nil {
@@ -70,12 +75,12 @@ func ParsePath(args []string, googlePhoto bool) ([]fs.FS, error) {
 				fsys = append(fsys, f)
 			}
 		} else {
-			fsys = append(fsys, os.DirFS(pa))
+			fsys = append(fsys, newDirFS(pa))
 		}
 	}
 
 	if len(p.zips) > 0 {
-		f, err := multiZip(p.zips...)
+		f, err := multiZip(passwords, p.zips...)
 		if err != nil {
 			p.err = errors.Join(err)
 		} else {
@@ -0,0 +1,96 @@
+package fshelper
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"strings"
+
+	_ "image/gif"  // register the GIF decoder
+	_ "image/jpeg" // register the JPEG decoder
+	_ "image/png"  // register the PNG decoder
+)
+
+// ErrInvalidContent is returned when the file content can't be a media
+var ErrInvalidContent = errors.New("invalid file content")
+
+// SniffMimeType returns the mime type detected from the first bytes of a file, or an empty string
+func SniffMimeType(head []byte) string {
+	switch {
+	case bytes.HasPrefix(head, []byte{0xFF, 0xD8, 0xFF}):
+		return "image/jpeg"
+	case bytes.HasPrefix(head, []byte("\x89PNG\r\n\x1a\n")):
+		return "image/png"
+	case bytes.HasPrefix(head, []byte("GIF87a")), bytes.HasPrefix(head, []byte("GIF89a")):
+		return "image/gif"
+	case bytes.HasPrefix(head, []byte("II*\x00")), bytes.HasPrefix(head, []byte("MM\x00*")):
+		return "image/tiff"
+	case len(head) >= 12 && bytes.Equal(head[:4], []byte("RIFF")) && bytes.Equal(head[8:12], []byte("WEBP")):
+		return "image/webp"
+	case len(head) >= 12 && bytes.Equal(head[:4], []byte("RIFF")) && bytes.Equal(head[8:12], []byte("AVI ")):
+		return "video/avi"
+	case bytes.HasPrefix(head, []byte{0xFF, 0x0A}), bytes.HasPrefix(head, []byte("\x00\x00\x00\x0cJXL \r\n\x87\n")):
+		return "image/jxl"
+	case bytes.HasPrefix(head, []byte{0x1A, 0x45, 0xDF, 0xA3}):
+		return "video/x-matroska"
+	case len(head) >= 12 && bytes.Equal(head[4:8], []byte("ftyp")):
+		brand := string(head[8:12])
+		switch {
+		case brand == "heic", brand == "heix", brand == "heim", brand == "heis", brand == "hevc", brand == "hevx", brand == "mif1", brand == "msf1":
+			return "image/heic"
+		case brand == "avif", brand == "avis":
+			return "image/avif"
+		case brand == "qt  ":
+			return "video/quicktime"
+		case strings.HasPrefix(brand, "3g"):
+			return "video/3gpp"
+		default:
+			return "video/mp4"
+		}
+	case len(head) >= 8:
+		switch string(head[4:8]) {
+		case "moov", "mdat", "wide", "free", "skip", "pnot":
+			return "video/quicktime"
+		}
+	}
+	return ""
+}
+
+// extensions for which the content is checked
+var checkedExtensions = map[string]bool{
+	".jpg": true, ".jpeg": true, ".jpe": true, ".png": true, ".gif": true, ".webp": true,
+	".heic": true, ".heif": true, ".avif": true, ".jxl": true, ".tif": true, ".tiff": true,
+	".mp4": true, ".m4v": true, ".mov": true, ".3gp": true, ".avi": true, ".mkv": true, ".webm": true,
+}
+
+// ValidateContent checks that the content read from r is a media that could be handled by the server.
+// Only well known formats are checked, other files are considered as valid.
+// JPEG, PNG and GIF headers are decoded to detect truncated files.
+func ValidateContent(ext string, r io.Reader) error {
+	ext = strings.ToLower(ext)
+	if !checkedExtensions[ext] {
+		return nil
+	}
+	head := make([]byte, 512)
+	n, err := io.ReadFull(r, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return err
+	}
+	head = head[:n]
+	if n == 0 {
+		return fmt.Errorf("%w: empty file", ErrInvalidContent)
+	}
+	mime := SniffMimeType(head)
+	switch mime {
+	case "":
+		return fmt.Errorf("%w: unknown format for a %s file", ErrInvalidContent, ext)
+	case "image/jpeg", "image/png", "image/gif":
+		_, _, err = image.DecodeConfig(io.MultiReader(bytes.NewReader(head), r))
+		if err != nil {
+			return fmt.Errorf("%w: can't decode the %s header: %s", ErrInvalidContent, mime, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package fshelper
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/jpeg"
+	"testing"
+)
+
+func TestValidateContent(t *testing.T) {
+	b := bytes.NewBuffer(nil)
+	err := jpeg.Encode(b, image.NewRGBA(image.Rect(0, 0, 16, 16)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	validJPEG := b.Bytes()
+
+	tc := []struct {
+		name    string
+		ext     string
+		content []byte
+		invalid bool
+	}{
+		{name: "valid jpeg", ext: ".jpg", content: validJPEG},
+		{name: "truncated jpeg", ext: ".JPG", content: validJPEG[:10], invalid: true},
+		{name: "empty jpeg", ext: ".jpg", content: nil, invalid: true},
+		{name: "text as jpeg", ext: ".jpeg", content: []byte("<html><body>not found</body></html>"), invalid: true},
+		{name: "png as jpeg", ext: ".jpg", content: []byte("\x89PNG\r\n\x1a\n"), invalid: true},
+		{name: "mp4", ext: ".mp4", content: []byte("\x00\x00\x00\x18ftypmp42\x00\x00\x00\x00mp42isom")},
+		{name: "mov without ftyp", ext: ".mov", content: []byte("\x00\x00\x00\x08wide\x00\x00\x00\x10mdat")},
+		{name: "heic", ext: ".heic", content: []byte("\x00\x00\x00\x18ftypheic\x00\x00\x00\x00mif1heic")},
+		{name: "avif", ext: ".avif", content: []byte("\x00\x00\x00\x1cftypavif\x00\x00\x00\x00avifmif1miaf")},
+		{name: "webp", ext: ".webp", content: []byte("RIFF\x24\x00\x00\x00WEBPVP8 ")},
+		{name: "jxl codestream", ext: ".jxl", content: []byte("\xff\x0a\xfa\x1f")},
+		{name: "jxl container", ext: ".JXL", content: []byte("\x00\x00\x00\x0cJXL \r\n\x87\n\x00\x00\x00\x14ftypjxl ")},
+		{name: "garbage mp4", ext: ".mp4", content: []byte("garbage content"), invalid: true},
+		{name: "raw not checked", ext: ".cr3", content: []byte("garbage content")},
+	}
+	for _, c := range tc {
+		t.Run(c.name, func(t *testing.T) {
+			err := ValidateContent(c.ext, bytes.NewReader(c.content))
+			if c.invalid != errors.Is(err, ErrInvalidContent) {
+				t.Errorf("ValidateContent()=%v, expecting invalid: %v", err, c.invalid)
+			}
+		})
+	}
+}
@@ -0,0 +1,316 @@
+package fshelper
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+var (
+	ErrZipPasswordMissing = errors.New("the archive is encrypted, a password is needed")
+	ErrZipPasswordWrong   = errors.New("wrong password for the archive")
+	ErrZipEncryption      = errors.New("unsupported encryption, only the AES encryption is supported")
+	ErrZipAuthentication  = errors.New("the authentication code of the encrypted data is invalid")
+)
+
+const (
+	zipMethodAES  = 99     // method of the entries encrypted with AES
+	zipExtraAES   = 0x9901 // id of the extra field describing the AES encryption
+	zipAuthLen    = 10     // length of the authentication code after the encrypted data
+	zipVerifyLen  = 2      // length of the password verifier after the salt
+	zipIterations = 1000   // PBKDF2 iterations of the WinZip AES specification
+)
+
+// ZipPasswords gives the passwords of the encrypted archives.
+// The password given without archive name applies to all archives.
+type ZipPasswords map[string]string
+
+// Set register a password given as password, or as name.zip=password
+func (zp *ZipPasswords) Set(s string) error {
+	name, pw := "", s
+	if n, p, ok := strings.Cut(s, "="); ok && strings.ToLower(filepath.Ext(n)) == ".zip" {
+		name, pw = n, p
+	}
+	if pw == "" {
+		return errors.New("the password can't be empty")
+	}
+	if *zp == nil {
+		*zp = ZipPasswords{}
+	}
+	(*zp)[name] = pw
+	return nil
+}
+
+// String doesn't reveal the passwords
+func (zp ZipPasswords) String() string {
+	l := []string{}
+	for n := range zp {
+		if n == "" {
+			n = "*"
+		}
+		l = append(l, n+"=***")
+	}
+	return strings.Join(l, ",")
+}
+
+// ReadFile registers the passwords given by the lines of the file, like the values of Set.
+// Empty lines and lines starting with # are ignored.
+func (zp *ZipPasswords) ReadFile(name string) error {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	for _, l := range strings.Split(string(b), "\n") {
+		l = strings.TrimSpace(l)
+		if l == "" || strings.HasPrefix(l, "#") {
+			continue
+		}
+		err = zp.Set(l)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Get the password of the archive, by its path, its base name, or the default password
+func (zp ZipPasswords) Get(name string) string {
+	if pw, ok := zp[name]; ok {
+		return pw
+	}
+	if pw, ok := zp[filepath.Base(name)]; ok {
+		return pw
+	}
+	return zp[""]
+}
+
+// encryptedZip gives access to an archive having encrypted entries
+type encryptedZip struct {
+	*zip.ReadCloser
+	password string
+	files    map[string]*zip.File // encrypted entries by name
+}
+
+// openZip opens the archive, and decrypts its encrypted entries with the password
+func openZip(name string, password string) (fs.FS, error) {
+	r, err := zip.OpenReader(name)
+	if err != nil {
+		return nil, err
+	}
+	var files map[string]*zip.File
+	for _, f := range r.File {
+		if f.Flags&0x1 == 0 {
+			continue
+		}
+		if files == nil {
+			files = map[string]*zip.File{}
+		}
+		files[f.Name] = f
+	}
+	if files == nil {
+		return r, nil
+	}
+	if password == "" {
+		r.Close()
+		return nil, fmt.Errorf("%s: %w, use the option -zip-password-file", name, ErrZipPasswordMissing)
+	}
+
+	// Check the password on one entry to fail before browsing the archive
+	for _, f := range files {
+		_, _, err = aesKeys(f, password)
+		if err != nil {
+			r.Close()
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		break
+	}
+	return &encryptedZip{ReadCloser: r, password: password, files: files}, nil
+}
+
+func (z *encryptedZip) Open(name string) (fs.File, error) {
+	f, ok := z.files[name]
+	if !ok {
+		return z.ReadCloser.Open(name)
+	}
+	rc, err := openAES(f, z.password)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return &zipAESFile{ReadCloser: rc, info: f.FileInfo()}, nil
+}
+
+type zipAESFile struct {
+	io.ReadCloser
+	info fs.FileInfo
+}
+
+func (f *zipAESFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+
+// aesParams reads the key strength and the actual compression method in the AES extra field
+func aesParams(f *zip.File) (keyLen int, method uint16, err error) {
+	if f.Method != zipMethodAES {
+		return 0, 0, fmt.Errorf("%s: %w", f.Name, ErrZipEncryption)
+	}
+	extra := f.Extra
+	for len(extra) >= 4 {
+		id := binary.LittleEndian.Uint16(extra)
+		size := int(binary.LittleEndian.Uint16(extra[2:]))
+		extra = extra[4:]
+		if size > len(extra) {
+			break
+		}
+		if id == zipExtraAES && size >= 7 {
+			// vendor version (2), vendor id "AE" (2), strength (1), method (2)
+			strength := extra[4]
+			if strength < 1 || strength > 3 {
+				break
+			}
+			return 8 * (int(strength) + 1), binary.LittleEndian.Uint16(extra[5:]), nil
+		}
+		extra = extra[size:]
+	}
+	return 0, 0, fmt.Errorf("%s: invalid AES extra field", f.Name)
+}
+
+// aesKeys derives the encryption and authentication keys from the password,
+// and checks the password verifier
+func aesKeys(f *zip.File, password string) (key []byte, authKey []byte, err error) {
+	keyLen, _, err := aesParams(f)
+	if err != nil {
+		return nil, nil, err
+	}
+	raw, err := f.OpenRaw()
+	if err != nil {
+		return nil, nil, err
+	}
+	header := make([]byte, keyLen/2+zipVerifyLen)
+	if _, err = io.ReadFull(raw, header); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", f.Name, err)
+	}
+	salt := header[:keyLen/2]
+	dk := pbkdf2.Key([]byte(password), salt, zipIterations, 2*keyLen+zipVerifyLen, sha1.New)
+	if !bytes.Equal(dk[2*keyLen:], header[keyLen/2:]) {
+		return nil, nil, ErrZipPasswordWrong
+	}
+	return dk[:keyLen], dk[keyLen : 2*keyLen], nil
+}
+
+// openAES gives the decrypted and decompressed content of an entry encrypted
+// following the WinZip AES specification
+func openAES(f *zip.File, password string) (io.ReadCloser, error) {
+	_, method, err := aesParams(f)
+	if err != nil {
+		return nil, err
+	}
+	key, authKey, err := aesKeys(f, password)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := f.OpenRaw()
+	if err != nil {
+		return nil, err
+	}
+	headerLen := int64(len(key)/2 + zipVerifyLen)
+	dataLen := int64(f.CompressedSize64) - headerLen - zipAuthLen
+	if dataLen < 0 {
+		return nil, fmt.Errorf("%s: %w", f.Name, zip.ErrFormat)
+	}
+	if _, err = io.CopyN(io.Discard, raw, headerLen); err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha1.New, authKey)
+	r := &aesReader{
+		r:     io.LimitReader(raw, dataLen),
+		raw:   raw,
+		mac:   mac,
+		block: block,
+	}
+	r.counter[0] = 1
+
+	switch method {
+	case zip.Store:
+		return io.NopCloser(r), nil
+	case zip.Deflate:
+		return &authReader{ReadCloser: flate.NewReader(r), aes: r}, nil
+	}
+	return nil, fmt.Errorf("%s: %w", f.Name, zip.ErrAlgorithm)
+}
+
+// aesReader decrypts the data with AES in CTR mode, with a little endian counter,
+// and checks the authentication code at the end of the data
+type aesReader struct {
+	r       io.Reader // encrypted data
+	raw     io.Reader // the raw entry, giving the authentication code after the data
+	mac     hash.Hash
+	block   cipher.Block
+	counter [aes.BlockSize]byte
+	stream  [aes.BlockSize]byte
+	pos     int // position in the key stream
+	checked bool
+}
+
+func (r *aesReader) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	r.mac.Write(b[:n])
+	for i := 0; i < n; i++ {
+		if r.pos%aes.BlockSize == 0 {
+			r.block.Encrypt(r.stream[:], r.counter[:])
+			for j := range r.counter {
+				r.counter[j]++
+				if r.counter[j] != 0 {
+					break
+				}
+			}
+			r.pos = 0
+		}
+		b[i] ^= r.stream[r.pos]
+		r.pos++
+	}
+	if err == io.EOF && !r.checked {
+		r.checked = true
+		code := make([]byte, zipAuthLen)
+		if _, err := io.ReadFull(r.raw, code); err != nil {
+			return n, err
+		}
+		if !hmac.Equal(code, r.mac.Sum(nil)[:zipAuthLen]) {
+			return n, ErrZipAuthentication
+		}
+	}
+	return n, err
+}
+
+// authReader gives the decompressed content. The decompressor stops at the end of the
+// compressed stream, the rest of the encrypted data is read to check the authentication code.
+type authReader struct {
+	io.ReadCloser
+	aes *aesReader
+}
+
+func (r *authReader) Read(b []byte) (int, error) {
+	n, err := r.ReadCloser.Read(b)
+	if err == io.EOF && !r.aes.checked {
+		if _, err := io.Copy(io.Discard, r.aes); err != nil {
+			return n, err
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,130 @@
+package fshelper
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEncryptedZip(t *testing.T) {
+	const (
+		photo   = "Takeout/Google Photos/Album/photo.jpg"
+		content = "first photo content, long enough to be deflated deflated deflated deflated\n"
+	)
+	tc := []struct {
+		name      string
+		file      string
+		passwords []string
+		err       error
+	}{
+		{name: "aes128", file: "TESTDATA/zip/aes128.zip", passwords: []string{"secret"}},
+		{name: "aes256", file: "TESTDATA/zip/aes256.zip", passwords: []string{"secret"}},
+		{name: "per archive", file: "TESTDATA/zip/aes256.zip", passwords: []string{"other", "aes256.zip=secret"}},
+		{name: "wrong password", file: "TESTDATA/zip/aes256.zip", passwords: []string{"wrong"}, err: ErrZipPasswordWrong},
+		{name: "missing password", file: "TESTDATA/zip/aes128.zip", err: ErrZipPasswordMissing},
+		{name: "zipcrypto", file: "TESTDATA/zip/zipcrypto.zip", passwords: []string{"secret"}, err: ErrZipEncryption},
+	}
+	for _, c := range tc {
+		t.Run(c.name, func(t *testing.T) {
+			var zp ZipPasswords
+			for _, p := range c.passwords {
+				if err := zp.Set(p); err != nil {
+					t.Fatal(err)
+				}
+			}
+			fsys, err := multiZip(zp, c.file)
+			if c.err != nil {
+				if !errors.Is(err, c.err) {
+					t.Fatalf("expecting error %v, got %v", c.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			f, err := fsys.Open(photo)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			b, err := io.ReadAll(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != content {
+				t.Errorf("unexpected content: %q", b)
+			}
+			i, err := fs.Stat(fsys, photo)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if i.Size() != int64(len(content)) {
+				t.Errorf("unexpected size: %d", i.Size())
+			}
+		})
+	}
+}
+
+func TestEncryptedZipAuthentication(t *testing.T) {
+	const photo = "Takeout/Google Photos/Album/photo.jpg"
+	b, err := os.ReadFile("TESTDATA/zip/aes256.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range zr.File {
+		if f.Name != photo {
+			continue
+		}
+		offset, err := f.DataOffset()
+		if err != nil {
+			t.Fatal(err)
+		}
+		// alter the authentication code, after the deflated data
+		b[offset+int64(f.CompressedSize64)-1] ^= 0xff
+	}
+	name := filepath.Join(t.TempDir(), "altered.zip")
+	if err = os.WriteFile(name, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	fsys, err := multiZip(ZipPasswords{"": "secret"}, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := fsys.Open(photo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	_, err = io.ReadAll(f)
+	if !errors.Is(err, ErrZipAuthentication) {
+		t.Errorf("expecting error %v, got %v", ErrZipAuthentication, err)
+	}
+}
+
+func TestZipPasswordsReadFile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "passwords")
+	err := os.WriteFile(name, []byte("# takeout passwords\nsecret\n\ntakeout-002.zip=other\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var zp ZipPasswords
+	if err = zp.ReadFile(name); err != nil {
+		t.Fatal(err)
+	}
+	if got := zp.Get("takeout-001.zip"); got != "secret" {
+		t.Errorf("password of takeout-001.zip = %q, want secret", got)
+	}
+	if got := zp.Get("/tmp/takeout-002.zip"); got != "other" {
+		t.Errorf("password of takeout-002.zip = %q, want other", got)
+	}
+}
@@ -0,0 +1,33 @@
+// Package nfc composes the letters of a string followed by combining marks,
+// with the Unicode normalization form C.
+//
+// File names written by macOS use the decomposed form (NFD), where é is an e
+// followed by a combining acute accent, while other systems use the composed
+// form (NFC). Both forms must give the same name to be compared.
+package nfc
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// String returns s in the Unicode normalization form C
+func String(s string) string {
+	return norm.NFC.String(s)
+}
+
+// StripMarks returns s without the combining marks of its letters, given in
+// the composed or in the decomposed form: "Café" gives "Cafe".
+func StripMarks(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, r := range norm.NFD.String(s) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return norm.NFC.String(b.String())
+}
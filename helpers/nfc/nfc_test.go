@@ -0,0 +1,48 @@
+package nfc
+
+import "testing"
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"photo.jpg", "photo.jpg"},
+		{"café.jpg", "café.jpg"},
+		{"café.jpg", "café.jpg"},
+		{"Köln Mère.jpg", "Köln Mère.jpg"},
+		{"Việt Nam.jpg", "Việt Nam.jpg"}, // two marks on the same letter
+		{"́accent.jpg", "́accent.jpg"},     // leading mark kept as is
+		{"日́.jpg", "日́.jpg"},               // no composed form
+		{"が.jpg", "が.jpg"},                // Japanese dakuten
+		{"й.jpg", "й.jpg"},                // Cyrillic short i
+	}
+	for _, tt := range tests {
+		if got := String(tt.in); got != tt.want {
+			t.Errorf("String(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestStripMarks(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Cafe", "Cafe"},
+		{"Café", "Cafe"},
+		{"Café", "Cafe"}, // decomposed form
+		{"Köln Mère", "Koln Mere"},
+		{"Việt Nam", "Viet Nam"}, // two marks on the same letter
+		{"Việt Nam", "Viet Nam"},
+		{"ÇA ÉTÉ", "CA ETE"},
+		{"日本", "日本"},
+		{"が", "か"}, // Japanese dakuten
+		{"й", "и"},  // Cyrillic short i
+	}
+	for _, tt := range tests {
+		if got := StripMarks(tt.in); got != tt.want {
+			t.Errorf("StripMarks(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
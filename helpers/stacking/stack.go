@@ -14,7 +14,7 @@ import (
 )
 
 type Key struct {
-	date     time.Time // time rounded at 5 min
+	date     time.Time // time rounded at the minute, or capture date of the first frame of a burst
 	baseName string    // stack group
 }
 
@@ -33,14 +33,27 @@ const (
 	StackBurst
 )
 
+// DefaultBurstWindow is the maximal interval between two frames of a burst
+const DefaultBurstWindow = 2 * time.Second
+
 type StackBuilder struct {
-	dateRange immich.DateRange // Set capture date range
-	stacks    map[Key]Stack
+	dateRange   immich.DateRange // Set capture date range
+	stacks      map[Key]Stack
+	burstWindow time.Duration            // Maximal interval between two frames of a burst
+	bursts      map[string][]burstFrames // Bursts by base name
+}
+
+// burstFrames gives the capture dates of the first and the last frames of a burst
+type burstFrames struct {
+	key         Key
+	first, last time.Time
 }
 
 func NewStackBuilder() *StackBuilder {
 	sb := StackBuilder{
-		stacks: map[Key]Stack{},
+		stacks:      map[Key]Stack{},
+		burstWindow: DefaultBurstWindow,
+		bursts:      map[string][]burstFrames{},
 	}
 	sb.dateRange.Set("1850-01-04,2030-01-01")
 
@@ -48,6 +61,40 @@ func NewStackBuilder() *StackBuilder {
 
 }
 
+// SetBurstWindow sets the maximal interval between two frames of a burst.
+//
+// The frames of a burst are recognized by their name, given by the camera. The frames having
+// the same base name are grouped when they are taken within the window from the first or the last
+// frame of the burst. A frame taken later starts a new burst.
+func (sb *StackBuilder) SetBurstWindow(d time.Duration) *StackBuilder {
+	sb.burstWindow = d
+	return sb
+}
+
+// burstKey gives the key of the burst having the base name and a frame within the
+// burst window of the capture date, or the key of a new burst
+func (sb *StackBuilder) burstKey(base string, captureDate time.Time) Key {
+	for i, b := range sb.bursts[base] {
+		if captureDate.Before(b.first.Add(-sb.burstWindow)) || captureDate.After(b.last.Add(sb.burstWindow)) {
+			continue
+		}
+		if captureDate.Before(b.first) {
+			b.first = captureDate
+		}
+		if captureDate.After(b.last) {
+			b.last = captureDate
+		}
+		sb.bursts[base][i] = b
+		return b.key
+	}
+	k := Key{
+		date:     captureDate,
+		baseName: base,
+	}
+	sb.bursts[base] = append(sb.bursts[base], burstFrames{key: k, first: captureDate, last: captureDate})
+	return k
+}
+
 func (sb *StackBuilder) ProcessAsset(ID string, fileName string, captureDate time.Time) {
 	if !sb.dateRange.InRange(captureDate) {
 		return
@@ -76,10 +123,14 @@ func (sb *StackBuilder) ProcessAsset(ID string, fileName string, captureDate tim
 		}
 	}
 
+	// The raw and jpg files of a photo are taken at the same time
 	k := Key{
 		date:     captureDate.Round(time.Minute),
 		baseName: base,
 	}
+	if burst {
+		k = sb.burstKey(base, captureDate)
+	}
 	s, ok := sb.stacks[k]
 	if !ok {
 		s.CoverID = ID
@@ -160,15 +211,10 @@ func (sb *StackBuilder) Stacks() []Stack {
 		hasVideo := 0
 
 		for _, n := range s.Names {
-			mime, err := fshelper.MimeFromExt(path.Ext(n))
-			if err != nil {
-				continue
-			}
-			s := strings.Split(mime[0], "/")
-			switch s[0] {
-			case "video":
+			switch fshelper.TypeFromExt(path.Ext(n)) {
+			case fshelper.TypeVideo:
 				hasVideo++
-			case "image":
+			case fshelper.TypeImage:
 				hasPhoto++
 			}
 		}
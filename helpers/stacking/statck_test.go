@@ -219,3 +219,63 @@ func Test_Stack(t *testing.T) {
 
 	}
 }
+
+func TestBurstWindow(t *testing.T) {
+	start := time.Date(2023, 12, 7, 10, 16, 5, 0, time.UTC)
+	tc := []struct {
+		name   string
+		window time.Duration
+		gaps   []time.Duration // interval between the frames
+		want   [][]string      // names of the stacked frames
+	}{
+		{
+			name:   "default window, just inside",
+			window: DefaultBurstWindow,
+			gaps:   []time.Duration{DefaultBurstWindow, DefaultBurstWindow},
+			want:   [][]string{{"20231207_101605_001.jpg", "20231207_101605_002.jpg", "20231207_101605_003.jpg"}},
+		},
+		{
+			name:   "default window, just outside",
+			window: DefaultBurstWindow,
+			gaps:   []time.Duration{DefaultBurstWindow + time.Millisecond, 100 * time.Millisecond},
+			want:   [][]string{{"20231207_101605_002.jpg", "20231207_101605_003.jpg"}},
+		},
+		{
+			name:   "short window, just inside",
+			window: 200 * time.Millisecond,
+			gaps:   []time.Duration{200 * time.Millisecond, 150 * time.Millisecond},
+			want:   [][]string{{"20231207_101605_001.jpg", "20231207_101605_002.jpg", "20231207_101605_003.jpg"}},
+		},
+		{
+			name:   "short window, just outside",
+			window: 200 * time.Millisecond,
+			gaps:   []time.Duration{201 * time.Millisecond, 201 * time.Millisecond},
+		},
+		{
+			name:   "long window",
+			window: 10 * time.Second,
+			gaps:   []time.Duration{55 * time.Second, 9 * time.Second},
+			want:   [][]string{{"20231207_101605_002.jpg", "20231207_101605_003.jpg"}},
+		},
+	}
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			sb := NewStackBuilder().SetBurstWindow(tt.window)
+			d := start
+			names := []string{"20231207_101605_001.jpg", "20231207_101605_002.jpg", "20231207_101605_003.jpg"}
+			for i, n := range names {
+				if i > 0 {
+					d = d.Add(tt.gaps[i-1])
+				}
+				sb.ProcessAsset(n, n, d)
+			}
+			got := [][]string{}
+			for _, s := range sb.Stacks() {
+				got = append(got, s.Names)
+			}
+			if len(got) != len(tt.want) || (len(got) > 0 && !reflect.DeepEqual(got, tt.want)) {
+				t.Errorf("stacks = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
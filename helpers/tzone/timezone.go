@@ -37,3 +37,19 @@ func SetLocal(tz string) (*time.Location, error) {
 func Local() (*time.Location, error) {
 	return SetLocal("")
 }
+
+var _naive *time.Location
+
+// SetNaive sets the time zone of the timestamps given without offset, like the EXIF dates of capture.
+// The local time zone is used when loc is nil.
+func SetNaive(loc *time.Location) {
+	_naive = loc
+}
+
+// Naive returns the time zone of the timestamps given without offset
+func Naive() (*time.Location, error) {
+	if _naive != nil {
+		return _naive, nil
+	}
+	return Local()
+}
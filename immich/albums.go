@@ -2,9 +2,13 @@ package immich
 
 import (
 	"context"
+	"errors"
 	"fmt"
 )
 
+// ErrAlbumNoAccess is returned when the album can't be updated by the user
+var ErrAlbumNoAccess = errors.New("the album doesn't exist or the user isn't allowed to add assets into it")
+
 type AlbumSimplified struct {
 	ID string `json:"id,omitempty"`
 	// OwnerID                    string    `json:"ownerId"`
@@ -15,7 +19,7 @@ type AlbumSimplified struct {
 	// SharedUsers                []string  `json:"sharedUsers"`
 	// Owner                      User      `json:"owner"`
 	// Shared                     bool      `json:"shared"`
-	// AssetCount                 int       `json:"assetCount"`
+	AssetCount int `json:"assetCount,omitempty"`
 	// LastModifiedAssetTimestamp time.Time `json:"lastModifiedAssetTimestamp"
 	AssetIds []string `json:"assetIds,omitempty"`
 }
@@ -106,6 +110,9 @@ func (ic *ImmichClient) AddAssetToAlbum(ctx context.Context, albumID string, ass
 			setJSONBody(body)),
 		responseJSON(&r))
 	if err != nil {
+		if isAccessError(err) {
+			return nil, fmt.Errorf("%w: %w", ErrAlbumNoAccess, err)
+		}
 		return nil, err
 	}
 	return r, nil
@@ -126,6 +133,25 @@ func (ic *ImmichClient) CreateAlbum(ctx context.Context, name string, assets []s
 	return r, nil
 }
 
+// AlbumInfo gives the album's properties to be updated, nil fields are left untouched
+type AlbumInfo struct {
+	Description       *string `json:"description,omitempty"`
+	IsActivityEnabled *bool   `json:"isActivityEnabled,omitempty"`
+	Order             *string `json:"order,omitempty"`
+	ThumbnailAssetID  *string `json:"albumThumbnailAssetId,omitempty"`
+}
+
+// Orders of the album's assets, sorted by their date of capture
+const (
+	AlbumOrderAsc  = "asc"
+	AlbumOrderDesc = "desc"
+)
+
+func (ic *ImmichClient) UpdateAlbumInfo(ctx context.Context, id string, info AlbumInfo) error {
+	return ic.newServerCall(ctx, "UpdateAlbumInfo").do(
+		patch("/album/"+id, setAcceptJSON(), setJSONBody(info)))
+}
+
 func (ic *ImmichClient) GetAssetAlbums(ctx context.Context, id string) ([]AlbumSimplified, error) {
 	var r []AlbumSimplified
 	err := ic.newServerCall(ctx, "GetAssetAlbums").do(
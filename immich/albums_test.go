@@ -0,0 +1,76 @@
+package immich
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAddAssetToAlbum(t *testing.T) {
+	tt := []struct {
+		name         string
+		server       testServer
+		expectedErr  bool
+		noAccessErr  bool
+		expectedRead int
+	}{
+		{
+			name: "ok",
+			server: testServer{
+				responseStatus: http.StatusOK,
+				responseBody:   `[{"id":"1","success":true},{"id":"2","success":false,"error":"duplicate"}]`,
+			},
+			expectedRead: 2,
+		},
+		{
+			name: "not shared with the user",
+			server: testServer{
+				responseStatus: http.StatusBadRequest,
+				responseBody:   `{"error": "Bad Request", "statusCode": "400", "message": ["Not found or no album.addAsset access"]}`,
+			},
+			expectedErr: true,
+			noAccessErr: true,
+		},
+		{
+			name: "invalid request",
+			server: testServer{
+				responseStatus: http.StatusBadRequest,
+				responseBody:   `{"error": "Bad Request", "statusCode": "400", "message": ["each value in ids must be a UUID"]}`,
+			},
+			expectedErr: true,
+		},
+		{
+			name: "server error",
+			server: testServer{
+				responseStatus: http.StatusInternalServerError,
+				responseBody:   `{"error": "Internal Server Error", "statusCode": "500"}`,
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			server := httptest.NewServer(&tst.server)
+			defer server.Close()
+			ic, err := NewImmichClient(server.URL, "1234", false)
+			if err != nil {
+				t.Fail()
+				return
+			}
+			r, err := ic.AddAssetToAlbum(context.Background(), "album", []string{"1", "2"})
+			if (err != nil) != tst.expectedErr {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			if errors.Is(err, ErrAlbumNoAccess) != tst.noAccessErr {
+				t.Errorf("expected ErrAlbumNoAccess: %t, got: %v", tst.noAccessErr, err)
+			}
+			if len(r) != tst.expectedRead {
+				t.Errorf("expected %d results, got %d", tst.expectedRead, len(r))
+			}
+		})
+	}
+}
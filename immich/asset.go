@@ -1,20 +1,26 @@
 package immich
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
-	"mime/multipart"
-	"net/textproto"
+	"io/fs"
 	"net/url"
 	"path"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/simulot/immich-go/browser"
 	"github.com/simulot/immich-go/helpers/fshelper"
 )
 
+// ErrQuotaExceeded is returned when the upload is refused because the user's quota or the server's storage is full
+var ErrQuotaExceeded = errors.New("the storage quota is exceeded")
+
 type AssetResponse struct {
 	ID        string `json:"id"`
 	Duplicate bool   `json:"duplicate"`
@@ -37,100 +43,106 @@ func formatDuration(duration time.Duration) string {
 
 func (ic *ImmichClient) AssetUpload(ctx context.Context, la *browser.LocalAssetFile) (AssetResponse, error) {
 	var ar AssetResponse
-	mtype, err := fshelper.MimeFromExt(path.Ext(la.FileName))
-	if err != nil {
-		return ar, err
+	mtype := []string{la.MimeType}
+	if la.MimeType == "" {
+		var err error
+		mtype, err = fshelper.MimeFromExt(path.Ext(la.FileName))
+		if err != nil {
+			return ar, err
+		}
 	}
 
 	f, err := la.Open()
 	if err != nil {
 		return ar, (err)
 	}
+	s, err := f.Stat()
+	if err != nil {
+		return ar, err
+	}
+	size := s.Size()
+	if size == 0 {
+		// the size is unknown, get it from the file system
+		fi, err := fs.Stat(la.FSys, la.FileName)
+		if err != nil {
+			return ar, err
+		}
+		size = fi.Size()
+	}
 
-	body, pw := io.Pipe()
-	m := multipart.NewWriter(pw)
+	m := newMultipartBody()
+	assetType := strings.ToUpper(strings.Split(mtype[0], "/")[0])
 
-	go func() {
-		defer func() {
-			m.Close()
-			pw.Close()
-		}()
-		s, err := f.Stat()
+	m.WriteField("deviceAssetId", fmt.Sprintf("%s-%d", path.Base(la.Title), s.Size()))
+	m.WriteField("deviceId", ic.DeviceUUID)
+	m.WriteField("assetType", assetType)
+	createdAt := la.DateTaken
+	if createdAt.IsZero() {
+		createdAt = s.ModTime()
+	}
+	m.WriteField("fileCreatedAt", createdAt.Format(time.RFC3339))
+	m.WriteField("fileModifiedAt", s.ModTime().Format(time.RFC3339))
+	m.WriteField("isFavorite", myBool(la.Favorite).String())
+	m.WriteField("fileExtension", path.Ext(la.FileName))
+	m.WriteField("duration", formatDuration(0))
+	m.WriteField("isReadOnly", "false")
+	if ic.libraryID != "" {
+		m.WriteField("libraryId", ic.libraryID)
+	}
+	// m.WriteField("isArchived", myBool(la.Archived).String()) // Not supported by the api
+
+	var r io.Reader = f
+	if ic.uploadProgress != nil {
+		r = &progressReader{r: f, la: la, size: size, fn: ic.uploadProgress}
+	}
+	err = m.AddFile("assetData", path.Base(la.Title), mtype[0], r, size)
+	if err != nil {
+		return ar, err
+	}
+
+	if la.LivePhotoData != "" {
+		b, err := la.FSys.Open(la.LivePhotoData)
 		if err != nil {
-			return
+			return ar, err
 		}
-		assetType := strings.ToUpper(strings.Split(mtype[0], "/")[0])
-
-		m.WriteField("deviceAssetId", fmt.Sprintf("%s-%d", path.Base(la.Title), s.Size()))
-		m.WriteField("deviceId", ic.DeviceUUID)
-		m.WriteField("assetType", assetType)
-		m.WriteField("fileCreatedAt", la.DateTaken.Format(time.RFC3339))
-		m.WriteField("fileModifiedAt", s.ModTime().Format(time.RFC3339))
-		m.WriteField("isFavorite", myBool(la.Favorite).String())
-		m.WriteField("fileExtension", path.Ext(la.FileName))
-		m.WriteField("duration", formatDuration(0))
-		m.WriteField("isReadOnly", "false")
-		// m.WriteField("isArchived", myBool(la.Archived).String()) // Not supported by the api
-		h := textproto.MIMEHeader{}
-		h.Set("Content-Disposition",
-			fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
-				escapeQuotes("assetData"), escapeQuotes(path.Base(la.Title))))
-		h.Set("Content-Type", mtype[0])
-
-		part, err := m.CreatePart(h)
+		defer b.Close()
+		bs, err := b.Stat()
 		if err != nil {
-			return
+			return ar, err
 		}
-		_, err = io.Copy(part, f)
+		err = m.AddFile("livePhotoData", path.Base(la.LivePhotoData), "application/binary", b, bs.Size())
 		if err != nil {
-			return
+			return ar, err
 		}
+	}
 
-		if la.LivePhotoData != "" {
-			h.Set("Content-Disposition",
-				fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
-					escapeQuotes("livePhotoData"), escapeQuotes(path.Base(la.LivePhotoData))))
-			h.Set("Content-Type", "application/binary")
-			part, err := m.CreatePart(h)
-			if err != nil {
-				return
-			}
-			b, err := la.FSys.Open(la.LivePhotoData)
-			if err != nil {
-				return
-			}
-			defer b.Close()
-			_, err = io.Copy(part, b)
-			if err != nil {
-				return
-			}
+	if la.SideCar != nil {
+		sc, err := la.SideCar.Open(la.FSys, la.SideCar.FileName)
+		if err != nil {
+			return ar, err
 		}
-
-		if la.SideCar != nil {
-			h.Set("Content-Disposition",
-				fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
-					escapeQuotes("sidecarData"), escapeQuotes(path.Base(la.SideCar.FileName))))
-			h.Set("Content-Type", "application/xml")
-
-			part, err := m.CreatePart(h)
-			if err != nil {
-				return
-			}
-			sc, err := la.SideCar.Open(la.FSys, la.SideCar.FileName)
-			if err != nil {
-				return
-			}
-			defer sc.Close()
-			_, err = io.Copy(part, sc)
-			if err != nil {
-				return
-			}
+		// sidecars are small, and the generated ones have no size before being read
+		b, err := io.ReadAll(sc)
+		sc.Close()
+		if err != nil {
+			return ar, err
+		}
+		err = m.AddFile("sidecarData", path.Base(la.SideCar.FileName), "application/xml", bytes.NewReader(b), int64(len(b)))
+		if err != nil {
+			return ar, err
 		}
-	}()
+	}
 
-	err = ic.newServerCall(ctx, "AssetUpload").
-		do(post("/asset/upload", m.FormDataContentType(), setAcceptJSON(), setBody(body)), responseJSON(&ar))
+	err = m.Close()
+	if err != nil {
+		return ar, err
+	}
 
+	err = ic.newServerCall(ctx, "AssetUpload", uploadCall()).
+		do(post("/asset/upload", m.FormDataContentType(), setAcceptJSON(), setSizedBody(m.Reader(), m.Len())), responseJSON(&ar))
+	if err != nil && isQuotaError(err) {
+		err = fmt.Errorf("%w: %w", ErrQuotaExceeded, err)
+	}
 	return ar, err
 
 }
@@ -141,38 +153,186 @@ func escapeQuotes(s string) string {
 	return quoteEscaper.Replace(s)
 }
 
+// GetAssetOptions filters the assets listed by GetAllAssets and GetAllAssetsWithFilter.
+// Zero values don't filter.
 type GetAssetOptions struct {
+	// Filters honored by the server
 	UserId        string
-	IsFavorite    bool
-	IsArchived    bool
+	IsFavorite    *bool
+	IsArchived    *bool
 	WithoutThumbs bool
 	Skip          string
+	Take          int
+	UpdatedAfter  time.Time
+	UpdatedBefore time.Time
+
+	// Pagination: the assets are requested by pages of PageSize assets, with
+	// up to Concurrency requests at the same time. 0 for a single request.
+	PageSize    int
+	Concurrency int
+
+	// Filters not honored by the server, applied on the received assets
+	Type        string // IMAGE or VIDEO
+	IsTrashed   *bool
+	TakenAfter  time.Time // Date of capture, included
+	TakenBefore time.Time // Date of capture, excluded
 }
 
+// Values gives the query parameters of the filters honored by the server
 func (o *GetAssetOptions) Values() url.Values {
 	if o == nil {
 		return nil
 	}
 	v := url.Values{}
-	v.Add("userId", o.UserId)
-	v.Add("isFavorite", myBool(o.IsFavorite).String())
-	v.Add("isArchived", myBool(o.IsArchived).String())
-	v.Add("withoutThumbs", myBool(o.WithoutThumbs).String())
-	v.Add("skip", o.Skip)
+	if o.UserId != "" {
+		v.Add("userId", o.UserId)
+	}
+	if o.IsFavorite != nil {
+		v.Add("isFavorite", myBool(*o.IsFavorite).String())
+	}
+	if o.IsArchived != nil {
+		v.Add("isArchived", myBool(*o.IsArchived).String())
+	}
+	if o.WithoutThumbs {
+		v.Add("withoutThumbs", "true")
+	}
+	if o.Skip != "" {
+		v.Add("skip", o.Skip)
+	}
+	if o.Take > 0 {
+		v.Add("take", strconv.Itoa(o.Take))
+	}
+	if !o.UpdatedAfter.IsZero() {
+		v.Add("updatedAfter", o.UpdatedAfter.UTC().Format(time.RFC3339))
+	}
+	if !o.UpdatedBefore.IsZero() {
+		v.Add("updatedBefore", o.UpdatedBefore.UTC().Format(time.RFC3339))
+	}
 	return v
 }
 
+// Match checks the filters not honored by the server
+func (o *GetAssetOptions) Match(a *Asset) bool {
+	if o == nil {
+		return true
+	}
+	if o.Type != "" && !strings.EqualFold(o.Type, a.Type) {
+		return false
+	}
+	if o.IsTrashed != nil && *o.IsTrashed != a.IsTrashed {
+		return false
+	}
+	d := a.ExifInfo.DateTimeOriginal.Time
+	if d.IsZero() {
+		d = a.FileCreatedAt.Time
+	}
+	if !o.TakenAfter.IsZero() && d.Before(o.TakenAfter) {
+		return false
+	}
+	if !o.TakenBefore.IsZero() && !d.Before(o.TakenBefore) {
+		return false
+	}
+	return true
+}
+
 func (ic *ImmichClient) GetAllAssets(ctx context.Context, opt *GetAssetOptions) ([]*Asset, error) {
 	var r []*Asset
-
-	err := ic.newServerCall(ctx, "GetAllAssets").do(get("/asset", setUrlValues(opt.Values()), setAcceptJSON()), responseJSON(&r))
+	err := ic.GetAllAssetsWithFilter(ctx, opt, func(a *Asset) {
+		r = append(r, a)
+	})
 	return r, err
-
 }
 
+// GetAllAssetsWithFilter calls the filter for each asset matching the options.
+// The filter is never called concurrently, even when pages are requested concurrently.
 func (ic *ImmichClient) GetAllAssetsWithFilter(ctx context.Context, opt *GetAssetOptions, filter func(*Asset)) error {
-	err := ic.newServerCall(ctx, "GetAllAssets").do(get("/asset", setUrlValues(opt.Values()), setAcceptJSON()), responseJSONWithFilter(filter))
-	return err
+	if opt != nil && opt.PageSize > 0 {
+		return ic.getAssetsByPage(ctx, opt, filter)
+	}
+	return ic.getAssets(ctx, opt, func(a *Asset) {
+		if opt.Match(a) {
+			filter(a)
+		}
+	})
+}
+
+func (ic *ImmichClient) getAssets(ctx context.Context, opt *GetAssetOptions, fn func(*Asset)) error {
+	return ic.newServerCall(ctx, "GetAllAssets").do(get("/asset", setUrlValues(opt.Values()), setAcceptJSON()), responseJSONWithFilter(fn))
+}
+
+// getAssetsByPage requests the pages until a page isn't full.
+// The pages are given by the skip and take parameters: assets added on the server
+// while reading the pages may shift the pages and be missed.
+func (ic *ImmichClient) getAssetsByPage(ctx context.Context, opt *GetAssetOptions, filter func(*Asset)) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mut      sync.Mutex
+		wg       sync.WaitGroup
+		next     int   // next page to request
+		lastPage = -1  // first page found not full, -1 when unknown
+		firstErr error // first error met
+	)
+	worker := func() {
+		defer wg.Done()
+		for {
+			mut.Lock()
+			page := next
+			if (lastPage >= 0 && page > lastPage) || firstErr != nil || ctx.Err() != nil {
+				mut.Unlock()
+				return
+			}
+			next++
+			mut.Unlock()
+
+			o := *opt
+			o.Skip = strconv.Itoa(page * opt.PageSize)
+			o.Take = opt.PageSize
+			count := 0
+			err := ic.getAssets(ctx, &o, func(a *Asset) {
+				mut.Lock()
+				defer mut.Unlock()
+				count++
+				if opt.Match(a) {
+					filter(a)
+				}
+			})
+
+			mut.Lock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				mut.Unlock()
+				return
+			}
+			if count < opt.PageSize && (lastPage < 0 || page < lastPage) {
+				lastPage = page
+			}
+			mut.Unlock()
+		}
+	}
+	for i := 0; i < max(opt.Concurrency, 1); i++ {
+		wg.Add(1)
+		go worker()
+	}
+	wg.Wait()
+	return firstErr
+}
+
+// AssetStatistics gives the number of the user's assets, trashed assets excluded
+type AssetStatistics struct {
+	Images int `json:"images"`
+	Videos int `json:"videos"`
+	Total  int `json:"total"`
+}
+
+func (ic *ImmichClient) GetAssetStatistics(ctx context.Context) (AssetStatistics, error) {
+	var r AssetStatistics
+	err := ic.newServerCall(ctx, "GetAssetStatistics").do(get("/asset/statistics?isTrashed=false", setAcceptJSON()), responseJSON(&r))
+	return r, err
 }
 
 func (ic *ImmichClient) DeleteAssets(ctx context.Context, id []string, forceDelete bool) error {
@@ -187,6 +347,16 @@ func (ic *ImmichClient) DeleteAssets(ctx context.Context, id []string, forceDele
 	return ic.newServerCall(ctx, "DeleteAsset").do(delete("/asset", setAcceptJSON(), setJSONBody(req)))
 }
 
+// RestoreAssets moves the trashed assets back to the library
+func (ic *ImmichClient) RestoreAssets(ctx context.Context, IDs []string) error {
+	req := struct {
+		IDs []string `json:"ids"`
+	}{
+		IDs: IDs,
+	}
+	return ic.newServerCall(ctx, "RestoreAssets").do(post("/trash/restore/assets", "application/json", setAcceptJSON(), setJSONBody(req)))
+}
+
 func (ic *ImmichClient) GetAssetByID(ctx context.Context, id string) (*Asset, error) {
 	r := Asset{}
 	err := ic.newServerCall(ctx, "GetAssetByID").do(get("/asset/assetById/"+id, setAcceptJSON()), responseJSON(&r))
@@ -219,14 +389,28 @@ func (ic *ImmichClient) UpdateAssets(ctx context.Context, IDs []string,
 	return ic.newServerCall(ctx, "updateAssets").do(put("/asset", setJSONBody(param)))
 }
 
+// ArchiveAssets sets the archived status of the assets, the other properties are left untouched
+func (ic *ImmichClient) ArchiveAssets(ctx context.Context, IDs []string, isArchived bool) error {
+	type archiveAssets struct {
+		IDs        []string `json:"ids"`
+		IsArchived bool     `json:"isArchived"`
+	}
+	param := archiveAssets{
+		IDs:        IDs,
+		IsArchived: isArchived,
+	}
+	return ic.newServerCall(ctx, "archiveAssets").do(put("/asset", setJSONBody(param)))
+}
+
 func (ic *ImmichClient) UpdateAsset(ctx context.Context, ID string, a *browser.LocalAssetFile) (*Asset, error) {
 
 	type updAsset struct {
-		IsArchived  bool    `json:"isArchived"`
-		IsFavorite  bool    `json:"isFavorite"`
-		Latitude    float64 `json:"latitude"`
-		Longitude   float64 `json:"longitude"`
-		Description string  `json:"description"`
+		IsArchived       bool    `json:"isArchived"`
+		IsFavorite       bool    `json:"isFavorite"`
+		Latitude         float64 `json:"latitude"`
+		Longitude        float64 `json:"longitude"`
+		Description      string  `json:"description"`
+		DateTimeOriginal string  `json:"dateTimeOriginal,omitempty"`
 	}
 	param := updAsset{
 		Description: a.Description,
@@ -235,8 +419,11 @@ func (ic *ImmichClient) UpdateAsset(ctx context.Context, ID string, a *browser.L
 		Latitude:    a.Latitude,
 		Longitude:   a.Longitude,
 	}
+	if a.ForceDate && !a.DateTaken.IsZero() {
+		param.DateTimeOriginal = a.DateTaken.Format(time.RFC3339)
+	}
 	r := Asset{}
-	err := ic.newServerCall(ctx, "updateAsset").do(put("/asset/"+ID, setJSONBody(param)), responseJSON(&r))
+	err := ic.newServerCall(ctx, "updateAsset", uploadCall()).do(put("/asset/"+ID, setJSONBody(param)), responseJSON(&r))
 	return &r, err
 }
 
@@ -0,0 +1,526 @@
+package immich
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/helpers/fshelper"
+)
+
+// uploadServer records the content type of the uploaded asset
+type uploadServer struct {
+	contentType string
+	assetType   string
+	libraryID   string
+}
+
+func (us *uploadServer) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	err := req.ParseMultipartForm(1 << 20)
+	if err != nil {
+		resp.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if files := req.MultipartForm.File["assetData"]; len(files) > 0 {
+		us.contentType = files[0].Header.Get("Content-Type")
+	}
+	us.assetType = req.FormValue("assetType")
+	us.libraryID = req.FormValue("libraryId")
+	resp.WriteHeader(http.StatusCreated)
+	resp.Write([]byte(`{"id":"1","duplicate":false}`))
+}
+
+func TestAssetUploadContentType(t *testing.T) {
+	fsys := fstest.MapFS{
+		"photo.cr3": {Data: []byte("raw content"), ModTime: time.Now()},
+		"movie.mp4": {Data: []byte("video content"), ModTime: time.Now()},
+	}
+	tc := []struct {
+		name          string
+		file          string
+		mimeType      string
+		wantType      string
+		wantAssetType string
+	}{
+		{name: "guessed", file: "photo.cr3", wantType: "image/cr3", wantAssetType: "IMAGE"},
+		{name: "forced", file: "photo.cr3", mimeType: "image/x-canon-cr3", wantType: "image/x-canon-cr3", wantAssetType: "IMAGE"},
+		{name: "forced video", file: "movie.mp4", mimeType: "video/x-custom", wantType: "video/x-custom", wantAssetType: "VIDEO"},
+	}
+	for _, c := range tc {
+		t.Run(c.name, func(t *testing.T) {
+			us := &uploadServer{}
+			server := httptest.NewServer(us)
+			defer server.Close()
+			ic, err := NewImmichClient(server.URL, "1234", false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			la := &browser.LocalAssetFile{FSys: fsys, FileName: c.file, Title: c.file, MimeType: c.mimeType}
+			_, err = ic.AssetUpload(context.Background(), la)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if us.contentType != c.wantType {
+				t.Errorf("Content-Type=%q, want %q", us.contentType, c.wantType)
+			}
+			if us.assetType != c.wantAssetType {
+				t.Errorf("assetType=%q, want %q", us.assetType, c.wantAssetType)
+			}
+		})
+	}
+}
+
+// countOpenFS counts the openings of the files
+type countOpenFS struct {
+	fstest.MapFS
+	opens int
+}
+
+func (c *countOpenFS) Open(name string) (fs.File, error) {
+	c.opens++
+	return c.MapFS.Open(name)
+}
+
+func TestAssetUploadChecksum(t *testing.T) {
+	content := make([]byte, 100*1024)
+	for i := range content {
+		content[i] = byte(i * 7)
+	}
+	want, err := fshelper.Checksum(bytes.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tc := []struct {
+		name     string
+		fileSize int // size known by the browser, 0 when unknown
+		partial  int // bytes read with the PartialSourceReader before the upload
+	}{
+		{name: "known size", fileSize: len(content)},
+		{name: "unknown size"},
+		{name: "after a partial read", fileSize: len(content), partial: 1024},
+	}
+	for _, c := range tc {
+		t.Run(c.name, func(t *testing.T) {
+			us := &uploadServer{}
+			server := httptest.NewServer(us)
+			defer server.Close()
+			ic, err := NewImmichClient(server.URL, "1234", false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fsys := &countOpenFS{MapFS: fstest.MapFS{"photo.cr3": {Data: content, ModTime: time.Now()}}}
+			la := &browser.LocalAssetFile{FSys: fsys, FileName: "photo.cr3", Title: "photo.cr3", FileSize: c.fileSize}
+			defer la.Close()
+			if c.partial > 0 {
+				r, err := la.PartialSourceReader()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, err = io.ReadFull(r, make([]byte, c.partial)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			_, err = ic.AssetUpload(context.Background(), la)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := la.Checksum()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != want {
+				t.Errorf("checksum %s, want %s", got, want)
+			}
+			if fsys.opens != 1 {
+				t.Errorf("file opened %d times, want 1", fsys.opens)
+			}
+		})
+	}
+}
+
+func TestAssetUploadLibrary(t *testing.T) {
+	fsys := fstest.MapFS{"photo.jpg": {Data: []byte("content"), ModTime: time.Now()}}
+	for _, library := range []string{"", "library-1"} {
+		us := &uploadServer{}
+		server := httptest.NewServer(us)
+		ic, err := NewImmichClient(server.URL, "1234", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ic.SetLibrary(library)
+		_, err = ic.AssetUpload(context.Background(), &browser.LocalAssetFile{FSys: fsys, FileName: "photo.jpg", Title: "photo.jpg"})
+		server.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if us.libraryID != library {
+			t.Errorf("libraryId=%q, want %q", us.libraryID, library)
+		}
+	}
+}
+
+func TestAssetUploadQuota(t *testing.T) {
+	fsys := fstest.MapFS{
+		"photo.jpg": {Data: []byte("content"), ModTime: time.Now()},
+	}
+	tc := []struct {
+		name   string
+		server testServer
+		want   bool
+	}{
+		{name: "quota message", server: testServer{responseStatus: http.StatusBadRequest, responseBody: `{"message":"Quota has been exceeded!","error":"Bad Request","statusCode":400}`}, want: true},
+		{name: "too large for the proxy", server: testServer{responseStatus: http.StatusRequestEntityTooLarge}, want: false},
+		{name: "insufficient storage", server: testServer{responseStatus: http.StatusInsufficientStorage}, want: true},
+		{name: "other error", server: testServer{responseStatus: http.StatusBadRequest, responseBody: `{"message":["assetData is missing"],"error":"Bad Request","statusCode":400}`}, want: false},
+	}
+	for _, c := range tc {
+		t.Run(c.name, func(t *testing.T) {
+			server := httptest.NewServer(&c.server)
+			defer server.Close()
+			ic, err := NewImmichClient(server.URL, "1234", false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			la := &browser.LocalAssetFile{FSys: fsys, FileName: "photo.jpg", Title: "photo.jpg"}
+			_, err = ic.AssetUpload(context.Background(), la)
+			if err == nil {
+				t.Fatal("expecting an error")
+			}
+			if got := errors.Is(err, ErrQuotaExceeded); got != c.want {
+				t.Errorf("errors.Is(err, ErrQuotaExceeded)=%v, want %v: %s", got, c.want, err)
+			}
+		})
+	}
+}
+
+// largeFS gives a single file made of zeros, without memory allocation
+type largeFS struct {
+	name string
+	size int64
+}
+
+func (l largeFS) Open(name string) (fs.File, error) {
+	if name != l.name {
+		return nil, fs.ErrNotExist
+	}
+	return &largeFile{fs: l}, nil
+}
+
+type largeFile struct {
+	fs   largeFS
+	read int64
+}
+
+func (f *largeFile) Read(b []byte) (int, error) {
+	if f.read >= f.fs.size {
+		return 0, io.EOF
+	}
+	n := int64(len(b))
+	if n > f.fs.size-f.read {
+		n = f.fs.size - f.read
+	}
+	clear(b[:n])
+	f.read += n
+	return int(n), nil
+}
+
+func (f *largeFile) Stat() (fs.FileInfo, error) { return f, nil }
+func (f *largeFile) Close() error               { return nil }
+func (f *largeFile) Name() string               { return f.fs.name }
+func (f *largeFile) Size() int64                { return f.fs.size }
+func (f *largeFile) Mode() fs.FileMode          { return 0o444 }
+func (f *largeFile) ModTime() time.Time         { return time.Now() }
+func (f *largeFile) IsDir() bool                { return false }
+func (f *largeFile) Sys() any                   { return nil }
+
+// countingServer checks the length of the received body
+type countingServer struct {
+	contentLength int64 // -1 when the body is chunked
+	received      int64
+}
+
+func (cs *countingServer) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	cs.contentLength = req.ContentLength
+	cs.received, _ = io.Copy(io.Discard, req.Body)
+	resp.WriteHeader(http.StatusCreated)
+	resp.Write([]byte(`{"id":"1","duplicate":false}`))
+}
+
+func TestAssetUploadLargeFile(t *testing.T) {
+	const size = 256 << 20
+	cs := &countingServer{}
+	server := httptest.NewServer(cs)
+	defer server.Close()
+	ic, err := NewImmichClient(server.URL, "1234", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	la := &browser.LocalAssetFile{
+		FSys:     largeFS{name: "drone.mp4", size: size},
+		FileName: "drone.mp4",
+		Title:    "drone.mp4",
+		FileSize: size,
+	}
+
+	var lastSent, lastSize int64
+	ic.SetUploadProgress(func(la *browser.LocalAssetFile, sent, size int64) {
+		lastSent, lastSize = sent, size
+	})
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	_, err = ic.AssetUpload(context.Background(), la)
+	runtime.ReadMemStats(&after)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cs.received <= size {
+		t.Errorf("the server received %d bytes, expecting more than %d", cs.received, size)
+	}
+	if cs.contentLength != cs.received {
+		t.Errorf("Content-Length=%d, received %d bytes", cs.contentLength, cs.received)
+	}
+	if lastSent != size || lastSize != size {
+		t.Errorf("the last progress is %d/%d, expecting %d/%d", lastSent, lastSize, size, size)
+	}
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > size/8 {
+		t.Errorf("the upload has allocated %d bytes for a file of %d bytes", allocated, size)
+	}
+}
+
+// assetListServer serves a fixed list of assets and records the query
+type assetListServer struct {
+	body  string
+	query url.Values
+}
+
+func (s *assetListServer) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet || req.URL.Path != "/api/asset" {
+		resp.WriteHeader(http.StatusNotFound)
+		return
+	}
+	s.query = req.URL.Query()
+	resp.Write([]byte(s.body))
+}
+
+func TestGetAllAssetsWithFilter(t *testing.T) {
+	body := `[
+	{"id":"1","type":"IMAGE","isFavorite":true,"fileCreatedAt":"2023-06-01T10:00:00.000Z"},
+	{"id":"2","type":"VIDEO","isFavorite":true,"fileCreatedAt":"2023-06-02T10:00:00.000Z"},
+	{"id":"3","type":"IMAGE","isTrashed":true,"fileCreatedAt":"2023-06-03T10:00:00.000Z"},
+	{"id":"4","type":"IMAGE","fileCreatedAt":"2023-06-04T10:00:00.000Z","exifInfo":{"dateTimeOriginal":"2022-01-01T10:00:00.000Z"}}
+	]`
+	yes, no := true, false
+	tests := []struct {
+		name      string
+		opt       *GetAssetOptions
+		wantQuery url.Values
+		wantIDs   []string
+	}{
+		{
+			name:    "no options",
+			wantIDs: []string{"1", "2", "3", "4"},
+		},
+		{
+			name:      "favorites",
+			opt:       &GetAssetOptions{IsFavorite: &yes},
+			wantQuery: url.Values{"isFavorite": {"true"}},
+			wantIDs:   []string{"1", "2", "3", "4"},
+		},
+		{
+			name:      "updated after",
+			opt:       &GetAssetOptions{UpdatedAfter: time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)},
+			wantQuery: url.Values{"updatedAfter": {"2023-06-01T00:00:00Z"}},
+			wantIDs:   []string{"1", "2", "3", "4"},
+		},
+		{
+			name:    "images",
+			opt:     &GetAssetOptions{Type: "IMAGE"},
+			wantIDs: []string{"1", "3", "4"},
+		},
+		{
+			name:    "not trashed videos",
+			opt:     &GetAssetOptions{Type: "video", IsTrashed: &no},
+			wantIDs: []string{"2"},
+		},
+		{
+			name: "taken between",
+			opt: &GetAssetOptions{
+				TakenAfter:  time.Date(2023, 6, 2, 10, 0, 0, 0, time.UTC),
+				TakenBefore: time.Date(2023, 6, 4, 0, 0, 0, 0, time.UTC),
+			},
+			wantIDs: []string{"2", "3"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &assetListServer{body: body}
+			server := httptest.NewServer(s)
+			defer server.Close()
+			ic, err := NewImmichClient(server.URL, "1234", false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ids []string
+			err = ic.GetAllAssetsWithFilter(context.Background(), tt.opt, func(a *Asset) {
+				ids = append(ids, a.ID)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) {
+				t.Errorf("assets = %v, want %v", ids, tt.wantIDs)
+			}
+			if tt.wantQuery == nil {
+				tt.wantQuery = url.Values{}
+			}
+			if !reflect.DeepEqual(s.query, tt.wantQuery) {
+				t.Errorf("query = %v, want %v", s.query, tt.wantQuery)
+			}
+		})
+	}
+}
+
+// pagingServer serves count assets by pages given by skip and take
+type pagingServer struct {
+	count    int
+	failSkip int // skip of the page answered by an error, 0 for none
+	mut      sync.Mutex
+	requests int
+}
+
+func (s *pagingServer) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	s.mut.Lock()
+	s.requests++
+	s.mut.Unlock()
+	skip, _ := strconv.Atoi(req.URL.Query().Get("skip"))
+	take, _ := strconv.Atoi(req.URL.Query().Get("take"))
+	if s.failSkip > 0 && skip == s.failSkip {
+		resp.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	l := []*Asset{}
+	for i := skip; i < min(skip+take, s.count); i++ {
+		l = append(l, &Asset{ID: strconv.Itoa(i), Type: "IMAGE"})
+	}
+	_ = json.NewEncoder(resp).Encode(l)
+}
+
+func TestGetAllAssetsByPage(t *testing.T) {
+	tests := []struct {
+		name     string
+		count    int
+		failSkip int
+		wantErr  bool
+	}{
+		{name: "several pages", count: 95},
+		{name: "full pages", count: 100},
+		{name: "empty", count: 0},
+		{name: "error", count: 95, failSkip: 40, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &pagingServer{count: tt.count, failSkip: tt.failSkip}
+			server := httptest.NewServer(s)
+			defer server.Close()
+			ic, err := NewImmichClient(server.URL, "1234", false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			seen := map[string]int{}
+			err = ic.GetAllAssetsWithFilter(context.Background(), &GetAssetOptions{PageSize: 10, Concurrency: 3}, func(a *Asset) {
+				seen[a.ID]++ // not protected: the filter is never called concurrently
+			})
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expecting an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(seen) != tt.count {
+				t.Errorf("%d assets received, want %d", len(seen), tt.count)
+			}
+			for id, n := range seen {
+				if n != 1 {
+					t.Errorf("asset %s received %d times", id, n)
+				}
+			}
+			// the pages after the last one may be requested by the other workers
+			if maxRequests := tt.count/10 + 3; s.requests > maxRequests {
+				t.Errorf("%d requests, want at most %d", s.requests, maxRequests)
+			}
+		})
+	}
+}
+
+func TestAssetImportNotSupported(t *testing.T) {
+	fsys := fstest.MapFS{
+		"photo.jpg": {Data: []byte("content"), ModTime: time.Now()},
+	}
+	server := httptest.NewServer(&testServer{responseStatus: http.StatusNotFound, responseBody: `{"message":"Cannot POST /api/asset/import","error":"Not Found","statusCode":404}`})
+	defer server.Close()
+	ic, err := NewImmichClient(server.URL, "1234", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	la := &browser.LocalAssetFile{FSys: fsys, FileName: "photo.jpg", Title: "photo.jpg"}
+	_, err = ic.AssetImport(context.Background(), la, "/data/photo.jpg", "")
+	if !errors.Is(err, ErrImportNotSupported) {
+		t.Errorf("expecting ErrImportNotSupported, got %v", err)
+	}
+}
+
+func TestUpdateAssetDateTimeOriginal(t *testing.T) {
+	date := time.Date(1987, 1, 1, 0, 0, 0, 0, time.UTC)
+	tc := []struct {
+		name      string
+		forceDate bool
+		want      string
+	}{
+		{name: "date of the file", want: ""},
+		{name: "forced date", forceDate: true, want: "1987-01-01T00:00:00Z"},
+	}
+	for _, c := range tc {
+		t.Run(c.name, func(t *testing.T) {
+			var body map[string]any
+			server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+				_ = json.NewDecoder(req.Body).Decode(&body)
+				resp.Header().Set("Content-Type", "application/json")
+				resp.Write([]byte(`{"id":"1"}`))
+			}))
+			defer server.Close()
+			ic, err := NewImmichClient(server.URL, "1234", false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = ic.UpdateAsset(context.Background(), "1", &browser.LocalAssetFile{DateTaken: date, ForceDate: c.forceDate})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, _ := body["dateTimeOriginal"].(string)
+			if got != c.want {
+				t.Errorf("dateTimeOriginal=%q, want %q", got, c.want)
+			}
+		})
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 )
 
 type TooManyInternalError struct {
@@ -27,24 +28,50 @@ type serverCall struct {
 	ic       *ImmichClient
 	err      error
 	ctx      context.Context
+	timeout  time.Duration
 }
 
 type serverCallOption func(sc *serverCall) error
 
+// uploadCall applies the upload deadline to the call instead of the API one
+func uploadCall() serverCallOption {
+	return func(sc *serverCall) error {
+		sc.timeout = sc.ic.UploadTimeout
+		return nil
+	}
+}
+
 // callError represents errors returned by the server
 type callError struct {
-	endPoint string
-	method   string
-	url      string
-	status   int
-	err      error
-	message  *ServerMessage
+	endPoint   string
+	method     string
+	url        string
+	status     int
+	err        error
+	message    *ServerMessage
+	key        string        // API key, hidden from the message
+	retryAfter time.Duration // Delay asked by the server with a 429 status
 }
 
 type ServerMessage struct {
-	Error      string   `json:"error"`
-	StatusCode string   `json:"statusCode"`
-	Message    []string `json:"message"`
+	Error      string         `json:"error"`
+	StatusCode string         `json:"statusCode"`
+	Message    ServerMessages `json:"message"`
+}
+
+// ServerMessages accepts the message of the server given as a string or as a list of strings
+type ServerMessages []string
+
+func (m *ServerMessages) UnmarshalJSON(b []byte) error {
+	var s string
+	if json.Unmarshal(b, &s) == nil {
+		*m = ServerMessages{s}
+		return nil
+	}
+	var l []string
+	err := json.Unmarshal(b, &l)
+	*m = l
+	return err
 }
 
 func (u callError) Is(target error) bool {
@@ -52,6 +79,10 @@ func (u callError) Is(target error) bool {
 	return ok
 }
 
+func (u callError) Unwrap() error {
+	return u.err
+}
+
 func (ce callError) Error() string {
 	b := strings.Builder{}
 	b.WriteString(ce.endPoint)
@@ -80,7 +111,61 @@ func (ce callError) Error() string {
 			}
 		}
 	}
-	return b.String()
+	return redactKey(b.String(), ce.key)
+}
+
+// callStatus returns the HTTP status of the server's error, 0 when not available
+func callStatus(err error) int {
+	var ce callError
+	if errors.As(err, &ce) {
+		return ce.status
+	}
+	return 0
+}
+
+// isQuotaError tells if the server refused the request because the storage is full.
+// A 413 status is given by the reverse proxies limiting the size of the requests, it isn't a quota error.
+func isQuotaError(err error) bool {
+	var ce callError
+	if !errors.As(err, &ce) {
+		return false
+	}
+	if ce.status == http.StatusInsufficientStorage {
+		return true
+	}
+	if ce.message == nil {
+		return false
+	}
+	for _, m := range ce.message.Message {
+		if strings.Contains(strings.ToLower(m), "quota") {
+			return true
+		}
+	}
+	return false
+}
+
+// isAccessError tells if the server refused the request because the user has no access to the resource.
+// The server gives a 400 status for a resource not shared with the user, with a message like
+// "Not found or no album.addAsset access", and for an invalid request too.
+func isAccessError(err error) bool {
+	var ce callError
+	if !errors.As(err, &ce) {
+		return false
+	}
+	switch ce.status {
+	case http.StatusForbidden, http.StatusNotFound:
+		return true
+	case http.StatusBadRequest:
+		if ce.message == nil {
+			return false
+		}
+		for _, m := range ce.message.Message {
+			if strings.Contains(strings.ToLower(m), "access") {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 func (ic *ImmichClient) newServerCall(ctx context.Context, api string, opts ...serverCallOption) *serverCall {
@@ -88,6 +173,7 @@ func (ic *ImmichClient) newServerCall(ctx context.Context, api string, opts ...s
 		endPoint: api,
 		ic:       ic,
 		ctx:      ctx,
+		timeout:  ic.APITimeout,
 	}
 	if sc.err == nil {
 		for _, opt := range opts {
@@ -101,6 +187,7 @@ func (sc *serverCall) Err(req *http.Request, resp *http.Response, msg *ServerMes
 	ce := callError{
 		endPoint: sc.endPoint,
 		err:      sc.err,
+		key:      sc.ic.key,
 	}
 	if req != nil {
 		ce.method = req.Method
@@ -108,6 +195,9 @@ func (sc *serverCall) Err(req *http.Request, resp *http.Response, msg *ServerMes
 	}
 	if resp != nil {
 		ce.status = resp.StatusCode
+		if resp.StatusCode == http.StatusTooManyRequests {
+			ce.retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		}
 	}
 	ce.message = msg
 	return ce
@@ -125,7 +215,7 @@ func (sc *serverCall) request(method string, url string, opts ...serverRequestOp
 	if sc.joinError(err) != nil {
 		return nil
 	}
-	opts = append(opts, setAPIKey())
+	opts = append(opts, setHeaders(), setAPIKey())
 	for _, opt := range opts {
 		if sc.joinError(opt(sc, req)) != nil {
 			return nil
@@ -169,6 +259,15 @@ func put(url string, opts ...serverRequestOption) requestFunction {
 	}
 }
 
+func patch(url string, opts ...serverRequestOption) requestFunction {
+	return func(sc *serverCall) *http.Request {
+		if sc.err != nil {
+			return nil
+		}
+		return sc.request(http.MethodPatch, sc.ic.endPoint+url, opts...)
+	}
+}
+
 func (sc *serverCall) do(fnRequest requestFunction, opts ...serverResponseOption) error {
 	if sc.err != nil || fnRequest == nil {
 		return sc.Err(nil, nil, nil)
@@ -179,10 +278,22 @@ func (sc *serverCall) do(fnRequest requestFunction, opts ...serverResponseOption
 		return sc.Err(req, nil, nil)
 	}
 
+	if sc.timeout > 0 {
+		ctx, cancel := context.WithTimeout(req.Context(), sc.timeout)
+		defer cancel()
+		req = req.WithContext(ctx)
+	}
+
 	if sc.ic.ApiTrace /* && req.Header.Get("Content-Type") == "application/json"*/ {
 		setTraceJSONRequest()(sc, req)
 	}
 
+	if sc.ic.limiter != nil {
+		if sc.joinError(sc.ic.limiter.wait(req.Context())) != nil {
+			return sc.Err(req, nil, nil)
+		}
+	}
+
 	var (
 		resp *http.Response
 		err  error
@@ -229,6 +340,15 @@ func setBody(body io.ReadCloser) serverRequestOption {
 	}
 }
 
+// setSizedBody sets a body of known length, sent without chunked encoding
+func setSizedBody(body io.Reader, length int64) serverRequestOption {
+	return func(sc *serverCall, req *http.Request) error {
+		req.Body = io.NopCloser(body)
+		req.ContentLength = length
+		return nil
+	}
+}
+
 func setHeader(key, value string) serverRequestOption {
 	return func(sc *serverCall, req *http.Request) error {
 		req.Header.Set(key, value)
@@ -242,6 +362,19 @@ func setAcceptJSON() serverRequestOption {
 	}
 }
 
+// setHeaders adds the headers given to the client
+func setHeaders() serverRequestOption {
+	return func(sc *serverCall, req *http.Request) error {
+		for h, vs := range sc.ic.headers {
+			req.Header.Del(h)
+			for _, v := range vs {
+				req.Header.Add(h, v)
+			}
+		}
+		return nil
+	}
+}
+
 func setAPIKey() serverRequestOption {
 	return func(sc *serverCall, req *http.Request) error {
 		req.Header.Set("x-api-key", sc.ic.key)
@@ -274,7 +407,7 @@ func setContentType(ctype string) serverRequestOption {
 func setUrlValues(values url.Values) serverRequestOption {
 	return func(sc *serverCall, req *http.Request) error {
 		if values != nil {
-			req.URL.RawPath = values.Encode()
+			req.URL.RawQuery = values.Encode()
 		}
 		return sc.err
 	}
@@ -2,18 +2,29 @@ package immich
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 )
 
 type testServer struct {
 	// endpoint       string
 	responseStatus int
 	responseBody   string
+	delay          time.Duration
 }
 
 func (ts *testServer) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	if ts.delay > 0 {
+		select {
+		case <-time.After(ts.delay):
+		case <-req.Context().Done():
+			return
+		}
+	}
 	resp.WriteHeader(ts.responseStatus)
 	resp.Write([]byte(ts.responseBody))
 }
@@ -90,3 +101,127 @@ func TestCall(t *testing.T) {
 	}
 
 }
+
+func TestCallTimeout(t *testing.T) {
+	tt := []struct {
+		name        string
+		opts        []serverCallOption
+		expectedErr bool
+	}{
+		{
+			name:        "api call too long",
+			expectedErr: true,
+		},
+		{
+			name: "upload within its deadline",
+			opts: []serverCallOption{uploadCall()},
+		},
+	}
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			server := httptest.NewServer(&testServer{
+				responseStatus: http.StatusOK,
+				responseBody:   `{"status": "All correct"}`,
+				delay:          100 * time.Millisecond,
+			})
+			defer server.Close()
+			ic, err := NewImmichClient(server.URL, "1234", false)
+			if err != nil {
+				t.Fail()
+				return
+			}
+			ic.SetTimeouts(time.Minute, 10*time.Millisecond)
+			r := map[string]string{}
+			err = ic.newServerCall(context.Background(), tst.name, tst.opts...).do(get("/assets", setAcceptJSON()), responseJSON(&r))
+			if tst.expectedErr != (err != nil) {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			if tst.expectedErr && !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("expected a deadline error, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestCallRedactKey(t *testing.T) {
+	const key = "secret-api-key"
+	server := httptest.NewServer(&testServer{
+		responseStatus: http.StatusUnauthorized,
+		responseBody:   `{"error": "Unauthorized", "statusCode": "401", "message": ["Invalid API key secret-api-key"]}`,
+	})
+	defer server.Close()
+	ic, err := NewImmichClient(server.URL, key, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ic.newServerCall(context.Background(), "redact").do(get("/assets?key="+key, setAcceptJSON()))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if strings.Contains(err.Error(), key) {
+		t.Errorf("the error shows the API key: %s", err)
+	}
+	if v := redactHeader("x-api-key", []string{key}); v[0] == key {
+		t.Errorf("the x-api-key header isn't redacted")
+	}
+}
+
+// headerServer records the headers and the URL of the last request
+type headerServer struct {
+	header http.Header
+	url    string
+}
+
+func (hs *headerServer) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	hs.header = req.Header.Clone()
+	hs.url = req.URL.String()
+	resp.Write([]byte(`{"res":"pong"}`))
+}
+
+func TestHeadersAndProxy(t *testing.T) {
+	hs := &headerServer{}
+	server := httptest.NewServer(hs)
+	defer server.Close()
+
+	ic, err := NewImmichClient("http://immich.example.com", "1234", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ic.SetHeaders(http.Header{
+		"Cf-Access-Client-Id":     {"client-id"},
+		"Cf-Access-Client-Secret": {"client-secret"},
+		"X-Api-Key":               {"not-the-key"},
+	})
+	// the test server acts as the proxy
+	err = ic.SetProxy(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ic.PingServer(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hs.url != "http://immich.example.com/api/server-info/ping" {
+		t.Errorf("the request hasn't been sent through the proxy, URL: %s", hs.url)
+	}
+	if hs.header.Get("Cf-Access-Client-Id") != "client-id" || hs.header.Get("Cf-Access-Client-Secret") != "client-secret" {
+		t.Errorf("the headers aren't sent: %v", hs.header)
+	}
+	if hs.header.Get("X-Api-Key") != "1234" {
+		t.Errorf("the API key is replaced by a header: %v", hs.header)
+	}
+
+	if v := redactHeader("Cf-Access-Client-Secret", []string{"client-secret"}); v[0] != "***" {
+		t.Errorf("the secret header isn't redacted")
+	}
+	if v := redactHeader("Accept", []string{"application/json"}); v[0] != "application/json" {
+		t.Errorf("the Accept header is redacted")
+	}
+
+	for _, p := range []string{"ftp://proxy", "://proxy"} {
+		if err := ic.SetProxy(p); err == nil {
+			t.Errorf("SetProxy(%q): expecting an error", p)
+		}
+	}
+}
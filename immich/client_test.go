@@ -0,0 +1,103 @@
+package immich
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSetHTTP2(t *testing.T) {
+	tc := []struct {
+		name  string
+		http2 bool
+		want  int
+	}{
+		{name: "HTTP/2 allowed", http2: true, want: 2},
+		{name: "HTTP/1.1 forced", http2: false, want: 1},
+	}
+	for _, c := range tc {
+		t.Run(c.name, func(t *testing.T) {
+			proto := 0
+			server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				proto = r.ProtoMajor
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"res":"pong"}`))
+			}))
+			server.EnableHTTP2 = true
+			server.StartTLS()
+			defer server.Close()
+
+			// the test server has a self-signed certificate
+			ic, err := NewImmichClient(server.URL, "1234", true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = ic.SetHTTP2(c.http2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = ic.PingServer(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if proto != c.want {
+				t.Errorf("protocol = HTTP/%d, want HTTP/%d", proto, c.want)
+			}
+		})
+	}
+}
+
+func TestSetMaxIdleConns(t *testing.T) {
+	ic, err := NewImmichClient("http://localhost", "1234", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ic.SetMaxIdleConns(-1); err == nil {
+		t.Error("expecting an error for a negative number")
+	}
+	if err = ic.SetMaxIdleConns(8); err != nil {
+		t.Fatal(err)
+	}
+	tr := ic.client.Transport.(*http.Transport)
+	if tr.MaxIdleConns != 8 || tr.MaxIdleConnsPerHost != 8 {
+		t.Errorf("idle connections = %d, %d per host, want 8", tr.MaxIdleConns, tr.MaxIdleConnsPerHost)
+	}
+}
+
+func TestValidateConnection(t *testing.T) {
+	const key = "secret-api-key"
+	tc := []struct {
+		name     string
+		server   testServer
+		wantUser string
+		wantAuth bool
+	}{
+		{name: "valid key", server: testServer{responseStatus: http.StatusOK, responseBody: `{"id":"1","email":"me@example.com"}`}, wantUser: "me@example.com"},
+		{name: "expired key", server: testServer{responseStatus: http.StatusUnauthorized, responseBody: `{"error":"Unauthorized","statusCode":401,"message":"Invalid API key secret-api-key"}`}, wantAuth: true},
+		{name: "forbidden", server: testServer{responseStatus: http.StatusForbidden, responseBody: `{"error":"Forbidden","statusCode":403,"message":"Missing permission"}`}, wantAuth: true},
+		{name: "server error", server: testServer{responseStatus: http.StatusInternalServerError}},
+	}
+	for _, c := range tc {
+		t.Run(c.name, func(t *testing.T) {
+			server := httptest.NewServer(&c.server)
+			defer server.Close()
+			ic, err := NewImmichClient(server.URL, key, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			u, err := ic.ValidateConnection(context.Background())
+			if got := errors.Is(err, ErrUnauthorized); got != c.wantAuth {
+				t.Errorf("errors.Is(err, ErrUnauthorized)=%v, want %v: %v", got, c.wantAuth, err)
+			}
+			if err != nil && strings.Contains(err.Error(), key) {
+				t.Errorf("the error shows the API key: %s", err)
+			}
+			if u.Email != c.wantUser {
+				t.Errorf("user=%q, want %q", u.Email, c.wantUser)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package immich
+
+import (
+	"cmp"
+	"time"
+)
+
+// CompareDate compares two dates of capture. The dates within 5 minutes are the same:
+// the cameras, the phones and the server don't record them with the same precision.
+func CompareDate(d1 time.Time, d2 time.Time) int {
+	diff := d1.Sub(d2)
+
+	switch {
+	case diff < -5*time.Minute:
+		return -1
+	case diff >= 5*time.Minute:
+		return +1
+	}
+	return 0
+}
+
+// ComparePixels compares two resolutions given by their width and height.
+// It gives 0 when one of them is unknown.
+func ComparePixels(w1, h1, w2, h2 int) int {
+	p1, p2 := w1*h1, w2*h2
+	if p1 == 0 || p2 == 0 {
+		return 0
+	}
+	return cmp.Compare(p1, p2)
+}
+
+// CompareCopies compares two copies of the same asset, having the same name and date of capture:
+// the higher resolution wins, then the bigger size when a resolution is unknown or when both are equal.
+func CompareCopies(w1, h1, size1, w2, h2, size2 int) int {
+	if c := ComparePixels(w1, h1, w2, h2); c != 0 {
+		return c
+	}
+	return cmp.Compare(size1, size2)
+}
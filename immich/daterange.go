@@ -5,7 +5,8 @@ import (
 	"time"
 )
 
-// DateRange represent the date range for capture date
+// DateRange represent the date range for capture date.
+// A zero After or Before leaves the range open on this side.
 type DateRange struct {
 	After, Before         time.Time
 	day, month, year, set bool
@@ -19,7 +20,14 @@ func (dr DateRange) String() string {
 	} else if dr.year {
 		return dr.After.Format("2006")
 	}
-	return dr.After.Format("2006-01-02") + "," + dr.Before.AddDate(0, 0, -1).Format("2006-01-02")
+	var after, before string
+	if !dr.After.IsZero() {
+		after = dr.After.Format("2006-01-02")
+	}
+	if !dr.Before.IsZero() {
+		before = dr.Before.AddDate(0, 0, -1).Format("2006-01-02")
+	}
+	return after + "," + before
 }
 
 func (dr *DateRange) Set(s string) (err error) {
@@ -62,6 +70,30 @@ func (dr *DateRange) Set(s string) (err error) {
 	return fmt.Errorf("invalid date range:%w", err)
 }
 
+// NewOpenDateRange gives the range from the beginning of since to the end of until,
+// given as YYYY, YYYY-MM or YYYY-MM-DD. An empty bound leaves the range open on this side.
+func NewOpenDateRange(since, until string) (DateRange, error) {
+	dr := DateRange{set: true}
+	if since != "" {
+		var b DateRange
+		if len(since) > 10 || b.Set(since) != nil {
+			return DateRange{}, fmt.Errorf("invalid date %q, expecting YYYY, YYYY-MM or YYYY-MM-DD", since)
+		}
+		dr.After = b.After
+	}
+	if until != "" {
+		var b DateRange
+		if len(until) > 10 || b.Set(until) != nil {
+			return DateRange{}, fmt.Errorf("invalid date %q, expecting YYYY, YYYY-MM or YYYY-MM-DD", until)
+		}
+		dr.Before = b.Before
+	}
+	if !dr.After.IsZero() && !dr.Before.IsZero() && !dr.After.Before(dr.Before) {
+		return DateRange{}, fmt.Errorf("the date %s is after the date %s", since, until)
+	}
+	return dr, nil
+}
+
 func (dr DateRange) IsSet() bool { return dr.set }
 
 func (dr DateRange) InRange(d time.Time) bool {
@@ -69,5 +101,19 @@ func (dr DateRange) InRange(d time.Time) bool {
 		return true
 	}
 	//	--------------After----------d------------Before
-	return (d.Compare(dr.After) >= 0 && dr.Before.Compare(d) > 0)
+	return (dr.After.IsZero() || d.Compare(dr.After) >= 0) && (dr.Before.IsZero() || dr.Before.Compare(d) > 0)
+}
+
+// SetLocation interprets the dates of the range in the given time zone,
+// the same way as the dates of capture given without time zone.
+func (dr *DateRange) SetLocation(loc *time.Location) {
+	if !dr.set || loc == nil {
+		return
+	}
+	if !dr.After.IsZero() {
+		dr.After = time.Date(dr.After.Year(), dr.After.Month(), dr.After.Day(), 0, 0, 0, 0, loc)
+	}
+	if !dr.Before.IsZero() {
+		dr.Before = time.Date(dr.Before.Year(), dr.Before.Month(), dr.Before.Day(), 0, 0, 0, 0, loc)
+	}
 }
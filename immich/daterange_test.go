@@ -166,3 +166,69 @@ func TestDateRange_InRange(t *testing.T) {
 		})
 	}
 }
+
+func TestDateRange_SetLocation(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skip("time zone database not available")
+	}
+	var dr DateRange
+	err = dr.Set("2023")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Taken on 2023-01-01 at 00:30 in Paris, 2022-12-31 at 23:30 UTC
+	d := time.Date(2023, 1, 1, 0, 30, 0, 0, paris)
+	if dr.InRange(d) {
+		t.Errorf("the date shouldn't be in the UTC range")
+	}
+	dr.SetLocation(paris)
+	if !dr.InRange(d) {
+		t.Errorf("the date should be in the Paris range")
+	}
+	if dr.String() != "2023" {
+		t.Errorf("the String() gives %q, want %q", dr.String(), "2023")
+	}
+}
+
+func TestNewOpenDateRange(t *testing.T) {
+	tests := []struct {
+		since, until string
+		str          string
+		in, out      []string
+		wantErr      bool
+	}{
+		{since: "2023-07", str: "2023-07-01,", in: []string{"2023-07-01 00:00:00", "2030-01-01 00:00:00"}, out: []string{"2023-06-30 23:59:59"}},
+		{until: "2023", str: ",2023-12-31", in: []string{"1900-01-01 00:00:00", "2023-12-31 23:59:59"}, out: []string{"2024-01-01 00:00:00"}},
+		{since: "2023-01-10", until: "2023-01-10", str: "2023-01-10,2023-01-10", in: []string{"2023-01-10 12:00:00"}, out: []string{"2023-01-11 00:00:00"}},
+		{since: "2023-02", until: "2023-01", wantErr: true},
+		{since: "2023-01-01,2023-02-01", wantErr: true},
+		{until: "yesterday", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.since+"_"+tt.until, func(t *testing.T) {
+			dr, err := NewOpenDateRange(tt.since, tt.until)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err != nil {
+				return
+			}
+			if dr.String() != tt.str {
+				t.Errorf("String() = %q, want %q", dr.String(), tt.str)
+			}
+			for _, s := range tt.in {
+				d, _ := time.ParseInLocation(time.DateTime, s, time.UTC)
+				if !dr.InRange(d) {
+					t.Errorf("%s should be in the range", s)
+				}
+			}
+			for _, s := range tt.out {
+				d, _ := time.ParseInLocation(time.DateTime, s, time.UTC)
+				if dr.InRange(d) {
+					t.Errorf("%s shouldn't be in the range", s)
+				}
+			}
+		})
+	}
+}
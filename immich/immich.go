@@ -40,6 +40,8 @@ type User struct {
 	DeletedAt            time.Time `json:"deletedAt"`
 	UpdatedAt            time.Time `json:"updatedAt"`
 	OauthID              string    `json:"oauthId"`
+	QuotaSizeInBytes     *int64    `json:"quotaSizeInBytes"`  // nil when the user has no quota
+	QuotaUsageInBytes    int64     `json:"quotaUsageInBytes"` // Storage used by the user
 }
 
 type List[T comparable] struct {
@@ -166,3 +168,11 @@ func (t *ImmichTime) UnmarshalJSON(b []byte) error {
 	t.Time = ts.In(local)
 	return nil
 }
+
+// ImmichTime.MarshalJSON writes the time as the server does, so it can be read back
+func (t ImmichTime) MarshalJSON() ([]byte, error) {
+	if t.IsZero() {
+		return []byte("null"), nil
+	}
+	return []byte(`"` + t.UTC().Format("2006-01-02T15:04:05.000Z") + `"`), nil
+}
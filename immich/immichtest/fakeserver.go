@@ -0,0 +1,597 @@
+package immichtest
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/helpers/fshelper"
+	"github.com/simulot/immich-go/immich"
+)
+
+// FakeServer is an Immich client working on the content of a scenario, without a server.
+// It is safe for concurrent use.
+type FakeServer struct {
+	mu        sync.Mutex
+	version   immich.ServerVersion
+	assets    []*immich.Asset
+	byID      map[string]*immich.Asset
+	albums    []*immich.AlbumSimplified
+	infos     map[string]immich.AlbumInfo // Album's info, by album ID
+	tags      []immich.Tag
+	tagged    map[string][]string // Assets IDs, by tag ID
+	people    []immich.Person
+	faces     map[string][]string // People IDs, by asset ID
+	libraries []immich.Library
+	errors    map[string]string
+	calls     map[string]int
+	uploaded  []string
+	lastID    int
+}
+
+// NewFakeServer gives a server with the content of the scenario
+func NewFakeServer(s *Scenario) *FakeServer {
+	srv := &FakeServer{
+		version:   s.Version,
+		byID:      map[string]*immich.Asset{},
+		infos:     map[string]immich.AlbumInfo{},
+		tags:      slices.Clone(s.Tags),
+		tagged:    map[string][]string{},
+		people:    slices.Clone(s.People),
+		faces:     map[string][]string{},
+		libraries: slices.Clone(s.Libraries),
+		errors:    s.Errors,
+		calls:     map[string]int{},
+	}
+	if srv.version == (immich.ServerVersion{}) {
+		srv.version = immich.MaxServerVersion
+	}
+	for _, a := range s.Assets {
+		a := a
+		srv.assets = append(srv.assets, &a)
+		srv.byID[a.ID] = &a
+	}
+	for _, al := range s.Albums {
+		al := al
+		al.AssetIds = slices.Clone(al.AssetIds)
+		srv.albums = append(srv.albums, &al)
+	}
+	return srv
+}
+
+// call counts the calls of the method, and gives the error of the scenario
+func (s *FakeServer) call(method string) error {
+	s.calls[method]++
+	if msg, ok := s.errors[method]; ok {
+		return fmt.Errorf("%s: %s", method, msg)
+	}
+	return nil
+}
+
+// newID gives a new ID with the prefix
+func (s *FakeServer) newID(prefix string) string {
+	s.lastID++
+	return fmt.Sprintf("%s-%04d", prefix, s.lastID)
+}
+
+// Calls gives the number of calls of the method
+func (s *FakeServer) Calls(method string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[method]
+}
+
+// Uploaded gives the names of the files uploaded or imported, in the order of the calls
+func (s *FakeServer) Uploaded() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.uploaded)
+}
+
+// Assets gives a copy of the server's assets, trashed ones included
+func (s *FakeServer) Assets() []immich.Asset {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l := make([]immich.Asset, 0, len(s.assets))
+	for _, a := range s.assets {
+		l = append(l, *a)
+	}
+	return l
+}
+
+// Albums gives a copy of the server's albums, with the IDs of their assets
+func (s *FakeServer) Albums() []immich.AlbumSimplified {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l := make([]immich.AlbumSimplified, 0, len(s.albums))
+	for _, al := range s.albums {
+		c := *al
+		c.AssetIds = slices.Clone(al.AssetIds)
+		c.AssetCount = len(c.AssetIds)
+		l = append(l, c)
+	}
+	return l
+}
+
+// AlbumInfo gives the last info set on the album
+func (s *FakeServer) AlbumInfo(albumID string) immich.AlbumInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.infos[albumID]
+}
+
+// TaggedAssets gives the IDs of the assets having the tag
+func (s *FakeServer) TaggedAssets(tagID string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.tagged[tagID])
+}
+
+// AssetPeople gives the IDs of the people on the asset
+func (s *FakeServer) AssetPeople(assetID string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.faces[assetID])
+}
+
+func (s *FakeServer) GetServerVersion(ctx context.Context) (immich.ServerVersion, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.version, s.call("GetServerVersion")
+}
+
+func (s *FakeServer) GetAllAssetsWithFilter(ctx context.Context, opt *immich.GetAssetOptions, filter func(*immich.Asset)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.call("GetAllAssetsWithFilter"); err != nil {
+		return err
+	}
+	for _, a := range s.assets {
+		if opt != nil && opt.IsFavorite != nil && *opt.IsFavorite != a.IsFavorite {
+			continue
+		}
+		if opt != nil && opt.IsArchived != nil && *opt.IsArchived != a.IsArchived {
+			continue
+		}
+		c := *a
+		filter(&c)
+	}
+	return nil
+}
+
+func (s *FakeServer) GetAssetsTakenBetween(ctx context.Context, after time.Time, before time.Time, filter func(*immich.Asset)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.call("GetAssetsTakenBetween"); err != nil {
+		return err
+	}
+	for _, a := range s.assets {
+		d := a.ExifInfo.DateTimeOriginal.Time
+		if !d.Before(after) && d.Before(before) {
+			c := *a
+			filter(&c)
+		}
+	}
+	return nil
+}
+
+func (s *FakeServer) GetAssetsUpdatedAfter(ctx context.Context, after time.Time, filter func(*immich.Asset)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.call("GetAssetsUpdatedAfter"); err != nil {
+		return err
+	}
+	for _, a := range s.assets {
+		if a.UpdatedAt.After(after) {
+			c := *a
+			filter(&c)
+		}
+	}
+	return nil
+}
+
+func (s *FakeServer) GetAssetStatistics(ctx context.Context) (immich.AssetStatistics, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := immich.AssetStatistics{}
+	for _, a := range s.assets {
+		if a.IsTrashed {
+			continue
+		}
+		switch a.Type {
+		case "IMAGE":
+			r.Images++
+		case "VIDEO":
+			r.Videos++
+		}
+		r.Total++
+	}
+	return r, s.call("GetAssetStatistics")
+}
+
+// AssetUpload adds the file as a new asset. The file is a duplicate when an asset has its checksum.
+func (s *FakeServer) AssetUpload(ctx context.Context, la *browser.LocalAssetFile) (immich.AssetResponse, error) {
+	return s.addAsset("AssetUpload", la, "upload/"+path.Base(la.Title))
+}
+
+// AssetImport registers the file as a new asset, at the given path
+func (s *FakeServer) AssetImport(ctx context.Context, la *browser.LocalAssetFile, assetPath string, sidecarPath string) (immich.AssetResponse, error) {
+	return s.addAsset("AssetImport", la, assetPath)
+}
+
+func (s *FakeServer) addAsset(method string, la *browser.LocalAssetFile, originalPath string) (immich.AssetResponse, error) {
+	// the file is read out of the lock, like it is sent to the server
+	sum, readErr := la.Checksum()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.call(method); err != nil {
+		return immich.AssetResponse{}, err
+	}
+	if readErr != nil {
+		return immich.AssetResponse{}, fmt.Errorf("%s: %w", method, readErr)
+	}
+	for _, a := range s.assets {
+		if a.Checksum == sum {
+			return immich.AssetResponse{ID: a.ID, Duplicate: true}, nil
+		}
+	}
+	now := immich.ImmichTime{Time: time.Now()}
+	a := &immich.Asset{
+		ID:               s.newID("asset"),
+		DeviceAssetID:    la.DeviceAssetID(),
+		Type:             strings.ToUpper(fshelper.TypeFromExt(path.Ext(la.FileName))),
+		OriginalPath:     originalPath,
+		OriginalFileName: strings.TrimSuffix(path.Base(la.Title), path.Ext(la.Title)),
+		FileCreatedAt:    immich.ImmichTime{Time: la.DateTaken},
+		FileModifiedAt:   immich.ImmichTime{Time: la.DateTaken},
+		UpdatedAt:        now,
+		IsFavorite:       la.Favorite,
+		IsArchived:       la.Archived,
+		Checksum:         sum,
+		ExifInfo: immich.ExifInfo{
+			FileSizeInByte:   int(la.Size()),
+			DateTimeOriginal: immich.ImmichTime{Time: la.DateTaken},
+			ExifImageWidth:   la.Width,
+			ExifImageHeight:  la.Height,
+			Latitude:         la.Latitude,
+			Longitude:        la.Longitude,
+			Description:      la.Description,
+		},
+	}
+	if la.ForceDate {
+		// the server takes the date of capture found in the file, the forced one comes with UpdateAsset
+		a.ExifInfo.DateTimeOriginal = immich.ImmichTime{}
+	}
+	s.assets = append(s.assets, a)
+	s.byID[a.ID] = a
+	s.uploaded = append(s.uploaded, la.FileName)
+	return immich.AssetResponse{ID: a.ID}, nil
+}
+
+// DeleteAssets moves the assets to the trash, or removes them with forceDelete
+func (s *FakeServer) DeleteAssets(ctx context.Context, IDs []string, forceDelete bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.call("DeleteAssets"); err != nil {
+		return err
+	}
+	for _, id := range IDs {
+		a, ok := s.byID[id]
+		if !ok {
+			continue
+		}
+		if !forceDelete {
+			a.IsTrashed = true
+			continue
+		}
+		delete(s.byID, id)
+		s.assets = slices.DeleteFunc(s.assets, func(sa *immich.Asset) bool { return sa.ID == id })
+		for _, al := range s.albums {
+			al.AssetIds = slices.DeleteFunc(al.AssetIds, func(aid string) bool { return aid == id })
+		}
+	}
+	return nil
+}
+
+func (s *FakeServer) RestoreAssets(ctx context.Context, IDs []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.call("RestoreAssets"); err != nil {
+		return err
+	}
+	for _, id := range IDs {
+		if a, ok := s.byID[id]; ok {
+			a.IsTrashed = false
+		}
+	}
+	return nil
+}
+
+func (s *FakeServer) GetAssetByID(ctx context.Context, id string) (*immich.Asset, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.call("GetAssetByID"); err != nil {
+		return nil, err
+	}
+	a, ok := s.byID[id]
+	if !ok {
+		return nil, fmt.Errorf("GetAssetByID: 404 Not Found: %s", id)
+	}
+	c := *a
+	return &c, nil
+}
+
+// UpdateAsset sets the flags, the location, the description and the forced date of capture of the file on the asset
+func (s *FakeServer) UpdateAsset(ctx context.Context, ID string, la *browser.LocalAssetFile) (*immich.Asset, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.call("UpdateAsset"); err != nil {
+		return nil, err
+	}
+	a, ok := s.byID[ID]
+	if !ok {
+		return nil, fmt.Errorf("UpdateAsset: 404 Not Found: %s", ID)
+	}
+	a.IsArchived = la.Archived
+	a.IsFavorite = la.Favorite
+	a.ExifInfo.Latitude = la.Latitude
+	a.ExifInfo.Longitude = la.Longitude
+	a.ExifInfo.Description = la.Description
+	if la.ForceDate && !la.DateTaken.IsZero() {
+		a.ExifInfo.DateTimeOriginal = immich.ImmichTime{Time: la.DateTaken}
+	}
+	a.UpdatedAt = immich.ImmichTime{Time: time.Now()}
+	c := *a
+	return &c, nil
+}
+
+func (s *FakeServer) UpdateAssets(ctx context.Context, IDs []string, isArchived bool, isFavorite bool, latitude float64, longitude float64, removeParent bool, stackParentId string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.call("UpdateAssets"); err != nil {
+		return err
+	}
+	for _, id := range IDs {
+		a, ok := s.byID[id]
+		if !ok {
+			continue
+		}
+		a.IsArchived = isArchived
+		a.IsFavorite = isFavorite
+		a.ExifInfo.Latitude = latitude
+		a.ExifInfo.Longitude = longitude
+		switch {
+		case removeParent:
+			a.StackParentId = ""
+		case stackParentId != "":
+			a.StackParentId = stackParentId
+		}
+		a.UpdatedAt = immich.ImmichTime{Time: time.Now()}
+	}
+	return nil
+}
+
+func (s *FakeServer) ArchiveAssets(ctx context.Context, IDs []string, isArchived bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.call("ArchiveAssets"); err != nil {
+		return err
+	}
+	for _, id := range IDs {
+		if a, ok := s.byID[id]; ok {
+			a.IsArchived = isArchived
+			a.UpdatedAt = immich.ImmichTime{Time: time.Now()}
+		}
+	}
+	return nil
+}
+
+// StackAssets puts the assets into the stack of the cover
+func (s *FakeServer) StackAssets(ctx context.Context, cover string, IDs []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.call("StackAssets"); err != nil {
+		return err
+	}
+	if _, ok := s.byID[cover]; !ok {
+		return fmt.Errorf("StackAssets: 404 Not Found: %s", cover)
+	}
+	for _, id := range IDs {
+		if a, ok := s.byID[id]; ok && id != cover {
+			a.StackParentId = cover
+		}
+	}
+	return nil
+}
+
+func (s *FakeServer) GetAllAlbums(ctx context.Context) ([]immich.AlbumSimplified, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.call("GetAllAlbums"); err != nil {
+		return nil, err
+	}
+	l := make([]immich.AlbumSimplified, 0, len(s.albums))
+	for _, al := range s.albums {
+		l = append(l, immich.AlbumSimplified{ID: al.ID, AlbumName: al.AlbumName, AssetCount: len(al.AssetIds)})
+	}
+	return l, nil
+}
+
+func (s *FakeServer) album(ID string) *immich.AlbumSimplified {
+	for _, al := range s.albums {
+		if al.ID == ID {
+			return al
+		}
+	}
+	return nil
+}
+
+// AddAssetToAlbum adds the assets to the album, the ones already in the album are duplicates
+func (s *FakeServer) AddAssetToAlbum(ctx context.Context, albumID string, IDs []string) ([]immich.UpdateAlbumResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.call("AddAssetToAlbum"); err != nil {
+		return nil, err
+	}
+	al := s.album(albumID)
+	if al == nil {
+		return nil, fmt.Errorf("AddAssetToAlbum: 404 Not Found: %s", albumID)
+	}
+	r := make([]immich.UpdateAlbumResult, 0, len(IDs))
+	for _, id := range IDs {
+		switch {
+		case s.byID[id] == nil:
+			r = append(r, immich.UpdateAlbumResult{ID: id, Error: "not_found"})
+		case slices.Contains(al.AssetIds, id):
+			r = append(r, immich.UpdateAlbumResult{ID: id, Error: "duplicate"})
+		default:
+			al.AssetIds = append(al.AssetIds, id)
+			r = append(r, immich.UpdateAlbumResult{ID: id, Success: true})
+		}
+	}
+	return r, nil
+}
+
+func (s *FakeServer) CreateAlbum(ctx context.Context, name string, IDs []string) (immich.AlbumSimplified, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.call("CreateAlbum"); err != nil {
+		return immich.AlbumSimplified{}, err
+	}
+	al := &immich.AlbumSimplified{ID: s.newID("album"), AlbumName: name}
+	for _, id := range IDs {
+		if s.byID[id] != nil && !slices.Contains(al.AssetIds, id) {
+			al.AssetIds = append(al.AssetIds, id)
+		}
+	}
+	s.albums = append(s.albums, al)
+	return immich.AlbumSimplified{ID: al.ID, AlbumName: al.AlbumName, AssetCount: len(al.AssetIds)}, nil
+}
+
+func (s *FakeServer) UpdateAlbumInfo(ctx context.Context, id string, info immich.AlbumInfo) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.call("UpdateAlbumInfo"); err != nil {
+		return err
+	}
+	if s.album(id) == nil {
+		return fmt.Errorf("UpdateAlbumInfo: 404 Not Found: %s", id)
+	}
+	s.infos[id] = info
+	return nil
+}
+
+func (s *FakeServer) GetAssetAlbums(ctx context.Context, id string) ([]immich.AlbumSimplified, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.call("GetAssetAlbums"); err != nil {
+		return nil, err
+	}
+	var l []immich.AlbumSimplified
+	for _, al := range s.albums {
+		if slices.Contains(al.AssetIds, id) {
+			l = append(l, immich.AlbumSimplified{ID: al.ID, AlbumName: al.AlbumName, AssetCount: len(al.AssetIds)})
+		}
+	}
+	return l, nil
+}
+
+// SetUploadProgress does nothing, the fake uploads are immediate
+func (s *FakeServer) SetUploadProgress(fn immich.UploadProgressFunc) {}
+
+func (s *FakeServer) GetAllLibraries(ctx context.Context) ([]immich.Library, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.libraries), s.call("GetAllLibraries")
+}
+
+// SetLibrary does nothing, the fake server has a single library
+func (s *FakeServer) SetLibrary(ID string) {}
+
+func (s *FakeServer) GetAllPeople(ctx context.Context) ([]immich.Person, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.people), s.call("GetAllPeople")
+}
+
+func (s *FakeServer) CreatePerson(ctx context.Context, name string) (immich.Person, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.call("CreatePerson"); err != nil {
+		return immich.Person{}, err
+	}
+	p := immich.Person{ID: s.newID("person"), Name: name}
+	s.people = append(s.people, p)
+	return p, nil
+}
+
+// AddPeopleToAsset gives a face of the asset to each person, the people without ID are created
+func (s *FakeServer) AddPeopleToAsset(ctx context.Context, assetID string, people []immich.Person) ([]immich.Person, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.call("AddPeopleToAsset"); err != nil {
+		return nil, err
+	}
+	if s.byID[assetID] == nil {
+		return nil, fmt.Errorf("AddPeopleToAsset: 404 Not Found: %s", assetID)
+	}
+	var created []immich.Person
+	for _, p := range people {
+		if p.ID == "" {
+			if err := s.call("CreatePerson"); err != nil {
+				return created, err
+			}
+			p.ID = s.newID("person")
+			s.people = append(s.people, p)
+			created = append(created, p)
+		}
+		if !slices.Contains(s.faces[assetID], p.ID) {
+			s.faces[assetID] = append(s.faces[assetID], p.ID)
+		}
+	}
+	return created, nil
+}
+
+func (s *FakeServer) GetAllTags(ctx context.Context) ([]immich.Tag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.tags), s.call("GetAllTags")
+}
+
+func (s *FakeServer) CreateTag(ctx context.Context, name string) (immich.Tag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.call("CreateTag"); err != nil {
+		return immich.Tag{}, err
+	}
+	t := immich.Tag{ID: s.newID("tag"), Name: name, Type: "CUSTOM"}
+	s.tags = append(s.tags, t)
+	return t, nil
+}
+
+// TagAssets tags the assets, the ones already tagged are duplicates
+func (s *FakeServer) TagAssets(ctx context.Context, tagID string, IDs []string) ([]immich.TagAssetResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.call("TagAssets"); err != nil {
+		return nil, err
+	}
+	r := make([]immich.TagAssetResult, 0, len(IDs))
+	for _, id := range IDs {
+		switch {
+		case s.byID[id] == nil:
+			r = append(r, immich.TagAssetResult{AssetID: id, Error: "not_found"})
+		case slices.Contains(s.tagged[tagID], id):
+			r = append(r, immich.TagAssetResult{AssetID: id, Error: "duplicate"})
+		default:
+			s.tagged[tagID] = append(s.tagged[tagID], id)
+			r = append(r, immich.TagAssetResult{AssetID: id, Success: true})
+		}
+	}
+	return r, nil
+}
@@ -0,0 +1,100 @@
+package immichtest
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/immich"
+)
+
+const scenario = `{
+	"version": {"major": 1, "minor": 100},
+	"assets": [
+		{
+			"id": "existing-01",
+			"type": "IMAGE",
+			"originalFileName": "photo_01",
+			"originalPath": "upload/photo_01.jpg",
+			"checksum": "sum-01",
+			"exifInfo": {"fileSizeInByte": 8, "dateTimeOriginal": "2023-10-06T06:30:00.000Z"}
+		}
+	],
+	"albums": [{"id": "album-trip", "albumName": "Trip", "assetIds": ["existing-01"]}],
+	"errors": {"CreateTag": "500 Internal Server Error"}
+}`
+
+func TestFakeServer(t *testing.T) {
+	s, err := ParseScenario(strings.NewReader(scenario))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := NewFakeServer(s)
+	ctx := context.Background()
+
+	v, err := srv.GetServerVersion(ctx)
+	if err != nil || v != (immich.ServerVersion{Major: 1, Minor: 100}) {
+		t.Errorf("version = %v, %v", v, err)
+	}
+
+	var taken []string
+	err = srv.GetAssetsTakenBetween(ctx, time.Date(2023, 10, 6, 0, 0, 0, 0, time.UTC), time.Date(2023, 10, 7, 0, 0, 0, 0, time.UTC), func(a *immich.Asset) {
+		taken = append(taken, a.ID)
+	})
+	if err != nil || !reflect.DeepEqual(taken, []string{"existing-01"}) {
+		t.Errorf("assets taken the 2023-10-06 = %v, %v", taken, err)
+	}
+
+	la := &browser.LocalAssetFile{
+		FSys:      fstest.MapFS{"photo_02.jpg": {Data: []byte("photo 02")}},
+		FileName:  "photo_02.jpg",
+		Title:     "photo_02.jpg",
+		FileSize:  8,
+		DateTaken: time.Date(2023, 10, 6, 7, 0, 0, 0, time.UTC),
+	}
+	r, err := srv.AssetUpload(ctx, la)
+	if err != nil || r.Duplicate {
+		t.Fatalf("upload = %+v, %v", r, err)
+	}
+	// the same content is a duplicate
+	again, err := srv.AssetUpload(ctx, la)
+	if err != nil || !again.Duplicate || again.ID != r.ID {
+		t.Errorf("second upload = %+v, %v, want a duplicate of %s", again, err, r.ID)
+	}
+
+	rr, err := srv.AddAssetToAlbum(ctx, "album-trip", []string{"existing-01", r.ID, "unknown"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []immich.UpdateAlbumResult{
+		{ID: "existing-01", Error: "duplicate"},
+		{ID: r.ID, Success: true},
+		{ID: "unknown", Error: "not_found"},
+	}
+	if !reflect.DeepEqual(rr, want) {
+		t.Errorf("album additions = %+v, want %+v", rr, want)
+	}
+	if albums := srv.Albums(); len(albums) != 1 || albums[0].AssetCount != 2 {
+		t.Errorf("albums = %+v", albums)
+	}
+
+	if _, err = srv.CreateTag(ctx, "Holidays"); err == nil {
+		t.Error("expected the error of the scenario")
+	}
+	if n := srv.Calls("CreateTag"); n != 1 {
+		t.Errorf("CreateTag called %d times", n)
+	}
+
+	err = srv.DeleteAssets(ctx, []string{"existing-01"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats, _ := srv.GetAssetStatistics(ctx)
+	if stats.Total != 1 {
+		t.Errorf("statistics = %+v, want the trashed asset excluded", stats)
+	}
+}
@@ -0,0 +1,82 @@
+/*
+Package immichtest provides a fake Immich server, to run the commands without a real server.
+
+The fake server keeps its assets, albums, tags and people in memory. Its initial content
+is given by a scenario, written in JSON with the server's field names:
+
+	{
+	  "version": {"major": 1, "minor": 105, "patch": 1},
+	  "assets": [
+	    {
+	      "id": "existing-01",
+	      "type": "IMAGE",
+	      "originalFileName": "PXL_20231006_063000139",
+	      "originalPath": "upload/library/PXL_20231006_063000139.jpg",
+	      "checksum": "B5ZsrW+VdDH3BC2QfZGK66jsLeo=",
+	      "exifInfo": {"fileSizeInByte": 1234, "dateTimeOriginal": "2023-10-06T06:30:00.000Z"}
+	    }
+	  ],
+	  "albums": [{"id": "album-trip", "albumName": "Trip", "assetIds": ["existing-01"]}],
+	  "tags": [{"id": "tag-holidays", "name": "Holidays"}],
+	  "errors": {"AssetUpload": "500 Internal Server Error"}
+	}
+
+The errors give the failure returned by each call of a method of the client, by the method's name.
+
+The FakeServer implements the client's methods used by the upload command, then the
+test checks the server's content once the command is done:
+
+	s, err := immichtest.ReadScenarioFile("scenario.json")
+	...
+	srv := immichtest.NewFakeServer(s)
+	err = cmdupload.UploadCommand(ctx, srv, log, args)
+	...
+	for _, a := range srv.Assets() {
+		...
+	}
+*/
+package immichtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/simulot/immich-go/immich"
+)
+
+// Scenario is the initial content of the fake server
+type Scenario struct {
+	Version   immich.ServerVersion     `json:"version"`   // Version of the server, the most recent supported one when missing
+	Assets    []immich.Asset           `json:"assets"`    // Assets on the server, trashed ones included
+	Albums    []immich.AlbumSimplified `json:"albums"`    // Albums with the IDs of their assets
+	Tags      []immich.Tag             `json:"tags"`      // Existing tags
+	People    []immich.Person          `json:"people"`    // Existing people
+	Libraries []immich.Library         `json:"libraries"` // Libraries of the user
+	Errors    map[string]string        `json:"errors"`    // Error returned by each call of a method, by method name
+}
+
+// ParseScenario reads the scenario given in JSON
+func ParseScenario(r io.Reader) (*Scenario, error) {
+	s := Scenario{}
+	err := json.NewDecoder(r).Decode(&s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid scenario: %w", err)
+	}
+	return &s, nil
+}
+
+// ReadScenarioFile reads the scenario from the JSON file
+func ReadScenarioFile(name string) (*Scenario, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	s, err := ParseScenario(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return s, nil
+}
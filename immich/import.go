@@ -0,0 +1,62 @@
+package immich
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/simulot/immich-go/browser"
+)
+
+// ErrImportNotSupported is returned when the server can't register the assets by their path
+var ErrImportNotSupported = errors.New("the server can't import the assets by their path")
+
+type importAsset struct {
+	AssetPath      string `json:"assetPath"`
+	SidecarPath    string `json:"sidecarPath,omitempty"`
+	DeviceAssetID  string `json:"deviceAssetId"`
+	DeviceID       string `json:"deviceId"`
+	FileCreatedAt  string `json:"fileCreatedAt"`
+	FileModifiedAt string `json:"fileModifiedAt"`
+	IsFavorite     bool   `json:"isFavorite"`
+	IsReadOnly     bool   `json:"isReadOnly"`
+	Duration       string `json:"duration"`
+	LibraryID      string `json:"libraryId,omitempty"`
+}
+
+// AssetImport registers the file found by the server at assetPath, without sending its content.
+// The sidecarPath is the server's path of the XMP sidecar, empty when none.
+func (ic *ImmichClient) AssetImport(ctx context.Context, la *browser.LocalAssetFile, assetPath string, sidecarPath string) (AssetResponse, error) {
+	var ar AssetResponse
+	s, err := fs.Stat(la.FSys, la.FileName)
+	if err != nil {
+		return ar, err
+	}
+	createdAt := la.DateTaken
+	if createdAt.IsZero() {
+		createdAt = s.ModTime()
+	}
+	body := importAsset{
+		AssetPath:      assetPath,
+		SidecarPath:    sidecarPath,
+		DeviceAssetID:  fmt.Sprintf("%s-%d", path.Base(la.Title), s.Size()),
+		DeviceID:       ic.DeviceUUID,
+		FileCreatedAt:  createdAt.Format(time.RFC3339),
+		FileModifiedAt: s.ModTime().Format(time.RFC3339),
+		IsFavorite:     la.Favorite,
+		IsReadOnly:     true,
+		Duration:       formatDuration(0),
+		LibraryID:      ic.libraryID,
+	}
+	err = ic.newServerCall(ctx, "AssetImport").do(
+		post("/asset/import", "application/json", setAcceptJSON(), setJSONBody(body)), responseJSON(&ar))
+	switch callStatus(err) {
+	case http.StatusNotFound, http.StatusMethodNotAllowed:
+		err = fmt.Errorf("%w: %w", ErrImportNotSupported, err)
+	}
+	return ar, err
+}
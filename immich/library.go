@@ -0,0 +1,27 @@
+package immich
+
+import (
+	"context"
+)
+
+// Library is a library of the user's assets on the server
+type Library struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	AssetCount int    `json:"assetCount"`
+}
+
+func (ic *ImmichClient) GetAllLibraries(ctx context.Context) ([]Library, error) {
+	var libraries []Library
+	err := ic.newServerCall(ctx, "GetAllLibraries").do(get("/library", setAcceptJSON()), responseJSON(&libraries))
+	if err != nil {
+		return nil, err
+	}
+	return libraries, nil
+}
+
+// SetLibrary sets the library receiving the uploaded assets, empty for the user's default library
+func (ic *ImmichClient) SetLibrary(ID string) {
+	ic.libraryID = ID
+}
@@ -0,0 +1,90 @@
+package metadata
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+/*
+Apple devices write the same content identifier into both parts of a Live Photo:
+- the photo has it in the Apple maker note, tag 0x0011
+- the video has it in the quicktime metadata under the key com.apple.quicktime.content.identifier
+*/
+
+const contentIdentifierWindow = 4 * 1024
+
+var uuidRE = regexp.MustCompile(`[0-9A-Fa-f]{8}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{12}`)
+
+// GetContentIdentifier returns the Apple Live Photo content identifier of the file
+func GetContentIdentifier(rd io.Reader, ext string) (string, error) {
+	switch strings.ToLower(ext) {
+	case ".heic", ".heif", ".jpg", ".jpeg":
+		return readAppleMakerNoteContentID(newSliceReader(rd))
+	case ".mov", ".mp4":
+		return readQuickTimeContentID(newSliceReader(rd))
+	}
+	return "", fmt.Errorf("can't get a content identifier from a %s file", ext)
+}
+
+// readAppleMakerNoteContentID locates the Apple maker note and reads the tag 0x0011
+func readAppleMakerNoteContentID(r *sliceReader) (string, error) {
+	b := make([]byte, searchBufferSize)
+	r, err := searchPattern(r, []byte("Apple iOS\x00"), b)
+	if err != nil {
+		return "", err
+	}
+	note := make([]byte, contentIdentifierWindow)
+	n, err := io.ReadFull(r, note)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	note = note[:n]
+
+	// "Apple iOS\0", version (2 bytes), byte order (2 bytes), then the IFD
+	if len(note) < 16 {
+		return "", io.ErrUnexpectedEOF
+	}
+	var order binary.ByteOrder = binary.BigEndian
+	if string(note[12:14]) == "II" {
+		order = binary.LittleEndian
+	}
+	count := int(order.Uint16(note[14:16]))
+	for i := 0; i < count; i++ {
+		e := 16 + i*12
+		if e+12 > len(note) {
+			break
+		}
+		if order.Uint16(note[e:e+2]) != 0x0011 || order.Uint16(note[e+2:e+4]) != 2 {
+			continue
+		}
+		l := int(order.Uint32(note[e+4 : e+8]))
+		ofs := int(order.Uint32(note[e+8 : e+12]))
+		if l <= 4 || ofs+l > len(note) {
+			break
+		}
+		return strings.TrimRight(string(note[ofs:ofs+l]), "\x00"), nil
+	}
+	return "", fmt.Errorf("no content identifier in the maker note")
+}
+
+// readQuickTimeContentID locates the content identifier key and returns the first identifier found after it
+func readQuickTimeContentID(r *sliceReader) (string, error) {
+	b := make([]byte, searchBufferSize)
+	r, err := searchPattern(r, []byte("com.apple.quicktime.content.identifier"), b)
+	if err != nil {
+		return "", err
+	}
+	meta := make([]byte, contentIdentifierWindow)
+	n, err := io.ReadFull(r, meta)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	id := uuidRE.Find(meta[:n])
+	if id == nil {
+		return "", fmt.Errorf("no content identifier in the quicktime metadata")
+	}
+	return string(id), nil
+}
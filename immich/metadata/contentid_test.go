@@ -0,0 +1,91 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+const testContentID = "2A7F0D3E-9C41-4B1E-8F2D-6E5A4B3C2D1E"
+
+// appleMakerNote builds a maker note with a single content identifier entry
+func appleMakerNote(order binary.ByteOrder, id string) []byte {
+	b := bytes.NewBuffer(nil)
+	b.WriteString("Apple iOS\x00")
+	b.Write([]byte{0, 1})
+	if order == binary.LittleEndian {
+		b.WriteString("II")
+	} else {
+		b.WriteString("MM")
+	}
+	v := []byte(id + "\x00")
+	_ = binary.Write(b, order, uint16(2))
+	_ = binary.Write(b, order, []uint16{0x0001, 9})
+	_ = binary.Write(b, order, []uint32{1, 14})
+	_ = binary.Write(b, order, []uint16{0x0011, 2})
+	_ = binary.Write(b, order, []uint32{uint32(len(v)), uint32(16 + 2*12 + 4)})
+	_ = binary.Write(b, order, uint32(0)) // next IFD
+	b.Write(v)
+	return b.Bytes()
+}
+
+func TestGetContentIdentifier(t *testing.T) {
+	filler := bytes.Repeat([]byte{0x55}, 50000)
+	tests := []struct {
+		name    string
+		ext     string
+		content []byte
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "heic",
+			ext:     ".HEIC",
+			content: append(append([]byte("....ftypheic"), filler...), appleMakerNote(binary.BigEndian, testContentID)...),
+			want:    testContentID,
+		},
+		{
+			name:    "jpg little endian",
+			ext:     ".jpg",
+			content: append([]byte("\xff\xd8\xff"), appleMakerNote(binary.LittleEndian, testContentID)...),
+			want:    testContentID,
+		},
+		{
+			name:    "jpg without maker note",
+			ext:     ".jpg",
+			content: append([]byte("\xff\xd8\xff"), filler...),
+			wantErr: true,
+		},
+		{
+			name: "mov",
+			ext:  ".MOV",
+			content: append(append([]byte("....ftypqt  "), filler...),
+				[]byte("\x00\x00\x00\x2emdtacom.apple.quicktime.content.identifier\x00\x00\x00\x34\x00\x00\x00\x01\x00\x00\x00\x2cdata\x00\x00\x00\x01\x00\x00\x00\x00"+testContentID)...),
+			want: testContentID,
+		},
+		{
+			name:    "mp4 without key",
+			ext:     ".mp4",
+			content: append([]byte("....ftypmp42"), filler...),
+			wantErr: true,
+		},
+		{
+			name:    "png",
+			ext:     ".png",
+			content: []byte("\x89PNG\r\n\x1a\n"),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetContentIdentifier(bytes.NewReader(tt.content), tt.ext)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetContentIdentifier() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("GetContentIdentifier() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -12,6 +12,7 @@ import (
 type MetaData struct {
 	DateTaken                     time.Time
 	Latitude, Longitude, Altitude float64
+	Width, Height                 int // Size of the image as displayed, 0 when unknown
 }
 
 func GetFileMetaData(fsys fs.FS, name string) (MetaData, error) {
@@ -35,74 +36,47 @@ func GetFromReader(rd io.Reader, ext string) (MetaData, error) {
 	r := newSliceReader(rd)
 	meta := MetaData{}
 	var err error
-	var dateTaken time.Time
 	switch strings.ToLower(ext) {
-	case ".heic", ".heif":
-		dateTaken, err = readHEIFDateTaken(r)
-	case ".jpg", ".jpeg", ".dng", ".cr2":
-		dateTaken, err = readExifDateTaken(r)
-	case ".mp4", ".mov":
-		dateTaken, err = readMP4DateTaken(r)
+	case ".heic", ".heif", ".avif":
+		meta, err = readHEIFMetaData(r)
+	case ".jpg", ".jpeg", ".dng", ".cr2", ".insp":
+		meta, err = getExifFromReader(r)
+	case ".mp4", ".mov", ".m4v", ".insv":
+		meta, err = readVideoMetaData(r)
 	case ".cr3":
-		dateTaken, err = readCR3DateTaken(r)
+		meta, err = readCR3MetaData(r)
 	default:
 		err = fmt.Errorf("can't determine the taken date from metadata (%s)", ext)
 	}
-	meta.DateTaken = dateTaken
 	return meta, err
 }
 
-// readExifDateTaken pase the file for Exif DateTaken
-func readExifDateTaken(r io.Reader) (time.Time, error) {
-
-	md, err := getExifFromReader(r)
-	return md.DateTaken, err
-}
-
 const searchBufferSize = 32 * 1024
 
-// readHEIFDateTaken locate the Exif part and return the date of capture
-func readHEIFDateTaken(r *sliceReader) (time.Time, error) {
+// readHEIFMetaData locate the Exif part and return the date of capture and the GPS coordinates
+func readHEIFMetaData(r *sliceReader) (MetaData, error) {
 	b := make([]byte, searchBufferSize)
 	r, err := searchPattern(r, []byte{0x45, 0x78, 0x69, 0x66, 0, 0, 0x4d, 0x4d}, b)
 	if err != nil {
-		return time.Time{}, err
+		return MetaData{}, err
 	}
 
 	filler := make([]byte, 6)
 	r.Read(filler)
 
-	md, err := getExifFromReader(r)
-	return md.DateTaken, err
-}
-
-// readMP4DateTaken locate the mvhd atom and decode the date of capture
-func readMP4DateTaken(r *sliceReader) (time.Time, error) {
-	b := make([]byte, searchBufferSize)
-
-	r, err := searchPattern(r, []byte{'m', 'v', 'h', 'd'}, b)
-	if err != nil {
-		return time.Time{}, err
-	}
-	atom, err := decodeMvhdAtom(r)
-	if err != nil {
-		return time.Time{}, err
-	}
-	return atom.CreationTime, nil
+	return getExifFromReader(r)
 }
 
-func readCR3DateTaken(r *sliceReader) (time.Time, error) {
+func readCR3MetaData(r *sliceReader) (MetaData, error) {
 	b := make([]byte, searchBufferSize)
 
 	r, err := searchPattern(r, []byte("CMT1"), b)
 	if err != nil {
-		return time.Time{}, err
+		return MetaData{}, err
 	}
 
 	filler := make([]byte, 4)
 	r.Read(filler)
 
-	md, err := getExifFromReader(r)
-	return md.DateTaken, err
-
+	return getExifFromReader(r)
 }
@@ -14,7 +14,7 @@ import (
 
 func getExifFromReader(r io.Reader) (MetaData, error) {
 	var md MetaData
-	local, err := tzone.Local()
+	local, err := tzone.Naive()
 	if err != nil {
 		return md, err
 	}
@@ -27,6 +27,11 @@ func getExifFromReader(r io.Reader) (MetaData, error) {
 		return md, fmt.Errorf("can't get DateTaken: %w", err)
 	}
 
+	if lat, long, err := x.LatLong(); err == nil {
+		md.Latitude, md.Longitude = lat, long
+	}
+	md.Width, md.Height = exifDimensions(x)
+
 	tag, err := getTagSting(x, exif.GPSDateStamp)
 	if err == nil {
 		md.DateTaken, err = time.ParseInLocation("2006:01:02 15:04:05Z", tag, local)
@@ -47,6 +52,32 @@ func getExifFromReader(r io.Reader) (MetaData, error) {
 	return md, err
 }
 
+// exifDimensions gives the size of the image as displayed, once rotated according to its orientation
+func exifDimensions(x *exif.Exif) (int, int) {
+	w, errW := getTagInt(x, exif.PixelXDimension)
+	h, errH := getTagInt(x, exif.PixelYDimension)
+	if errW != nil || errH != nil || w == 0 || h == 0 {
+		w, errW = getTagInt(x, exif.ImageWidth)
+		h, errH = getTagInt(x, exif.ImageLength)
+		if errW != nil || errH != nil {
+			return 0, 0
+		}
+	}
+	// Orientations 5 to 8 are rotated by 90°
+	if o, err := getTagInt(x, exif.Orientation); err == nil && o >= 5 && o <= 8 {
+		w, h = h, w
+	}
+	return w, h
+}
+
+func getTagInt(x *exif.Exif, tagName exif.FieldName) (int, error) {
+	t, err := x.Get(tagName)
+	if err != nil {
+		return 0, err
+	}
+	return t.Int(0)
+}
+
 func getTagSting(x *exif.Exif, tagName exif.FieldName) (string, error) {
 	t, err := x.Get(tagName)
 	if err != nil {
@@ -0,0 +1,202 @@
+package metadata
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/simulot/immich-go/helpers/tzone"
+)
+
+// Formats of the JSON sidecar files
+const (
+	SidecarAuto     = "auto"     // detect the format of the file
+	SidecarExiftool = "exiftool" // written by exiftool -json
+	SidecarNone     = "none"     // JSON sidecar files are ignored
+)
+
+var ErrUnknownSidecar = errors.New("unknown format of JSON sidecar")
+
+// jsonSidecarParser reads one format of JSON sidecar
+type jsonSidecarParser struct {
+	detect func(b []byte) bool
+	parse  func(b []byte) (JSONSidecar, error)
+}
+
+// jsonSidecarParsers lists the known formats, new formats are added here
+var jsonSidecarParsers = map[string]jsonSidecarParser{
+	SidecarExiftool: {detect: detectExiftool, parse: parseExiftool},
+}
+
+// JSONSidecar is the metadata found in a JSON sidecar file
+type JSONSidecar struct {
+	DateTaken                     time.Time
+	Latitude, Longitude, Altitude float64
+	Description                   string
+}
+
+// ParseSidecarFormat checks the value of the -sidecar-format option
+func ParseSidecarFormat(s string) (string, error) {
+	switch f := strings.ToLower(s); f {
+	case SidecarAuto, SidecarNone:
+		return f, nil
+	default:
+		if _, ok := jsonSidecarParsers[f]; ok {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unknown sidecar format %q, expecting %s, %s or %s", s, SidecarAuto, SidecarExiftool, SidecarNone)
+}
+
+// ReadJSONSidecar reads the sidecar with the parser of the format.
+// With SidecarAuto, the format is detected, and ErrUnknownSidecar is returned
+// when no format or several formats match the content.
+func ReadJSONSidecar(r io.Reader, format string) (JSONSidecar, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return JSONSidecar{}, err
+	}
+	if format != SidecarAuto {
+		p, ok := jsonSidecarParsers[format]
+		if !ok {
+			return JSONSidecar{}, fmt.Errorf("%w: %s", ErrUnknownSidecar, format)
+		}
+		return p.parse(b)
+	}
+
+	found := []string{}
+	for f, p := range jsonSidecarParsers {
+		if p.detect(b) {
+			found = append(found, f)
+		}
+	}
+	sort.Strings(found)
+	switch len(found) {
+	case 0:
+		return JSONSidecar{}, ErrUnknownSidecar
+	case 1:
+		return jsonSidecarParsers[found[0]].parse(b)
+	}
+	return JSONSidecar{}, fmt.Errorf("%w: the file matches the formats %s, use -sidecar-format", ErrUnknownSidecar, strings.Join(found, ", "))
+}
+
+// exiftool -json writes an array of objects having the SourceFile key.
+// The tags may be prefixed by their group when using -G.
+
+func detectExiftool(b []byte) bool {
+	var l []map[string]json.RawMessage
+	if json.Unmarshal(b, &l) != nil || len(l) != 1 {
+		return false
+	}
+	_, ok := l[0]["SourceFile"]
+	return ok
+}
+
+func parseExiftool(b []byte) (JSONSidecar, error) {
+	var l []map[string]any
+	if err := json.Unmarshal(b, &l); err != nil {
+		return JSONSidecar{}, fmt.Errorf("can't read exiftool sidecar: %w", err)
+	}
+	if len(l) == 0 {
+		return JSONSidecar{}, errors.New("can't read exiftool sidecar: empty file")
+	}
+	tags := map[string]any{}
+	for k, v := range l[0] {
+		if i := strings.LastIndex(k, ":"); i >= 0 {
+			k = k[i+1:]
+		}
+		if _, exists := tags[k]; !exists {
+			tags[k] = v
+		}
+	}
+
+	sc := JSONSidecar{}
+	for _, k := range []string{"DateTimeOriginal", "CreateDate", "DateTimeCreated"} {
+		if s, ok := tags[k].(string); ok {
+			if t, err := parseExiftoolDate(s); err == nil {
+				sc.DateTaken = t
+				break
+			}
+		}
+	}
+	sc.Latitude, _ = exiftoolCoordinate(tags["GPSLatitude"], tags["GPSLatitudeRef"])
+	sc.Longitude, _ = exiftoolCoordinate(tags["GPSLongitude"], tags["GPSLongitudeRef"])
+	if a, err := exiftoolCoordinate(tags["GPSAltitude"], nil); err == nil {
+		sc.Altitude = a
+	}
+	for _, k := range []string{"Description", "ImageDescription", "Caption-Abstract"} {
+		if s, ok := tags[k].(string); ok && strings.TrimSpace(s) != "" {
+			sc.Description = s
+			break
+		}
+	}
+	return sc, nil
+}
+
+// parseExiftoolDate reads dates like 2023:08:01 10:20:30, with optional sub seconds and time offset
+func parseExiftoolDate(s string) (time.Time, error) {
+	for _, layout := range []string{"2006:01:02 15:04:05.999999999-07:00", "2006:01:02 15:04:05-07:00", "2006:01:02 15:04:05Z"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	local, err := tzone.Naive()
+	if err != nil {
+		return time.Time{}, err
+	}
+	for _, layout := range []string{"2006:01:02 15:04:05.999999999", "2006:01:02 15:04:05"} {
+		if t, err := time.ParseInLocation(layout, s, local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("can't parse the date %q", s)
+}
+
+var reDegrees = regexp.MustCompile(`^([\d.]+) deg (?:([\d.]+)' )?(?:([\d.]+)")?\s*([NSEW])?`)
+
+// exiftoolCoordinate reads coordinates given as number (exiftool -n) or as
+// text like 48 deg 51' 24.00" N, and altitudes like 35 m Above Sea Level
+func exiftoolCoordinate(v any, ref any) (float64, error) {
+	var f float64
+	switch v := v.(type) {
+	case float64:
+		f = v
+	case string:
+		if m := reDegrees.FindStringSubmatch(v); m != nil {
+			for i, div := range []float64{1, 60, 3600} {
+				if m[i+1] != "" {
+					n, err := strconv.ParseFloat(m[i+1], 64)
+					if err != nil {
+						return 0, err
+					}
+					f += n / div
+				}
+			}
+			if m[4] == "S" || m[4] == "W" {
+				f = -f
+			}
+		} else {
+			s, _, _ := strings.Cut(v, " ")
+			n, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return 0, err
+			}
+			f = n
+			if strings.Contains(v, "Below") {
+				f = -f
+			}
+		}
+	default:
+		return 0, errors.New("no value")
+	}
+	if r, ok := ref.(string); ok && f > 0 && (strings.HasPrefix(r, "S") || strings.HasPrefix(r, "W")) {
+		f = -f
+	}
+	return f, nil
+}
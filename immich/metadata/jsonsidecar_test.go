@@ -0,0 +1,97 @@
+package metadata
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadJSONSidecar(t *testing.T) {
+	tc := []struct {
+		name     string
+		format   string
+		json     string
+		date     string
+		lat, lon float64
+		alt      float64
+		desc     string
+		err      error
+	}{
+		{
+			name:   "exiftool numbers",
+			format: SidecarAuto,
+			json:   `[{"SourceFile":"photo.jpg","DateTimeOriginal":"2023:08:01 10:20:30+02:00","GPSLatitude":48.856614,"GPSLongitude":-2.352222,"GPSAltitude":35,"Description":"Paris"}]`,
+			date:   "2023-08-01T10:20:30+02:00",
+			lat:    48.856614,
+			lon:    -2.352222,
+			alt:    35,
+			desc:   "Paris",
+		},
+		{
+			name:   "exiftool text",
+			format: SidecarAuto,
+			json:   `[{"SourceFile":"photo.jpg","DateTimeOriginal":"2023:08:01 10:20:30.25Z","GPSLatitude":"48 deg 51' 36.00\" S","GPSLongitude":"2 deg 21' 0.00\" E","GPSAltitude":"12 m Below Sea Level","ImageDescription":"a photo"}]`,
+			date:   "2023-08-01T10:20:30.25Z",
+			lat:    -48.86,
+			lon:    2.35,
+			alt:    -12,
+			desc:   "a photo",
+		},
+		{
+			name:   "exiftool groups",
+			format: SidecarAuto,
+			json:   `[{"SourceFile":"photo.jpg","EXIF:CreateDate":"2023:08:01 10:20:30Z","EXIF:GPSLatitude":10.5,"EXIF:GPSLatitudeRef":"South","EXIF:GPSLongitude":20.5,"EXIF:GPSLongitudeRef":"West"}]`,
+			date:   "2023-08-01T10:20:30Z",
+			lat:    -10.5,
+			lon:    -20.5,
+		},
+		{
+			name:   "google takeout",
+			format: SidecarAuto,
+			json:   `{"title":"photo.jpg","photoTakenTime":{"timestamp":"1690878030"}}`,
+			err:    ErrUnknownSidecar,
+		},
+		{
+			name:   "forced format",
+			format: SidecarExiftool,
+			json:   `[{"DateTimeOriginal":"2023:08:01 10:20:30Z"}]`,
+			date:   "2023-08-01T10:20:30Z",
+		},
+	}
+	for _, c := range tc {
+		t.Run(c.name, func(t *testing.T) {
+			sc, err := ReadJSONSidecar(strings.NewReader(c.json), c.format)
+			if c.err != nil || err != nil {
+				if !errors.Is(err, c.err) {
+					t.Fatalf("expecting error %v, got %v", c.err, err)
+				}
+				return
+			}
+			if c.date != "" {
+				date, _ := time.Parse(time.RFC3339Nano, c.date)
+				if !sc.DateTaken.Equal(date) {
+					t.Errorf("DateTaken: expecting %s, got %s", date, sc.DateTaken)
+				}
+			}
+			if math.Abs(sc.Latitude-c.lat) > 1e-6 || math.Abs(sc.Longitude-c.lon) > 1e-6 || sc.Altitude != c.alt {
+				t.Errorf("GPS: expecting %f,%f,%f, got %f,%f,%f", c.lat, c.lon, c.alt, sc.Latitude, sc.Longitude, sc.Altitude)
+			}
+			if sc.Description != c.desc {
+				t.Errorf("Description: expecting %q, got %q", c.desc, sc.Description)
+			}
+		})
+	}
+}
+
+func TestParseSidecarFormat(t *testing.T) {
+	for _, s := range []string{"auto", "EXIFTOOL", "none"} {
+		if _, err := ParseSidecarFormat(s); err != nil {
+			t.Errorf("ParseSidecarFormat(%q): %v", s, err)
+		}
+	}
+	if _, err := ParseSidecarFormat("digikam"); err == nil {
+		t.Errorf("ParseSidecarFormat(digikam): expecting an error")
+	}
+}
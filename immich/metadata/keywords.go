@@ -0,0 +1,131 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"html"
+	"io"
+	"strings"
+)
+
+// keywordsSearchSize is the size of the beginning of the file searched for the keywords
+const keywordsSearchSize = 256 * 1024
+
+// ReadKeywords gives the keywords found in the XMP packet embedded at the beginning of the file,
+// or in its IPTC record when the XMP packet has none.
+// The hierarchical keywords are given with the / separator, like Places/Europe/Italy.
+func ReadKeywords(r io.Reader) ([]string, error) {
+	b, err := io.ReadAll(io.LimitReader(r, keywordsSearchSize))
+	if err != nil {
+		return nil, err
+	}
+	if start := bytes.Index(b, []byte("<x:xmpmeta")); start >= 0 {
+		if end := bytes.Index(b[start:], []byte("</x:xmpmeta>")); end >= 0 {
+			if k := XMPKeywords(b[start : start+end]); len(k) > 0 {
+				return k, nil
+			}
+		}
+	}
+	return iptcKeywords(b), nil
+}
+
+// XMPKeywords gives the keywords of the XMP content. The Lightroom hierarchical subjects,
+// like Places|Europe|Italy, are preferred to the flat dc:subject list.
+func XMPKeywords(b []byte) []string {
+	s := string(b)
+	if l := xmpList(s, "lr:hierarchicalSubject"); len(l) > 0 {
+		for i := range l {
+			l[i] = strings.ReplaceAll(l[i], "|", "/")
+		}
+		return uniqueKeywords(l)
+	}
+	return uniqueKeywords(xmpList(s, "dc:subject"))
+}
+
+// xmpList gives the items of the rdf:Bag or rdf:Seq of the property
+func xmpList(s string, name string) []string {
+	i := strings.Index(s, "<"+name+">")
+	if i < 0 {
+		return nil
+	}
+	s = s[i:]
+	j := strings.Index(s, "</"+name+">")
+	if j < 0 {
+		return nil
+	}
+	s = s[:j]
+
+	var l []string
+	for {
+		i := strings.Index(s, "<rdf:li")
+		if i < 0 {
+			break
+		}
+		s = s[i:]
+		i = strings.Index(s, ">")
+		j := strings.Index(s, "</rdf:li>")
+		if i < 0 || j < i {
+			break
+		}
+		l = append(l, html.UnescapeString(s[i+1:j]))
+		s = s[j:]
+	}
+	return l
+}
+
+// iptcKeywords gives the keywords (dataset 2:25) of the IPTC record found in the
+// Photoshop resource 0x0404 of a JPEG file
+func iptcKeywords(b []byte) []string {
+	i := bytes.Index(b, []byte("8BIM\x04\x04"))
+	if i < 0 {
+		return nil
+	}
+	b = b[i+6:]
+	if len(b) < 1 {
+		return nil
+	}
+	// the resource name is a pascal string padded to an even length
+	nameLen := int(b[0]) + 1
+	nameLen += nameLen % 2
+	if len(b) < nameLen+4 {
+		return nil
+	}
+	size := int(binary.BigEndian.Uint32(b[nameLen:]))
+	b = b[nameLen+4:]
+	if size < len(b) {
+		b = b[:size]
+	}
+
+	var l []string
+	for len(b) >= 5 && b[0] == 0x1c {
+		record, dataset := b[1], b[2]
+		n := int(binary.BigEndian.Uint16(b[3:]))
+		if n&0x8000 != 0 || len(b) < 5+n {
+			// extended datasets aren't used for the keywords
+			break
+		}
+		if record == 2 && dataset == 25 {
+			l = append(l, string(b[5:5+n]))
+		}
+		b = b[5+n:]
+	}
+	return uniqueKeywords(l)
+}
+
+// uniqueKeywords removes the empty and the repeated keywords
+func uniqueKeywords(l []string) []string {
+	seen := map[string]bool{}
+	r := []string{}
+	for _, k := range l {
+		k = strings.TrimSpace(k)
+		if k == "" || seen[k] {
+			continue
+		}
+		seen[k] = true
+		r = append(r, k)
+	}
+	if len(r) == 0 {
+		return nil
+	}
+	return r
+}
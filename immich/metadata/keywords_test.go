@@ -0,0 +1,82 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+func TestXMPKeywords(t *testing.T) {
+	tc := []struct {
+		name string
+		xmp  string
+		want []string
+	}{
+		{name: "none", xmp: `<rdf:Description xmp:Rating="3"/>`},
+		{
+			name: "subjects",
+			xmp: `<dc:subject><rdf:Bag>
+				<rdf:li>Italy</rdf:li>
+				<rdf:li xml:lang="x-default">Fish &amp; Chips</rdf:li>
+				<rdf:li>Italy</rdf:li>
+				<rdf:li> </rdf:li>
+			</rdf:Bag></dc:subject>`,
+			want: []string{"Italy", "Fish & Chips"},
+		},
+		{
+			name: "hierarchical",
+			xmp: `<dc:subject><rdf:Bag><rdf:li>Italy</rdf:li></rdf:Bag></dc:subject>
+			<lr:hierarchicalSubject><rdf:Bag>
+				<rdf:li>Places|Europe|Italy</rdf:li>
+				<rdf:li>People</rdf:li>
+			</rdf:Bag></lr:hierarchicalSubject>`,
+			want: []string{"Places/Europe/Italy", "People"},
+		},
+	}
+	for _, c := range tc {
+		t.Run(c.name, func(t *testing.T) {
+			if got := XMPKeywords([]byte(c.xmp)); !reflect.DeepEqual(got, c.want) {
+				t.Errorf("XMPKeywords() = %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+// iptcResource builds a Photoshop resource 0x0404 with the datasets 2:25
+func iptcResource(keywords ...string) []byte {
+	iptc := []byte{0x1c, 2, 0, 0, 2, 0, 4} // record version
+	for _, k := range keywords {
+		iptc = append(iptc, 0x1c, 2, 25)
+		iptc = binary.BigEndian.AppendUint16(iptc, uint16(len(k)))
+		iptc = append(iptc, k...)
+	}
+	b := []byte("Photoshop 3.0\x008BIM\x04\x04\x00\x00")
+	b = binary.BigEndian.AppendUint32(b, uint32(len(iptc)))
+	return append(b, iptc...)
+}
+
+func TestReadKeywords(t *testing.T) {
+	xmp := `<x:xmpmeta xmlns:x="adobe:ns:meta/"><dc:subject><rdf:Bag><rdf:li>Sea</rdf:li></rdf:Bag></dc:subject></x:xmpmeta>`
+	tc := []struct {
+		name    string
+		content []byte
+		want    []string
+	}{
+		{name: "nothing", content: []byte("\xff\xd8\xff\xe0 JFIF")},
+		{name: "iptc", content: append([]byte("\xff\xd8\xff\xed"), iptcResource("Sea", "Beach", "Sea")...), want: []string{"Sea", "Beach"}},
+		{name: "xmp", content: append([]byte("\xff\xd8\xff\xe1"), xmp...), want: []string{"Sea"}},
+		{name: "xmp preferred", content: append(append([]byte("\xff\xd8\xff\xe1"), xmp...), iptcResource("Beach")...), want: []string{"Sea"}},
+	}
+	for _, c := range tc {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := ReadKeywords(bytes.NewReader(c.content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("ReadKeywords() = %q, want %q", got, c.want)
+			}
+		})
+	}
+}
@@ -32,6 +32,14 @@ func TestTakeTimeFromName(t *testing.T) {
 		// 	name:     "Bebop2_20180719194940+0200.mp4",
 		// 	expected: time.Date(2018, 07, 19, 19, 49, 40, 0, local),
 		// },
+		{
+			name:     "IMG_20230115_143000.jpg",
+			expected: time.Date(2023, 1, 15, 15, 30, 0, 0, local),
+		},
+		{
+			name:     "2023-01-15 14.30.00.jpg",
+			expected: time.Date(2023, 1, 15, 15, 30, 0, 0, local),
+		},
 		{
 			name:     "AR_EFFECT_20141126193511.mp4",
 			expected: time.Date(2014, 11, 26, 20, 35, 11, 0, local),
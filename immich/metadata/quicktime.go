@@ -2,6 +2,7 @@ package metadata
 
 import (
 	"encoding/binary"
+	"io"
 	"time"
 )
 
@@ -47,48 +48,40 @@ type MvhdAtom struct {
 	// NextTrackID      uint32
 }
 
-func decodeMvhdAtom(r *sliceReader) (*MvhdAtom, error) {
-
-	a := &MvhdAtom{}
-
-	// Read the mvhd marker (4 bytes)
-	a.Marker, _ = r.ReadSlice(4)
-
-	// Read the mvhd version (1 byte)
-	a.Version, _ = r.ReadByte()
-
-	// Read the mvhd flags (3 bytes)
-	a.Flags, _ = r.ReadSlice(3)
+// decodeMvhdAtom decodes the content of the mvhd atom, after its size and its type
+func decodeMvhdAtom(b []byte) (*MvhdAtom, error) {
+	a := &MvhdAtom{Marker: []byte("mvhd")}
+	if len(b) < 4 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	a.Version = b[0]
+	a.Flags = b[1:4]
+	b = b[4:]
 
+	// the creation time is given before the modification time
 	if a.Version == 0 {
-		// Read the creation time (4 bytes)
-		b, _ := r.ReadSlice(4)
-		a.ModificationTime = convertTime32(binary.BigEndian.Uint32(b))
-		b, _ = r.ReadSlice(4)
+		if len(b) < 8 {
+			return nil, io.ErrUnexpectedEOF
+		}
 		a.CreationTime = convertTime32(binary.BigEndian.Uint32(b))
-
+		a.ModificationTime = convertTime32(binary.BigEndian.Uint32(b[4:]))
 	} else {
-		// Read the creation time (4 bytes)
-		b, _ := r.ReadSlice(8)
-		a.ModificationTime = convertTime64(binary.BigEndian.Uint64(b))
-
-		b, _ = r.ReadSlice(8)
+		if len(b) < 16 {
+			return nil, io.ErrUnexpectedEOF
+		}
 		a.CreationTime = convertTime64(binary.BigEndian.Uint64(b))
+		a.ModificationTime = convertTime64(binary.BigEndian.Uint64(b[8:]))
 	}
-
 	return a, nil
 }
 
+// The times are given in seconds since January 1, 1904
+const epochOffset = int64(2082844800)
+
 func convertTime32(timestamp uint32) time.Time {
-	return time.Unix(int64(timestamp)-int64(2082844800), 0)
+	return time.Unix(int64(timestamp)-epochOffset, 0)
 }
-func convertTime64(timestamp uint64) time.Time {
-	// Unix epoch starts on January 1, 1970, subtracting the number of seconds from January 1, 1904 to January 1, 1970.
-	epochOffset := int64(2082844800)
-
-	// Convert the creation time to Unix timestamp
-	unixTimestamp := int64(timestamp>>32) - epochOffset
 
-	// Convert the Unix timestamp to time.Time
-	return time.Unix(unixTimestamp, 0)
+func convertTime64(timestamp uint64) time.Time {
+	return time.Unix(int64(timestamp)-epochOffset, 0)
 }
@@ -0,0 +1,136 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+// mp4WithMvhd builds the beginning of a MP4 file with a version 0 mvhd atom
+func mp4WithMvhd(date time.Time) []byte {
+	b := bytes.NewBuffer(nil)
+	b.Write([]byte("\x00\x00\x00\x18ftypisom\x00\x00\x00\x00isomavc1"))
+	b.Write([]byte("\x00\x00\x00\x74moov\x00\x00\x00\x6cmvhd\x00\x00\x00\x00"))
+	ts := make([]byte, 4)
+	binary.BigEndian.PutUint32(ts, uint32(date.Unix()+2082844800))
+	b.Write(ts) // creation time
+	b.Write(ts) // modification time
+	b.Write(make([]byte, 92))
+	return b.Bytes()
+}
+
+func TestGetFromReaderMP4(t *testing.T) {
+	date := time.Date(2023, 6, 23, 13, 32, 52, 0, time.UTC)
+	for _, ext := range []string{".mp4", ".MOV", ".insv"} {
+		t.Run(ext, func(t *testing.T) {
+			md, err := GetFromReader(bytes.NewReader(mp4WithMvhd(date)), ext)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !md.DateTaken.Equal(date) {
+				t.Errorf("DateTaken = %s, want %s", md.DateTaken, date)
+			}
+		})
+	}
+}
+
+// atom builds an atom with the given type and content
+func atom(typ string, content ...[]byte) []byte {
+	b := bytes.NewBuffer(nil)
+	size := 8
+	for _, c := range content {
+		size += len(c)
+	}
+	_ = binary.Write(b, binary.BigEndian, uint32(size))
+	b.WriteString(typ)
+	for _, c := range content {
+		b.Write(c)
+	}
+	return b.Bytes()
+}
+
+func mvhd(date time.Time) []byte {
+	b := make([]byte, 100)
+	binary.BigEndian.PutUint32(b[4:], uint32(date.Unix()+2082844800))
+	binary.BigEndian.PutUint32(b[8:], uint32(date.Unix()+2082844800))
+	return atom("mvhd", b)
+}
+
+// appleKeys builds the meta atom of an iPhone video with the given keys and values
+func appleKeys(kv ...string) []byte {
+	keys := bytes.NewBuffer(nil)
+	ilst := bytes.NewBuffer(nil)
+	_ = binary.Write(keys, binary.BigEndian, uint32(0))
+	_ = binary.Write(keys, binary.BigEndian, uint32(len(kv)/2))
+	for i := 0; i < len(kv); i += 2 {
+		keys.Write(atom("mdta", []byte(kv[i])))
+		index := make([]byte, 4)
+		binary.BigEndian.PutUint32(index, uint32(i/2+1))
+		item := atom("data", []byte{0, 0, 0, 1, 0, 0, 0, 0}, []byte(kv[i+1]))
+		ilst.Write(atom(string(index), item))
+	}
+	return atom("meta", atom("hdlr", make([]byte, 25)), atom("keys", keys.Bytes()), atom("ilst", ilst.Bytes()))
+}
+
+func userData(s string) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+func TestVideoMetaData(t *testing.T) {
+	encoded := time.Date(2023, 6, 23, 14, 0, 0, 0, time.UTC)
+	taken := time.Date(2023, 6, 23, 13, 32, 52, 0, time.UTC)
+	ftyp := atom("ftyp", []byte("qt  \x00\x00\x00\x00qt  "))
+	mdat := atom("mdat", make([]byte, 1000))
+
+	tc := []struct {
+		name      string
+		ext       string
+		file      []byte
+		date      time.Time
+		latitude  float64
+		longitude float64
+	}{
+		{
+			name: "iPhone mov",
+			ext:  ".MOV",
+			file: bytes.Join([][]byte{ftyp, mdat, atom("moov", mvhd(encoded), appleKeys(
+				"com.apple.quicktime.location.ISO6709", "+48.8583+002.2945+035.000/",
+				"com.apple.quicktime.creationdate", "2023-06-23T15:32:52+0200",
+			))}, nil),
+			date:      taken,
+			latitude:  48.8583,
+			longitude: 2.2945,
+		},
+		{
+			name:      "Android mp4",
+			ext:       ".mp4",
+			file:      bytes.Join([][]byte{ftyp, atom("moov", mvhd(taken), atom("udta", atom("\xa9xyz", userData("-33.8568+151.2153/")))), mdat}, nil),
+			date:      taken,
+			latitude:  -33.8568,
+			longitude: 151.2153,
+		},
+		{
+			name: "m4v without location",
+			ext:  ".m4v",
+			file: bytes.Join([][]byte{ftyp, atom("moov", mvhd(taken)), mdat}, nil),
+			date: taken,
+		},
+	}
+	for _, c := range tc {
+		t.Run(c.name, func(t *testing.T) {
+			md, err := GetFromReader(bytes.NewReader(c.file), c.ext)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !md.DateTaken.Equal(c.date) {
+				t.Errorf("DateTaken = %s, want %s", md.DateTaken, c.date)
+			}
+			if md.Latitude != c.latitude || md.Longitude != c.longitude {
+				t.Errorf("location = %f,%f, want %f,%f", md.Latitude, md.Longitude, c.latitude, c.longitude)
+			}
+		})
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"io"
 	"io/fs"
 	"math"
+	"strconv"
+	"strings"
 	"text/template"
 	"time"
 )
@@ -14,6 +16,7 @@ import (
 type SideCar struct {
 	FileName string
 	OnFSsys  bool
+	Merge    bool // When the sidecar is on the FS, merge the date and GPS fields into it
 
 	DateTaken time.Time
 	Latitude  float64
@@ -34,7 +37,14 @@ func cmpFloats(a, b float64) int {
 
 func (sc *SideCar) Open(fsys fs.FS, name string) (io.ReadCloser, error) {
 	if sc.OnFSsys {
-		return fsys.Open(name)
+		if !sc.Merge {
+			return fsys.Open(name)
+		}
+		b, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		return io.NopCloser(bytes.NewReader(sc.mergeXMP(b))), nil
 	}
 
 	b := bytes.NewBuffer(nil)
@@ -57,6 +67,126 @@ func (sc *SideCar) Bytes() ([]byte, error) {
 	return b.Bytes(), nil
 }
 
+// HasGPS tells if the sidecar file present on the FS gives GPS coordinates
+func (sc *SideCar) HasGPS(fsys fs.FS) bool {
+	if !sc.OnFSsys {
+		return sc.Latitude != 0 || sc.Longitude != 0
+	}
+	b, err := fs.ReadFile(fsys, sc.FileName)
+	if err != nil {
+		return false
+	}
+	return bytes.Contains(b, []byte("GPSLatitude"))
+}
+
+// mergeXMP sets the date and GPS fields of the sidecar into an existing XMP content.
+// Existing properties are replaced, missing ones are added to the first rdf:Description.
+func (sc *SideCar) mergeXMP(b []byte) []byte {
+	s := string(b)
+	props := [][2]string{}
+	if !sc.DateTaken.IsZero() {
+		props = append(props, [2]string{"exif:DateTimeOriginal", sc.DateTaken.Local().Format("2006-01-02T15:04:05")})
+	}
+	if sc.Latitude != 0 || sc.Longitude != 0 {
+		props = append(props,
+			[2]string{"exif:GPSLatitude", strconv.FormatFloat(sc.Latitude, 'f', -1, 64)},
+			[2]string{"exif:GPSLongitude", strconv.FormatFloat(sc.Longitude, 'f', -1, 64)},
+		)
+		// the altitude is unknown when 0, the one of the file is kept
+		if sc.Elevation != 0 {
+			props = append(props, [2]string{"exif:GPSAltitude", strconv.FormatFloat(sc.Elevation, 'f', -1, 64)})
+		}
+	}
+
+	missing := ""
+	for _, p := range props {
+		var ok bool
+		s, ok = replaceXMPProperty(s, p[0], p[1])
+		if !ok {
+			missing += "\n  <" + p[0] + ">" + p[1] + "</" + p[0] + ">"
+		}
+	}
+	if missing == "" {
+		return []byte(s)
+	}
+
+	start := strings.Index(s, "<rdf:Description")
+	if start < 0 {
+		return []byte(s)
+	}
+	end := strings.Index(s[start:], ">")
+	if end < 0 {
+		return []byte(s)
+	}
+	end += start
+	tag := s[start:end]
+	selfClosing := strings.HasSuffix(tag, "/")
+	tag = strings.TrimSuffix(tag, "/")
+	if !strings.Contains(s, "xmlns:exif=") {
+		tag += "\n  xmlns:exif='http://ns.adobe.com/exif/1.0/'"
+	}
+	tag += ">" + missing
+	if selfClosing {
+		tag += "\n </rdf:Description>"
+	}
+	return []byte(s[:start] + tag + s[end+1:])
+}
+
+// XMPRating gives the star rating found in the XMP content, 0 when absent, -1 for rejected photos
+func XMPRating(b []byte) int {
+	v, ok := xmpProperty(string(b), "xmp:Rating")
+	if !ok {
+		return 0
+	}
+	r, err := strconv.Atoi(strings.TrimSpace(v))
+	if err != nil {
+		return 0
+	}
+	return r
+}
+
+// xmpProperty gives the value of the property given as element or as attribute
+func xmpProperty(s string, name string) (string, bool) {
+	open := "<" + name + ">"
+	if i := strings.Index(s, open); i >= 0 {
+		j := strings.Index(s[i:], "</"+name+">")
+		if j >= 0 {
+			return s[i+len(open) : i+j], true
+		}
+	}
+	for _, q := range []string{"'", `"`} {
+		attr := name + "=" + q
+		if i := strings.Index(s, attr); i >= 0 {
+			j := strings.Index(s[i+len(attr):], q)
+			if j >= 0 {
+				return s[i+len(attr) : i+len(attr)+j], true
+			}
+		}
+	}
+	return "", false
+}
+
+// replaceXMPProperty replaces the value of the property given as element or as attribute
+func replaceXMPProperty(s string, name string, value string) (string, bool) {
+	open := "<" + name + ">"
+	if i := strings.Index(s, open); i >= 0 {
+		j := strings.Index(s[i:], "</"+name+">")
+		if j >= 0 {
+			return s[:i+len(open)] + value + s[i+j:], true
+		}
+	}
+	for _, q := range []string{"'", `"`} {
+		attr := name + "=" + q
+		if i := strings.Index(s, attr); i >= 0 {
+			j := strings.Index(s[i+len(attr):], q)
+			if j >= 0 {
+				return s[:i+len(attr)] + value + s[i+len(attr)+j:], true
+			}
+		}
+	}
+	return s, false
+}
+
 var sidecarTemplate = template.Must(template.New("xmp").Parse(`<x:xmpmeta xmlns:x='adobe:ns:meta/' x:xmptk='Image::ExifTool 12.56'>
 <rdf:RDF xmlns:rdf='http://www.w3.org/1999/02/22-rdf-syntax-ns#'>
  <rdf:Description rdf:about=''
@@ -0,0 +1,138 @@
+package metadata
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestMergeXMP(t *testing.T) {
+	date := time.Date(2023, 10, 1, 10, 20, 30, 0, time.Local)
+	tc := []struct {
+		name     string
+		xmp      string
+		sc       SideCar
+		contains []string
+		excludes []string
+	}{
+		{
+			name: "replace element",
+			xmp: `<rdf:RDF><rdf:Description rdf:about='' xmlns:exif='http://ns.adobe.com/exif/1.0/' xmlns:xmp='http://ns.adobe.com/xap/1.0/'>
+  <xmp:Rating>5</xmp:Rating>
+  <exif:DateTimeOriginal>2000-01-01T00:00:00</exif:DateTimeOriginal>
+ </rdf:Description></rdf:RDF>`,
+			sc: SideCar{DateTaken: date},
+			contains: []string{
+				"<xmp:Rating>5</xmp:Rating>",
+				"<exif:DateTimeOriginal>2023-10-01T10:20:30</exif:DateTimeOriginal>",
+			},
+		},
+		{
+			name: "replace attribute",
+			xmp:  `<rdf:RDF><rdf:Description rdf:about='' exif:DateTimeOriginal="2000-01-01T00:00:00" xmp:Rating="3"/></rdf:RDF>`,
+			sc:   SideCar{DateTaken: date},
+			contains: []string{
+				`exif:DateTimeOriginal="2023-10-01T10:20:30"`,
+				`xmp:Rating="3"`,
+			},
+		},
+		{
+			name: "add missing",
+			xmp:  `<rdf:RDF><rdf:Description rdf:about='' xmp:Rating="3"/></rdf:RDF>`,
+			sc:   SideCar{DateTaken: date, Latitude: 48.8583, Longitude: 2.2945},
+			contains: []string{
+				`xmp:Rating="3"`,
+				"xmlns:exif='http://ns.adobe.com/exif/1.0/'",
+				"<exif:DateTimeOriginal>2023-10-01T10:20:30</exif:DateTimeOriginal>",
+				"<exif:GPSLatitude>48.8583</exif:GPSLatitude>",
+				"<exif:GPSLongitude>2.2945</exif:GPSLongitude>",
+				"</rdf:Description></rdf:RDF>",
+			},
+			excludes: []string{"GPSAltitude"},
+		},
+		{
+			name: "keep the altitude",
+			xmp: `<rdf:RDF><rdf:Description rdf:about='' xmlns:exif='http://ns.adobe.com/exif/1.0/'>
+  <exif:GPSAltitude>120</exif:GPSAltitude>
+ </rdf:Description></rdf:RDF>`,
+			sc: SideCar{Latitude: 48.8583, Longitude: 2.2945},
+			contains: []string{
+				"<exif:GPSAltitude>120</exif:GPSAltitude>",
+				"<exif:GPSLatitude>48.8583</exif:GPSLatitude>",
+			},
+		},
+		{
+			name: "replace the altitude",
+			xmp: `<rdf:RDF><rdf:Description rdf:about='' xmlns:exif='http://ns.adobe.com/exif/1.0/'>
+  <exif:GPSAltitude>120</exif:GPSAltitude>
+ </rdf:Description></rdf:RDF>`,
+			sc: SideCar{Latitude: 48.8583, Longitude: 2.2945, Elevation: 35},
+			contains: []string{
+				"<exif:GPSAltitude>35</exif:GPSAltitude>",
+			},
+		},
+	}
+	for _, c := range tc {
+		t.Run(c.name, func(t *testing.T) {
+			got := string(c.sc.mergeXMP([]byte(c.xmp)))
+			for _, s := range c.contains {
+				if !strings.Contains(got, s) {
+					t.Errorf("merged XMP doesn't contain %q\n%s", s, got)
+				}
+			}
+			for _, s := range c.excludes {
+				if strings.Contains(got, s) {
+					t.Errorf("merged XMP contains %q\n%s", s, got)
+				}
+			}
+		})
+	}
+}
+
+func TestSideCarHasGPS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"gps.jpg.xmp":   {Data: []byte("<rdf:Description exif:GPSLatitude='48.8583' exif:GPSLongitude='2.2945'/>")},
+		"nogps.jpg.xmp": {Data: []byte("<rdf:Description xmp:Rating='3'/>")},
+	}
+	tc := []struct {
+		name string
+		sc   SideCar
+		want bool
+	}{
+		{name: "file with GPS", sc: SideCar{FileName: "gps.jpg.xmp", OnFSsys: true}, want: true},
+		{name: "file without GPS", sc: SideCar{FileName: "nogps.jpg.xmp", OnFSsys: true}},
+		{name: "missing file", sc: SideCar{FileName: "missing.jpg.xmp", OnFSsys: true}},
+		{name: "generated with GPS", sc: SideCar{Latitude: 48.8583, Longitude: 2.2945}, want: true},
+		{name: "generated without GPS", sc: SideCar{}},
+	}
+	for _, c := range tc {
+		t.Run(c.name, func(t *testing.T) {
+			if got := c.sc.HasGPS(fsys); got != c.want {
+				t.Errorf("HasGPS()=%v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestXMPRating(t *testing.T) {
+	tc := []struct {
+		name string
+		xmp  string
+		want int
+	}{
+		{name: "element", xmp: `<rdf:Description rdf:about=''><xmp:Rating>4</xmp:Rating></rdf:Description>`, want: 4},
+		{name: "attribute", xmp: `<rdf:Description rdf:about='' xmp:Rating="5" xmp:Label="Red"/>`, want: 5},
+		{name: "single quotes", xmp: `<rdf:Description rdf:about='' xmp:Rating='2'/>`, want: 2},
+		{name: "rejected", xmp: `<rdf:Description xmp:Rating="-1"/>`, want: -1},
+		{name: "absent", xmp: `<rdf:Description xmp:Label="Red"/>`, want: 0},
+		{name: "invalid", xmp: `<xmp:Rating>four</xmp:Rating>`, want: 0},
+	}
+	for _, c := range tc {
+		t.Run(c.name, func(t *testing.T) {
+			if got := XMPRating([]byte(c.xmp)); got != c.want {
+				t.Errorf("XMPRating()=%d, want %d", got, c.want)
+			}
+		})
+	}
+}
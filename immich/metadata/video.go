@@ -0,0 +1,229 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+The QuickTime and MP4 files are made of atoms: a size, a type and a content. The metadata are in the moov atom:
+- moov/mvhd gives the creation time in UTC, often left empty or set to the time of the encoding
+- moov/meta/keys and moov/meta/ilst give the Apple metadata, like com.apple.quicktime.creationdate
+  and com.apple.quicktime.location.ISO6709
+- moov/udta/©day and moov/udta/©xyz give the date and the location written by Android phones and cameras
+
+The mdat atom holding the media can be before the moov atom, it is skipped without being kept in memory.
+*/
+
+// maxMoovSize is the size of the largest moov atom read
+const maxMoovSize = 64 * 1024 * 1024
+
+var iso6709RE = regexp.MustCompile(`([+-]\d+(?:\.\d+)?)([+-]\d+(?:\.\d+)?)([+-]\d+(?:\.\d+)?)?`)
+
+// readVideoMetaData walks the top level atoms up to the moov atom, and decodes the date of capture and the location
+func readVideoMetaData(r io.Reader) (MetaData, error) {
+	for {
+		typ, size, err := readAtomHeader(r)
+		if err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				err = errors.New("no moov atom in the video")
+			}
+			return MetaData{}, err
+		}
+		if typ != "moov" {
+			if size < 0 {
+				return MetaData{}, errors.New("no moov atom in the video")
+			}
+			_, err = io.CopyN(io.Discard, r, size)
+			if err != nil {
+				return MetaData{}, err
+			}
+			continue
+		}
+		if size < 0 || size > maxMoovSize {
+			return MetaData{}, fmt.Errorf("the moov atom of the video is too large: %d bytes", size)
+		}
+		b := make([]byte, size)
+		n, err := io.ReadFull(r, b)
+		if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return MetaData{}, err
+		}
+		return decodeMoov(b[:n])
+	}
+}
+
+// readAtomHeader reads the type and the size of the content of the atom. The size is -1 for an atom ending with the file.
+func readAtomHeader(r io.Reader) (string, int64, error) {
+	h := make([]byte, 8)
+	_, err := io.ReadFull(r, h)
+	if err != nil {
+		return "", 0, err
+	}
+	size := int64(binary.BigEndian.Uint32(h))
+	typ := string(h[4:8])
+	switch size {
+	case 0:
+		return typ, -1, nil
+	case 1:
+		_, err = io.ReadFull(r, h)
+		if err != nil {
+			return "", 0, err
+		}
+		size = int64(binary.BigEndian.Uint64(h)) - 16
+	default:
+		size -= 8
+	}
+	if size < 0 {
+		return "", 0, fmt.Errorf("invalid size of the atom %q", typ)
+	}
+	return typ, size, nil
+}
+
+// atoms gives the children of the atom content, by type
+func atoms(b []byte) map[string][]byte {
+	children := map[string][]byte{}
+	r := bytes.NewReader(b)
+	for {
+		typ, size, err := readAtomHeader(r)
+		if err != nil {
+			return children
+		}
+		if size < 0 || size > int64(r.Len()) {
+			size = int64(r.Len())
+		}
+		c := make([]byte, size)
+		_, _ = io.ReadFull(r, c)
+		if _, exists := children[typ]; !exists {
+			children[typ] = c
+		}
+	}
+}
+
+func decodeMoov(moov []byte) (MetaData, error) {
+	md := MetaData{}
+	children := atoms(moov)
+
+	var date, location string
+	if meta, ok := children["meta"]; ok {
+		date, location = quickTimeKeys(meta)
+	}
+	if udta, ok := children["udta"]; ok {
+		u := atoms(udta)
+		if date == "" {
+			date = userDataString(u["\xa9day"])
+		}
+		if location == "" {
+			location = userDataString(u["\xa9xyz"])
+		}
+	}
+
+	if date != "" {
+		md.DateTaken = parseVideoDate(date)
+	}
+	if md.DateTaken.IsZero() {
+		if mvhd, ok := children["mvhd"]; ok {
+			a, err := decodeMvhdAtom(mvhd)
+			// the mvhd times are often left to 0, in 1904
+			if err == nil && a.CreationTime.Year() > 1970 {
+				md.DateTaken = a.CreationTime
+			}
+		}
+	}
+	if location != "" {
+		md.Latitude, md.Longitude, md.Altitude = parseISO6709(location)
+	}
+	if md.DateTaken.IsZero() {
+		return md, errors.New("no date of capture in the video")
+	}
+	return md, nil
+}
+
+// quickTimeKeys gives the creation date and the location found in the Apple metadata
+func quickTimeKeys(meta []byte) (date string, location string) {
+	// the meta atom of MP4 files has a version and flags, not the QuickTime one
+	if len(meta) >= 4 && binary.BigEndian.Uint32(meta) == 0 {
+		meta = meta[4:]
+	}
+	children := atoms(meta)
+	keys := children["keys"]
+	if len(keys) < 8 {
+		return "", ""
+	}
+	count := int(binary.BigEndian.Uint32(keys[4:]))
+	names := map[uint32]string{}
+	p := 8
+	for i := 1; i <= count && p+8 <= len(keys); i++ {
+		l := int(binary.BigEndian.Uint32(keys[p:]))
+		if l < 8 || p+l > len(keys) {
+			break
+		}
+		names[uint32(i)] = string(keys[p+8 : p+l])
+		p += l
+	}
+
+	ilst := children["ilst"]
+	for len(ilst) >= 8 {
+		l := int(binary.BigEndian.Uint32(ilst))
+		if l < 8 || l > len(ilst) {
+			break
+		}
+		index := binary.BigEndian.Uint32(ilst[4:])
+		value := ""
+		// the data atom: type and locale, then the value
+		if data, ok := atoms(ilst[8:l])["data"]; ok && len(data) > 8 {
+			value = string(data[8:])
+		}
+		switch names[index] {
+		case "com.apple.quicktime.creationdate":
+			date = value
+		case "com.apple.quicktime.location.ISO6709":
+			location = value
+		}
+		ilst = ilst[l:]
+	}
+	return date, location
+}
+
+// userDataString decodes the QuickTime user data text: its length, its language and the text
+func userDataString(b []byte) string {
+	if len(b) < 4 {
+		return ""
+	}
+	l := int(binary.BigEndian.Uint16(b))
+	if 4+l > len(b) {
+		l = len(b) - 4
+	}
+	return string(b[4 : 4+l])
+}
+
+// parseVideoDate reads the dates like 2023-06-23T15:32:52+0200
+func parseVideoDate(s string) time.Time {
+	s = strings.TrimRight(strings.TrimSpace(s), "\x00")
+	for _, layout := range []string{"2006-01-02T15:04:05-0700", time.RFC3339, "2006-01-02T15:04:05Z0700", "2006-01-02 15:04:05-0700"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// parseISO6709 reads the location like +48.8583+002.2945+035.000/
+func parseISO6709(s string) (latitude float64, longitude float64, altitude float64) {
+	m := iso6709RE.FindStringSubmatch(s)
+	if m == nil {
+		return 0, 0, 0
+	}
+	latitude, _ = strconv.ParseFloat(m[1], 64)
+	longitude, _ = strconv.ParseFloat(m[2], 64)
+	if m[3] != "" {
+		altitude, _ = strconv.ParseFloat(m[3], 64)
+	}
+	return latitude, longitude, altitude
+}
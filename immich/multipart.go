@@ -0,0 +1,73 @@
+package immich
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+)
+
+// multipartBody is a multipart body made of small buffers for the fields and headers,
+// and of the readers of the files. The files are streamed during the upload, never
+// loaded into memory, and the length of the body is known before sending it.
+type multipartBody struct {
+	w       *multipart.Writer
+	current *bytes.Buffer
+	parts   []io.Reader
+	length  int64
+}
+
+func newMultipartBody() *multipartBody {
+	mb := &multipartBody{current: bytes.NewBuffer(nil)}
+	mb.w = multipart.NewWriter(mb)
+	return mb
+}
+
+// Write receives the fields and the part headers from the multipart writer
+func (mb *multipartBody) Write(b []byte) (int, error) {
+	mb.length += int64(len(b))
+	return mb.current.Write(b)
+}
+
+func (mb *multipartBody) WriteField(name, value string) error {
+	return mb.w.WriteField(name, value)
+}
+
+// AddFile adds a part with the content of the reader. The reader must give exactly size bytes.
+func (mb *multipartBody) AddFile(field, fileName, ctype string, r io.Reader, size int64) error {
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Disposition",
+		fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+			escapeQuotes(field), escapeQuotes(fileName)))
+	h.Set("Content-Type", ctype)
+	_, err := mb.w.CreatePart(h)
+	if err != nil {
+		return err
+	}
+	mb.parts = append(mb.parts, mb.current, io.LimitReader(r, size))
+	mb.length += size
+	mb.current = bytes.NewBuffer(nil)
+	return nil
+}
+
+// Close writes the closing boundary
+func (mb *multipartBody) Close() error {
+	err := mb.w.Close()
+	mb.parts = append(mb.parts, mb.current)
+	return err
+}
+
+func (mb *multipartBody) FormDataContentType() string {
+	return mb.w.FormDataContentType()
+}
+
+// Reader gives the body, once closed
+func (mb *multipartBody) Reader() io.Reader {
+	return io.MultiReader(mb.parts...)
+}
+
+// Len gives the length of the body, once closed
+func (mb *multipartBody) Len() int64 {
+	return mb.length
+}
@@ -0,0 +1,98 @@
+package immich
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// ErrNoFaceDetected is returned when the server hasn't detected faces on the asset yet
+var ErrNoFaceDetected = errors.New("no face detected on the asset")
+
+// ErrAmbiguousFaces is returned when the faces of the asset can't be matched with the names
+var ErrAmbiguousFaces = errors.New("the faces can't be matched with the names")
+
+type Person struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	IsHidden bool   `json:"isHidden"`
+}
+
+type AssetFace struct {
+	ID     string  `json:"id"`
+	Person *Person `json:"person"`
+}
+
+func (ic *ImmichClient) GetAllPeople(ctx context.Context) ([]Person, error) {
+	var resp struct {
+		People []Person `json:"people"`
+	}
+	err := ic.newServerCall(ctx, "GetAllPeople").do(get("/person?withHidden=true", setAcceptJSON()), responseJSON(&resp))
+	if err != nil {
+		return nil, err
+	}
+	return resp.People, nil
+}
+
+func (ic *ImmichClient) CreatePerson(ctx context.Context, name string) (Person, error) {
+	body := struct {
+		Name string `json:"name"`
+	}{Name: name}
+	var p Person
+	err := ic.newServerCall(ctx, "CreatePerson").do(post("/person", "application/json", setAcceptJSON(), setJSONBody(body)), responseJSON(&p))
+	return p, err
+}
+
+// AddPeopleToAsset assigns the asset's faces to the given people.
+// Faces already assigned are kept, people already present on the asset are skipped.
+// The names don't tell which face is whose: the remaining person is given to the remaining face only
+// when there is a single one of each. ErrAmbiguousFaces is returned otherwise, and nothing is changed.
+// ErrNoFaceDetected is returned when the server hasn't detected any face yet.
+// The people without ID are created on the server only when they get the face, they are returned with their ID.
+func (ic *ImmichClient) AddPeopleToAsset(ctx context.Context, assetID string, people []Person) ([]Person, error) {
+	var faces []AssetFace
+	err := ic.newServerCall(ctx, "GetAssetFaces").do(get("/face?id="+url.QueryEscape(assetID), setAcceptJSON()), responseJSON(&faces))
+	if err != nil {
+		return nil, err
+	}
+	if len(faces) == 0 {
+		return nil, ErrNoFaceDetected
+	}
+
+	present := map[string]bool{}
+	var free []AssetFace
+	for _, f := range faces {
+		if f.Person != nil {
+			present[f.Person.ID] = true
+		} else {
+			free = append(free, f)
+		}
+	}
+	var missing []Person
+	for _, p := range people {
+		if p.ID == "" || !present[p.ID] {
+			missing = append(missing, p)
+		}
+	}
+	if len(missing) == 0 {
+		return nil, nil
+	}
+	if len(missing) != 1 || len(free) != 1 {
+		return nil, fmt.Errorf("%w: %d name(s) for %d unassigned face(s)", ErrAmbiguousFaces, len(missing), len(free))
+	}
+
+	var created []Person
+	p := missing[0]
+	if p.ID == "" {
+		p, err = ic.CreatePerson(ctx, p.Name)
+		if err != nil {
+			return nil, fmt.Errorf("can't create the person %q: %w", missing[0].Name, err)
+		}
+		created = append(created, p)
+	}
+	body := struct {
+		ID string `json:"id"`
+	}{ID: p.ID}
+	return created, ic.newServerCall(ctx, "ReassignFace").do(put("/face/"+free[0].ID, setAcceptJSON(), setJSONBody(body)))
+}
@@ -0,0 +1,94 @@
+package immich
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// facesServer gives the faces of an asset and records their assignments and the created people
+type facesServer struct {
+	faces    []AssetFace
+	assigned map[string]string // person ID, by face ID
+	created  []string
+}
+
+func (s *facesServer) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	switch {
+	case req.Method == http.MethodPost && req.URL.Path == "/api/person":
+		var p Person
+		_ = json.NewDecoder(req.Body).Decode(&p)
+		s.created = append(s.created, p.Name)
+		p.ID = "new-" + p.Name
+		_ = json.NewEncoder(resp).Encode(p)
+	case req.Method == http.MethodGet && req.URL.Path == "/api/face":
+		_ = json.NewEncoder(resp).Encode(s.faces)
+	case req.Method == http.MethodPut && strings.HasPrefix(req.URL.Path, "/api/face/"):
+		var body struct {
+			ID string `json:"id"`
+		}
+		_ = json.NewDecoder(req.Body).Decode(&body)
+		s.assigned[strings.TrimPrefix(req.URL.Path, "/api/face/")] = body.ID
+		resp.Write([]byte(`{}`))
+	default:
+		resp.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestAddPeopleToAsset(t *testing.T) {
+	alice := Person{ID: "alice", Name: "Alice"}
+	bob := Person{ID: "bob", Name: "Bob"}
+	carol := Person{Name: "Carol"}
+	tests := []struct {
+		name         string
+		faces        []AssetFace
+		people       []Person
+		wantErr      error
+		wantAssigned map[string]string
+		wantCreated  []string
+	}{
+		{name: "no face", people: []Person{alice}, wantErr: ErrNoFaceDetected},
+		{name: "one face, one name", faces: []AssetFace{{ID: "f1"}}, people: []Person{alice}, wantAssigned: map[string]string{"f1": "alice"}},
+		{name: "one free face, one missing name", faces: []AssetFace{{ID: "f1", Person: &alice}, {ID: "f2"}}, people: []Person{alice, bob}, wantAssigned: map[string]string{"f2": "bob"}},
+		{name: "already tagged", faces: []AssetFace{{ID: "f1", Person: &alice}}, people: []Person{alice}},
+		{name: "two faces, two names", faces: []AssetFace{{ID: "f1"}, {ID: "f2"}}, people: []Person{alice, bob}, wantErr: ErrAmbiguousFaces},
+		{name: "two faces, one name", faces: []AssetFace{{ID: "f1"}, {ID: "f2"}}, people: []Person{alice}, wantErr: ErrAmbiguousFaces},
+		{name: "one face, two names", faces: []AssetFace{{ID: "f1"}}, people: []Person{alice, bob}, wantErr: ErrAmbiguousFaces},
+		{name: "new person", faces: []AssetFace{{ID: "f1", Person: &alice}, {ID: "f2"}}, people: []Person{alice, carol}, wantAssigned: map[string]string{"f2": "new-Carol"}, wantCreated: []string{"Carol"}},
+		{name: "new person, no face", people: []Person{carol}, wantErr: ErrNoFaceDetected},
+		{name: "new person, ambiguous", faces: []AssetFace{{ID: "f1"}, {ID: "f2"}}, people: []Person{alice, carol}, wantErr: ErrAmbiguousFaces},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := &facesServer{faces: tt.faces, assigned: map[string]string{}}
+			server := httptest.NewServer(fs)
+			defer server.Close()
+			ic, err := NewImmichClient(server.URL, "1234", false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			created, err := ic.AddPeopleToAsset(context.Background(), "asset", tt.people)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(fs.created, tt.wantCreated) {
+				t.Errorf("created = %v, want %v", fs.created, tt.wantCreated)
+			}
+			if len(created) != len(tt.wantCreated) || (len(created) > 0 && created[0].ID == "") {
+				t.Errorf("returned people = %v, want %v with their ID", created, tt.wantCreated)
+			}
+			want := tt.wantAssigned
+			if want == nil {
+				want = map[string]string{}
+			}
+			if !reflect.DeepEqual(fs.assigned, want) {
+				t.Errorf("assigned = %v, want %v", fs.assigned, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package immich
+
+import (
+	"io"
+
+	"github.com/simulot/immich-go/browser"
+)
+
+// UploadProgressFunc is called during the upload of a file with the number of bytes sent and the file size
+type UploadProgressFunc func(la *browser.LocalAssetFile, sent, size int64)
+
+// progressReader reports the bytes read from the file during its upload
+type progressReader struct {
+	r    io.Reader
+	la   *browser.LocalAssetFile
+	sent int64
+	size int64
+	fn   UploadProgressFunc
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.sent += int64(n)
+	p.fn(p.la, p.sent, p.size)
+	return n, err
+}
@@ -0,0 +1,80 @@
+package immich
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RetryAfter tells if the server, or its proxy, refused the request because of too many requests,
+// and gives the delay asked by the Retry-After header, 0 when not given
+func RetryAfter(err error) (time.Duration, bool) {
+	var ce callError
+	if !errors.As(err, &ce) || ce.status != http.StatusTooManyRequests {
+		return 0, false
+	}
+	return ce.retryAfter, true
+}
+
+// parseRetryAfter reads the Retry-After header, given in seconds or as a date
+func parseRetryAfter(h string, now time.Time) time.Duration {
+	h = strings.TrimSpace(h)
+	if h == "" {
+		return 0
+	}
+	if s, err := strconv.Atoi(h); err == nil {
+		if s < 0 {
+			return 0
+		}
+		return time.Duration(s) * time.Second
+	}
+	if t, err := http.ParseTime(h); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
+
+// rateLimiter spaces the requests to stay under a number of requests per second
+type rateLimiter struct {
+	mut      sync.Mutex
+	interval time.Duration // minimal duration between two requests
+	next     time.Time     // time of the next allowed request
+}
+
+// wait until the next request is allowed
+func (l *rateLimiter) wait(ctx context.Context) error {
+	l.mut.Lock()
+	now := time.Now()
+	at := l.next
+	if at.Before(now) {
+		at = now
+	}
+	l.next = at.Add(l.interval)
+	l.mut.Unlock()
+
+	d := at.Sub(now)
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// SetMaxRequestsPerSecond limits the rate of the server calls, 0 for no limit
+func (ic *ImmichClient) SetMaxRequestsPerSecond(n float64) *ImmichClient {
+	ic.limiter = nil
+	if n > 0 {
+		ic.limiter = &rateLimiter{interval: time.Duration(float64(time.Second) / n)}
+	}
+	return ic
+}
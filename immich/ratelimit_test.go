@@ -0,0 +1,92 @@
+package immich
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	tc := []struct {
+		header string
+		want   time.Duration
+	}{
+		{"", 0},
+		{"120", 2 * time.Minute},
+		{" 3 ", 3 * time.Second},
+		{"-1", 0},
+		{"Fri, 01 Mar 2024 10:00:30 GMT", 30 * time.Second},
+		{"Fri, 01 Mar 2024 09:00:00 GMT", 0},
+		{"soon", 0},
+	}
+	for _, c := range tc {
+		if got := parseRetryAfter(c.header, now); got != c.want {
+			t.Errorf("parseRetryAfter(%q) = %s, want %s", c.header, got, c.want)
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tc := []struct {
+		name      string
+		status    int
+		header    string
+		throttled bool
+		delay     time.Duration
+	}{
+		{name: "429 with Retry-After", status: http.StatusTooManyRequests, header: "5", throttled: true, delay: 5 * time.Second},
+		{name: "429 without Retry-After", status: http.StatusTooManyRequests, throttled: true},
+		{name: "500", status: http.StatusInternalServerError, header: "5"},
+	}
+	for _, c := range tc {
+		t.Run(c.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if c.header != "" {
+					w.Header().Set("Retry-After", c.header)
+				}
+				w.WriteHeader(c.status)
+			}))
+			defer server.Close()
+			ic, err := NewImmichClient(server.URL, "1234", false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = ic.PingServer(context.Background())
+			delay, throttled := RetryAfter(err)
+			if throttled != c.throttled || delay != c.delay {
+				t.Errorf("RetryAfter = %s, %v, want %s, %v", delay, throttled, c.delay, c.throttled)
+			}
+		})
+	}
+}
+
+func TestMaxRequestsPerSecond(t *testing.T) {
+	server := httptest.NewServer(&testServer{responseStatus: http.StatusOK, responseBody: `{"res":"pong"}`})
+	defer server.Close()
+	ic, err := NewImmichClient(server.URL, "1234", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ic.SetMaxRequestsPerSecond(50)
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if err := ic.PingServer(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the first request is sent at once, the next ones every 20ms
+	if d := time.Since(start); d < 60*time.Millisecond {
+		t.Errorf("4 requests sent in %s, expecting at least 60ms", d)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	ic.SetMaxRequestsPerSecond(0.1)
+	ic.limiter.next = time.Now().Add(time.Minute)
+	if err := ic.PingServer(ctx); err == nil {
+		t.Errorf("expecting the cancellation of the call")
+	}
+}
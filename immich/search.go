@@ -0,0 +1,63 @@
+package immich
+
+import (
+	"context"
+	"time"
+)
+
+const searchPageSize = 1000
+
+type searchMetadataQuery struct {
+	TakenAfter   *time.Time `json:"takenAfter,omitempty"`
+	TakenBefore  *time.Time `json:"takenBefore,omitempty"`
+	UpdatedAfter *time.Time `json:"updatedAfter,omitempty"`
+	WithDeleted  bool       `json:"withDeleted,omitempty"`
+	WithExif     bool       `json:"withExif"`
+	Page         int        `json:"page"`
+	Size         int        `json:"size"`
+}
+
+type searchMetadataResponse struct {
+	Assets struct {
+		Items    []*Asset `json:"items"`
+		NextPage *string  `json:"nextPage"`
+	} `json:"assets"`
+}
+
+// GetAssetsTakenBetween calls the filter for each asset taken between after and before, trashed assets included
+func (ic *ImmichClient) GetAssetsTakenBetween(ctx context.Context, after time.Time, before time.Time, filter func(*Asset)) error {
+	return ic.searchMetadata(ctx, "GetAssetsTakenBetween", searchMetadataQuery{
+		TakenAfter:  &after,
+		TakenBefore: &before,
+		WithDeleted: true,
+	}, filter)
+}
+
+// GetAssetsUpdatedAfter calls the filter for each asset updated after the given time, trashed assets included
+func (ic *ImmichClient) GetAssetsUpdatedAfter(ctx context.Context, after time.Time, filter func(*Asset)) error {
+	return ic.searchMetadata(ctx, "GetAssetsUpdatedAfter", searchMetadataQuery{
+		UpdatedAfter: &after,
+		WithDeleted:  true,
+	}, filter)
+}
+
+// searchMetadata gets the result of the search page by page
+func (ic *ImmichClient) searchMetadata(ctx context.Context, api string, q searchMetadataQuery, filter func(*Asset)) error {
+	q.WithExif = true
+	q.Size = searchPageSize
+	for q.Page = 1; ; q.Page++ {
+		var r searchMetadataResponse
+		err := ic.newServerCall(ctx, api).do(
+			post("/search/metadata", "application/json", setAcceptJSON(), setJSONBody(q)),
+			responseJSON(&r))
+		if err != nil {
+			return err
+		}
+		for _, a := range r.Assets.Items {
+			filter(a)
+		}
+		if r.Assets.NextPage == nil || len(r.Assets.Items) == 0 {
+			return nil
+		}
+	}
+}
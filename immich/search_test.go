@@ -0,0 +1,70 @@
+package immich
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// searchServer serves the pages of the search
+type searchServer struct {
+	pages   [][]string // asset IDs by page
+	queries []searchMetadataQuery
+}
+
+func (s *searchServer) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost || req.URL.Path != "/api/search/metadata" {
+		resp.WriteHeader(http.StatusNotFound)
+		return
+	}
+	var q searchMetadataQuery
+	if err := json.NewDecoder(req.Body).Decode(&q); err != nil {
+		resp.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	s.queries = append(s.queries, q)
+	var r searchMetadataResponse
+	if q.Page >= 1 && q.Page <= len(s.pages) {
+		for _, id := range s.pages[q.Page-1] {
+			r.Assets.Items = append(r.Assets.Items, &Asset{ID: id})
+		}
+	}
+	if q.Page < len(s.pages) {
+		next := fmt.Sprint(q.Page + 1)
+		r.Assets.NextPage = &next
+	}
+	_ = json.NewEncoder(resp).Encode(r)
+}
+
+func TestGetAssetsTakenBetween(t *testing.T) {
+	s := &searchServer{pages: [][]string{{"1", "2"}, {"3"}}}
+	server := httptest.NewServer(s)
+	defer server.Close()
+	ic, err := NewImmichClient(server.URL, "1234", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	after := time.Date(2023, 6, 23, 0, 0, 0, 0, time.UTC)
+	before := after.AddDate(0, 0, 1)
+	var ids []string
+	err = ic.GetAssetsTakenBetween(context.Background(), after, before, func(a *Asset) {
+		ids = append(ids, a.ID)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ids, []string{"1", "2", "3"}) {
+		t.Errorf("assets = %v", ids)
+	}
+	if len(s.queries) != 2 {
+		t.Fatalf("expecting 2 queries, got %d", len(s.queries))
+	}
+	if q := s.queries[0]; q.TakenAfter == nil || !q.TakenAfter.Equal(after) || q.TakenBefore == nil || !q.TakenBefore.Equal(before) || !q.WithExif {
+		t.Errorf("unexpected query: %+v", q)
+	}
+}
@@ -0,0 +1,50 @@
+package immich
+
+import (
+	"context"
+	"fmt"
+)
+
+type Tag struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// TagAssetResult is the result of the association of an asset with a tag
+type TagAssetResult struct {
+	AssetID string `json:"assetId"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+func (ic *ImmichClient) GetAllTags(ctx context.Context) ([]Tag, error) {
+	var tags []Tag
+	err := ic.newServerCall(ctx, "GetAllTags").do(get("/tag", setAcceptJSON()), responseJSON(&tags))
+	if err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+func (ic *ImmichClient) CreateTag(ctx context.Context, name string) (Tag, error) {
+	body := struct {
+		Name string `json:"name"`
+		Type string `json:"type"`
+	}{Name: name, Type: "CUSTOM"}
+	var t Tag
+	err := ic.newServerCall(ctx, "CreateTag").do(post("/tag", "application/json", setAcceptJSON(), setJSONBody(body)), responseJSON(&t))
+	return t, err
+}
+
+// TagAssets associates the assets with the tag
+func (ic *ImmichClient) TagAssets(ctx context.Context, tagID string, assets []string) ([]TagAssetResult, error) {
+	body := struct {
+		AssetIDs []string `json:"assetIds"`
+	}{AssetIDs: assets}
+	var r []TagAssetResult
+	err := ic.newServerCall(ctx, "TagAssets").do(
+		put(fmt.Sprintf("/tag/%s/assets", tagID), setAcceptJSON(), setJSONBody(body)),
+		responseJSON(&r))
+	return r, err
+}
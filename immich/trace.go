@@ -29,9 +29,9 @@ func (sb *smartBodyCloser) Read(b []byte) (int, error) {
 func setTraceJSONRequest() serverRequestOption {
 	return func(sc *serverCall, req *http.Request) error {
 		fmt.Println("--------------------")
-		fmt.Println(req.Method, req.URL.String())
+		fmt.Println(req.Method, redactKey(req.URL.String(), sc.ic.key))
 		for h, v := range req.Header {
-			fmt.Println(h, v)
+			fmt.Println(h, redactHeader(h, v))
 		}
 		if req.Body != nil {
 			tr := io.TeeReader(req.Body, os.Stdout)
@@ -60,10 +60,7 @@ func traceRequest(req *http.Request) {
 	u.Host = "***"
 	fmt.Println(req.Method, u.String())
 	for h, vs := range req.Header {
-		if h == "X-Api-Key" {
-			vs = []string{"***"}
-		}
-		fmt.Println(h, ":", strings.Join(vs, ","))
+		fmt.Println(h, ":", strings.Join(redactHeader(h, vs), ","))
 	}
 	if isJSON {
 		fmt.Println("--- JSON BODY ---")
@@ -72,3 +69,26 @@ func traceRequest(req *http.Request) {
 	}
 
 }
+
+// redactKey hides the API key when it appears in s
+func redactKey(s string, key string) string {
+	if key == "" {
+		return s
+	}
+	return strings.ReplaceAll(s, key, "***")
+}
+
+// redactHeader hides the value of the headers carrying the API key or other credentials
+func redactHeader(h string, vs []string) []string {
+	switch http.CanonicalHeaderKey(h) {
+	case "X-Api-Key", "Authorization", "Proxy-Authorization", "Cookie":
+		return []string{"***"}
+	}
+	h = strings.ToLower(h)
+	for _, s := range []string{"secret", "token", "key", "auth", "password", "session", "jwt"} {
+		if strings.Contains(h, s) {
+			return []string{"***"}
+		}
+	}
+	return vs
+}
@@ -0,0 +1,73 @@
+package immich
+
+import (
+	"context"
+	"fmt"
+)
+
+// ServerVersion is the version of the Immich server
+type ServerVersion struct {
+	Major int `json:"major"`
+	Minor int `json:"minor"`
+	Patch int `json:"patch"`
+}
+
+// Range of the server versions known to work with immich-go.
+// The patch level of the maximum version is ignored.
+var (
+	MinServerVersion = ServerVersion{Major: 1, Minor: 82}
+	MaxServerVersion = ServerVersion{Major: 1, Minor: 105}
+)
+
+// StackServerVersion is the first server version able to stack assets
+var StackServerVersion = ServerVersion{Major: 1, Minor: 83}
+
+// JXLServerVersion is the first server version able to process JPEG XL files
+var JXLServerVersion = ServerVersion{Major: 1, Minor: 88}
+
+func (v ServerVersion) String() string {
+	return fmt.Sprintf("v%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// IsZero tells if the version is unknown
+func (v ServerVersion) IsZero() bool {
+	return v == ServerVersion{}
+}
+
+// Compare returns -1, 0 or +1 when v is older, equal or newer than o
+func (v ServerVersion) Compare(o ServerVersion) int {
+	for _, d := range [3]int{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+		switch {
+		case d < 0:
+			return -1
+		case d > 0:
+			return +1
+		}
+	}
+	return 0
+}
+
+// IsSupported tells if the version is in the range of the known versions
+func (v ServerVersion) IsSupported() bool {
+	if v.Compare(MinServerVersion) < 0 {
+		return false
+	}
+	return v.Major < MaxServerVersion.Major || (v.Major == MaxServerVersion.Major && v.Minor <= MaxServerVersion.Minor)
+}
+
+// CanStack tells if the server is able to stack assets
+func (v ServerVersion) CanStack() bool {
+	return v.Compare(StackServerVersion) >= 0
+}
+
+// CanJXL tells if the server is able to process JPEG XL files
+func (v ServerVersion) CanJXL() bool {
+	return v.Compare(JXLServerVersion) >= 0
+}
+
+// GetServerVersion returns the version of the server
+func (ic *ImmichClient) GetServerVersion(ctx context.Context) (ServerVersion, error) {
+	var v ServerVersion
+	err := ic.newServerCall(ctx, "GetServerVersion").do(get("/server-info/version", setAcceptJSON()), responseJSON(&v))
+	return v, err
+}
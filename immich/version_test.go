@@ -0,0 +1,57 @@
+package immich
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestServerVersion(t *testing.T) {
+	tests := []struct {
+		v         ServerVersion
+		supported bool
+		canStack  bool
+		canJXL    bool
+	}{
+		{v: ServerVersion{1, 77, 0}},
+		{v: ServerVersion{1, 82, 1}, supported: true},
+		{v: ServerVersion{1, 83, 0}, supported: true, canStack: true},
+		{v: ServerVersion{1, 88, 0}, supported: true, canStack: true, canJXL: true},
+		{v: ServerVersion{1, 105, 1}, supported: true, canStack: true, canJXL: true},
+		{v: ServerVersion{1, 106, 0}, canStack: true, canJXL: true},
+		{v: ServerVersion{2, 0, 0}, canStack: true, canJXL: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.v.String(), func(t *testing.T) {
+			if got := tt.v.IsSupported(); got != tt.supported {
+				t.Errorf("IsSupported() = %v, want %v", got, tt.supported)
+			}
+			if got := tt.v.CanStack(); got != tt.canStack {
+				t.Errorf("CanStack() = %v, want %v", got, tt.canStack)
+			}
+			if got := tt.v.CanJXL(); got != tt.canJXL {
+				t.Errorf("CanJXL() = %v, want %v", got, tt.canJXL)
+			}
+		})
+	}
+}
+
+func TestGetServerVersion(t *testing.T) {
+	server := httptest.NewServer(&testServer{
+		responseStatus: http.StatusOK,
+		responseBody:   `{"major":1,"minor":94,"patch":1}`,
+	})
+	defer server.Close()
+	ic, err := NewImmichClient(server.URL, "1234", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := ic.GetServerVersion(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != (ServerVersion{1, 94, 1}) {
+		t.Errorf("GetServerVersion() = %s, want v1.94.1", v)
+	}
+}
@@ -0,0 +1,160 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileLog writes the messages into a file, one timestamped line per message.
+// The file is rotated when it reaches the maximum size. The previous files are
+// kept with the suffixes .1, .2...
+type FileLog struct {
+	mut        sync.Mutex
+	name       string
+	level      Level
+	maxSize    int64 // 0 for no rotation
+	maxBackups int
+	f          *os.File
+	size       int64
+	pending    strings.Builder // message being built by MessageContinue
+}
+
+// NewFileLog opens the log file for appending messages up to the given level
+func NewFileLog(name string, level Level, maxSize int64) (*FileLog, error) {
+	l := FileLog{
+		name:       name,
+		level:      level,
+		maxSize:    maxSize,
+		maxBackups: 5,
+	}
+	err := l.open()
+	if err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+func (l *FileLog) open() error {
+	f, err := os.OpenFile(l.name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("can't open the log file: %w", err)
+	}
+	s, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("can't open the log file: %w", err)
+	}
+	l.f = f
+	l.size = s.Size()
+	return nil
+}
+
+// rotate renames the current file with the suffix .1, after shifting the previous ones
+func (l *FileLog) rotate() error {
+	err := l.f.Close()
+	if err != nil {
+		return err
+	}
+	os.Remove(fmt.Sprintf("%s.%d", l.name, l.maxBackups))
+	for i := l.maxBackups - 1; i > 0; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", l.name, i), fmt.Sprintf("%s.%d", l.name, i+1))
+	}
+	err = os.Rename(l.name, l.name+".1")
+	if err != nil {
+		return err
+	}
+	return l.open()
+}
+
+func (l *FileLog) write(level Level, s string) {
+	line := time.Now().Format("2006-01-02T15:04:05.000Z07:00") + " " + fmt.Sprintf("%-7s", strings.ToUpper(level.String())) + " " + strings.TrimRight(s, "\n") + "\n"
+	if l.maxSize > 0 && l.size > 0 && l.size+int64(len(line)) > l.maxSize {
+		if err := l.rotate(); err != nil {
+			fmt.Fprintf(os.Stderr, "can't rotate the log file: %s\n", err)
+		}
+	}
+	if l.f == nil {
+		return
+	}
+	n, _ := l.f.WriteString(line)
+	l.size += int64(n)
+}
+
+func (l *FileLog) Close() error {
+	if l == nil {
+		return nil
+	}
+	l.mut.Lock()
+	defer l.mut.Unlock()
+	if l.f == nil {
+		return nil
+	}
+	err := l.f.Close()
+	l.f = nil
+	return err
+}
+
+func (l *FileLog) Message(level Level, f string, v ...any) {
+	if l == nil || level > l.level {
+		return
+	}
+	l.mut.Lock()
+	defer l.mut.Unlock()
+	l.write(level, fmt.Sprintf(f, v...))
+}
+
+func (l *FileLog) Debug(f string, v ...any)   { l.Message(Debug, f, v...) }
+func (l *FileLog) Info(f string, v ...any)    { l.Message(Info, f, v...) }
+func (l *FileLog) OK(f string, v ...any)      { l.Message(OK, f, v...) }
+func (l *FileLog) Warning(f string, v ...any) { l.Message(Warning, f, v...) }
+func (l *FileLog) Error(f string, v ...any)   { l.Message(Error, f, v...) }
+func (l *FileLog) Fatal(f string, v ...any)   { l.Message(Fatal, f, v...) }
+
+func (l *FileLog) DebugObject(name string, v any) {
+	if l == nil || Debug > l.level {
+		return
+	}
+	if d, ok := v.(DebugObject); ok {
+		v = d.DebugObject()
+	}
+	b := bytes.NewBuffer(nil)
+	enc := json.NewEncoder(b)
+	enc.SetIndent("", " ")
+	err := enc.Encode(v)
+	if err != nil {
+		l.Error("can't display object %s: %s", name, err)
+		return
+	}
+	l.Message(Debug, "%s:\n%s", name, b.String())
+}
+
+// Progress messages are for the screen only
+func (l *FileLog) Progress(level Level, f string, v ...any) {}
+
+func (l *FileLog) MessageContinue(level Level, f string, v ...any) {
+	if l == nil || level > l.level {
+		return
+	}
+	l.mut.Lock()
+	defer l.mut.Unlock()
+	if l.pending.Len() > 0 {
+		l.pending.WriteString(" ")
+	}
+	fmt.Fprintf(&l.pending, f, v...)
+}
+
+func (l *FileLog) MessageTerminate(level Level, f string, v ...any) {
+	if l == nil || level > l.level {
+		return
+	}
+	l.mut.Lock()
+	defer l.mut.Unlock()
+	fmt.Fprintf(&l.pending, f, v...)
+	l.write(level, l.pending.String())
+	l.pending.Reset()
+}
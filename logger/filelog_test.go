@@ -0,0 +1,77 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileLog(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "immich-go.log")
+	l, err := NewFileLog(name, OK, 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Info("not written")
+	for i := 0; i < 10; i++ {
+		l.OK("message %d", i)
+	}
+	l.Error("last message")
+	err = l.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) > 200 {
+		t.Errorf("the log file size is %d, expecting at most 200", len(b))
+	}
+	if !strings.Contains(string(b), " ERROR   last message\n") {
+		t.Errorf("the last message is missing from the log file:\n%s", b)
+	}
+	if _, err = os.Stat(name + ".1"); err != nil {
+		t.Errorf("the log file hasn't been rotated: %s", err)
+	}
+	for _, f := range []string{name, name + ".1", name + ".2"} {
+		b, _ = os.ReadFile(f)
+		if strings.Contains(string(b), "not written") {
+			t.Errorf("the info message is written into %s", f)
+		}
+	}
+}
+
+func TestLogSetFile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "immich-go.log")
+	f, err := NewFileLog(name, Debug, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := NewLogger(Warning, true, false).SetFile(f)
+	l.OK("not written")
+	l.Progress(Error, "not written")
+	l.Warning("warning %d", 1)
+	l.MessageContinue(Error, "part")
+	l.MessageTerminate(Error, ": end")
+	err = l.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := string(b)
+	if strings.Contains(s, "not written") {
+		t.Errorf("a filtered message is written into the log file:\n%s", s)
+	}
+	for _, want := range []string{" WARNING warning 1\n", " ERROR   part: end\n"} {
+		if !strings.Contains(s, want) {
+			t.Errorf("%q is missing from the log file:\n%s", want, s)
+		}
+	}
+}
@@ -1,16 +1,37 @@
 package logger
 
 import (
+	"fmt"
+	"io"
+	"path"
+	"sort"
 	"strings"
 	"sync"
 )
 
 type Journal struct {
-	mut    sync.Mutex
-	counts map[Action]int
+	mut         sync.Mutex
+	counts      map[Action]int
+	byType      map[string]map[Action]int // counts by file extension
+	statsByType bool                      // Report the counts by file extension
+	verbosity   Verbosity                 // Messages given to the Logger
+	out         sync.Mutex                // keeps the entries of an asset together
+	grouped     bool                      // The entries of an asset are given at the end of its scope
+	scopes      map[string][]entry        // Entries waiting for the end of their asset's scope
+	files       map[Action][]string       // Names of the files of the actions given to KeepFiles
+	records     *records                  // Outcome of each file, kept for the CSV report
+	report      ReportFormat              // Serialization of the final report, table by default
+	reportOut   io.Writer                 // Receives the CSV report
 	Logger
 }
 
+// entry is a message kept until the end of the asset's scope
+type entry struct {
+	level Level
+	f     string
+	v     []any
+}
+
 type Action string
 
 const (
@@ -26,6 +47,8 @@ const (
 	STACKED          Action = "Stacked"
 	SERVER_BETTER    Action = "Server's asset is better"
 	ALBUM            Action = "Added to an album"
+	PEOPLE           Action = "Tagged with people"
+	TAGGED           Action = "Tagged"
 	LIVE_PHOTO       Action = "Live photo"
 	FAILED_VIDEO     Action = "Failed video"
 	UNSUPPORTED      Action = "File type not supported"
@@ -34,6 +57,18 @@ const (
 	INFO             Action = "Info"
 	NOT_SELECTED     Action = "Not selected because options"
 	SERVER_ERROR     Action = "Server error"
+	VERIFY_FAILED    Action = "Upload verification failed"
+	SERVER_TRASHED   Action = "Server's asset is trashed"
+	ORPHAN_SIDECAR   Action = "Orphan sidecar"
+)
+
+// Verbosity of the journal
+type Verbosity int
+
+const (
+	Normal  Verbosity = iota
+	Quiet             // only the warnings, the errors and the final report
+	Verbose           // with the dumps of the DebugObject
 )
 
 func NewJournal(log Logger) *Journal {
@@ -41,6 +76,7 @@ func NewJournal(log Logger) *Journal {
 		// files:  map[string]Entries{},
 		Logger: log,
 		counts: map[Action]int{},
+		byType: map[string]map[Action]int{},
 	}
 }
 
@@ -50,35 +86,226 @@ func (j *Journal) AddEntry(file string, action Action, comment ...string) {
 	}
 	c := strings.Join(comment, ", ")
 	if j.Logger != nil {
+		level := Info
 		switch action {
-		case ERROR, SERVER_ERROR:
-			j.Logger.Error("%-25s: %s: %s", action, file, c)
+		case ERROR, SERVER_ERROR, VERIFY_FAILED:
+			level = Error
+		case ORPHAN_SIDECAR:
+			level = Warning
 		case DISCOVERED_FILE:
-			j.Logger.Debug("%-25s: %s: %s", action, file, c)
+			level = Debug
 		case UPLOADED:
-			j.Logger.OK("%-25s: %s: %s", action, file, c)
-		default:
-			j.Logger.Info("%-25s: %s: %s", action, file, c)
+			level = OK
 		}
+		j.entry(file, level, "%-25s: %s: %s", action, file, c)
 	}
+	ext := strings.ToLower(path.Ext(file))
 	j.mut.Lock()
 	j.counts[action] = j.counts[action] + 1
+	if j.byType[ext] == nil {
+		j.byType[ext] = map[Action]int{}
+	}
+	j.byType[ext][action]++
 	if action == UPGRADED {
 		j.counts[UPLOADED]--
+		j.byType[ext][UPLOADED]--
+	}
+	if _, ok := j.files[action]; ok {
+		j.files[action] = append(j.files[action], file)
+	}
+	if j.records != nil {
+		j.records.addEntry(file, action, comment)
 	}
 	j.mut.Unlock()
 }
+
+// KeepFiles makes the journal keep the names of the files of these actions
+func (j *Journal) KeepFiles(actions ...Action) {
+	j.mut.Lock()
+	defer j.mut.Unlock()
+	if j.files == nil {
+		j.files = map[Action][]string{}
+	}
+	for _, a := range actions {
+		j.files[a] = []string{}
+	}
+}
+
+// Files gives the names of the files of the action, when kept
+func (j *Journal) Files(action Action) []string {
+	j.mut.Lock()
+	defer j.mut.Unlock()
+	return append([]string{}, j.files[action]...)
+}
+
+// SetGroupByAsset makes the journal keep the entries of an asset until the end of its scope,
+// to give them as a block when assets are handled concurrently
+func (j *Journal) SetGroupByAsset(flag bool) {
+	j.mut.Lock()
+	defer j.mut.Unlock()
+	j.grouped = flag
+}
+
+// BeginAsset starts the scope of the asset, when the entries are grouped by asset
+func (j *Journal) BeginAsset(file string) {
+	if j == nil {
+		return
+	}
+	j.mut.Lock()
+	defer j.mut.Unlock()
+	if !j.grouped {
+		return
+	}
+	if j.scopes == nil {
+		j.scopes = map[string][]entry{}
+	}
+	j.scopes[file] = []entry{}
+}
+
+// EndAsset ends the scope of the asset and gives its entries without interleaved messages
+func (j *Journal) EndAsset(file string) {
+	if j == nil {
+		return
+	}
+	j.mut.Lock()
+	if !j.grouped {
+		j.mut.Unlock()
+		return
+	}
+	entries := j.scopes[file]
+	delete(j.scopes, file)
+	j.mut.Unlock()
+
+	j.out.Lock()
+	defer j.out.Unlock()
+	for _, e := range entries {
+		if !j.muted(e.level) {
+			j.Logger.Message(e.level, e.f, e.v...)
+		}
+	}
+}
+
+// entry gives the message, or keeps it when the asset is in scope
+func (j *Journal) entry(file string, level Level, f string, v ...any) {
+	j.mut.Lock()
+	if l, ok := j.scopes[file]; ok {
+		j.scopes[file] = append(l, entry{level: level, f: f, v: v})
+		j.mut.Unlock()
+		return
+	}
+	j.mut.Unlock()
+	j.Message(level, f, v...)
+}
+
+// debugSetter is implemented by the loggers having a debug flag
+type debugSetter interface {
+	SetDebugFlag(flag bool)
+}
+
+// SetVerbosity sets the messages given to the Logger. The final report is always given.
+func (j *Journal) SetVerbosity(v Verbosity) {
+	j.verbosity = v
+	if d, ok := j.Logger.(debugSetter); ok && v == Verbose {
+		d.SetDebugFlag(true)
+	}
+}
+
+// muted tells if the messages of this level are suppressed by the quiet mode
+func (j *Journal) muted(level Level) bool {
+	return j.verbosity == Quiet && level > Warning
+}
+
+func (j *Journal) Message(level Level, f string, v ...any) {
+	if !j.muted(level) {
+		j.out.Lock()
+		defer j.out.Unlock()
+		j.Logger.Message(level, f, v...)
+	}
+}
+
+func (j *Journal) Debug(f string, v ...any) { j.Message(Debug, f, v...) }
+func (j *Journal) Info(f string, v ...any)  { j.Message(Info, f, v...) }
+func (j *Journal) OK(f string, v ...any)    { j.Message(OK, f, v...) }
+
+func (j *Journal) Progress(level Level, f string, v ...any) {
+	if !j.muted(level) {
+		j.out.Lock()
+		defer j.out.Unlock()
+		j.Logger.Progress(level, f, v...)
+	}
+}
+
+func (j *Journal) MessageContinue(level Level, f string, v ...any) {
+	if !j.muted(level) {
+		j.out.Lock()
+		defer j.out.Unlock()
+		j.Logger.MessageContinue(level, f, v...)
+	}
+}
+
+func (j *Journal) MessageTerminate(level Level, f string, v ...any) {
+	if !j.muted(level) {
+		j.out.Lock()
+		defer j.out.Unlock()
+		j.Logger.MessageTerminate(level, f, v...)
+	}
+}
+
+// DebugObject dumps the object in verbose mode only
+func (j *Journal) DebugObject(name string, v any) {
+	if j.verbosity == Verbose {
+		j.Logger.DebugObject(name, v)
+	}
+}
+
+// Summary gives a line of the final report, whatever the verbosity
+func (j *Journal) Summary(f string, v ...any) {
+	j.Logger.OK(f, v...)
+}
+
+// SetStatsByType adds to the report the counts by file extension
+func (j *Journal) SetStatsByType(flag bool) {
+	j.statsByType = flag
+}
+
+// CountByType returns the number of entries for the action and the file extension
+func (j *Journal) CountByType(ext string, action Action) int {
+	j.mut.Lock()
+	defer j.mut.Unlock()
+	return j.byType[strings.ToLower(ext)][action]
+}
+
+// Count returns the number of entries for the action
+func (j *Journal) Count(action Action) int {
+	j.mut.Lock()
+	defer j.mut.Unlock()
+	return j.counts[action]
+}
+
 func (j *Journal) Report() {
+	if j.report == ReportCSV {
+		err := j.WriteCSV(j.reportOut)
+		if err != nil {
+			j.Logger.Error("can't write the report: %s", err)
+		}
+		return
+	}
 
-	checkFiles := j.counts[SCANNED_IMAGE] + j.counts[SCANNED_VIDEO] + j.counts[METADATA] + j.counts[UNSUPPORTED] + j.counts[FAILED_VIDEO] + j.counts[DISCARDED]
-	handledFiles := j.counts[NOT_SELECTED] + j.counts[LOCAL_DUPLICATE] + j.counts[SERVER_DUPLICATE] + j.counts[SERVER_BETTER] + j.counts[UPLOADED] + j.counts[UPGRADED] + j.counts[SERVER_ERROR]
+	checkFiles := j.counts[SCANNED_IMAGE] + j.counts[SCANNED_VIDEO] + j.counts[METADATA] + j.counts[UNSUPPORTED] + j.counts[FAILED_VIDEO] + j.counts[DISCARDED] + j.counts[LIVE_PHOTO]
+	handledFiles := j.counts[NOT_SELECTED] + j.counts[LOCAL_DUPLICATE] + j.counts[SERVER_DUPLICATE] + j.counts[SERVER_BETTER] + j.counts[UPLOADED] + j.counts[UPGRADED] + j.counts[SERVER_ERROR] + j.counts[SERVER_TRASHED]
 	j.Logger.OK("Scan of the sources:")
 	j.Logger.OK("%6d files in the input", j.counts[DISCOVERED_FILE])
 	j.Logger.OK("--------------------------------------------------------")
 	j.Logger.OK("%6d photos", j.counts[SCANNED_IMAGE])
 	j.Logger.OK("%6d videos", j.counts[SCANNED_VIDEO])
+	if j.counts[LIVE_PHOTO] > 0 {
+		j.Logger.OK("%6d videos uploaded with their photo as live photos", j.counts[LIVE_PHOTO])
+	}
 	j.Logger.OK("%6d metadata files", j.counts[METADATA])
 	j.Logger.OK("%6d files with metadata", j.counts[ASSOCIATED_META])
+	if j.counts[ORPHAN_SIDECAR] > 0 {
+		j.Logger.OK("%6d metadata files without their media file", j.counts[ORPHAN_SIDECAR])
+	}
 	j.Logger.OK("%6d discarded files", j.counts[DISCARDED])
 	j.Logger.OK("%6d files having a type not supported", j.counts[UNSUPPORTED])
 	j.Logger.OK("%6d discarded files because in folder failed videos", j.counts[FAILED_VIDEO])
@@ -93,7 +320,64 @@ func (j *Journal) Report() {
 	j.Logger.OK("%6d discarded files because duplicated in the input", j.counts[LOCAL_DUPLICATE])
 	j.Logger.OK("%6d discarded files because server has a better image", j.counts[SERVER_BETTER])
 	j.Logger.OK("%6d errors when uploading", j.counts[SERVER_ERROR])
+	if j.counts[SERVER_TRASHED] > 0 {
+		j.Logger.OK("%6d files matching a trashed server's asset", j.counts[SERVER_TRASHED])
+	}
+	if j.counts[VERIFY_FAILED] > 0 {
+		j.Logger.OK("%6d uploads uploaded again after a failed verification", j.counts[VERIFY_FAILED])
+	}
 
 	j.Logger.OK("%6d handled total (difference %d)", handledFiles, j.counts[SCANNED_IMAGE]+j.counts[SCANNED_VIDEO]-handledFiles)
+	if j.statsByType {
+		j.reportByType()
+	}
+}
 
+// reportByType prints the handled files counts by file extension
+func (j *Journal) reportByType() {
+	columns := []struct {
+		title  string
+		action Action
+	}{
+		{"uploaded", UPLOADED},
+		{"upgraded", UPGRADED},
+		{"on server", SERVER_DUPLICATE},
+		{"better", SERVER_BETTER},
+		{"local dup", LOCAL_DUPLICATE},
+		{"options", NOT_SELECTED},
+		{"errors", SERVER_ERROR},
+	}
+
+	exts := []string{}
+	for ext, counts := range j.byType {
+		for _, c := range columns {
+			if counts[c.action] != 0 {
+				exts = append(exts, ext)
+				break
+			}
+		}
+	}
+	if len(exts) == 0 {
+		return
+	}
+	sort.Strings(exts)
+
+	line := "type     "
+	for _, c := range columns {
+		line += fmt.Sprintf(" %9s", c.title)
+	}
+	j.Logger.OK("--------------------------------------------------------")
+	j.Logger.OK("Handled files by type:")
+	j.Logger.OK("%s", line)
+	for _, ext := range exts {
+		name := ext
+		if name == "" {
+			name = "(none)"
+		}
+		line = fmt.Sprintf("%-9s", name)
+		for _, c := range columns {
+			line += fmt.Sprintf(" %9d", j.byType[ext][c.action])
+		}
+		j.Logger.OK("%s", line)
+	}
 }
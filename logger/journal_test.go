@@ -0,0 +1,143 @@
+package logger
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestJournalCountByType(t *testing.T) {
+	j := NewJournal(NoLogger{})
+	j.AddEntry("a/photo.jpg", UPLOADED)
+	j.AddEntry("a/PHOTO2.JPG", UPLOADED)
+	j.AddEntry("a/photo3.jpg", SERVER_DUPLICATE)
+	j.AddEntry("a/raw.dng", UPGRADED) // an upgrade is also journaled as an upload
+	j.AddEntry("a/raw.dng", UPLOADED)
+	j.AddEntry("a/movie.mp4", NOT_SELECTED)
+
+	tc := []struct {
+		ext    string
+		action Action
+		want   int
+	}{
+		{".jpg", UPLOADED, 2},
+		{".JPG", SERVER_DUPLICATE, 1},
+		{".dng", UPGRADED, 1},
+		{".dng", UPLOADED, 0},
+		{".mp4", NOT_SELECTED, 1},
+		{".mp4", UPLOADED, 0},
+	}
+	for _, c := range tc {
+		if got := j.CountByType(c.ext, c.action); got != c.want {
+			t.Errorf("CountByType(%q, %q)=%d, want %d", c.ext, c.action, got, c.want)
+		}
+	}
+}
+
+// recordLogger keeps the messages
+type recordLogger struct {
+	NoLogger
+	messages []string
+	debug    bool
+}
+
+func (r *recordLogger) Message(level Level, f string, v ...any) {
+	r.messages = append(r.messages, level.String())
+}
+func (r *recordLogger) OK(f string, v ...any)       { r.Message(OK, f, v...) }
+func (r *recordLogger) Warning(f string, v ...any)  { r.Message(Warning, f, v...) }
+func (r *recordLogger) DebugObject(n string, v any) { r.Message(Debug, n) }
+func (r *recordLogger) SetDebugFlag(flag bool)      { r.debug = flag }
+
+func TestJournalVerbosity(t *testing.T) {
+	tc := []struct {
+		verbosity Verbosity
+		want      []string
+	}{
+		{Normal, []string{"Info", "OK", "Error", "Warning", "OK"}},
+		{Quiet, []string{"Error", "Warning", "OK"}},
+		{Verbose, []string{"Info", "OK", "Error", "Warning", "Debug", "OK"}},
+	}
+	for _, c := range tc {
+		r := &recordLogger{}
+		j := NewJournal(r)
+		j.SetVerbosity(c.verbosity)
+		j.AddEntry("a/photo.jpg", INFO)
+		j.AddEntry("a/photo.jpg", UPLOADED)
+		j.AddEntry("a/movie.mp4", ERROR)
+		j.Warning("warning")
+		j.DebugObject("asset", nil)
+		j.Summary("report")
+		if strings.Join(r.messages, ",") != strings.Join(c.want, ",") {
+			t.Errorf("verbosity %d: messages %v, want %v", c.verbosity, r.messages, c.want)
+		}
+		if r.debug != (c.verbosity == Verbose) {
+			t.Errorf("verbosity %d: debug flag %v", c.verbosity, r.debug)
+		}
+		if j.Count(INFO) != 1 {
+			t.Errorf("verbosity %d: the entries must be counted", c.verbosity)
+		}
+	}
+}
+
+// textLogger keeps the formatted messages
+type textLogger struct {
+	NoLogger
+	lines []string
+}
+
+func (l *textLogger) Message(level Level, f string, v ...any) {
+	l.lines = append(l.lines, fmt.Sprintf(f, v...))
+}
+
+func TestJournalGroupByAsset(t *testing.T) {
+	tc := []struct {
+		name    string
+		grouped bool
+		want    []string
+	}{
+		{
+			name: "not grouped",
+			want: []string{"a.jpg", "b.jpg", "message", "a.jpg", "b.jpg"},
+		},
+		{
+			name:    "grouped",
+			grouped: true,
+			want:    []string{"message", "b.jpg", "b.jpg", "a.jpg", "a.jpg"},
+		},
+	}
+	for _, c := range tc {
+		t.Run(c.name, func(t *testing.T) {
+			l := &textLogger{}
+			j := NewJournal(l)
+			j.SetGroupByAsset(c.grouped)
+			j.BeginAsset("a.jpg")
+			j.BeginAsset("b.jpg")
+			j.AddEntry("a.jpg", INFO)
+			j.AddEntry("b.jpg", INFO)
+			j.Info("message")
+			j.AddEntry("a.jpg", UPLOADED)
+			j.AddEntry("b.jpg", UPLOADED)
+			j.EndAsset("b.jpg")
+			j.EndAsset("a.jpg")
+
+			got := []string{}
+			for _, line := range l.lines {
+				switch {
+				case strings.Contains(line, "a.jpg"):
+					got = append(got, "a.jpg")
+				case strings.Contains(line, "b.jpg"):
+					got = append(got, "b.jpg")
+				default:
+					got = append(got, line)
+				}
+			}
+			if strings.Join(got, ",") != strings.Join(c.want, ",") {
+				t.Errorf("lines = %v, want %v", got, c.want)
+			}
+			if j.Count(UPLOADED) != 2 {
+				t.Errorf("uploaded = %d, want 2", j.Count(UPLOADED))
+			}
+		})
+	}
+}
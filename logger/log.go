@@ -68,6 +68,7 @@ type Log struct {
 	colorStrings map[Level]string
 	debug        bool
 	out          io.WriteCloser
+	file         *FileLog // when set, receives the messages instead of out
 }
 
 func NewLogger(DisplayLevel Level, noColors bool, debug bool) *Log {
@@ -85,6 +86,9 @@ func NewLogger(DisplayLevel Level, noColors bool, debug bool) *Log {
 }
 
 func (l *Log) Close() error {
+	if l.file != nil {
+		return l.file.Close()
+	}
 	if l.out != os.Stdout {
 		return l.out.Close()
 	}
@@ -121,6 +125,14 @@ func (l *Log) SetWriter(w io.WriteCloser) *Log {
 	return l
 }
 
+// SetFile writes the messages into the file instead of the screen
+func (l *Log) SetFile(f *FileLog) *Log {
+	if l != nil && f != nil {
+		l.file = f
+	}
+	return l
+}
+
 func (l *Log) Debug(f string, v ...any) {
 	if l == nil || l.out == nil {
 		return
@@ -139,6 +151,10 @@ func (l *Log) DebugObject(name string, v any) {
 	if l.out == nil {
 		return
 	}
+	if l.file != nil {
+		l.file.DebugObject(name, v)
+		return
+	}
 	if d, ok := v.(DebugObject); ok {
 		v = d.DebugObject()
 	}
@@ -200,6 +216,10 @@ func (l *Log) Message(level Level, f string, v ...any) {
 	if level > l.displayLevel {
 		return
 	}
+	if l.file != nil {
+		l.file.Message(level, f, v...)
+		return
+	}
 	if l.needCR {
 		fmt.Fprintln(l.out)
 		l.needCR = false
@@ -214,7 +234,7 @@ func (l *Log) Message(level Level, f string, v ...any) {
 }
 
 func (l *Log) Progress(level Level, f string, v ...any) {
-	if l == nil || l.out == nil {
+	if l == nil || l.out == nil || l.file != nil {
 		return
 	}
 	if level > l.displayLevel {
@@ -231,6 +251,10 @@ func (l *Log) MessageContinue(level Level, f string, v ...any) {
 	if level > l.displayLevel {
 		return
 	}
+	if l.file != nil {
+		l.file.MessageContinue(level, f, v...)
+		return
+	}
 	if l.needCR {
 		fmt.Fprintln(l.out)
 		l.needCR = false
@@ -251,6 +275,10 @@ func (l *Log) MessageTerminate(level Level, f string, v ...any) {
 	if level > l.displayLevel {
 		return
 	}
+	if l.file != nil {
+		l.file.MessageTerminate(level, f, v...)
+		return
+	}
 	fmt.Fprint(l.out, l.colorStrings[level])
 	fmt.Fprintf(l.out, f, v...)
 	if !l.noColors {
@@ -0,0 +1,142 @@
+package logger
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ReportFormat is the serialization of the final report
+type ReportFormat string
+
+const (
+	ReportTable ReportFormat = "table" // Counts of the files by outcome, for a human
+	ReportCSV   ReportFormat = "csv"   // One line per file, for a spreadsheet
+)
+
+// ParseReportFormat checks the name of the report format
+func ParseReportFormat(s string) (ReportFormat, error) {
+	switch f := ReportFormat(strings.ToLower(s)); f {
+	case ReportTable, ReportCSV:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown report format %q, expecting table or csv", s)
+}
+
+// Record is the outcome of a file, kept for the CSV report
+type Record struct {
+	File     string   // Path of the file
+	Action   Action   // Last outcome of the file
+	ServerID string   // ID of the server's asset, when known
+	Albums   []string // Albums of the asset
+	Size     int64    // Size of the file
+	Error    string   // Message of the last error
+}
+
+// records keeps the outcome of each file, in the order of their first entry
+type records struct {
+	byFile map[string]*Record
+	order  []*Record
+}
+
+// informative actions don't change the outcome of the file
+var informative = map[Action]bool{
+	DISCOVERED_FILE: true,
+	INFO:            true,
+	ALBUM:           true,
+	TAGGED:          true,
+	PEOPLE:          true,
+	STACKED:         true,
+	ASSOCIATED_META: true,
+	VERIFY_FAILED:   true,
+}
+
+// SetReportFormat sets the serialization of the final report. With the CSV format,
+// the journal keeps a record of each file, and the report is written into w.
+func (j *Journal) SetReportFormat(f ReportFormat, w io.Writer) {
+	j.mut.Lock()
+	defer j.mut.Unlock()
+	j.report = f
+	j.reportOut = w
+	if f == ReportCSV && j.records == nil {
+		j.records = &records{byFile: map[string]*Record{}}
+	}
+}
+
+// record gives the record of the file, created when missing. The journal must be locked.
+func (r *records) record(file string) *Record {
+	rec, ok := r.byFile[file]
+	if !ok {
+		rec = &Record{File: file}
+		r.byFile[file] = rec
+		r.order = append(r.order, rec)
+	}
+	return rec
+}
+
+// addEntry updates the record of the file with the entry. The journal must be locked.
+func (r *records) addEntry(file string, action Action, comment []string) {
+	rec := r.record(file)
+	switch action {
+	case ALBUM:
+		rec.Albums = append(rec.Albums, comment...)
+	case ERROR, SERVER_ERROR:
+		rec.Error = strings.Join(comment, ", ")
+	}
+	if !informative[action] {
+		rec.Action = action
+	}
+}
+
+// RecordAsset gives the ID of the server's asset and the size of the file to its record.
+// Empty values are ignored.
+func (j *Journal) RecordAsset(file string, serverID string, size int64) {
+	if j == nil {
+		return
+	}
+	j.mut.Lock()
+	defer j.mut.Unlock()
+	if j.records == nil {
+		return
+	}
+	rec := j.records.record(file)
+	if serverID != "" {
+		rec.ServerID = serverID
+	}
+	if size > 0 {
+		rec.Size = size
+	}
+}
+
+// Records gives the records of the files, in the order of their first entry
+func (j *Journal) Records() []Record {
+	j.mut.Lock()
+	defer j.mut.Unlock()
+	if j.records == nil {
+		return nil
+	}
+	l := make([]Record, 0, len(j.records.order))
+	for _, r := range j.records.order {
+		c := *r
+		c.Albums = append([]string{}, r.Albums...)
+		l = append(l, c)
+	}
+	return l
+}
+
+// WriteCSV writes the records of the files, with a header line
+func (j *Journal) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"path", "action", "server ID", "albums", "size", "error"})
+	for _, r := range j.Records() {
+		size := ""
+		if r.Size > 0 {
+			size = strconv.FormatInt(r.Size, 10)
+		}
+		_ = cw.Write([]string{r.File, string(r.Action), r.ServerID, strings.Join(r.Albums, "; "), size, r.Error})
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,42 @@
+package logger
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestJournalCSVReport(t *testing.T) {
+	b := bytes.NewBuffer(nil)
+	j := NewJournal(NoLogger{})
+	j.SetReportFormat(ReportCSV, b)
+
+	j.AddEntry("2023/photo.jpg", DISCOVERED_FILE)
+	j.AddEntry("2023/photo.jpg", SCANNED_IMAGE)
+	j.RecordAsset("2023/photo.jpg", "", 1234)
+	j.AddEntry("2023/photo.jpg", UPLOADED, "photo.jpg")
+	j.AddEntry("2023/photo.jpg", ALBUM, "2023", "Trip, Italy")
+	j.RecordAsset("2023/photo.jpg", "id-1", 0)
+	j.AddEntry("2023/movie.mp4", SCANNED_VIDEO)
+	j.RecordAsset("2023/movie.mp4", "", 5678)
+	j.AddEntry("2023/movie.mp4", SERVER_ERROR, "500 Internal Server Error")
+	j.AddEntry("2023/movie.mp4", INFO, "the file can be uploaded again")
+	j.Report()
+
+	want := "path,action,server ID,albums,size,error\n" +
+		"2023/photo.jpg,Uploaded,id-1,\"2023; Trip, Italy\",1234,\n" +
+		"2023/movie.mp4,Server error,,,5678,500 Internal Server Error\n"
+	if got := b.String(); got != want {
+		t.Errorf("report =\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestParseReportFormat(t *testing.T) {
+	for _, s := range []string{"table", "CSV"} {
+		if _, err := ParseReportFormat(s); err != nil {
+			t.Errorf("ParseReportFormat(%q): %s", s, err)
+		}
+	}
+	if _, err := ParseReportFormat("json"); err == nil {
+		t.Error("expected an error for json")
+	}
+}
@@ -5,10 +5,13 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
 	"strings"
+	"time"
 
+	"github.com/simulot/immich-go/cmddeleteduplicates"
 	"github.com/simulot/immich-go/cmdduplicate"
 	"github.com/simulot/immich-go/cmdmetadata"
 	"github.com/simulot/immich-go/cmdstack"
@@ -17,6 +20,7 @@ import (
 	"github.com/simulot/immich-go/helpers/fshelper/myflag"
 	"github.com/simulot/immich-go/helpers/tzone"
 	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/immich/immichtest"
 	"github.com/simulot/immich-go/logger"
 )
 
@@ -41,8 +45,11 @@ func main() {
 
 	go func() {
 		<-signalChannel
-		fmt.Println("\nCtrl+C received. Shutting down...")
+		fmt.Println("\nCtrl+C received. Finishing the current work, press Ctrl+C again to abort...")
 		cancel() // Cancel the context when Ctrl+C is received
+		<-signalChannel
+		fmt.Println("\nCtrl+C received again. Aborting.")
+		os.Exit(1)
 	}()
 
 	select {
@@ -62,6 +69,7 @@ type Application struct {
 	Server      string // Immich server address (http://<your-ip>:2283/api or https://<your-domain>/api)
 	API         string // Immich api endpoint (http://container_ip:3301)
 	Key         string // API Key
+	KeyFile     string // File containing the API Key
 	DeviceUUID  string // Set a device UUID
 	ApiTrace    bool   // Enable API call traces
 	NoLogColors bool   // Disable log colors
@@ -69,10 +77,22 @@ type Application struct {
 	Debug       bool   // Enable the debug mode
 	TimeZone    string // Override default TZ
 	SkipSSL     bool   // Skip SSL Verification
+	Proxy       string // URL of the proxy used for the server calls
+	Simulate    string // Scenario of the fake server used instead of a real one
+
+	Headers http.Header // Headers added to the server calls
 
-	Immich  *immich.ImmichClient // Immich client
-	Logger  *logger.Log          // Program's logger
-	LogFile string               //Log file
+	UploadTimeout time.Duration // Deadline for uploads and asset updates
+	APITimeout    time.Duration // Deadline for other server calls
+
+	MaxRequestsPerSecond float64 // Limit of the server calls rate, 0 for no limit
+	HTTP2                bool    // Allow HTTP/2 with the server
+	MaxIdleConns         int     // Connections kept open with the server, 0 for the default
+
+	Immich     *immich.ImmichClient // Immich client
+	Logger     *logger.Log          // Program's logger
+	LogFile    string               //Log file
+	LogMaxSize myflag.ByteSize      // Size of the LogFile triggering its rotation
 
 }
 
@@ -87,15 +107,36 @@ func Run(ctx context.Context, log *logger.Log) (*logger.Log, error) {
 	app := Application{}
 	flag.StringVar(&app.Server, "server", "", "Immich server address (http://<your-ip>:2283 or https://<your-domain>)")
 	flag.StringVar(&app.API, "api", "", "Immich api endpoint (http://container_ip:3301)")
-	flag.StringVar(&app.Key, "key", "", "API Key")
+	flag.StringVar(&app.Key, "key", "", "API Key, prefer -key-file or the IMMICH_API_KEY environment variable")
+	flag.StringVar(&app.KeyFile, "key-file", "", "Read the API Key from this file")
 	flag.StringVar(&app.DeviceUUID, "device-uuid", deviceID, "Set a device UUID")
 	flag.BoolFunc("no-colors-log", "Disable colors on logs", myflag.BoolFlagFn(&app.NoLogColors, false))
 	flag.StringVar(&app.LogLevel, "log-level", "ok", "Log level (Error|Warning|OK|Info), default OK")
-	flag.StringVar(&app.LogFile, "log-file", "", "Write log messages into the file")
+	flag.StringVar(&app.LogFile, "log-file", "", "Write log messages into the file, one timestamped line per message. The messages are appended to an existing file")
+	flag.Var(&app.LogMaxSize, "log-max-size", "Rotate the -log-file when it reaches this size (ex: 10M). The 5 previous files are kept with the suffixes .1 to .5 (default: no rotation)")
 	flag.BoolFunc("api-trace", "enable api call traces", myflag.BoolFlagFn(&app.ApiTrace, false))
 	flag.BoolFunc("debug", "enable debug messages", myflag.BoolFlagFn(&app.Debug, false))
 	flag.StringVar(&app.TimeZone, "time-zone", "", "Override the system time zone")
 	flag.BoolFunc("skip-verify-ssl", "Skip SSL verification", myflag.BoolFlagFn(&app.SkipSSL, false))
+	flag.BoolFunc("tls-insecure", "Accept any server certificate, like a self-signed one. Same as -skip-verify-ssl", myflag.BoolFlagFn(&app.SkipSSL, false))
+	flag.BoolFunc("http2", "Allow the HTTP/2 protocol with the server, HTTP/1.1 is used otherwise (default FALSE)", myflag.BoolFlagFn(&app.HTTP2, false))
+	flag.IntVar(&app.MaxIdleConns, "max-idle-conns", 0, "Number of connections kept open with the server between the calls (default: Go's default)")
+	flag.DurationVar(&app.UploadTimeout, "upload-timeout", 0, "Deadline for each upload and asset update, like 10m (default: no deadline)")
+	flag.DurationVar(&app.APITimeout, "api-timeout", 0, "Deadline for other server calls, like 1m (default: no deadline)")
+	flag.Func("header", "Add the header name=value to all server calls, like the ones required by an authenticating proxy. Can be repeated", func(s string) error {
+		name, value, err := parseHeader(s)
+		if err != nil {
+			return err
+		}
+		if app.Headers == nil {
+			app.Headers = http.Header{}
+		}
+		app.Headers.Add(name, value)
+		return nil
+	})
+	flag.Float64Var(&app.MaxRequestsPerSecond, "max-requests-per-second", 0, "Limit the rate of the server calls, like 5, to stay under the threshold of a rate-limiting proxy (default: no limit)")
+	flag.StringVar(&app.Proxy, "proxy", "", "Send the server calls through this proxy, like http://proxy:3128 (default: no proxy)")
+	flag.StringVar(&app.Simulate, "simulate-server", "", "Run the upload against a fake server whose content is given by this JSON scenario, without a real server")
 	flag.Parse()
 
 	app.Server = strings.TrimSuffix(app.Server, "/")
@@ -106,22 +147,28 @@ func Run(ctx context.Context, log *logger.Log) (*logger.Log, error) {
 	}
 
 	if len(app.LogFile) > 0 {
-		flog, err := os.Create(app.LogFile)
+		// the messages are filtered by the -log-level of the logger
+		flog, err := logger.NewFileLog(app.LogFile, logger.Debug, int64(app.LogMaxSize))
 		if err != nil {
-			return log, fmt.Errorf("can't open the log file: %w", err)
+			return log, err
 		}
-		log.SetWriter(flog)
+		log.SetFile(flog)
 		log.OK("immich-go  %s, commit %s, built at %s\n", version, commit, date)
 	}
 
 	switch {
+	case app.Simulate != "":
 	case len(app.Server) == 0 && len(app.API) == 0:
 		err = errors.Join(err, errors.New("missing -server, Immich server address (http://<your-ip>:2283 or https://<your-domain>)"))
 	case len(app.Server) > 0 && len(app.API) > 0:
 		err = errors.Join(err, errors.New("give either the -server or the -api option"))
 	}
-	if len(app.Key) == 0 {
-		err = errors.Join(err, errors.New("missing -key"))
+	if app.Simulate == "" {
+		key, e := apiKey(app.Key, app.KeyFile)
+		if e != nil {
+			err = errors.Join(err, e)
+		}
+		app.Key = key
 	}
 
 	logLevel, e := logger.StringToLevel(app.LogLevel)
@@ -130,7 +177,7 @@ func Run(ctx context.Context, log *logger.Log) (*logger.Log, error) {
 	}
 
 	if len(flag.Args()) == 0 {
-		err = errors.Join(err, errors.New("missing command upload|duplicate|stack"))
+		err = errors.Join(err, errors.New("missing command upload|duplicate|delete-duplicates|stack"))
 	}
 
 	log.SetLevel(logLevel)
@@ -143,6 +190,10 @@ func Run(ctx context.Context, log *logger.Log) (*logger.Log, error) {
 		return app.Logger, err
 	}
 
+	if app.Simulate != "" {
+		return app.Logger, app.runSimulated(ctx)
+	}
+
 	app.Immich, err = immich.NewImmichClient(app.Server, app.Key, app.SkipSSL)
 	if err != nil {
 		return app.Logger, err
@@ -153,25 +204,50 @@ func Run(ctx context.Context, log *logger.Log) (*logger.Log, error) {
 	if app.ApiTrace {
 		app.Immich.EnableAppTrace(true)
 	}
-
-	err = app.Immich.PingServer(ctx)
+	app.Immich.SetTimeouts(app.UploadTimeout, app.APITimeout)
+	app.Immich.SetHeaders(app.Headers)
+	app.Immich.SetMaxRequestsPerSecond(app.MaxRequestsPerSecond)
+	if app.SkipSSL {
+		app.Logger.Warning("TLS certificate verification is DISABLED: the connection to the server can be intercepted. Use it only with a self-signed certificate you trust.")
+	}
+	err = app.Immich.SetHTTP2(app.HTTP2)
 	if err != nil {
 		return app.Logger, err
 	}
-	app.Logger.OK("Server status: OK")
+	err = app.Immich.SetMaxIdleConns(app.MaxIdleConns)
+	if err != nil {
+		return app.Logger, err
+	}
+	if app.Proxy != "" {
+		err = app.Immich.SetProxy(app.Proxy)
+		if err != nil {
+			return app.Logger, err
+		}
+	}
 
-	user, err := app.Immich.ValidateConnection(ctx)
+	err = app.Immich.PingServer(ctx)
 	if err != nil {
 		return app.Logger, err
 	}
-	app.Logger.Info("Connected, user: %s", user.Email)
+	app.Logger.OK("Server status: OK")
 
 	cmd := flag.Args()[0]
+	if cmd != "upload" {
+		// the upload command checks the user itself, with its storage quota
+		user, err := app.Immich.ValidateConnection(ctx)
+		if err != nil {
+			return app.Logger, err
+		}
+		app.Logger.Info("Connected, user: %s", user.Email)
+	}
+
 	switch cmd {
 	case "upload":
 		err = cmdupload.UploadCommand(ctx, app.Immich, app.Logger, flag.Args()[1:])
 	case "duplicate":
 		err = cmdduplicate.DuplicateCommand(ctx, app.Immich, app.Logger, flag.Args()[1:])
+	case "delete-duplicates":
+		err = cmddeleteduplicates.DeleteDuplicatesCommand(ctx, app.Immich, app.Logger, flag.Args()[1:])
 	case "metadata":
 		err = cmdmetadata.MetadataCommand(ctx, app.Immich, app.Logger, flag.Args()[1:])
 	case "stack":
@@ -183,3 +259,58 @@ func Run(ctx context.Context, log *logger.Log) (*logger.Log, error) {
 	}
 	return app.Logger, err
 }
+
+// apiKey returns the API Key read from the -key-file, or from the IMMICH_API_KEY environment variable,
+// or given with -key, in this order. The key given on the command line is visible in the process list.
+func apiKey(flagKey string, keyFile string) (string, error) {
+	if keyFile != "" {
+		b, err := os.ReadFile(keyFile)
+		if err != nil {
+			return "", fmt.Errorf("can't read the -key-file: %w", err)
+		}
+		key := strings.TrimSpace(string(b))
+		if key == "" {
+			return "", fmt.Errorf("the -key-file %s is empty", keyFile)
+		}
+		return key, nil
+	}
+	if key := strings.TrimSpace(os.Getenv("IMMICH_API_KEY")); key != "" {
+		return key, nil
+	}
+	if flagKey != "" {
+		return flagKey, nil
+	}
+	return "", errors.New("missing API Key: give it with -key-file, the IMMICH_API_KEY environment variable or -key")
+}
+
+// parseHeader splits a header given as name=value.
+// The value isn't shown in the error, as it's usually a secret.
+func parseHeader(s string) (string, string, error) {
+	name, value, ok := strings.Cut(s, "=")
+	name = strings.TrimSpace(name)
+	switch {
+	case !ok || name == "":
+		return "", "", errors.New("invalid header, expecting name=value")
+	case strings.ContainsAny(name, " :\t\r\n") || strings.ContainsAny(value, "\r\n"):
+		return "", "", fmt.Errorf("invalid header %q, expecting name=value", name)
+	}
+	return name, strings.TrimSpace(value), nil
+}
+
+// runSimulated runs the upload against the fake server of the scenario, to test a command line without a server
+func (app *Application) runSimulated(ctx context.Context) error {
+	s, err := immichtest.ReadScenarioFile(app.Simulate)
+	if err != nil {
+		return err
+	}
+	srv := immichtest.NewFakeServer(s)
+	app.Logger.Warning("Simulated server: nothing is sent to a real server")
+	cmd := flag.Args()[0]
+	switch cmd {
+	case "upload":
+		return cmdupload.UploadCommand(ctx, srv, app.Logger, flag.Args()[1:])
+	case "delete-duplicates":
+		return cmddeleteduplicates.DeleteDuplicatesCommand(ctx, srv, app.Logger, flag.Args()[1:])
+	}
+	return fmt.Errorf("the command %q can't run with -simulate-server", cmd)
+}
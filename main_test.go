@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAPIKey(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "key")
+	if err := os.WriteFile(keyFile, []byte("file-key\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	emptyFile := filepath.Join(dir, "empty")
+	if err := os.WriteFile(emptyFile, []byte("\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		flagKey string
+		keyFile string
+		env     string
+		want    string
+		wantErr bool
+	}{
+		{name: "flag", flagKey: "flag-key", want: "flag-key"},
+		{name: "env over flag", flagKey: "flag-key", env: "env-key", want: "env-key"},
+		{name: "file over env", flagKey: "flag-key", env: "env-key", keyFile: keyFile, want: "file-key"},
+		{name: "missing file", keyFile: filepath.Join(dir, "missing"), wantErr: true},
+		{name: "empty file", keyFile: emptyFile, wantErr: true},
+		{name: "no key", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("IMMICH_API_KEY", tt.env)
+			got, err := apiKey(tt.flagKey, tt.keyFile)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("apiKey() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("apiKey() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseHeader(t *testing.T) {
+	tests := []struct {
+		in        string
+		wantName  string
+		wantValue string
+		wantErr   bool
+	}{
+		{in: "CF-Access-Client-Id=abc.access", wantName: "CF-Access-Client-Id", wantValue: "abc.access"},
+		{in: "X-Token = a=b ", wantName: "X-Token", wantValue: "a=b"},
+		{in: "X-Empty=", wantName: "X-Empty"},
+		{in: "X-Token: secret", wantErr: true},
+		{in: "=secret", wantErr: true},
+		{in: "X-Token=a\nb", wantErr: true},
+	}
+	for _, tt := range tests {
+		name, value, err := parseHeader(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseHeader(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			if strings.Contains(err.Error(), "secret") {
+				t.Errorf("parseHeader(%q) error shows the value: %s", tt.in, err)
+			}
+			continue
+		}
+		if name != tt.wantName || value != tt.wantValue {
+			t.Errorf("parseHeader(%q) = %q, %q, want %q, %q", tt.in, name, value, tt.wantName, tt.wantValue)
+		}
+	}
+}
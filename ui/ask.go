@@ -22,8 +22,13 @@ func ConfirmYesNo(ctx context.Context, prompt string, defaultAnswer string) (str
 	runeChan := make(chan (rune))
 
 	go func() {
+		defer close(runeChan)
 		for {
-			r, _, _ := reader.ReadRune()
+			r, _, err := reader.ReadRune()
+			if err != nil {
+				// no more input, like a closed stdin
+				return
+			}
 			select {
 			case runeChan <- r:
 			case <-ctx.Done():
@@ -35,7 +40,10 @@ func ConfirmYesNo(ctx context.Context, prompt string, defaultAnswer string) (str
 	for {
 		fmt.Printf("%s [%s]/%s: ", prompt, defaultAnswer, other)
 		select {
-		case r := <-runeChan:
+		case r, ok := <-runeChan:
+			if !ok {
+				return defaultAnswer, nil
+			}
 			userInput := strings.ToLower(string(r))
 			switch userInput {
 			case "":